@@ -0,0 +1,141 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"path"
+	"strings"
+)
+
+// WithDeniedFiles refuses to serve files matching any of the specified glob
+// patterns, answering with 404 instead, even if such files are present in the
+// fs.FS. This guards against accidentally shipping files that never should be
+// served, such as source maps or environment files:
+//
+//	WithDeniedFiles("*.map", ".env*", "*.ts")
+//
+// Patterns without a slash “/” are matched against the last element of the
+// request path, while patterns with a slash are matched against the full
+// (unrooted) request path. The pattern syntax is that of path.Match; malformed
+// patterns never match.
+func WithDeniedFiles(globs ...string) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		for _, glob := range globs {
+			if glob == "" {
+				continue
+			}
+			h.deniedFiles = append(h.deniedFiles, strings.TrimPrefix(glob, "/"))
+		}
+	}
+}
+
+// isDenied returns true if the specified request path must never be served.
+//
+// IMPORTANT: the passed uripath must have already been sanitized.
+func (h *SPAHandler) isDenied(uripath string) bool {
+	if uripath == "/" {
+		return false
+	}
+	if h.blockDotfiles && h.isHidden(uripath) {
+		return true
+	}
+	name := uripath[1:]
+	base := path.Base(uripath)
+	for _, glob := range h.deniedFiles {
+		subject := base
+		if strings.Contains(glob, "/") {
+			subject = name
+		}
+		if matched, _ := path.Match(glob, subject); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// WithAllowedExtensions serves only static assets with the specified file
+// extensions, such as ".js", ".css", and ".png". Requests for all other files
+// are treated as if the files were missing, so these requests fall back to the
+// index or get answered with 404, depending on the other options. This is
+// useful for locked-down appliance deployments where only known static asset
+// types should ever be served directly.
+//
+// Specifying WithAllowedExtensions multiple times adds to the allowed
+// extensions. An empty string allows files without any extension.
+func WithAllowedExtensions(exts ...string) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		for _, ext := range exts {
+			if ext != "" && !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			h.allowedExts = append(h.allowedExts, strings.ToLower(ext))
+		}
+		if h.allowedExts == nil {
+			h.allowedExts = []string{} // ...nothing allowed at all.
+		}
+	}
+}
+
+// isAllowedExtension returns true if the specified (unrooted) file name has an
+// allowed extension, or if there are no extension restrictions.
+func (h *SPAHandler) isAllowedExtension(name string) bool {
+	if h.allowedExts == nil {
+		return true
+	}
+	ext := strings.ToLower(path.Ext(name))
+	for _, allowed := range h.allowedExts {
+		if ext == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// WithBlockedDotfiles refuses requests whose path contains an element beginning
+// with a dot “.”, such as “/.git/config” or “/.env”, answering them with 404.
+// The exceptions specify names of dotfiles and hidden directories that
+// nevertheless are allowed, such as ".well-known".
+//
+// Especially deployments serving from an os.DirFS otherwise can easily leak
+// such files.
+func WithBlockedDotfiles(exceptions ...string) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.blockDotfiles = true
+		h.dotfileExceptions = append(h.dotfileExceptions, exceptions...)
+	}
+}
+
+// isHidden returns true if any element of the specified request path is a
+// dotfile or hidden directory that isn't explicitly excepted.
+//
+// IMPORTANT: the passed uripath must have already been sanitized.
+func (h *SPAHandler) isHidden(uripath string) bool {
+	for _, element := range strings.Split(uripath[1:], "/") {
+		if !strings.HasPrefix(element, ".") {
+			continue
+		}
+		excepted := false
+		for _, exception := range h.dotfileExceptions {
+			if element == exception {
+				excepted = true
+				break
+			}
+		}
+		if !excepted {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("file access", func() {
+
+	serve := func(h http.Handler, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	DescribeTable("denies serving files",
+		func(path string, expectedStatus int) {
+			h := NewSPAHandler(embStaticFs, "index.html",
+				WithDeniedFiles("*.map", ".env*", "", "/static/js/*.ts"))
+			Expect(serve(h, path).Code).To(Equal(expectedStatus))
+		},
+		Entry("allowed asset", "/static/js/some.js", http.StatusOK),
+		Entry("allowed stylesheet", "/static/css/some.css", http.StatusOK),
+		Entry("source map", "/static/js/some.js.map", http.StatusNotFound),
+		Entry("environment file", "/.env.production", http.StatusNotFound),
+		Entry("TypeScript source", "/static/js/some.ts", http.StatusNotFound),
+		Entry("route", "/some/route", http.StatusOK),
+		Entry("root", "/", http.StatusOK),
+	)
+
+	DescribeTable("allows serving only files with specific extensions",
+		func(opts []SPAHandlerOption, path string, expectedStatus int, expectedCanary string) {
+			h := NewSPAHandler(embStaticFs, "index.html", opts...)
+			w := serve(h, path)
+			Expect(w.Code).To(Equal(expectedStatus))
+			Expect(w.Body.String()).To(ContainSubstring(expectedCanary))
+		},
+		Entry("allowed extension", []SPAHandlerOption{WithAllowedExtensions(".js", "CSS")},
+			"/static/css/some.css", http.StatusOK, "CANARY CSS"),
+		Entry("disallowed extension falls back", []SPAHandlerOption{WithAllowedExtensions(".js")},
+			"/static/css/some.css", http.StatusOK, "CANARY INDEX"),
+		Entry("disallowed extension gets 404", []SPAHandlerOption{WithAllowedExtensions(".js"), WithAssetNotFound()},
+			"/static/css/some.css", http.StatusNotFound, ""),
+		Entry("nothing allowed", []SPAHandlerOption{WithAllowedExtensions()},
+			"/static/js/some.js", http.StatusOK, "CANARY INDEX"),
+		Entry("index still served", []SPAHandlerOption{WithAllowedExtensions(".js")},
+			"/", http.StatusOK, "CANARY INDEX"),
+	)
+
+	DescribeTable("blocks dotfiles and hidden directories",
+		func(path string, expectedStatus int) {
+			h := NewSPAHandler(embStaticFs, "index.html",
+				WithBlockedDotfiles(".well-known"))
+			Expect(serve(h, path).Code).To(Equal(expectedStatus))
+		},
+		Entry("regular asset", "/static/js/some.js", http.StatusOK),
+		Entry("dotfile", "/.env.production", http.StatusNotFound),
+		Entry("hidden directory", "/.git/config", http.StatusNotFound),
+		Entry("nested hidden directory", "/static/.cache/foo", http.StatusNotFound),
+		Entry("excepted hidden directory", "/.well-known/foo", http.StatusOK),
+		Entry("route", "/some/route", http.StatusOK),
+	)
+
+	It("serves dotfiles unless blocked", func() {
+		h := NewSPAHandler(embStaticFs, "index.html")
+		w := serve(h, "/.env.production")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(ContainSubstring("SECRET"))
+	})
+
+})
@@ -0,0 +1,85 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd socket
+// activation.
+const listenFDsStart = 3
+
+// ActivatedListener returns the listener passed by systemd socket activation,
+// or nil if the process hasn't been socket-activated. Socket activation allows
+// running spaserve on privileged ports without binding privileges, as well as
+// starting spaserve on demand:
+//
+//	l, err := spaserve.ActivatedListener()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	var opts []spaserve.ServeOption
+//	if l != nil {
+//	    opts = append(opts, spaserve.WithListener(l))
+//	}
+//	err = spaserve.Serve(ctx, ":8080", handler, opts...)
+//
+// Only a single passed socket is supported. ActivatedListener unsets the
+// LISTEN_PID, LISTEN_FDS, and LISTEN_FDNAMES environment variables, so they
+// aren't inherited by child processes.
+func ActivatedListener() (net.Listener, error) {
+	defer func() {
+		for _, name := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+			_ = os.Unsetenv(name)
+		}
+	}()
+	return activatedListener(os.Getenv, os.Getpid(), listenFDsStart)
+}
+
+// activatedListener returns the listener on the specified file descriptor if
+// the environment variables, as returned by getenv, indicate that the process
+// with the specified PID has been passed a single socket. Otherwise, it
+// returns nil.
+func activatedListener(getenv func(string) string, pid int, fd int) (net.Listener, error) {
+	listenPID := getenv("LISTEN_PID")
+	if listenPID == "" {
+		return nil, nil
+	}
+	if p, err := strconv.Atoi(listenPID); err != nil || p != pid {
+		return nil, nil // ...not meant for us.
+	}
+	fds, err := strconv.Atoi(getenv("LISTEN_FDS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LISTEN_FDS: %w", err)
+	}
+	switch {
+	case fds == 0:
+		return nil, nil
+	case fds > 1:
+		return nil, errors.New("socket activation with multiple sockets is unsupported")
+	}
+	f := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+	defer f.Close()
+	l, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid socket-activated listener: %w", err)
+	}
+	return l, nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("socket activation", func() {
+
+	env := func(vars map[string]string) func(string) string {
+		return func(name string) string { return vars[name] }
+	}
+
+	pid := strconv.Itoa(os.Getpid())
+
+	It("returns the socket-activated listener", func() {
+		l := Successful(net.Listen("tcp", "127.0.0.1:0"))
+		defer l.Close()
+		f := Successful(l.(*net.TCPListener).File())
+		defer f.Close()
+		// activatedListener takes ownership of the file descriptor passed.
+		al := Successful(activatedListener(env(map[string]string{
+			"LISTEN_PID": pid,
+			"LISTEN_FDS": "1",
+		}), os.Getpid(), Successful(syscall.Dup(int(f.Fd())))))
+		Expect(al).NotTo(BeNil())
+		defer al.Close()
+		Expect(al.Addr().String()).To(Equal(l.Addr().String()))
+	})
+
+	DescribeTable("not socket-activated",
+		func(vars map[string]string) {
+			Expect(activatedListener(env(vars), os.Getpid(), -1)).To(BeNil())
+		},
+		Entry("no environment", nil),
+		Entry("other process", map[string]string{"LISTEN_PID": "1", "LISTEN_FDS": "1"}),
+		Entry("invalid PID", map[string]string{"LISTEN_PID": "foo", "LISTEN_FDS": "1"}),
+		Entry("no sockets", map[string]string{"LISTEN_PID": pid, "LISTEN_FDS": "0"}),
+	)
+
+	DescribeTable("rejects invalid socket activations",
+		func(fds string, fd int) {
+			Expect(activatedListener(env(map[string]string{
+				"LISTEN_PID": pid,
+				"LISTEN_FDS": fds,
+			}), os.Getpid(), fd)).Error().To(HaveOccurred())
+		},
+		Entry("invalid number of sockets", "foo", -1),
+		Entry("multiple sockets", "2", -1),
+	)
+
+	It("rejects non-sockets", func() {
+		f := Successful(os.Open(os.DevNull))
+		defer f.Close()
+		Expect(activatedListener(env(map[string]string{
+			"LISTEN_PID": pid,
+			"LISTEN_FDS": "1",
+		}), os.Getpid(), Successful(syscall.Dup(int(f.Fd()))))).Error().To(HaveOccurred())
+	})
+
+	It("unsets the environment", func() {
+		GinkgoT().Setenv("LISTEN_PID", "1")
+		GinkgoT().Setenv("LISTEN_FDS", "1")
+		Expect(ActivatedListener()).To(BeNil())
+		_, ok := os.LookupEnv("LISTEN_PID")
+		Expect(ok).To(BeFalse())
+	})
+
+})
@@ -0,0 +1,188 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// DefaultMaxArchiveSize is the default maximum total uncompressed size of the
+// files in an archive loaded by ArchiveFS.
+const DefaultMaxArchiveSize = 1 << 30
+
+// ErrArchiveTooLarge signals that the files in an archive exceed the maximum
+// total uncompressed size.
+var ErrArchiveTooLarge = errors.New("archive exceeds maximum uncompressed size")
+
+// ArchiveOption configures loading archives using ArchiveFS.
+type ArchiveOption func(*archiveLimit)
+
+// archiveLimit tracks the remaining uncompressed size of archive files that
+// still can be loaded into memory.
+type archiveLimit struct {
+	remaining int64
+}
+
+// WithMaxArchiveSize sets the maximum total uncompressed size of the files in
+// an archive, defaulting to DefaultMaxArchiveSize. Non-positive sizes select
+// the default.
+func WithMaxArchiveSize(size int64) ArchiveOption {
+	return func(l *archiveLimit) {
+		if size > 0 {
+			l.remaining = size
+		}
+	}
+}
+
+// ArchiveFS returns a read-only fs.FS with the contents of the specified zip,
+// tar, or gzip'ed tar archive, loading all files into memory. The archive
+// format is detected automatically. File modification times are taken from the
+// archive entries, so that Last-Modified headers reflect the bundle's build
+// time and not the time the archive was loaded.
+//
+// Entries with invalid paths, such as trying to escape the archive's root, as
+// well as symbolic links and other special tar entries are skipped. If the SPA
+// bundle is inside a top-level directory of the archive, use [fs.Sub] on the
+// returned fs.FS.
+//
+// As compressed archives might decompress to huge sizes, ArchiveFS fails with
+// an error wrapping ErrArchiveTooLarge when the files exceed a maximum total
+// uncompressed size, see WithMaxArchiveSize.
+func ArchiveFS(r io.ReaderAt, size int64, opts ...ArchiveOption) (fs.FS, error) {
+	limit := &archiveLimit{remaining: DefaultMaxArchiveSize}
+	for _, opt := range opts {
+		opt(limit)
+	}
+	magic := make([]byte, 2)
+	if _, err := r.ReadAt(magic, 0); err != nil {
+		return nil, fmt.Errorf("cannot read archive, %w", err)
+	}
+	switch {
+	case bytes.Equal(magic, []byte("PK")):
+		return zipFS(r, size, limit)
+	case bytes.Equal(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(io.NewSectionReader(r, 0, size))
+		if err != nil {
+			return nil, fmt.Errorf("cannot read gzip'ed archive, %w", err)
+		}
+		defer gz.Close()
+		return tarFS(gz, limit)
+	default:
+		return tarFS(io.NewSectionReader(r, 0, size), limit)
+	}
+}
+
+// read reads the contents of an archive file, failing with ErrArchiveTooLarge
+// when exceeding the remaining uncompressed size.
+func (l *archiveLimit) read(r io.Reader) ([]byte, error) {
+	contents, err := io.ReadAll(io.LimitReader(r, l.remaining+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(contents)) > l.remaining {
+		return nil, ErrArchiveTooLarge
+	}
+	l.remaining -= int64(len(contents))
+	return contents, nil
+}
+
+// zipFS returns an in-memory fs.FS with the contents of a zip archive.
+func zipFS(r io.ReaderAt, size int64, limit *archiveLimit) (fs.FS, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read zip archive, %w", err)
+	}
+	m := newMemFS()
+	for _, f := range zr.File {
+		name, ok := cleanArchivePath(f.Name)
+		if !ok {
+			continue
+		}
+		if f.FileInfo().IsDir() {
+			m.add(name, nil, f.Modified, true)
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read %q from zip archive, %w", f.Name, err)
+		}
+		contents, err := limit.read(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read %q from zip archive, %w", f.Name, err)
+		}
+		m.add(name, contents, f.Modified, false)
+	}
+	return m, nil
+}
+
+// tarFS returns an in-memory fs.FS with the contents of a tar archive.
+func tarFS(r io.Reader, limit *archiveLimit) (fs.FS, error) {
+	tr := tar.NewReader(r)
+	m := newMemFS()
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return m, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot read tar archive, %w", err)
+		}
+		name, ok := cleanArchivePath(hdr.Name)
+		if !ok {
+			continue
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			m.add(name, nil, hdr.ModTime, true)
+		case tar.TypeReg:
+			contents, err := limit.read(tr)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read %q from tar archive, %w", hdr.Name, err)
+			}
+			m.add(name, contents, hdr.ModTime, false)
+		}
+	}
+}
+
+// NewSPAHandlerFromArchive returns a new SPA handler serving the SPA bundle
+// from the zip, tar, or gzip'ed tar archive with the specified file name. See
+// [ArchiveFS] for details.
+func NewSPAHandlerFromArchive(name string, index string, opts ...SPAHandlerOption) (*SPAHandler, error) {
+	contents, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return NewSPAHandlerFromArchiveReader(bytes.NewReader(contents), int64(len(contents)), index, opts...)
+}
+
+// NewSPAHandlerFromArchiveReader returns a new SPA handler serving the SPA
+// bundle from the zip, tar, or gzip'ed tar archive read from r. See
+// [ArchiveFS] for details.
+func NewSPAHandlerFromArchiveReader(r io.ReaderAt, size int64, index string, opts ...SPAHandlerOption) (*SPAHandler, error) {
+	fsys, err := ArchiveFS(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return NewSPAHandler(fsys, index, opts...), nil
+}
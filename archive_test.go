@@ -0,0 +1,152 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var archiveModTime = time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+
+var archiveFiles = map[string]string{
+	"index.html":    `<html><head><base href="./" /></head></html>`,
+	"assets/app.js": "console.log('app');",
+}
+
+func zipArchive() []byte {
+	GinkgoHelper()
+	var buff bytes.Buffer
+	zw := zip.NewWriter(&buff)
+	for name, contents := range archiveFiles {
+		w := Successful(zw.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: archiveModTime,
+		}))
+		Expect(w.Write([]byte(contents))).Error().NotTo(HaveOccurred())
+	}
+	Expect(zw.Close()).To(Succeed())
+	return buff.Bytes()
+}
+
+func tarArchive(compressed bool) []byte {
+	GinkgoHelper()
+	var buff bytes.Buffer
+	var w io.Writer = &buff
+	var gz *gzip.Writer
+	if compressed {
+		gz = gzip.NewWriter(&buff)
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+	Expect(tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir, Name: "./assets/", Mode: 0755, ModTime: archiveModTime,
+	})).To(Succeed())
+	Expect(tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeSymlink, Name: "./passwd", Linkname: "/etc/passwd", ModTime: archiveModTime,
+	})).To(Succeed())
+	for name, contents := range archiveFiles {
+		Expect(tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     "./" + name,
+			Mode:     0644,
+			Size:     int64(len(contents)),
+			ModTime:  archiveModTime,
+		})).To(Succeed())
+		Expect(tw.Write([]byte(contents))).Error().NotTo(HaveOccurred())
+	}
+	Expect(tw.Close()).To(Succeed())
+	if gz != nil {
+		Expect(gz.Close()).To(Succeed())
+	}
+	return buff.Bytes()
+}
+
+var _ = Describe("archive bundles", func() {
+
+	DescribeTable("serving from archives",
+		func(archive func() []byte) {
+			a := archive()
+			h := Successful(NewSPAHandlerFromArchiveReader(
+				bytes.NewReader(a), int64(len(a)), "index.html"))
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/foo/bar", nil))
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(ContainSubstring(`<base href="/" />`))
+
+			rec = httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/assets/app.js", nil))
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(Equal(archiveFiles["assets/app.js"]))
+			Expect(rec.Header().Get("Last-Modified")).To(Equal(archiveModTime.Format(http.TimeFormat)))
+
+			rec = httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/passwd", nil))
+			Expect(rec.Body.String()).NotTo(ContainSubstring("root"))
+		},
+		Entry("zip", zipArchive),
+		Entry("tar", func() []byte { return tarArchive(false) }),
+		Entry("tar.gz", func() []byte { return tarArchive(true) }),
+	)
+
+	DescribeTable("limiting the uncompressed size",
+		func(archive func() []byte) {
+			a := archive()
+			Expect(ArchiveFS(bytes.NewReader(a), int64(len(a)), WithMaxArchiveSize(63))).Error().NotTo(HaveOccurred())
+			Expect(ArchiveFS(bytes.NewReader(a), int64(len(a)), WithMaxArchiveSize(62))).Error().To(
+				MatchError(ErrArchiveTooLarge))
+		},
+		Entry("zip", zipArchive),
+		Entry("tar", func() []byte { return tarArchive(false) }),
+		Entry("tar.gz", func() []byte { return tarArchive(true) }),
+	)
+
+	It("serves from an archive file", func() {
+		name := filepath.Join(GinkgoT().TempDir(), "bundle.zip")
+		Expect(os.WriteFile(name, zipArchive(), 0644)).To(Succeed())
+		h := Successful(NewSPAHandlerFromArchive(name, "index.html"))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/assets/app.js", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+	})
+
+	It("reports invalid archives", func() {
+		Expect(NewSPAHandlerFromArchive("/nonexisting.zip", "index.html")).Error().To(HaveOccurred())
+		for _, a := range [][]byte{
+			[]byte("PK garbage"),
+			{0x1f, 0x8b, 0x00},
+			[]byte("not a tar archive, not at all, even if you squint hard"),
+			{},
+		} {
+			Expect(NewSPAHandlerFromArchiveReader(bytes.NewReader(a), int64(len(a)), "index.html")).
+				Error().To(HaveOccurred())
+		}
+	})
+
+})
@@ -0,0 +1,78 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrUnauthorized signals a request lacking valid authentication, and gets
+// mapped to the HTTP status code 401 (Unauthorized).
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Authenticator returns true if the specified request for an SPA route is
+// authenticated. Otherwise, it returns false together with an optional
+// challenge writing the response to unauthenticated requests, such as a
+// redirect to a login page or a 401 with a “WWW-Authenticate” header.
+type Authenticator func(r *http.Request) (ok bool, challenge func(w http.ResponseWriter))
+
+// WithAuthenticator authenticates requests for SPA routes, that is, requests
+// falling back to the index, using the specified authenticator. In contrast
+// to wrapping the whole SPAHandler, static assets are still served without
+// authentication, so hashed assets remain publicly cacheable by CDNs and
+// browsers, while only the index gets protected. For instance:
+//
+//	h := NewSPAHandler(bundle, "index.html",
+//	    WithAuthenticator(func(r *http.Request) (bool, func(http.ResponseWriter)) {
+//	        if _, err := r.Cookie("session"); err == nil {
+//	            return true, nil
+//	        }
+//	        return false, func(w http.ResponseWriter) {
+//	            http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()),
+//	                http.StatusFound)
+//	        }
+//	    }))
+//
+// Unauthenticated requests get answered by the challenge returned from the
+// authenticator, or with 401 (Unauthorized) if there is no challenge, using
+// the error document for 401 if configured (see WithErrorPage). Responses to
+// unauthenticated requests are never cached. As the index now depends on the
+// request's credentials, consider also setting a private index cache policy
+// using WithIndexCachePolicy, so shared caches don't store the index.
+func WithAuthenticator(auth Authenticator) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.authenticator = auth
+	}
+}
+
+// challengeUnauthenticated answers an unauthenticated request for an SPA route,
+// returning true. Otherwise, nothing is served and false is returned.
+func (h *SPAHandler) challengeUnauthenticated(w http.ResponseWriter, r *http.Request) bool {
+	if h.authenticator == nil {
+		return false
+	}
+	ok, challenge := h.authenticator(r)
+	if ok {
+		return false
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if challenge != nil {
+		challenge(w)
+		return true
+	}
+	h.writeError(w, r, ErrUnauthorized)
+	return true
+}
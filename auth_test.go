@@ -0,0 +1,89 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("authentication", func() {
+
+	spafs := fstest.MapFS{
+		"index.html":       &fstest.MapFile{Data: []byte(`<base href="./" />INDEX`)},
+		"401.html":         &fstest.MapFile{Data: []byte(`<base href="./" />LOGIN`)},
+		"assets/app-42.js": &fstest.MapFile{Data: []byte(`app();`)},
+	}
+
+	authenticated := func(r *http.Request) bool {
+		_, err := r.Cookie("session")
+		return err == nil
+	}
+
+	redirectToLogin := func(r *http.Request) (bool, func(http.ResponseWriter)) {
+		if authenticated(r) {
+			return true, nil
+		}
+		return false, func(w http.ResponseWriter) {
+			http.Redirect(w, r, "/login", http.StatusFound)
+		}
+	}
+
+	get := func(h *SPAHandler, path string, session bool) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://example.org"+path, nil)
+		if session {
+			r.AddCookie(&http.Cookie{Name: "session", Value: "1234"})
+		}
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	It("challenges unauthenticated index requests", func() {
+		h := NewSPAHandler(spafs, "index.html", WithAuthenticator(redirectToLogin))
+		w := get(h, "/dashboard", false)
+		Expect(w.Code).To(Equal(http.StatusFound))
+		Expect(w.Header().Get("Location")).To(Equal("/login"))
+		Expect(w.Header().Get("Cache-Control")).To(Equal("no-store"))
+
+		w = get(h, "/dashboard", true)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(HaveSuffix("INDEX"))
+	})
+
+	It("serves static assets without authentication", func() {
+		h := NewSPAHandler(spafs, "index.html", WithAuthenticator(redirectToLogin))
+		w := get(h, "/assets/app-42.js", false)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(Equal("app();"))
+	})
+
+	It("defaults to 401 using the error page", func() {
+		h := NewSPAHandler(spafs, "index.html",
+			WithErrorPage("401.html", http.StatusUnauthorized),
+			WithAuthenticator(func(r *http.Request) (bool, func(http.ResponseWriter)) {
+				return authenticated(r), nil
+			}))
+		w := get(h, "/dashboard", false)
+		Expect(w.Code).To(Equal(http.StatusUnauthorized))
+		Expect(w.Body.String()).To(HaveSuffix("LOGIN"))
+		Expect(w.Header().Get("Cache-Control")).To(Equal("no-store"))
+	})
+
+})
@@ -0,0 +1,54 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func BenchmarkServeHTTP(b *testing.B) {
+	for _, bm := range []struct {
+		name   string
+		path   string
+		prefix string
+		opts   []SPAHandlerOption
+	}{
+		{name: "static hit", path: "/static/js/some.js"},
+		{name: "index fallback", path: "/some/route"},
+		{name: "prefix rewrite", path: "/some/route", prefix: "/app"},
+		{name: "prefix rewrite with rewriter", path: "/some/route", prefix: "/app", opts: []SPAHandlerOption{
+			WithIndexRewriter(func(_ *http.Request, index string) string { return index }),
+		}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			h := NewSPAHandler(embStaticFs, "index.html", bm.opts...)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				r := httptest.NewRequest(http.MethodGet, bm.path, nil)
+				if bm.prefix != "" {
+					r.Header.Set(ForwardedPrefixHeader, bm.prefix)
+				}
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+				if w.Code != http.StatusOK {
+					b.Fatalf("unexpected status %d", w.Code)
+				}
+			}
+		})
+	}
+}
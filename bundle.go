@@ -0,0 +1,84 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"sync"
+)
+
+// bundle is an SPA bundle served by an SPAHandler, together with all the
+// information cached about the bundle. Swapping the bundle thus automatically
+// drops all cached information about the previous bundle.
+type bundle struct {
+	fs              fs.FS        // the FS to serve static resources from.
+	fileServer      http.Handler // FS adapted to http's file serving handler needs.
+	segments        sync.Map     // cached index files, pre-split, by name.
+	segmentsMu      sync.Mutex   // serializes reloading index files.
+	indexMetas      sync.Map     // cached metadata of rewritten indices, by indexMetaKey.
+	integrityHashes sync.Map     // cached SRI hashes of assets.
+	assetETags      sync.Map     // cached strong ETags of assets, by name.
+	indexOnce       sync.Once    // resolves the index file candidates once.
+	index           string       // resolved index file, see SPAHandler.bundleIndex.
+}
+
+// newBundle returns a new bundle serving from the specified fs.FS.
+func newBundle(fsys fs.FS) *bundle {
+	return &bundle{
+		fs:         fsys,
+		fileServer: newFileServer(fsys),
+	}
+}
+
+// current returns the bundle currently served.
+func (h *SPAHandler) current() *bundle {
+	return h.bundle.Load()
+}
+
+// FS returns the fs.FS currently served.
+func (h *SPAHandler) FS() fs.FS {
+	return h.current().fs
+}
+
+// SwapFS atomically swaps the fs.FS served by this SPAHandler for the specified
+// fs.FS, such as when rolling out a new SPA bundle at runtime. New requests
+// are served from the new fs.FS, while requests in flight complete without
+// interruption. However, requests in flight might see a mix of both fs.FSes,
+// so the old fs.FS must stay functional for a short while after swapping.
+//
+// If an overlay has been set using WithOverlayFS, it is layered on top of the
+// new fs.FS.
+//
+// If the cache primer has been enabled using WithCachePrimer, SwapFS replays
+// the most popular requests against the new fs.FS in the background, see also
+// SwapFSContext.
+func (h *SPAHandler) SwapFS(fsys fs.FS) {
+	h.SwapFSContext(context.Background(), fsys)
+}
+
+// SwapFSContext atomically swaps the fs.FS served by this SPAHandler for the
+// specified fs.FS, exactly as SwapFS does. Replaying the most popular requests
+// in the background stops when the specified context gets cancelled, or when
+// the next swap supersedes the new fs.FS.
+func (h *SPAHandler) SwapFSContext(ctx context.Context, fsys fs.FS) {
+	b := newBundle(h.overlaid(fsys))
+	h.hashAssets(b)
+	h.bundle.Store(b)
+	if h.primer != nil {
+		h.primeInBackground(ctx)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gleak"
+)
+
+var _ = Describe("swapping bundles", func() {
+
+	oldfs := fstest.MapFS{
+		"index.html":  &fstest.MapFile{Data: []byte(`<base href="./" />OLD`)},
+		"old.js":      &fstest.MapFile{Data: []byte(`old();`)},
+		"errors.html": &fstest.MapFile{Data: []byte(`OLD ERROR`)},
+	}
+	newfs := fstest.MapFS{
+		"index.html":  &fstest.MapFile{Data: []byte(`<base href="./" />NEW!`)},
+		"new.js":      &fstest.MapFile{Data: []byte(`new();`)},
+		"errors.html": &fstest.MapFile{Data: []byte(`NEW ERROR`)},
+	}
+
+	get := func(h http.Handler, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	It("swaps the served fs.FS", func() {
+		h := NewSPAHandler(oldfs, "index.html",
+			WithAssetNotFound(), WithErrorPage("errors.html", http.StatusNotFound))
+		Expect(h.FS()).To(Equal(oldfs))
+		oldIndex := get(h, "/")
+		Expect(oldIndex.Body.String()).To(HaveSuffix("OLD"))
+		Expect(get(h, "/old.js").Body.String()).To(Equal("old();"))
+
+		h.SwapFS(newfs)
+		Expect(h.FS()).To(Equal(newfs))
+		newIndex := get(h, "/")
+		Expect(newIndex.Body.String()).To(HaveSuffix("NEW!"))
+		Expect(newIndex.Header().Get("ETag")).NotTo(Equal(oldIndex.Header().Get("ETag")))
+		Expect(get(h, "/new.js").Body.String()).To(Equal("new();"))
+		w := get(h, "/old.js")
+		Expect(w.Code).To(Equal(http.StatusNotFound))
+		Expect(w.Body.String()).To(Equal("NEW ERROR"))
+	})
+
+	It("doesn't answer HEAD requests from stale metadata", func() {
+		h := NewSPAHandler(oldfs, "index.html")
+		get(h, "/")
+		h.SwapFS(newfs)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/", nil))
+		Expect(w.Header().Get("Content-Length")).To(Equal("21"))
+	})
+
+	It("cancels priming superseded bundles", func() {
+		priming := func() int {
+			n := 0
+			for _, g := range Goroutines() {
+				if strings.HasSuffix(g.CreatorFunction, ".primeInBackground") {
+					n++
+				}
+			}
+			return n
+		}
+
+		h := NewSPAHandler(oldfs, "index.html", WithCachePrimer(2, 0.001, 1))
+		get(h, "/foo")
+		get(h, "/bar")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		h.SwapFSContext(ctx, newfs)
+		h.SwapFSContext(ctx, oldfs)
+		Eventually(priming).Should(Equal(1))
+		Eventually(func() int { return syncMapLen(&h.current().indexMetas) }).Should(Equal(1))
+		cancel()
+		Eventually(priming).Should(BeZero())
+	})
+
+	It("completes requests in flight", func() {
+		sfs := &slowOpeningFS{MapFS: oldfs}
+		h := NewSPAHandler(sfs, "index.html")
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer GinkgoRecover()
+			defer wg.Done()
+			w := get(h, "/")
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(HaveSuffix("OLD"))
+		}()
+		Eventually(sfs.opens.Load).Should(Equal(int32(1)))
+		h.SwapFS(newfs)
+		wg.Wait()
+		Expect(get(h, "/").Body.String()).To(HaveSuffix("NEW!"))
+	})
+
+	It("primes the new bundle", func() {
+		h := NewSPAHandler(oldfs, "index.html", WithCachePrimer(10, 1000, 10))
+		get(h, "/some/route")
+		sfs := &slowOpeningFS{MapFS: newfs}
+		h.SwapFS(sfs)
+		Eventually(sfs.opens.Load).Should(Equal(int32(1)))
+	})
+
+})
@@ -0,0 +1,168 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CachePolicy describes the Cache-Control response header to send. The zero
+// value sends no Cache-Control header at all.
+type CachePolicy struct {
+	NoStore   bool          // never store responses.
+	NoCache   bool          // store, but always revalidate responses before reuse.
+	Private   bool          // only browsers may store responses, shared caches must not.
+	MaxAge    time.Duration // freshness lifetime, in whole seconds.
+	Immutable bool          // responses never change while fresh.
+
+	// StaleWhileRevalidate allows caches to serve stale responses for this
+	// long after they became stale, while revalidating in the background.
+	StaleWhileRevalidate time.Duration
+	// StaleIfError allows caches to serve stale responses for this long after
+	// they became stale, if revalidating fails due to an unavailable origin.
+	StaleIfError time.Duration
+}
+
+// DefaultIndexCachePolicy returns the default cache policy of the index,
+// forcing caches to always revalidate the index. As the index gets its base
+// element rewritten per request and changes with every deployment, caches
+// must never reuse it without asking first.
+func DefaultIndexCachePolicy() CachePolicy {
+	return CachePolicy{NoCache: true}
+}
+
+// String returns the Cache-Control header value of the cache policy, which is
+// empty for the zero value.
+func (p CachePolicy) String() string {
+	var directives []string
+	if p.NoStore {
+		directives = append(directives, "no-store")
+	}
+	if p.NoCache {
+		directives = append(directives, "no-cache")
+	}
+	if p.Private {
+		directives = append(directives, "private")
+	}
+	directives = appendSeconds(directives, "max-age", p.MaxAge)
+	if p.Immutable {
+		directives = append(directives, "immutable")
+	}
+	directives = appendSeconds(directives, "stale-while-revalidate", p.StaleWhileRevalidate)
+	directives = appendSeconds(directives, "stale-if-error", p.StaleIfError)
+	return strings.Join(directives, ", ")
+}
+
+// appendSeconds appends the named directive with the specified duration in
+// whole seconds, unless the duration is zero or negative.
+func appendSeconds(directives []string, name string, d time.Duration) []string {
+	if d <= 0 {
+		return directives
+	}
+	return append(directives, name+"="+strconv.FormatInt(int64(d/time.Second), 10))
+}
+
+// WithIndexCachePolicy sets the cache policy of the index, overriding the
+// DefaultIndexCachePolicy. For instance, to allow browsers to reuse the index
+// for a minute without revalidating, but not shared caches:
+//
+//	h := NewSPAHandler(fsys, "index.html",
+//	    WithIndexCachePolicy(CachePolicy{Private: true, MaxAge: time.Minute}))
+//
+// Passing the zero CachePolicy sends no Cache-Control header for the index.
+//
+// In CDN-fronted deployments, allowing the CDN to serve a stale index keeps the
+// SPA available during brief origin outages:
+//
+//	h := NewSPAHandler(fsys, "index.html",
+//	    WithIndexCachePolicy(CachePolicy{
+//	        MaxAge:       time.Minute,
+//	        StaleIfError: 24 * time.Hour,
+//	    }))
+func WithIndexCachePolicy(p CachePolicy) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.indexCache = p.String()
+	}
+}
+
+// setIndexCacheControl sets the Cache-Control header of index responses, if
+// any.
+func (h *SPAHandler) setIndexCacheControl(w http.ResponseWriter) {
+	if h.indexCache != "" {
+		w.Header().Set("Cache-Control", h.indexCache)
+	}
+}
+
+// cacheRule is an asset cache policy rule, matching static assets by glob.
+type cacheRule struct {
+	glob         string // glob pattern, matching base names if without slashes.
+	cacheControl string // Cache-Control header value.
+}
+
+// WithAssetCachePolicy sets the cache policy of static assets matching the
+// specified glob pattern, such as "*.js" or "assets/*". Patterns without any
+// slash match base names of assets in any directory, while patterns with
+// slashes match the (unrooted) paths of assets. For instance, to let browsers
+// and CDNs keep fingerprinted assets forever, and CDNs serve stale images for a
+// day when the origin is unavailable:
+//
+//	h := NewSPAHandler(fsys, "index.html",
+//	    WithAssetCachePolicy("assets/*", CachePolicy{
+//	        MaxAge:    365 * 24 * time.Hour,
+//	        Immutable: true,
+//	    }),
+//	    WithAssetCachePolicy("*.png", CachePolicy{
+//	        MaxAge:               time.Hour,
+//	        StaleWhileRevalidate: time.Minute,
+//	        StaleIfError:         24 * time.Hour,
+//	    }))
+//
+// Specifying WithAssetCachePolicy multiple times adds further rules; the first
+// matching rule wins. Static assets not matching any rule get no Cache-Control
+// header.
+func WithAssetCachePolicy(glob string, p CachePolicy) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		if _, err := path.Match(glob, ""); err != nil {
+			h.invalidOption("WithAssetCachePolicy", "malformed glob %q", glob)
+		}
+		h.assetCache = append(h.assetCache, cacheRule{
+			glob:         strings.TrimPrefix(glob, "/"),
+			cacheControl: p.String(),
+		})
+	}
+}
+
+// setAssetCacheControl sets the Cache-Control header of the static asset with
+// the specified (unrooted) path according to the first matching rule, if any.
+func (h *SPAHandler) setAssetCacheControl(w http.ResponseWriter, name string) {
+	base := path.Base(name)
+	for _, rule := range h.assetCache {
+		subject := base
+		if strings.Contains(rule.glob, "/") {
+			subject = name
+		}
+		if matched, _ := path.Match(rule.glob, subject); !matched {
+			continue
+		}
+		if rule.cacheControl != "" {
+			w.Header().Set("Cache-Control", rule.cacheControl)
+		}
+		return
+	}
+}
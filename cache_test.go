@@ -0,0 +1,111 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("cache policies", func() {
+
+	DescribeTable("rendering Cache-Control header values",
+		func(p CachePolicy, expected string) {
+			Expect(p.String()).To(Equal(expected))
+		},
+		Entry("zero value", CachePolicy{}, ""),
+		Entry("default index", DefaultIndexCachePolicy(), "no-cache"),
+		Entry("all directives",
+			CachePolicy{NoStore: true, NoCache: true, Private: true, MaxAge: 90*time.Second + 500*time.Millisecond, Immutable: true},
+			"no-store, no-cache, private, max-age=90, immutable"),
+		Entry("stale directives",
+			CachePolicy{MaxAge: time.Minute, StaleWhileRevalidate: 30 * time.Second, StaleIfError: time.Hour},
+			"max-age=60, stale-while-revalidate=30, stale-if-error=3600"),
+	)
+
+	spafs := fstest.MapFS{
+		"index.html":         &fstest.MapFile{Data: []byte(`<base href="./" />`)},
+		"main.js":            &fstest.MapFile{Data: []byte(`main()`)},
+		"assets/app-1234.js": &fstest.MapFile{Data: []byte(`app()`)},
+		"assets/logo.png":    &fstest.MapFile{Data: []byte(`PNG`)},
+		"img/logo.png":       &fstest.MapFile{Data: []byte(`PNG`)},
+	}
+
+	serve := func(h http.Handler, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	It("sends no-cache on the index by default, but not on assets", func() {
+		h := NewSPAHandler(spafs, "index.html")
+		w := serve(h, "/foo")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("Cache-Control")).To(Equal("no-cache"))
+
+		w = serve(h, "/main.js")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header()).NotTo(HaveKey("Cache-Control"))
+	})
+
+	It("sends the index cache policy also on not modified responses", func() {
+		h := NewSPAHandler(spafs, "index.html")
+		etag := serve(h, "/").Header().Get("ETag")
+		Expect(etag).NotTo(BeEmpty())
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("If-None-Match", etag)
+		h.ServeHTTP(w, r)
+		Expect(w.Code).To(Equal(http.StatusNotModified))
+		Expect(w.Header().Get("Cache-Control")).To(Equal("no-cache"))
+	})
+
+	It("overrides the index cache policy", func() {
+		h := NewSPAHandler(spafs, "index.html",
+			WithIndexCachePolicy(CachePolicy{Private: true, MaxAge: time.Minute}))
+		Expect(serve(h, "/foo").Header().Get("Cache-Control")).To(Equal("private, max-age=60"))
+
+		h = NewSPAHandler(spafs, "index.html", WithIndexCachePolicy(CachePolicy{}))
+		Expect(serve(h, "/foo").Header()).NotTo(HaveKey("Cache-Control"))
+	})
+
+	DescribeTable("applies the first matching asset cache policy rule",
+		func(path string, expected string) {
+			h := NewSPAHandler(spafs, "index.html",
+				WithIndexCachePolicy(CachePolicy{MaxAge: time.Minute, StaleIfError: time.Hour}),
+				WithAssetCachePolicy("/assets/*", CachePolicy{MaxAge: 24 * time.Hour, Immutable: true}),
+				WithAssetCachePolicy("*.png", CachePolicy{MaxAge: time.Hour, StaleWhileRevalidate: time.Minute, StaleIfError: time.Hour}),
+				WithAssetCachePolicy("main.js", CachePolicy{}))
+			w := serve(h, path)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			if expected == "" {
+				Expect(w.Header()).NotTo(HaveKey("Cache-Control"))
+				return
+			}
+			Expect(w.Header().Get("Cache-Control")).To(Equal(expected))
+		},
+		Entry("index", "/foo", "max-age=60, stale-if-error=3600"),
+		Entry("path rule", "/assets/app-1234.js", "max-age=86400, immutable"),
+		Entry("path rule before base name rule", "/assets/logo.png", "max-age=86400, immutable"),
+		Entry("base name rule", "/img/logo.png", "max-age=3600, stale-while-revalidate=60, stale-if-error=3600"),
+		Entry("zero policy rule", "/main.js", ""),
+	)
+
+})
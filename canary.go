@@ -0,0 +1,151 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"context"
+	"io/fs"
+	"math/rand"
+	"net/http"
+)
+
+// CookieName is the name of an HTTP cookie.
+type CookieName string
+
+// Values of the canary stickiness cookie.
+const (
+	canaryCookiePrimary = "primary"
+	canaryCookieCanary  = "canary"
+)
+
+// canary is a canary bundle served to a certain percentage of clients.
+type canary struct {
+	bundle  *bundle
+	percent int
+	cookie  CookieName
+}
+
+// bundleCtxKey is the context key for the bundle selected for a request.
+type bundleCtxKey struct{}
+
+// canaryCookieCtxKey is the context key for the stickiness cookie value of a
+// newly assigned bundle that still needs to be set.
+type canaryCookieCtxKey struct{}
+
+// WithCanary serves the canary fs.FS to the specified percentage of clients,
+// and the primary fs.FS to all other clients. The primary fs.FS replaces the
+// fs.FS passed to NewSPAHandler, which thus can be nil. For instance, to serve
+// a new bundle to 10% of clients:
+//
+//	h := NewSPAHandler(nil, "index.html",
+//	    WithCanary(current, next, 10, "spa-canary"))
+//
+// Clients are kept on their bundle using a stickiness cookie with the
+// specified (non-empty) name, which is set on the first index response to a
+// client without this cookie. Static asset responses never set the cookie, so
+// they stay cacheable. As the index now depends on the cookie, “Vary: Cookie”
+// gets added to index responses. Static assets of both bundles remain servable
+// to all clients, with assets of a client's bundle taking precedence; clients
+// might still reference assets from the other bundle, such as when their
+// stickiness cookie expired.
+//
+// Please note that SwapFS swaps the primary fs.FS, leaving the canary fs.FS in
+// place.
+func WithCanary(primary, canaryfs fs.FS, percent int, stickiness CookieName) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		if percent < 0 || percent > 100 {
+			h.invalidOption("WithCanary", "percentage %d not in [0, 100]", percent)
+		}
+		if stickiness == "" {
+			h.invalidOption("WithCanary", "empty stickiness cookie name")
+		}
+		h.bundle.Store(newBundle(primary))
+		h.canary = &canary{
+			bundle:  newBundle(canaryfs),
+			percent: min(max(percent, 0), 100),
+			cookie:  stickiness,
+		}
+	}
+}
+
+// selectBundle selects the bundle to serve the specified request from in
+// canary mode, returning the request with the selected bundle. If the request
+// doesn't carry a stickiness cookie yet, a bundle is randomly selected, leaving
+// it to assignCanaryCookie to set the stickiness cookie. Without canary mode,
+// or when the request has already been assigned a bundle, selectBundle returns
+// the specified request as is.
+func (h *SPAHandler) selectBundle(r *http.Request) *http.Request {
+	if h.canary == nil || r.Context().Value(bundleCtxKey{}) != nil {
+		return r
+	}
+	ctx := r.Context()
+	var useCanary bool
+	if cookie, err := r.Cookie(string(h.canary.cookie)); err == nil &&
+		(cookie.Value == canaryCookiePrimary || cookie.Value == canaryCookieCanary) {
+		useCanary = cookie.Value == canaryCookieCanary
+	} else {
+		useCanary = rand.Intn(100) < h.canary.percent
+		value := canaryCookiePrimary
+		if useCanary {
+			value = canaryCookieCanary
+		}
+		ctx = context.WithValue(ctx, canaryCookieCtxKey{}, value)
+	}
+	b := h.current()
+	if useCanary {
+		b = h.canary.bundle
+	}
+	return r.WithContext(context.WithValue(ctx, bundleCtxKey{}, b))
+}
+
+// assignCanaryCookie sets the stickiness cookie of the bundle newly selected
+// for the specified request, if any. It must only be called for index
+// responses, so that static asset responses stay cacheable.
+func (h *SPAHandler) assignCanaryCookie(w http.ResponseWriter, r *http.Request) {
+	value, ok := r.Context().Value(canaryCookieCtxKey{}).(string)
+	if !ok {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     string(h.canary.cookie),
+		Value:    value,
+		Path:     h.basename(r),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// bundleFor returns the bundle to serve the specified request from.
+func (h *SPAHandler) bundleFor(r *http.Request) *bundle {
+	if b, ok := r.Context().Value(bundleCtxKey{}).(*bundle); ok {
+		return b
+	}
+	return h.current()
+}
+
+// otherBundle returns the other bundle in canary mode, or nil if not in canary
+// mode or the specified bundle isn't taking part in canary mode.
+func (h *SPAHandler) otherBundle(b *bundle) *bundle {
+	if h.canary == nil {
+		return nil
+	}
+	switch b {
+	case h.canary.bundle:
+		return h.current()
+	case h.current():
+		return h.canary.bundle
+	}
+	return nil
+}
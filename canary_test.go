@@ -0,0 +1,129 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("canary bundles", func() {
+
+	primaryfs := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<base href="./" />PRIMARY`)},
+		"v1.js":      &fstest.MapFile{Data: []byte(`v1();`)},
+		"common.js":  &fstest.MapFile{Data: []byte(`common(1);`)},
+	}
+	canaryfs := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<base href="./" />CANARY`)},
+		"v2.js":      &fstest.MapFile{Data: []byte(`v2();`)},
+		"common.js":  &fstest.MapFile{Data: []byte(`common(2);`)},
+	}
+
+	get := func(h http.Handler, path string, cookie string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set(ForwardedPrefixHeader, "/app")
+		if cookie != "" {
+			r.AddCookie(&http.Cookie{Name: "spa-canary", Value: cookie})
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	stickiness := func(w *httptest.ResponseRecorder) *http.Cookie {
+		for _, cookie := range w.Result().Cookies() {
+			if cookie.Name == "spa-canary" {
+				return cookie
+			}
+		}
+		return nil
+	}
+
+	DescribeTable("selects bundles",
+		func(percent int, expectedIndex string, expectedCookie string) {
+			h := NewSPAHandler(nil, "index.html", WithCanary(primaryfs, canaryfs, percent, "spa-canary"))
+			w := get(h, "/foo", "")
+			Expect(w.Body.String()).To(HaveSuffix(expectedIndex))
+			cookie := stickiness(w)
+			Expect(cookie).NotTo(BeNil())
+			Expect(cookie.Value).To(Equal(expectedCookie))
+			Expect(cookie.Path).To(Equal("/app/"))
+			Expect(cookie.HttpOnly).To(BeTrue())
+			Expect(w.Header().Values("Vary")).To(ContainElement("Cookie"))
+		},
+		Entry("no canary", 0, "PRIMARY", "primary"),
+		Entry("all canary", 100, "CANARY", "canary"),
+		Entry("clamped", 1000, "CANARY", "canary"),
+	)
+
+	It("sticks clients to their bundle", func() {
+		h := NewSPAHandler(primaryfs, "index.html", WithCanary(primaryfs, canaryfs, 0, "spa-canary"))
+		w := get(h, "/foo", "canary")
+		Expect(w.Body.String()).To(HaveSuffix("CANARY"))
+		Expect(stickiness(w)).To(BeNil())
+		Expect(get(h, "/common.js", "canary").Body.String()).To(Equal("common(2);"))
+		Expect(get(h, "/common.js", "primary").Body.String()).To(Equal("common(1);"))
+
+		w = get(h, "/foo", "bogus")
+		Expect(w.Body.String()).To(HaveSuffix("PRIMARY"))
+		Expect(stickiness(w).Value).To(Equal("primary"))
+	})
+
+	It("assigns bundles only on index responses", func() {
+		h := NewSPAHandler(nil, "index.html", WithCanary(primaryfs, canaryfs, 50, "spa-canary"))
+		w := get(h, "/common.js", "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(stickiness(w)).To(BeNil())
+		Expect(w.Header().Values("Vary")).NotTo(ContainElement("Cookie"))
+	})
+
+	It("rejects an empty stickiness cookie name", func() {
+		Expect(NewSPAHandlerE(nil, "index.html", WithCanary(primaryfs, canaryfs, 50, ""))).Error().To(
+			MatchError(ContainSubstring("empty stickiness cookie name")))
+	})
+
+	It("serves the assets of both bundles", func() {
+		h := NewSPAHandler(primaryfs, "index.html", WithCanary(primaryfs, canaryfs, 50, "spa-canary"))
+		for _, cookie := range []string{"primary", "canary"} {
+			Expect(get(h, "/v1.js", cookie).Body.String()).To(Equal("v1();"))
+			Expect(get(h, "/v2.js", cookie).Body.String()).To(Equal("v2();"))
+		}
+	})
+
+	It("distributes clients according to the percentage", func() {
+		h := NewSPAHandler(primaryfs, "index.html", WithCanary(primaryfs, canaryfs, 20, "spa-canary"))
+		canaries := 0
+		for i := 0; i < 1000; i++ {
+			if strings.HasSuffix(get(h, "/foo", "").Body.String(), "CANARY") {
+				canaries++
+			}
+		}
+		Expect(canaries).To(BeNumerically("~", 200, 80))
+	})
+
+	It("swaps only the primary bundle", func() {
+		h := NewSPAHandler(primaryfs, "index.html", WithCanary(primaryfs, canaryfs, 0, "spa-canary"))
+		h.SwapFS(fstest.MapFS{"index.html": &fstest.MapFile{Data: []byte(`NEXT`)}})
+		Expect(get(h, "/foo", "primary").Body.String()).To(Equal("NEXT"))
+		Expect(get(h, "/foo", "canary").Body.String()).To(HaveSuffix("CANARY"))
+	})
+
+})
@@ -0,0 +1,63 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"io/fs"
+	"path"
+)
+
+// WithIndexCandidates specifies further index file candidates to try in the
+// given order when the index file passed to NewSPAHandler doesn't exist,
+// serving the first candidate that exists. This supports serving bundles
+// from different SPA toolchains that name their index files differently. For
+// instance:
+//
+//	h := NewSPAHandler(fsys, "index.html",
+//	    WithIndexCandidates("index.htm", "200.html"))
+//
+// The candidates are evaluated on the first request for the index and then
+// stick with the bundle; they are evaluated again after swapping the fs.FS
+// using SwapFS or after changes detected by WithCacheInvalidation. If none of
+// the candidates exists, the index file passed to NewSPAHandler is used.
+func WithIndexCandidates(candidates ...string) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		for _, candidate := range candidates {
+			if candidate == "" {
+				continue
+			}
+			h.indexCandidates = append(h.indexCandidates, path.Clean("/" + candidate)[1:])
+		}
+	}
+}
+
+// bundleIndex returns the (unrooted) path and name of the index file of the
+// specified bundle, that is, the first index file candidate existing in the
+// bundle's fs.FS.
+func (h *SPAHandler) bundleIndex(b *bundle) string {
+	if len(h.indexCandidates) == 0 || b.fs == nil {
+		return h.index
+	}
+	b.indexOnce.Do(func() {
+		b.index = h.index
+		for _, candidate := range append([]string{h.index}, h.indexCandidates...) {
+			if fileInfo, err := fs.Stat(b.fs, candidate); err == nil && fileInfo.Mode().IsRegular() {
+				b.index = candidate
+				return
+			}
+		}
+	})
+	return b.index
+}
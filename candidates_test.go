@@ -0,0 +1,98 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("index candidates", func() {
+
+	doc := func(body string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(`<html><head><base href="./" /></head><body>` + body + `</body></html>`)}
+	}
+
+	serve := func(h *SPAHandler) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/some/route", nil))
+		return w
+	}
+
+	It("serves the first existing candidate", func() {
+		h := NewSPAHandler(fstest.MapFS{
+			"index.htm": doc("dot-htm"),
+			"200.html":  doc("two-hundred"),
+		}, "index.html", WithIndexCandidates("", "/index.htm", "200.html"))
+		Expect(h.Index()).To(Equal("index.htm"))
+		w := serve(h)
+		Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(ContainSubstring("dot-htm"))
+	})
+
+	It("prefers the index passed to the handler", func() {
+		h := NewSPAHandler(fstest.MapFS{
+			"index.html": doc("main"),
+			"200.html":   doc("two-hundred"),
+		}, "index.html", WithIndexCandidates("200.html"))
+		Expect(h.Index()).To(Equal("index.html"))
+		Expect(serve(h).Body.String()).To(ContainSubstring("main"))
+	})
+
+	It("falls back to the index passed to the handler", func() {
+		h := NewSPAHandler(fstest.MapFS{
+			"200.html/foo": doc("two-hundred"),
+		}, "index.html", WithIndexCandidates("200.html"))
+		Expect(h.Index()).To(Equal("index.html"))
+		Expect(serve(h).Result().StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("re-evaluates the candidates after swapping", func() {
+		h := NewSPAHandler(fstest.MapFS{
+			"200.html": doc("two-hundred"),
+		}, "index.html", WithIndexCandidates("index.htm", "200.html"))
+		Expect(serve(h).Body.String()).To(ContainSubstring("two-hundred"))
+		h.SwapFS(fstest.MapFS{
+			"index.htm": doc("dot-htm"),
+		})
+		Expect(h.Index()).To(Equal("index.htm"))
+		Expect(serve(h).Body.String()).To(ContainSubstring("dot-htm"))
+	})
+
+	It("names localized index files after the candidate found", func() {
+		h := NewSPAHandler(fstest.MapFS{
+			"200.html":    doc("two-hundred"),
+			"200.de.html": doc("zweihundert"),
+		}, "index.html", WithIndexCandidates("200.html"), WithLocalizedIndex("de"))
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/some/route", nil)
+		r.Header.Set("Accept-Language", "de-AT, en;q=0.5")
+		h.ServeHTTP(w, r)
+		Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(ContainSubstring("zweihundert"))
+	})
+
+	It("validates that some candidate exists", func() {
+		Expect(NewSPAHandlerE(fstest.MapFS{"200.html": doc("two-hundred")}, "index.html",
+			WithIndexCandidates("200.html"))).Error().NotTo(HaveOccurred())
+		Expect(NewSPAHandlerE(fstest.MapFS{}, "index.html",
+			WithIndexCandidates("200.html"))).Error().To(MatchError(ErrInvalidConfiguration))
+	})
+
+})
@@ -0,0 +1,56 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"net/http"
+)
+
+// WithCanonicalIndexRedirect redirects direct requests for the index file, such
+// as “/app/index.html”, to the SPA's base path, such as “/app/”, using the
+// specified redirect status code. The status code typically is either
+// http.StatusMovedPermanently (301) or http.StatusPermanentRedirect (308);
+// other status codes default to http.StatusMovedPermanently, and are rejected
+// by NewSPAHandlerE. The redirect preserves the externally visible prefix of
+// the SPA, as well as any query.
+//
+// This avoids duplicate-content URLs, as well as broken resolution of relative
+// asset URLs when users bookmark the index file instead of the SPA.
+func WithCanonicalIndexRedirect(code int) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		if code != http.StatusMovedPermanently && code != http.StatusPermanentRedirect {
+			h.invalidOption("WithCanonicalIndexRedirect", "status code %d neither 301 nor 308", code)
+			code = http.StatusMovedPermanently
+		}
+		h.indexRedirect = code
+	}
+}
+
+// redirectToCanonicalIndex redirects requests for the index file to the SPA's
+// base path, returning true. Otherwise, nothing is served and false is
+// returned.
+//
+// IMPORTANT: the passed r.URL.Path must have already been sanitized.
+func (h *SPAHandler) redirectToCanonicalIndex(w http.ResponseWriter, r *http.Request) bool {
+	if h.indexRedirect == 0 || r.URL.Path != "/"+h.bundleIndex(h.bundleFor(r)) {
+		return false
+	}
+	location := escapedPath(h.basename(r))
+	if r.URL.RawQuery != "" {
+		location += "?" + r.URL.RawQuery
+	}
+	http.Redirect(w, r, location, h.indexRedirect)
+	return true
+}
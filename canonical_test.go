@@ -0,0 +1,56 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("canonical index redirects", func() {
+
+	spafs := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<base href="./" />`)},
+	}
+
+	DescribeTable("redirects requests for the index file to the base path",
+		func(code int, url string, prefix string, expectedCode int, expectedLocation string) {
+			h := NewSPAHandler(spafs, "index.html", WithCanonicalIndexRedirect(code))
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, url, nil)
+			if prefix != "" {
+				r.Header.Set(ForwardedPrefixHeader, prefix)
+			}
+			h.ServeHTTP(w, r)
+			Expect(w.Code).To(Equal(expectedCode))
+			Expect(w.Header().Get("Location")).To(Equal(expectedLocation))
+		},
+		Entry("root", http.StatusMovedPermanently, "/index.html", "",
+			http.StatusMovedPermanently, "/"),
+		Entry("prefixed", http.StatusPermanentRedirect, "/index.html", "/app",
+			http.StatusPermanentRedirect, "/app/"),
+		Entry("with query", http.StatusPermanentRedirect, "/index.html?lang=de", "/app",
+			http.StatusPermanentRedirect, "/app/?lang=de"),
+		Entry("invalid status code", http.StatusOK, "/index.html", "/app",
+			http.StatusMovedPermanently, "/app/"),
+		Entry("nested index file names untouched", http.StatusPermanentRedirect, "/foo/index.html", "/app",
+			http.StatusOK, ""),
+	)
+
+})
@@ -0,0 +1,62 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+)
+
+// WithCleanURLs serves the HTML page “about.html” for requests to “/about”
+// if present, before falling back to the index, similar to the “clean URLs”
+// of Netlify or Vercel. This allows serving hybrid bundles with additional
+// static HTML pages next to the SPA, such as for legal notes or marketing
+// pages. Requests for paths with file extensions as well as for the index
+// itself are never served clean. The pages are served as they are, that is,
+// without any base element rewriting.
+func WithCleanURLs() SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.cleanURLs = true
+	}
+}
+
+// serveCleanURL serves the HTML page of the same name as the request path with
+// an additional “.html” extension, if present, returning true. Otherwise,
+// nothing is served and false is returned.
+//
+// IMPORTANT: the passed r.URL.Path must have already been sanitized.
+func (h *SPAHandler) serveCleanURL(w http.ResponseWriter, r *http.Request) bool {
+	if !h.cleanURLs || r.URL.Path == "/" || path.Ext(r.URL.Path) != "" {
+		return false
+	}
+	page := r.URL.Path + ".html"
+	if name := page[1:]; name == h.index || name == h.indexFor(r) {
+		return false
+	}
+	return h.serveStaticAsset(w, withURLPath(r, page))
+}
+
+// withURLPath returns a shallow copy of the specified request with its URL path
+// set to the specified path.
+func withURLPath(r *http.Request, p string) *http.Request {
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path = p
+	r2.URL.RawPath = ""
+	return r2
+}
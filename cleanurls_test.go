@@ -0,0 +1,59 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("clean URLs", func() {
+
+	spafs := fstest.MapFS{
+		"index.html":         &fstest.MapFile{Data: []byte(`<base href="./" />SPA`)},
+		"about.html":         &fstest.MapFile{Data: []byte(`<base href="./" />about`)},
+		"legal/terms.html":   &fstest.MapFile{Data: []byte(`terms`)},
+		"legal/imprint":      &fstest.MapFile{Data: []byte(`extensionless`)},
+		"legal/imprint.html": &fstest.MapFile{Data: []byte(`imprint`)},
+	}
+
+	DescribeTable("serves HTML pages for extensionless paths",
+		func(cleanURLs bool, path string, expected string) {
+			var opts []SPAHandlerOption
+			if cleanURLs {
+				opts = append(opts, WithCleanURLs())
+			}
+			h := NewSPAHandler(spafs, "index.html", opts...)
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, path, nil)
+			r.Header.Set(ForwardedPrefixHeader, "/app")
+			h.ServeHTTP(w, r)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(Equal(expected))
+		},
+		Entry("disabled", false, "/about", `<base href="/app/" />SPA`),
+		Entry("page", true, "/about", `<base href="./" />about`),
+		Entry("nested page", true, "/legal/terms", `terms`),
+		Entry("existing file first", true, "/legal/imprint", `extensionless`),
+		Entry("missing page", true, "/contact", `<base href="/app/" />SPA`),
+		Entry("never the index", true, "/index", `<base href="/app/" />SPA`),
+		Entry("never with extension", true, "/about.htm", `<base href="/app/" />SPA`),
+	)
+
+})
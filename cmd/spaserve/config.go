@@ -0,0 +1,302 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thediveo/spaserve"
+	"gopkg.in/yaml.v3"
+)
+
+// config is the configuration of the spaserve command. It can be read from
+// YAML or JSON configuration files, as well as from environment variables and
+// command line flags.
+type config struct {
+	File            string           `yaml:"-"`               // optional configuration file.
+	Dir             string           `yaml:"dir"`             // directory with the SPA's static files.
+	Listen          string           `yaml:"listen"`          // address to listen on.
+	Base            string           `yaml:"base"`            // base path to serve the SPA from.
+	Gzip            bool             `yaml:"gzip"`            // gzip-compress responses.
+	Index           string           `yaml:"index"`           // index file of the SPA inside Dir.
+	IndexCache      string           `yaml:"indexCache"`      // optional Cache-Control directives of the index.
+	AssetCache      []assetCacheRule `yaml:"assetCache"`      // optional cache rules of static assets.
+	SecurityHeaders *securityHeaders `yaml:"securityHeaders"` // optional security headers.
+	TLSCert         string           `yaml:"tlsCert"`         // optional TLS certificate file.
+	TLSKey          string           `yaml:"tlsKey"`          // optional TLS key file.
+	ACMEDomains     []string         `yaml:"acmeDomains"`     // optional domains to obtain certificates for via ACME.
+	ACMECache       string           `yaml:"acmeCache"`       // directory caching ACME certificates.
+	RedirectHTTP    string           `yaml:"redirectHTTP"`    // optional address to redirect plain HTTP requests from.
+	AltSvc          string           `yaml:"altSvc"`          // optional Alt-Svc header value.
+}
+
+// assetCacheRule is the cache policy for static assets matching a glob
+// pattern.
+type assetCacheRule struct {
+	Glob         string `yaml:"glob"`         // glob pattern of static assets.
+	CacheControl string `yaml:"cacheControl"` // Cache-Control directives.
+}
+
+// securityHeaders configures the security headers, optionally starting from
+// the default security headers.
+type securityHeaders struct {
+	Defaults           bool   `yaml:"defaults"`           // start from spaserve.DefaultSecurityHeaders.
+	ContentTypeOptions string `yaml:"contentTypeOptions"` // X-Content-Type-Options.
+	ReferrerPolicy     string `yaml:"referrerPolicy"`     // Referrer-Policy.
+	FrameOptions       string `yaml:"frameOptions"`       // X-Frame-Options.
+	FrameAncestors     string `yaml:"frameAncestors"`     // frame-ancestors CSP directive value.
+	PermissionsPolicy  string `yaml:"permissionsPolicy"`  // Permissions-Policy.
+}
+
+// defaultConfig returns the default configuration.
+func defaultConfig() *config {
+	return &config{
+		Dir:    ".",
+		Listen: ":8080",
+		Base:   "/",
+		Index:  "index.html",
+	}
+}
+
+// loadConfig returns the configuration from the optional configuration file,
+// the environment variables, and the specified command line arguments, in
+// increasing order of precedence. The configuration file is specified either
+// by the --config flag or the SPASERVE_CONFIG environment variable.
+func loadConfig(args []string, getenv func(string) string) (*config, error) {
+	// The first pass over the command line arguments only determines the
+	// configuration file, as command line arguments later need to take
+	// precedence over the contents of the configuration file.
+	probe := defaultConfig()
+	if err := probe.parseFlags(args); err != nil {
+		return nil, err
+	}
+	cfg := defaultConfig()
+	file := probe.File
+	if file == "" {
+		file = getenv(envPrefix + "CONFIG")
+	}
+	if file != "" {
+		if err := cfg.loadFile(file); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.applyEnv(getenv); err != nil {
+		return nil, err
+	}
+	if err := cfg.parseFlags(args); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// parseFlags updates the configuration from the specified command line
+// arguments, returning flag.ErrHelp when help was requested.
+func (c *config) parseFlags(args []string) error {
+	flags := flag.NewFlagSet("spaserve", flag.ContinueOnError)
+	flags.StringVar(&c.File, "config", c.File, "YAML or JSON configuration file")
+	flags.StringVar(&c.Dir, "dir", c.Dir, "directory with the SPA's static files")
+	flags.StringVar(&c.Listen, "listen", c.Listen, "address to listen on")
+	flags.StringVar(&c.Base, "base", c.Base, "base path to serve the SPA from")
+	flags.BoolVar(&c.Gzip, "gzip", c.Gzip, "gzip-compress responses to clients accepting it")
+	flags.StringVar(&c.Index, "spa-index", c.Index, "index file of the SPA inside the directory")
+	flags.StringVar(&c.TLSCert, "tls-cert", c.TLSCert, "TLS certificate file, enables HTTPS")
+	flags.StringVar(&c.TLSKey, "tls-key", c.TLSKey, "TLS key file, enables HTTPS")
+	flags.Func("acme-domains", "comma-separated domains to obtain certificates for via ACME, enables HTTPS",
+		func(value string) error {
+			c.ACMEDomains = splitList(value)
+			return nil
+		})
+	flags.StringVar(&c.ACMECache, "acme-cache", c.ACMECache, "directory caching ACME certificates")
+	flags.StringVar(&c.RedirectHTTP, "redirect-http", c.RedirectHTTP, "address to redirect plain HTTP requests to HTTPS from")
+	flags.StringVar(&c.AltSvc, "alt-svc", c.AltSvc, "Alt-Svc header value advertising alternative services")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments %q", flags.Args())
+	}
+	return nil
+}
+
+// loadFile updates the configuration from the specified YAML or JSON
+// configuration file, rejecting unknown fields.
+func (c *config) loadFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("invalid configuration file %s: %w", name, err)
+	}
+	return nil
+}
+
+// handler returns the HTTP handler serving the SPA as configured.
+func (c *config) handler() (http.Handler, error) {
+	info, err := os.Stat(c.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", c.Dir)
+	}
+	if c.Index == "" {
+		return nil, errors.New("missing index file")
+	}
+	opts, err := c.options()
+	if err != nil {
+		return nil, err
+	}
+	// Mounting the SPA on the base path strips the base path from the
+	// request paths and passes it on as the forwarded prefix, so the index
+	// gets its base element rewritten correctly.
+	mount := strings.Trim(path.Clean("/"+c.Base), "/")
+	var handler http.Handler = spaserve.NewMultiSPAHandler(map[string]spaserve.SPAConfig{
+		mount: {FS: os.DirFS(c.Dir), Index: c.Index, Options: opts},
+	})
+	if c.Gzip {
+		handler = gzipHandler(handler)
+	}
+	return handler, nil
+}
+
+// redirectHandler returns the HTTP handler redirecting plain HTTP requests to
+// HTTPS on the port of the listening address.
+func (c *config) redirectHandler() (http.Handler, error) {
+	if c.TLSCert == "" && len(c.ACMEDomains) == 0 {
+		return nil, errors.New("redirecting to HTTPS requires TLS")
+	}
+	_, port, err := net.SplitHostPort(c.Listen)
+	if err != nil {
+		return nil, fmt.Errorf("invalid listen address: %w", err)
+	}
+	portnum, err := net.LookupPort("tcp", port)
+	if err != nil {
+		return nil, fmt.Errorf("invalid listen address: %w", err)
+	}
+	return spaserve.NewHTTPSRedirectHandler(portnum), nil
+}
+
+// options returns the SPAHandler options for the cache rules, security
+// headers, and alternative services.
+func (c *config) options() ([]spaserve.SPAHandlerOption, error) {
+	var opts []spaserve.SPAHandlerOption
+	if c.AltSvc != "" {
+		opts = append(opts, spaserve.WithAltSvc(c.AltSvc))
+	}
+	if c.IndexCache != "" {
+		policy, err := parseCachePolicy(c.IndexCache)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index cache policy: %w", err)
+		}
+		opts = append(opts, spaserve.WithIndexCachePolicy(policy))
+	}
+	for _, rule := range c.AssetCache {
+		policy, err := parseCachePolicy(rule.CacheControl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache policy for %q: %w", rule.Glob, err)
+		}
+		opts = append(opts, spaserve.WithAssetCachePolicy(rule.Glob, policy))
+	}
+	if sh := c.SecurityHeaders; sh != nil {
+		var headers spaserve.SecurityHeaders
+		if sh.Defaults {
+			headers = spaserve.DefaultSecurityHeaders()
+		}
+		for _, field := range []struct {
+			value string
+			dest  *string
+		}{
+			{sh.ContentTypeOptions, &headers.ContentTypeOptions},
+			{sh.ReferrerPolicy, &headers.ReferrerPolicy},
+			{sh.FrameOptions, &headers.FrameOptions},
+			{sh.FrameAncestors, &headers.FrameAncestors},
+			{sh.PermissionsPolicy, &headers.PermissionsPolicy},
+		} {
+			if field.value != "" {
+				*field.dest = field.value
+			}
+		}
+		opts = append(opts, spaserve.WithSecurityHeaders(headers))
+	}
+	return opts, nil
+}
+
+// parseCachePolicy returns the cache policy for the specified Cache-Control
+// directives, such as “max-age=31536000, immutable”. Durations are either in
+// seconds, as in Cache-Control headers, or Go durations, such as “1h”.
+func parseCachePolicy(directives string) (spaserve.CachePolicy, error) {
+	var p spaserve.CachePolicy
+	for _, directive := range strings.Split(directives, ",") {
+		name, value, hasValue := strings.Cut(strings.TrimSpace(directive), "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		var flag *bool
+		var duration *time.Duration
+		switch name {
+		case "":
+			continue
+		case "no-store":
+			flag = &p.NoStore
+		case "no-cache":
+			flag = &p.NoCache
+		case "private":
+			flag = &p.Private
+		case "immutable":
+			flag = &p.Immutable
+		case "max-age":
+			duration = &p.MaxAge
+		case "stale-while-revalidate":
+			duration = &p.StaleWhileRevalidate
+		case "stale-if-error":
+			duration = &p.StaleIfError
+		default:
+			return spaserve.CachePolicy{}, fmt.Errorf("unsupported directive %q", name)
+		}
+		if flag != nil {
+			if hasValue {
+				return spaserve.CachePolicy{}, fmt.Errorf("unexpected value of directive %q", name)
+			}
+			*flag = true
+			continue
+		}
+		d, err := parseSeconds(strings.TrimSpace(value))
+		if err != nil {
+			return spaserve.CachePolicy{}, fmt.Errorf("invalid duration of directive %q: %w", name, err)
+		}
+		*duration = d
+	}
+	return p, nil
+}
+
+// parseSeconds returns the duration specified either in seconds or as a Go
+// duration.
+func parseSeconds(s string) (time.Duration, error) {
+	if seconds, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,225 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/thediveo/spaserve"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+// env returns a getenv function returning the specified environment
+// variables.
+func env(vars map[string]string) func(string) string {
+	return func(name string) string { return vars[name] }
+}
+
+var _ = Describe("configuration", func() {
+
+	It("parses flags", func() {
+		cfg := defaultConfig()
+		Expect(cfg.parseFlags([]string{
+			"--dir", "./dist",
+			"--listen", ":1234",
+			"--base", "/app/",
+			"--gzip",
+			"--spa-index", "main.html",
+		})).To(Succeed())
+		Expect(cfg).To(Equal(&config{
+			Dir:    "./dist",
+			Listen: ":1234",
+			Base:   "/app/",
+			Gzip:   true,
+			Index:  "main.html",
+		}))
+
+		Expect(defaultConfig().parseFlags([]string{"foo"})).To(MatchError(ContainSubstring("unexpected arguments")))
+	})
+
+	It("rejects invalid configurations", func() {
+		cfg := defaultConfig()
+		cfg.Dir = "/nonexisting"
+		Expect(cfg.handler()).Error().To(HaveOccurred())
+
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "index.html"), nil, 0o644)).To(Succeed())
+		cfg.Dir = filepath.Join(dir, "index.html")
+		Expect(cfg.handler()).Error().To(MatchError(ContainSubstring("is not a directory")))
+
+		cfg.Dir = dir
+		cfg.Index = ""
+		Expect(cfg.handler()).Error().To(MatchError("missing index file"))
+	})
+
+	DescribeTable("serves the SPA from its base path",
+		func(base string, reqPath string, expectedCode int, expectedBody string) {
+			dir := GinkgoT().TempDir()
+			Expect(os.WriteFile(filepath.Join(dir, "index.html"), []byte(`<base href="./" />`), 0o644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, "main.js"), []byte(`main()`), 0o644)).To(Succeed())
+			cfg := defaultConfig()
+			cfg.Dir = dir
+			cfg.Base = base
+			h := Successful(cfg.handler())
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, reqPath, nil))
+			Expect(w.Code).To(Equal(expectedCode))
+			if expectedBody != "" {
+				Expect(w.Body.String()).To(Equal(expectedBody))
+			}
+		},
+		Entry("root", "/", "/foo", http.StatusOK, `<base href="/" />`),
+		Entry("base", "/app/", "/app/foo", http.StatusOK, `<base href="/app/" />`),
+		Entry("asset below base", "/app", "/app/main.js", http.StatusOK, `main()`),
+		Entry("outside base", "/app/", "/foo", http.StatusNotFound, ""),
+	)
+
+	DescribeTable("parsing cache policies",
+		func(directives string, expected spaserve.CachePolicy) {
+			Expect(parseCachePolicy(directives)).To(Equal(expected))
+		},
+		Entry("empty", "", spaserve.CachePolicy{}),
+		Entry("flags", "No-Store, no-cache,private , immutable",
+			spaserve.CachePolicy{NoStore: true, NoCache: true, Private: true, Immutable: true}),
+		Entry("seconds and durations", "max-age=3600, stale-while-revalidate=1m, stale-if-error=24h",
+			spaserve.CachePolicy{MaxAge: time.Hour, StaleWhileRevalidate: time.Minute, StaleIfError: 24 * time.Hour}),
+	)
+
+	DescribeTable("rejecting invalid cache policies",
+		func(directives string) {
+			Expect(parseCachePolicy(directives)).Error().To(HaveOccurred())
+		},
+		Entry("unsupported directive", "public"),
+		Entry("flag with value", "no-cache=foo"),
+		Entry("missing duration", "max-age"),
+		Entry("invalid duration", "max-age=forever"),
+	)
+
+	It("loads configuration files, environment variables, and flags in order of precedence", func() {
+		dir := GinkgoT().TempDir()
+		file := filepath.Join(dir, "spaserve.yaml")
+		Expect(os.WriteFile(file, []byte(`
+dir: /srv/spa
+listen: ":1234"
+base: /app/
+index: main.html
+indexCache: no-cache
+assetCache:
+  - glob: "assets/*"
+    cacheControl: max-age=31536000, immutable
+securityHeaders:
+  defaults: true
+`), 0o644)).To(Succeed())
+		cfg := Successful(loadConfig([]string{"--config", file, "--listen", ":4321"}, env(map[string]string{
+			"SPASERVE_BASE":          "/other/",
+			"SPASERVE_LISTEN":        ":2345",
+			"SPASERVE_FRAME_OPTIONS": "SAMEORIGIN",
+		})))
+		Expect(cfg).To(Equal(&config{
+			File:       file,
+			Dir:        "/srv/spa",
+			Listen:     ":4321",
+			Base:       "/other/",
+			Index:      "main.html",
+			IndexCache: "no-cache",
+			AssetCache: []assetCacheRule{
+				{Glob: "assets/*", CacheControl: "max-age=31536000, immutable"},
+			},
+			SecurityHeaders: &securityHeaders{Defaults: true, FrameOptions: "SAMEORIGIN"},
+		}))
+	})
+
+	It("loads JSON configuration files from the environment", func() {
+		dir := GinkgoT().TempDir()
+		file := filepath.Join(dir, "spaserve.json")
+		Expect(os.WriteFile(file, []byte(`{"gzip": true, "base": "/app/"}`), 0o644)).To(Succeed())
+		cfg := Successful(loadConfig(nil, env(map[string]string{"SPASERVE_CONFIG": file})))
+		Expect(cfg.Gzip).To(BeTrue())
+		Expect(cfg.Base).To(Equal("/app/"))
+		Expect(cfg.Dir).To(Equal("."))
+	})
+
+	It("rejects invalid configuration files", func() {
+		dir := GinkgoT().TempDir()
+		file := filepath.Join(dir, "spaserve.yaml")
+		Expect(os.WriteFile(file, []byte("foo: bar\n"), 0o644)).To(Succeed())
+		Expect(loadConfig([]string{"--config", file}, env(nil))).Error().To(
+			MatchError(ContainSubstring("invalid configuration file")))
+		Expect(loadConfig([]string{"--config", filepath.Join(dir, "missing.yaml")}, env(nil))).Error().To(
+			HaveOccurred())
+		Expect(loadConfig([]string{"--foo"}, env(nil))).Error().To(HaveOccurred())
+	})
+
+	It("serves with cache policies and security headers", func() {
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "index.html"), []byte(`<base href="./" />`), 0o644)).To(Succeed())
+		Expect(os.Mkdir(filepath.Join(dir, "assets"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "assets", "main.js"), []byte(`main()`), 0o644)).To(Succeed())
+		cfg := defaultConfig()
+		cfg.Dir = dir
+		cfg.IndexCache = "max-age=60"
+		cfg.AssetCache = []assetCacheRule{{Glob: "assets/*", CacheControl: "max-age=31536000, immutable"}}
+		cfg.SecurityHeaders = &securityHeaders{Defaults: true, FrameOptions: "SAMEORIGIN"}
+		cfg.AltSvc = `h3=":443"`
+		h := Successful(cfg.handler())
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/foo", nil))
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("Cache-Control")).To(Equal("max-age=60"))
+		Expect(w.Header().Get("X-Frame-Options")).To(Equal("SAMEORIGIN"))
+		Expect(w.Header().Get("X-Content-Type-Options")).To(Equal("nosniff"))
+		Expect(w.Header().Get("Alt-Svc")).To(Equal(`h3=":443"`))
+
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/assets/main.js", nil))
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("Cache-Control")).To(Equal("max-age=31536000, immutable"))
+
+		cfg.AssetCache = []assetCacheRule{{Glob: "*", CacheControl: "public"}}
+		Expect(cfg.handler()).Error().To(HaveOccurred())
+		cfg.AssetCache = nil
+		cfg.IndexCache = "public"
+		Expect(cfg.handler()).Error().To(HaveOccurred())
+	})
+
+	It("redirects plain HTTP requests to HTTPS", func() {
+		cfg := defaultConfig()
+		cfg.Listen = ":8443"
+		Expect(cfg.redirectHandler()).Error().To(MatchError(ContainSubstring("requires TLS")))
+
+		cfg.TLSCert = "tls.crt"
+		h := Successful(cfg.redirectHandler())
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.org/app/foo", nil))
+		Expect(w.Code).To(Equal(http.StatusPermanentRedirect))
+		Expect(w.Header().Get("Location")).To(Equal("https://example.org:8443/app/foo"))
+
+		cfg.Listen = ":https"
+		Expect(cfg.redirectHandler()).NotTo(BeNil())
+		cfg.Listen = "8443"
+		Expect(cfg.redirectHandler()).Error().To(HaveOccurred())
+		cfg.Listen = ":foobar"
+		Expect(cfg.redirectHandler()).Error().To(HaveOccurred())
+	})
+
+})
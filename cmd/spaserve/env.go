@@ -0,0 +1,128 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// envPrefix is the prefix of the environment variables configuring spaserve.
+const envPrefix = "SPASERVE_"
+
+// applyEnv updates the configuration from the SPASERVE_* environment
+// variables, as returned by the specified getenv function. Unset or empty
+// environment variables leave the configuration unchanged.
+//
+//   - SPASERVE_DIR, SPASERVE_LISTEN, SPASERVE_BASE, SPASERVE_SPA_INDEX,
+//     SPASERVE_TLS_CERT, SPASERVE_TLS_KEY, SPASERVE_REDIRECT_HTTP,
+//     SPASERVE_ALT_SVC, SPASERVE_ACME_CACHE: same as the corresponding
+//     command line flags.
+//   - SPASERVE_ACME_DOMAINS: comma-separated domains, same as the
+//     --acme-domains command line flag.
+//   - SPASERVE_GZIP: boolean, such as "true" or "1".
+//   - SPASERVE_INDEX_CACHE: Cache-Control directives of the index.
+//   - SPASERVE_ASSET_CACHE: semicolon-separated cache rules of static
+//     assets, each in the form "glob=directives", such as
+//     "assets/*=max-age=31536000, immutable; *.png=max-age=3600".
+//   - SPASERVE_SECURITY_HEADERS: boolean, enabling the default security
+//     headers.
+//   - SPASERVE_CONTENT_TYPE_OPTIONS, SPASERVE_REFERRER_POLICY,
+//     SPASERVE_FRAME_OPTIONS, SPASERVE_FRAME_ANCESTORS,
+//     SPASERVE_PERMISSIONS_POLICY: individual security headers.
+func (c *config) applyEnv(getenv func(string) string) error {
+	for name, dest := range map[string]*string{
+		"DIR":           &c.Dir,
+		"LISTEN":        &c.Listen,
+		"BASE":          &c.Base,
+		"SPA_INDEX":     &c.Index,
+		"TLS_CERT":      &c.TLSCert,
+		"TLS_KEY":       &c.TLSKey,
+		"REDIRECT_HTTP": &c.RedirectHTTP,
+		"ALT_SVC":       &c.AltSvc,
+		"ACME_CACHE":    &c.ACMECache,
+	} {
+		if value := getenv(envPrefix + name); value != "" {
+			*dest = value
+		}
+	}
+	if value := getenv(envPrefix + "ACME_DOMAINS"); value != "" {
+		c.ACMEDomains = splitList(value)
+	}
+	if value := getenv(envPrefix + "GZIP"); value != "" {
+		gzip, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid %sGZIP: %w", envPrefix, err)
+		}
+		c.Gzip = gzip
+	}
+	if value := getenv(envPrefix + "INDEX_CACHE"); value != "" {
+		c.IndexCache = value
+	}
+	if value := getenv(envPrefix + "ASSET_CACHE"); value != "" {
+		c.AssetCache = nil
+		for _, rule := range strings.Split(value, ";") {
+			if strings.TrimSpace(rule) == "" {
+				continue
+			}
+			glob, directives, ok := strings.Cut(rule, "=")
+			if !ok {
+				return fmt.Errorf("invalid %sASSET_CACHE rule %q", envPrefix, rule)
+			}
+			c.AssetCache = append(c.AssetCache, assetCacheRule{
+				Glob:         strings.TrimSpace(glob),
+				CacheControl: strings.TrimSpace(directives),
+			})
+		}
+	}
+	return c.applySecurityHeadersEnv(getenv)
+}
+
+// applySecurityHeadersEnv updates the security headers configuration from the
+// SPASERVE_* environment variables.
+func (c *config) applySecurityHeadersEnv(getenv func(string) string) error {
+	if value := getenv(envPrefix + "SECURITY_HEADERS"); value != "" {
+		defaults, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid %sSECURITY_HEADERS: %w", envPrefix, err)
+		}
+		if !defaults {
+			c.SecurityHeaders = nil
+		} else {
+			if c.SecurityHeaders == nil {
+				c.SecurityHeaders = &securityHeaders{}
+			}
+			c.SecurityHeaders.Defaults = true
+		}
+	}
+	for name, dest := range map[string]func(*securityHeaders) *string{
+		"CONTENT_TYPE_OPTIONS": func(sh *securityHeaders) *string { return &sh.ContentTypeOptions },
+		"REFERRER_POLICY":      func(sh *securityHeaders) *string { return &sh.ReferrerPolicy },
+		"FRAME_OPTIONS":        func(sh *securityHeaders) *string { return &sh.FrameOptions },
+		"FRAME_ANCESTORS":      func(sh *securityHeaders) *string { return &sh.FrameAncestors },
+		"PERMISSIONS_POLICY":   func(sh *securityHeaders) *string { return &sh.PermissionsPolicy },
+	} {
+		value := getenv(envPrefix + name)
+		if value == "" {
+			continue
+		}
+		if c.SecurityHeaders == nil {
+			c.SecurityHeaders = &securityHeaders{}
+		}
+		*dest(c.SecurityHeaders) = value
+	}
+	return nil
+}
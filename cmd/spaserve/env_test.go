@@ -0,0 +1,93 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("environment variables", func() {
+
+	It("leaves the configuration unchanged without environment variables", func() {
+		cfg := defaultConfig()
+		Expect(cfg.applyEnv(env(nil))).To(Succeed())
+		Expect(cfg).To(Equal(defaultConfig()))
+	})
+
+	It("applies environment variables", func() {
+		cfg := defaultConfig()
+		Expect(cfg.applyEnv(env(map[string]string{
+			"SPASERVE_DIR":                  "/srv/spa",
+			"SPASERVE_LISTEN":               ":1234",
+			"SPASERVE_BASE":                 "/app/",
+			"SPASERVE_SPA_INDEX":            "main.html",
+			"SPASERVE_GZIP":                 "true",
+			"SPASERVE_INDEX_CACHE":          "no-cache",
+			"SPASERVE_ASSET_CACHE":          "assets/*=max-age=31536000, immutable; *.png = max-age=3600;",
+			"SPASERVE_SECURITY_HEADERS":     "1",
+			"SPASERVE_CONTENT_TYPE_OPTIONS": "nosniff",
+			"SPASERVE_REFERRER_POLICY":      "no-referrer",
+			"SPASERVE_FRAME_OPTIONS":        "SAMEORIGIN",
+			"SPASERVE_FRAME_ANCESTORS":      "'self'",
+			"SPASERVE_PERMISSIONS_POLICY":   "camera=()",
+			"SPASERVE_TLS_CERT":             "/etc/tls.crt",
+			"SPASERVE_TLS_KEY":              "/etc/tls.key",
+			"SPASERVE_REDIRECT_HTTP":        ":80",
+			"SPASERVE_ALT_SVC":              `h3=":443"`,
+		}))).To(Succeed())
+		Expect(cfg).To(Equal(&config{
+			Dir:        "/srv/spa",
+			Listen:     ":1234",
+			Base:       "/app/",
+			Index:      "main.html",
+			Gzip:       true,
+			IndexCache: "no-cache",
+			AssetCache: []assetCacheRule{
+				{Glob: "assets/*", CacheControl: "max-age=31536000, immutable"},
+				{Glob: "*.png", CacheControl: "max-age=3600"},
+			},
+			SecurityHeaders: &securityHeaders{
+				Defaults:           true,
+				ContentTypeOptions: "nosniff",
+				ReferrerPolicy:     "no-referrer",
+				FrameOptions:       "SAMEORIGIN",
+				FrameAncestors:     "'self'",
+				PermissionsPolicy:  "camera=()",
+			},
+			TLSCert:      "/etc/tls.crt",
+			TLSKey:       "/etc/tls.key",
+			RedirectHTTP: ":80",
+			AltSvc:       `h3=":443"`,
+		}))
+	})
+
+	It("disables security headers", func() {
+		cfg := defaultConfig()
+		cfg.SecurityHeaders = &securityHeaders{Defaults: true}
+		Expect(cfg.applyEnv(env(map[string]string{"SPASERVE_SECURITY_HEADERS": "false"}))).To(Succeed())
+		Expect(cfg.SecurityHeaders).To(BeNil())
+	})
+
+	DescribeTable("rejecting invalid environment variables",
+		func(name string, value string) {
+			Expect(defaultConfig().applyEnv(env(map[string]string{name: value}))).NotTo(Succeed())
+		},
+		Entry("gzip", "SPASERVE_GZIP", "sure"),
+		Entry("asset cache", "SPASERVE_ASSET_CACHE", "assets/*"),
+		Entry("security headers", "SPASERVE_SECURITY_HEADERS", "sure"),
+	)
+
+})
@@ -0,0 +1,156 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// compressibleTypes lists the (prefixes of) media types worth compressing.
+var compressibleTypes = []string{
+	"text/",
+	"application/javascript",
+	"application/json",
+	"application/manifest+json",
+	"application/wasm",
+	"application/xml",
+	"image/svg+xml",
+}
+
+// gzipHandler returns a handler gzip-compressing the responses of the
+// specified handler for clients accepting gzip-encoded responses, as long as
+// the responses are of compressible media types and not already encoded.
+// Range requests are passed on as is, as ranges refer to the unencoded
+// representation.
+func gzipHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if r.Header.Get("Range") != "" || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer func() { _ = gw.Close() }()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip returns true if the specified Accept-Encoding header value
+// accepts gzip-encoded responses.
+func acceptsGzip(acceptEncoding string) bool {
+	for _, element := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(element, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		name, value, _ := strings.Cut(params, "=")
+		if strings.TrimSpace(name) == "q" {
+			if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && q <= 0 {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter gzip-compresses the response body if the response turns
+// out to be compressible when writing the response header.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	decided  bool         // true after the (final) response header has been written.
+	compress bool         // true if the response body gets compressed.
+	gz       *gzip.Writer // lazily created gzip writer.
+}
+
+// WriteHeader decides whether to compress the response body, based on the
+// status code and response header, and then writes the response header.
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.decided || status < http.StatusOK {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.decided = true
+	header := w.Header()
+	if status != http.StatusNoContent &&
+		status != http.StatusPartialContent &&
+		status != http.StatusNotModified &&
+		header.Get("Content-Encoding") == "" &&
+		compressible(header.Get("Content-Type")) {
+		w.compress = true
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", "gzip")
+		// Strong ETags must differ between the unencoded and encoded
+		// representations, so weaken them instead.
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes the (compressed) response body, writing the response header
+// first if not already done.
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.compress {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.gz == nil {
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+	return w.gz.Write(b)
+}
+
+// Flush flushes any buffered compressed data to the client.
+func (w *gzipResponseWriter) Flush() {
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Close finishes the compressed response body, if any.
+func (w *gzipResponseWriter) Close() error {
+	if w.gz == nil {
+		return nil
+	}
+	return w.gz.Close()
+}
+
+// Unwrap returns the wrapped response writer, for http.ResponseController.
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// compressible returns true if the specified Content-Type is worth compressing.
+func compressible(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, prefix := range compressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
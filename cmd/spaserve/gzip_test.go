@@ -0,0 +1,98 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("gzip compression", func() {
+
+	DescribeTable("accepting gzip",
+		func(acceptEncoding string, expected bool) {
+			Expect(acceptsGzip(acceptEncoding)).To(Equal(expected))
+		},
+		Entry("empty", "", false),
+		Entry("gzip", "gzip", true),
+		Entry("list", "br, GZIP;q=0.5", true),
+		Entry("wildcard", "*", true),
+		Entry("rejected", "br, gzip;q=0", false),
+		Entry("other", "br, deflate", false),
+	)
+
+	body := strings.Repeat("Hello, World! ", 100)
+
+	serve := func(contentType string, status int, header http.Header) *httptest.ResponseRecorder {
+		h := gzipHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if contentType != "" {
+				w.Header().Set("Content-Type", contentType)
+			}
+			w.Header().Set("ETag", `"foo"`)
+			w.Header().Set("Content-Length", "1400")
+			if status != 0 {
+				w.WriteHeader(status)
+			}
+			_, _ = io.WriteString(w, body)
+		}))
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		for name, values := range header {
+			r.Header[name] = values
+		}
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	It("compresses compressible responses", func() {
+		w := serve("text/html; charset=utf-8", http.StatusOK, http.Header{"Accept-Encoding": {"gzip"}})
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("Content-Encoding")).To(Equal("gzip"))
+		Expect(w.Header().Get("Vary")).To(Equal("Accept-Encoding"))
+		Expect(w.Header().Get("ETag")).To(Equal(`W/"foo"`))
+		Expect(w.Header()).NotTo(HaveKey("Content-Length"))
+		Expect(w.Body.Len()).To(BeNumerically("<", len(body)))
+		gz := Successful(gzip.NewReader(w.Body))
+		Expect(io.ReadAll(gz)).To(Equal([]byte(body)))
+	})
+
+	It("detects the content type", func() {
+		w := serve("", 0, http.Header{"Accept-Encoding": {"gzip"}})
+		Expect(w.Header().Get("Content-Type")).To(Equal("text/plain; charset=utf-8"))
+		Expect(w.Header().Get("Content-Encoding")).To(Equal("gzip"))
+	})
+
+	DescribeTable("passes on other responses uncompressed",
+		func(contentType string, status int, header http.Header) {
+			w := serve(contentType, status, header)
+			Expect(w.Code).To(Equal(status))
+			Expect(w.Header()).NotTo(HaveKey("Content-Encoding"))
+			Expect(w.Header().Get("ETag")).To(Equal(`"foo"`))
+			Expect(w.Body.String()).To(Equal(body))
+		},
+		Entry("not accepted", "text/html", http.StatusOK, nil),
+		Entry("range request", "text/html", http.StatusOK, http.Header{"Accept-Encoding": {"gzip"}, "Range": {"bytes=0-1"}}),
+		Entry("incompressible", "image/png", http.StatusOK, http.Header{"Accept-Encoding": {"gzip"}}),
+		Entry("partial content", "text/html", http.StatusPartialContent, http.Header{"Accept-Encoding": {"gzip"}}),
+	)
+
+})
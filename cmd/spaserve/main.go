@@ -0,0 +1,163 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Command spaserve serves a single page application (SPA) from a directory,
+rewriting the base element of its index.html to the base path the SPA is
+served from. This allows using the spaserve package standalone, such as in
+containers, without writing any Go code:
+
+	spaserve --dir ./dist --listen :8080 --base /app/ --gzip --spa-index index.html
+
+The flags are:
+
+	--config         YAML or JSON configuration file
+	--dir            directory with the SPA's static files (default ".")
+	--listen         address to listen on (default ":8080")
+	--base           base path to serve the SPA from (default "/")
+	--gzip           gzip-compress responses to clients accepting it
+	--spa-index      index file of the SPA inside the directory (default "index.html")
+	--tls-cert       TLS certificate file, enables HTTPS
+	--tls-key        TLS key file, enables HTTPS
+	--acme-domains   comma-separated domains to obtain certificates for via ACME, enables HTTPS
+	--acme-cache     directory caching ACME certificates
+	--redirect-http  address to redirect plain HTTP requests to HTTPS from
+	--alt-svc        Alt-Svc header value, such as 'h3=":443"; ma=86400'
+
+Alternatively, spaserve can be configured using a YAML or JSON configuration
+file, such as from a Kubernetes ConfigMap, specified either using the --config
+flag or the SPASERVE_CONFIG environment variable:
+
+	dir: /srv/spa
+	listen: ":8080"
+	base: /app/
+	gzip: true
+	index: index.html
+	indexCache: no-cache
+	assetCache:
+	  - glob: "assets/*"
+	    cacheControl: max-age=31536000, immutable
+	  - glob: "*.png"
+	    cacheControl: max-age=1h, stale-while-revalidate=1m, stale-if-error=24h
+	securityHeaders:
+	  defaults: true
+	  frameOptions: SAMEORIGIN
+	  frameAncestors: "'self'"
+	tlsCert: /etc/spaserve/tls.crt
+	tlsKey: /etc/spaserve/tls.key
+	redirectHTTP: ":80"
+
+Additionally, SPASERVE_* environment variables override the settings of the
+configuration file, and command line flags override both:
+
+	SPASERVE_DIR, SPASERVE_LISTEN, SPASERVE_BASE, SPASERVE_GZIP, SPASERVE_SPA_INDEX
+	SPASERVE_TLS_CERT, SPASERVE_TLS_KEY, SPASERVE_REDIRECT_HTTP, SPASERVE_ALT_SVC
+	SPASERVE_ACME_DOMAINS="example.com,www.example.com", SPASERVE_ACME_CACHE
+	SPASERVE_INDEX_CACHE="no-cache"
+	SPASERVE_ASSET_CACHE="assets/*=max-age=31536000, immutable; *.png=max-age=3600"
+	SPASERVE_SECURITY_HEADERS=true
+	SPASERVE_CONTENT_TYPE_OPTIONS, SPASERVE_REFERRER_POLICY, SPASERVE_FRAME_OPTIONS,
+	SPASERVE_FRAME_ANCESTORS, SPASERVE_PERMISSIONS_POLICY
+
+When serving HTTPS, spaserve optionally redirects plain HTTP requests arriving
+on the --redirect-http address, such as ":80", to HTTPS on the port of the
+--listen address. spaserve automatically reloads the certificate and key files
+when they change, such as after renewal by an ACME client like certbot.
+Alternatively, spaserve obtains and renews certificates for the --acme-domains
+itself from Let's Encrypt, caching them in the --acme-cache directory. This
+uses the “tls-alpn-01” challenge, so spaserve must be reachable on port 443.
+
+When started by systemd socket activation, spaserve serves on the passed
+socket instead of listening on the --listen address. This allows serving on
+privileged ports without binding privileges, for instance using this
+spaserve.socket unit together with a corresponding spaserve.service unit:
+
+	[Socket]
+	ListenStream=80
+
+	[Install]
+	WantedBy=sockets.target
+
+Behind forwarding proxies passing X-Forwarded-Prefix or X-Forwarded-Uri, the
+base element of the SPA additionally reflects the externally visible prefix.
+spaserve gracefully shuts down on SIGINT and SIGTERM.
+*/
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/thediveo/spaserve"
+)
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	if err := run(ctx, os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "spaserve: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// run serves the SPA as configured by the specified command line arguments
+// until the specified context gets cancelled.
+func run(ctx context.Context, args []string) error {
+	cfg, err := loadConfig(args, os.Getenv)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	handler, err := cfg.handler()
+	if err != nil {
+		return err
+	}
+	var opts []spaserve.ServeOption
+	l, err := spaserve.ActivatedListener()
+	if err != nil {
+		return err
+	}
+	if l != nil {
+		opts = append(opts, spaserve.WithListener(l))
+	}
+	tlsOpts, err := cfg.tlsOptions()
+	if err != nil {
+		return err
+	}
+	opts = append(opts, tlsOpts...)
+	if cfg.RedirectHTTP == "" {
+		return spaserve.Serve(ctx, cfg.Listen, handler, opts...)
+	}
+	redirect, err := cfg.redirectHandler()
+	if err != nil {
+		return err
+	}
+	// Serve both HTTPS and the redirects from plain HTTP until either fails
+	// or the context gets cancelled.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, 2)
+	go func() { errs <- spaserve.Serve(ctx, cfg.RedirectHTTP, redirect) }()
+	go func() { errs <- spaserve.Serve(ctx, cfg.Listen, handler, opts...) }()
+	err = <-errs
+	cancel()
+	return errors.Join(err, <-errs)
+}
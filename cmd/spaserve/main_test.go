@@ -0,0 +1,80 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("spaserve command", func() {
+
+	BeforeEach(func() {
+		stderr := os.Stderr
+		DeferCleanup(func() { os.Stderr = stderr })
+		devnull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(devnull.Close)
+		os.Stderr = devnull // ...silence usage output of the flag package.
+	})
+
+	It("shows help", func() {
+		Expect(run(context.Background(), []string{"--help"})).To(Succeed())
+	})
+
+	It("rejects invalid flags", func() {
+		Expect(run(context.Background(), []string{"--foo"})).To(HaveOccurred())
+		Expect(run(context.Background(), []string{"--dir", "/nonexisting"})).To(HaveOccurred())
+	})
+
+	It("rejects invalid socket activations", func() {
+		dir := GinkgoT().TempDir()
+		GinkgoT().Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+		GinkgoT().Setenv("LISTEN_FDS", "2")
+		Expect(run(context.Background(), []string{"--dir", dir})).To(
+			MatchError(ContainSubstring("multiple sockets")))
+	})
+
+	It("serves until cancelled", func() {
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "index.html"), []byte(`<base href="./" />`), 0o644)).To(Succeed())
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		Expect(run(ctx, []string{"--dir", dir, "--listen", "127.0.0.1:0"})).To(Succeed())
+	})
+
+	It("serves HTTPS until cancelled", func() {
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "index.html"), []byte(`<base href="./" />`), 0o644)).To(Succeed())
+		certFile := filepath.Join(dir, "tls.crt")
+		keyFile := filepath.Join(dir, "tls.key")
+		writeCert(certFile, keyFile, 1, time.Now())
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		Expect(run(ctx, []string{"--dir", dir, "--listen", "127.0.0.1:0",
+			"--tls-cert", certFile, "--tls-key", keyFile})).To(Succeed())
+		Expect(run(ctx, []string{"--dir", dir, "--listen", "127.0.0.1:0",
+			"--tls-cert", certFile, "--tls-key", keyFile, "--redirect-http", "127.0.0.1:0"})).To(Succeed())
+		Expect(run(ctx, []string{"--dir", dir, "--tls-cert", certFile})).NotTo(Succeed())
+		Expect(run(ctx, []string{"--dir", dir, "--redirect-http", "127.0.0.1:0"})).NotTo(Succeed())
+	})
+
+})
@@ -0,0 +1,130 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thediveo/spaserve"
+)
+
+// tlsOptions returns the serve options for HTTPS as configured, either using
+// certificate and key files, or certificates obtained automatically via ACME.
+// Without any TLS configuration, it returns no options, so plain HTTP gets
+// served.
+func (c *config) tlsOptions() ([]spaserve.ServeOption, error) {
+	files := c.TLSCert != "" || c.TLSKey != ""
+	if len(c.ACMEDomains) > 0 {
+		if files {
+			return nil, errors.New("ACME cannot be combined with TLS certificate and key files")
+		}
+		if c.ACMECache == "" {
+			return nil, errors.New("ACME requires a cache directory")
+		}
+		return []spaserve.ServeOption{spaserve.WithAutocert(c.ACMEDomains, c.ACMECache)}, nil
+	}
+	if !files {
+		return nil, nil
+	}
+	certs, err := newCertReloader(c.TLSCert, c.TLSKey)
+	if err != nil {
+		return nil, err
+	}
+	return []spaserve.ServeOption{spaserve.WithTLSConfig(certs.tlsConfig())}, nil
+}
+
+// splitList returns the non-empty, trimmed elements of the specified
+// comma-separated list.
+func splitList(list string) []string {
+	var elements []string
+	for _, element := range strings.Split(list, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			elements = append(elements, element)
+		}
+	}
+	return elements
+}
+
+// certReloader provides the TLS certificate loaded from a pair of certificate
+// and key files, reloading it whenever either file changes. This way, spaserve
+// picks up certificates renewed by ACME clients, such as certbot or lego,
+// without needing to be restarted.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	certTime time.Time // modification time of the loaded certificate file.
+	keyTime  time.Time // modification time of the loaded key file.
+}
+
+// newCertReloader returns a new certReloader for the specified certificate and
+// key files, or an error if the certificate cannot be loaded.
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("TLS requires both a certificate and a key file")
+	}
+	cr := &certReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := cr.certificate(); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// tlsConfig returns a TLS server configuration using the reloaded certificate.
+func (cr *certReloader) tlsConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return cr.certificate()
+		},
+	}
+}
+
+// certificate returns the current certificate, reloading it if either the
+// certificate or key file has changed since last loaded. If reloading fails,
+// the previously loaded certificate is returned, if any.
+func (cr *certReloader) certificate() (*tls.Certificate, error) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	certInfo, certErr := os.Stat(cr.certFile)
+	keyInfo, keyErr := os.Stat(cr.keyFile)
+	if err := errors.Join(certErr, keyErr); err != nil {
+		if cr.cert != nil {
+			return cr.cert, nil
+		}
+		return nil, err
+	}
+	if cr.cert != nil && certInfo.ModTime().Equal(cr.certTime) && keyInfo.ModTime().Equal(cr.keyTime) {
+		return cr.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		if cr.cert != nil {
+			return cr.cert, nil // ...probably caught in the middle of renewal.
+		}
+		return nil, err
+	}
+	cr.cert = &cert
+	cr.certTime = certInfo.ModTime()
+	cr.keyTime = keyInfo.ModTime()
+	return cr.cert, nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+// writeCert writes a new self-signed certificate with the specified serial
+// number and its key into the specified files, setting their modification
+// times to the specified time.
+func writeCert(certFile, keyFile string, serial int64, modTime time.Time) {
+	GinkgoHelper()
+	key := Successful(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der := Successful(x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key))
+	Expect(os.WriteFile(certFile,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644)).To(Succeed())
+	keyDER := Successful(x509.MarshalECPrivateKey(key))
+	Expect(os.WriteFile(keyFile,
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)).To(Succeed())
+	Expect(os.Chtimes(certFile, modTime, modTime)).To(Succeed())
+	Expect(os.Chtimes(keyFile, modTime, modTime)).To(Succeed())
+}
+
+// serialOf returns the serial number of the specified reloader's current
+// certificate.
+func serialOf(cr *certReloader) int64 {
+	GinkgoHelper()
+	cert := Successful(cr.tlsConfig().GetCertificate(nil))
+	return Successful(x509.ParseCertificate(cert.Certificate[0])).SerialNumber.Int64()
+}
+
+var _ = Describe("TLS certificates", func() {
+
+	var certFile, keyFile string
+
+	BeforeEach(func() {
+		dir := GinkgoT().TempDir()
+		certFile = filepath.Join(dir, "tls.crt")
+		keyFile = filepath.Join(dir, "tls.key")
+	})
+
+	It("rejects incomplete or invalid certificates", func() {
+		Expect(newCertReloader(certFile, "")).Error().To(HaveOccurred())
+		Expect(newCertReloader(certFile, keyFile)).Error().To(HaveOccurred())
+		Expect(os.WriteFile(certFile, []byte("foo"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(keyFile, []byte("bar"), 0o600)).To(Succeed())
+		Expect(newCertReloader(certFile, keyFile)).Error().To(HaveOccurred())
+	})
+
+	It("reloads changed certificates", func() {
+		start := time.Now().Add(-time.Minute)
+		writeCert(certFile, keyFile, 1, start)
+		cr := Successful(newCertReloader(certFile, keyFile))
+		Expect(cr.tlsConfig().MinVersion).NotTo(BeZero())
+		Expect(serialOf(cr)).To(Equal(int64(1)))
+
+		writeCert(certFile, keyFile, 2, start.Add(time.Second))
+		Expect(serialOf(cr)).To(Equal(int64(2)))
+
+		By("keeping the current certificate while renewal is in progress")
+		Expect(os.WriteFile(certFile, []byte("foo"), 0o644)).To(Succeed())
+		Expect(serialOf(cr)).To(Equal(int64(2)))
+		Expect(os.Remove(keyFile)).To(Succeed())
+		Expect(serialOf(cr)).To(Equal(int64(2)))
+	})
+
+	It("configures ACME from flags and environment variables", func() {
+		cfg := defaultConfig()
+		Expect(cfg.applyEnv(func(name string) string {
+			return map[string]string{
+				"SPASERVE_ACME_DOMAINS": " example.com, ,www.example.com",
+				"SPASERVE_ACME_CACHE":   "/var/cache/spaserve",
+			}[name]
+		})).To(Succeed())
+		Expect(cfg.ACMEDomains).To(Equal([]string{"example.com", "www.example.com"}))
+		Expect(cfg.ACMECache).To(Equal("/var/cache/spaserve"))
+
+		Expect(cfg.parseFlags([]string{"--acme-domains", "example.org", "--acme-cache", "/tmp/acme"})).To(Succeed())
+		Expect(cfg.ACMEDomains).To(Equal([]string{"example.org"}))
+		Expect(cfg.ACMECache).To(Equal("/tmp/acme"))
+		Expect(Successful(cfg.tlsOptions())).To(HaveLen(1))
+		Expect(cfg.redirectHandler()).Error().NotTo(HaveOccurred())
+	})
+
+	It("rejects invalid ACME configurations", func() {
+		cfg := defaultConfig()
+		cfg.ACMEDomains = []string{"example.com"}
+		Expect(cfg.tlsOptions()).Error().To(MatchError(ContainSubstring("cache directory")))
+		cfg.ACMECache = "/tmp/acme"
+		cfg.TLSCert = certFile
+		Expect(cfg.tlsOptions()).Error().To(MatchError(ContainSubstring("cannot be combined")))
+	})
+
+	It("serves plain HTTP without TLS configuration", func() {
+		Expect(Successful(defaultConfig().tlsOptions())).To(BeEmpty())
+	})
+
+})
@@ -0,0 +1,103 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// ConfigJSON returns a VirtualFileGenerator serving the specified runtime
+// configuration, such as API endpoints and feature flags, serialized as JSON.
+// The configuration is serialized only once, so later changes to it won't be
+// picked up; please use ConfigJSONFunc instead in this case. For instance:
+//
+//	h := NewSPAHandler(bundle, "index.html",
+//	    WithVirtualFile("config.json", ConfigJSON(cfg)))
+func ConfigJSON(config any) VirtualFileGenerator {
+	contents, err := json.Marshal(config)
+	modTime := time.Now()
+	return func(*http.Request) ([]byte, time.Time, error) {
+		return contents, modTime, err
+	}
+}
+
+// ConfigJSONFunc returns a VirtualFileGenerator serving the runtime
+// configuration returned by the specified function, serialized as JSON. The
+// function gets called for each request, such as to serve per-tenant
+// configurations.
+func ConfigJSONFunc(config func(r *http.Request) (any, error)) VirtualFileGenerator {
+	return func(r *http.Request) ([]byte, time.Time, error) {
+		v, err := config(r)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		contents, err := json.Marshal(v)
+		return contents, time.Time{}, err
+	}
+}
+
+// ConfigScript returns a VirtualFileGenerator serving a JavaScript script that
+// assigns the specified runtime configuration to the specified global
+// variable, such as "__CONFIG__". The SPA then loads this script before its
+// own scripts, using a script element with a relative URL, such as:
+//
+//	<script src="config.js"></script>
+//
+// Similar to ConfigJSON, the configuration is serialized only once.
+func ConfigScript(global string, config any) VirtualFileGenerator {
+	contents, err := configScript(global, config)
+	modTime := time.Now()
+	return func(*http.Request) ([]byte, time.Time, error) {
+		return contents, modTime, err
+	}
+}
+
+// ConfigScriptFunc returns a VirtualFileGenerator serving a JavaScript script
+// that assigns the runtime configuration returned by the specified function to
+// the specified global variable. The function gets called for each request.
+func ConfigScriptFunc(global string, config func(r *http.Request) (any, error)) VirtualFileGenerator {
+	return func(r *http.Request) ([]byte, time.Time, error) {
+		v, err := config(r)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		contents, err := configScript(global, v)
+		return contents, time.Time{}, err
+	}
+}
+
+// configScript returns a JavaScript script assigning the specified
+// configuration to the specified global variable. Both the variable name and
+// the configuration are JSON-encoded, so they cannot break out of the script;
+// JSON encoding also escapes “<”, “>”, and “&”.
+func configScript(global string, config any) ([]byte, error) {
+	name, err := json.Marshal(global)
+	if err != nil {
+		return nil, err
+	}
+	contents, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	script := make([]byte, 0, len(name)+len(contents)+16)
+	script = append(script, "window["...)
+	script = append(script, name...)
+	script = append(script, "] = "...)
+	script = append(script, contents...)
+	script = append(script, ";\n"...)
+	return script, nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("runtime configuration", func() {
+
+	type config struct {
+		API      string          `json:"api"`
+		Features map[string]bool `json:"features"`
+	}
+
+	spafs := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<base href="./" />INDEX`)},
+	}
+	cfg := config{API: "/api/v2", Features: map[string]bool{"</script>": true}}
+
+	newHandler := func() *SPAHandler {
+		return NewSPAHandler(spafs, "index.html",
+			WithVirtualFile("config.json", ConfigJSON(cfg)),
+			WithVirtualFile("config.js", ConfigScript("__CONFIG__", cfg)),
+			WithVirtualFile("tenant.json", ConfigJSONFunc(func(r *http.Request) (any, error) {
+				return config{API: "https://" + r.Host + "/api"}, nil
+			})),
+			WithVirtualFile("tenant.js", ConfigScriptFunc(`"];alert(1);//`, func(r *http.Request) (any, error) {
+				return r.Host, nil
+			})),
+			WithVirtualFile("broken.json", ConfigJSONFunc(func(*http.Request) (any, error) {
+				return nil, errors.New("D'oh!")
+			})),
+			WithVirtualFile("unserializable.js", ConfigScript("foo", func() {})))
+	}
+
+	DescribeTable("serves configuration",
+		func(path string, expectedStatus int, expectedType string, expectedBody string) {
+			w := httptest.NewRecorder()
+			newHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.org"+path, nil))
+			Expect(w.Code).To(Equal(expectedStatus))
+			Expect(w.Header().Get("Content-Type")).To(HavePrefix(expectedType))
+			Expect(w.Body.String()).To(Equal(expectedBody))
+		},
+		Entry("JSON", "/config.json", http.StatusOK, "application/json",
+			`{"api":"/api/v2","features":{"\u003c/script\u003e":true}}`),
+		Entry("script", "/config.js", http.StatusOK, "text/javascript",
+			`window["__CONFIG__"] = {"api":"/api/v2","features":{"\u003c/script\u003e":true}};`+"\n"),
+		Entry("per-request JSON", "/tenant.json", http.StatusOK, "application/json",
+			`{"api":"https://example.org/api","features":null}`),
+		Entry("per-request script with weird global", "/tenant.js", http.StatusOK, "text/javascript",
+			`window["\"];alert(1);//"] = "example.org";`+"\n"),
+		Entry("failing configuration", "/broken.json", http.StatusInternalServerError, "text/plain",
+			"500 Internal Server Error\n"),
+		Entry("unserializable configuration", "/unserializable.js", http.StatusInternalServerError, "text/plain",
+			"500 Internal Server Error\n"),
+	)
+
+})
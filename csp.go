@@ -0,0 +1,103 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// CSPNoncePlaceholder is replaced by the per-request CSP nonce in both the
+// Content-Security-Policy passed to WithCSPNonce and the index contents, such
+// as in “<script nonce="__CSP_NONCE__">”.
+const CSPNoncePlaceholder = "__CSP_NONCE__"
+
+// DefaultCSPPolicy is the Content-Security-Policy used by WithCSPNonce when no
+// explicit policy is specified.
+const DefaultCSPPolicy = "default-src 'self'; " +
+	"script-src 'self' 'nonce-" + CSPNoncePlaceholder + "'; " +
+	"style-src 'self' 'nonce-" + CSPNoncePlaceholder + "'; " +
+	"object-src 'none'; base-uri 'self'"
+
+// cspNonceSize is the number of random bytes of a CSP nonce.
+const cspNonceSize = 16
+
+// scriptStyleTagRe matches opening script and style elements, capturing the
+// element name and its attributes.
+var scriptStyleTagRe = regexp.MustCompile(`(?i)<(script|style)(\s[^>]*)?>`)
+
+// nonceAttrRe matches an existing nonce attribute.
+var nonceAttrRe = regexp.MustCompile(`(?i)\snonce\s*=`)
+
+// cspNonceCtxKey is the context key for the per-request CSP nonce.
+type cspNonceCtxKey struct{}
+
+// WithCSPNonce generates a cryptographic nonce for each index request, injects
+// it into the index's script and style elements, and sets a matching
+// Content-Security-Policy response header. Script and style elements already
+// having a nonce attribute are left untouched, so that the CSPNoncePlaceholder
+// can be used instead in such attributes, as well as elsewhere in the index.
+//
+// The policy must contain the CSPNoncePlaceholder wherever the nonce is to be
+// placed; an empty policy defaults to DefaultCSPPolicy.
+//
+// The nonce is passed on to an IndexRewriter via the request context; use
+// CSPNonce to retrieve it, for instance, when injecting additional scripts.
+func WithCSPNonce(policy string) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		if policy == "" {
+			policy = DefaultCSPPolicy
+		}
+		if !strings.Contains(policy, CSPNoncePlaceholder) {
+			h.invalidOption("WithCSPNonce", "policy lacks %s", CSPNoncePlaceholder)
+		}
+		h.cspPolicy = policy
+	}
+}
+
+// CSPNonce returns the CSP nonce from the specified context, or an empty string
+// if there is none. When enabled using WithCSPNonce, the request contexts
+// passed to IndexRewriters contain the CSP nonce for the particular request.
+func CSPNonce(ctx context.Context) string {
+	nonce, _ := ctx.Value(cspNonceCtxKey{}).(string)
+	return nonce
+}
+
+// injectCSPNonce generates a new CSP nonce and injects it into the specified
+// index contents, setting the Content-Security-Policy response header. It
+// returns the request with the nonce added to its context, and the updated
+// index contents.
+func (h *SPAHandler) injectCSPNonce(w http.ResponseWriter, r *http.Request, index []byte) (*http.Request, []byte, error) {
+	b := make([]byte, cspNonceSize)
+	if _, err := rand.Read(b); err != nil {
+		return r, nil, err
+	}
+	nonce := base64.StdEncoding.EncodeToString(b)
+	index = scriptStyleTagRe.ReplaceAllFunc(index, func(tag []byte) []byte {
+		if nonceAttrRe.Match(tag) {
+			return tag
+		}
+		m := scriptStyleTagRe.FindSubmatch(tag)
+		return []byte("<" + string(m[1]) + ` nonce="` + nonce + `"` + string(m[2]) + ">")
+	})
+	index = bytes.ReplaceAll(index, []byte(CSPNoncePlaceholder), []byte(nonce))
+	w.Header().Add("Content-Security-Policy", strings.ReplaceAll(h.cspPolicy, CSPNoncePlaceholder, nonce))
+	return r.WithContext(context.WithValue(r.Context(), cspNonceCtxKey{}, nonce)), index, nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing/fstest"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/thediveo/spaserve/test/fixture"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("CSP nonces", func() {
+
+	It("returns no nonce from a plain context", func() {
+		Expect(CSPNonce(context.Background())).To(BeEmpty())
+	})
+
+	It("injects per-request nonces", func() {
+		bundle := fixture.New(fixture.WithFile("index.html", `<html><head>
+<script src="a.js"></script>
+<SCRIPT type="module" nonce="keep">1</SCRIPT>
+<style>body{}</style>
+<meta name="csp-nonce" content="__CSP_NONCE__">
+</head></html>`))
+		var rewriterNonce string
+		h := NewSPAHandler(bundle.FS, "index.html",
+			WithCSPNonce(""),
+			WithIndexRewriter(func(r *http.Request, index string) string {
+				rewriterNonce = CSPNonce(r.Context())
+				return index
+			}))
+
+		serve := func() (string, string) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			Expect(w.Code).To(Equal(http.StatusOK))
+			csp := w.Header().Get("Content-Security-Policy")
+			m := regexp.MustCompile(`'nonce-([^']+)'`).FindStringSubmatch(csp)
+			Expect(m).To(HaveLen(2))
+			return m[1], w.Body.String()
+		}
+
+		nonce, body := serve()
+		Expect(nonce).To(MatchRegexp(`^[A-Za-z0-9+/]{22}==$`))
+		Expect(rewriterNonce).To(Equal(nonce))
+		doc := Successful(goquery.NewDocumentFromReader(strings.NewReader(body)))
+		Expect(doc.Find(`script[src="a.js"]`).AttrOr("nonce", "")).To(Equal(nonce))
+		Expect(doc.Find(`script[type="module"]`).AttrOr("nonce", "")).To(Equal("keep"))
+		Expect(doc.Find(`style`).AttrOr("nonce", "")).To(Equal(nonce))
+		Expect(doc.Find(`meta[name="csp-nonce"]`).AttrOr("content", "")).To(Equal(nonce))
+
+		nonce2, _ := serve()
+		Expect(nonce2).NotTo(Equal(nonce))
+	})
+
+	It("never answers conditional requests with stale nonces", func() {
+		h := NewSPAHandler(fstest.MapFS{
+			"index.html": &fstest.MapFile{
+				Data:    []byte(`<html><head><script src="a.js"></script></head></html>`),
+				ModTime: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+			},
+		}, "index.html", WithCSPNonce(""))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("Last-Modified")).To(BeEmpty())
+		Expect(w.Header().Get("ETag")).To(BeEmpty())
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("If-Modified-Since", time.Now().UTC().Format(http.TimeFormat))
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		nonce := regexp.MustCompile(`'nonce-([^']+)'`).FindStringSubmatch(
+			w.Header().Get("Content-Security-Policy"))
+		Expect(nonce).To(HaveLen(2))
+		Expect(w.Body.String()).To(ContainSubstring(nonce[1]))
+	})
+
+	It("uses a custom policy", func() {
+		h := NewSPAHandler(embStaticFs, "index.html",
+			WithCSPNonce("script-src 'nonce-"+CSPNoncePlaceholder+"' 'strict-dynamic'"))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		Expect(w.Header().Get("Content-Security-Policy")).To(
+			MatchRegexp(`^script-src 'nonce-[^']+' 'strict-dynamic'$`))
+	})
+
+})
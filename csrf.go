@@ -0,0 +1,154 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Defaults for the CSRF token cookie, request header, and meta tag.
+const (
+	DefaultCSRFCookieName = "csrf_token"
+	DefaultCSRFHeaderName = "X-CSRF-Token"
+	CSRFMetaName          = "csrf-token"
+)
+
+// CSRFTokenPlaceholder is replaced by the CSRF token of the session in the
+// index contents, such as in inline scripts.
+const CSRFTokenPlaceholder = "__CSRF_TOKEN__"
+
+// csrfTokenSize is the number of random bytes of a CSRF token.
+const csrfTokenSize = 32
+
+// csrfToken is the configuration for injecting CSRF tokens into the index.
+type csrfToken struct {
+	cookieName string
+	maxAge     time.Duration
+}
+
+// csrfTokenCtxKey is the context key for the per-session CSRF token.
+type csrfTokenCtxKey struct{}
+
+// WithCSRFToken injects a CSRF token into the index as a meta tag named
+// “csrf-token” (see CSRFMetaName), as well as in place of any
+// CSRFTokenPlaceholder, for use with the double-submit cookie pattern: the SPA
+// reads the token from the meta tag and sends it in a request header, such as
+// “X-CSRF-Token”, which backend handlers then check against the token cookie
+// using ValidCSRFToken.
+//
+// The token is kept per session in the specified cookie, defaulting to
+// DefaultCSRFCookieName, which is HttpOnly, SameSite=Strict, and Secure for
+// HTTPS requests. A new token gets generated when the cookie is missing or
+// invalid, so tokens rotate with the cookie's lifetime: a positive maxAge
+// limits the lifetime, otherwise the cookie lasts for the browser session.
+//
+// As the index now contains a per-session token, the index is never stored in
+// shared caches: its Cache-Control is set to “private, no-cache” (unless the
+// index cache policy already forbids storing) and “Vary: Cookie” gets added.
+// Also, the index is served without any validators, so a client never gets a
+// 304 answer together with a new token cookie.
+// The token is passed on to an IndexRewriter via the request context; use
+// CSRFToken to retrieve it.
+func WithCSRFToken(cookieName string, maxAge time.Duration) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		if cookieName == "" {
+			cookieName = DefaultCSRFCookieName
+		}
+		h.csrf = &csrfToken{
+			cookieName: cookieName,
+			maxAge:     maxAge,
+		}
+	}
+}
+
+// CSRFToken returns the CSRF token from the specified context, or an empty
+// string if there is none. When enabled using WithCSRFToken, the request
+// contexts passed to IndexRewriters contain the CSRF token of the session.
+func CSRFToken(ctx context.Context) string {
+	token, _ := ctx.Value(csrfTokenCtxKey{}).(string)
+	return token
+}
+
+// ValidCSRFToken returns true if the specified request carries a CSRF token in
+// the specified header that matches the token in the specified cookie. Empty
+// names default to DefaultCSRFHeaderName and DefaultCSRFCookieName.
+func ValidCSRFToken(r *http.Request, headerName, cookieName string) bool {
+	if headerName == "" {
+		headerName = DefaultCSRFHeaderName
+	}
+	if cookieName == "" {
+		cookieName = DefaultCSRFCookieName
+	}
+	cookie, err := r.Cookie(cookieName)
+	if err != nil || !isCSRFToken(cookie.Value) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get(headerName)), []byte(cookie.Value)) == 1
+}
+
+// isCSRFToken returns true if the specified token is well-formed.
+func isCSRFToken(token string) bool {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	return err == nil && len(b) == csrfTokenSize
+}
+
+// injectCSRFToken injects the CSRF token of the session into the specified
+// index contents, generating a new token and setting its cookie if necessary.
+// It returns the request with the token added to its context, and the updated
+// index contents.
+func (h *SPAHandler) injectCSRFToken(w http.ResponseWriter, r *http.Request, index []byte) (*http.Request, []byte, error) {
+	var token string
+	if cookie, err := r.Cookie(h.csrf.cookieName); err == nil && isCSRFToken(cookie.Value) {
+		token = cookie.Value
+	} else {
+		b := make([]byte, csrfTokenSize)
+		if _, err := rand.Read(b); err != nil {
+			return r, nil, err
+		}
+		token = base64.RawURLEncoding.EncodeToString(b)
+		cookie := &http.Cookie{
+			Name:     h.csrf.cookieName,
+			Value:    token,
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   originalScheme(r) == "https",
+			SameSite: http.SameSiteStrictMode,
+		}
+		if h.csrf.maxAge > 0 {
+			cookie.MaxAge = int(h.csrf.maxAge / time.Second)
+		}
+		// Never rotate the token cookie without also handing out the index
+		// with the new token, as otherwise the client would be stuck with a
+		// stale token.
+		if r.Method != http.MethodHead {
+			http.SetCookie(w, cookie)
+		}
+	}
+	header := w.Header()
+	header.Add("Vary", "Cookie")
+	if !strings.Contains(h.indexCache, "no-store") {
+		header.Set("Cache-Control", "private, no-cache")
+	}
+	index = injectMeta(index, &MetaTags{Names: map[string]string{CSRFMetaName: token}})
+	index = bytes.ReplaceAll(index, []byte(CSRFTokenPlaceholder), []byte(token))
+	return r.WithContext(context.WithValue(r.Context(), csrfTokenCtxKey{}, token)), index, nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing/fstest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CSRF tokens", func() {
+
+	spafs := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(
+			`<html><head><base href="./" /></head><script>t="__CSRF_TOKEN__"</script></html>`)},
+	}
+
+	metaRe := regexp.MustCompile(`<meta name="csrf-token" content="([^"]+)">`)
+
+	get := func(h *SPAHandler, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "https://example.org/route", nil)
+		for _, cookie := range cookies {
+			r.AddCookie(cookie)
+		}
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	It("generates a token with cookie and injects it", func() {
+		var seen string
+		h := NewSPAHandler(spafs, "index.html",
+			WithCSRFToken("", time.Hour),
+			WithIndexRewriter(func(r *http.Request, index string) string {
+				seen = CSRFToken(r.Context())
+				return index
+			}))
+		w := get(h)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		cookies := w.Result().Cookies()
+		Expect(cookies).To(HaveLen(1))
+		cookie := cookies[0]
+		Expect(cookie.Name).To(Equal(DefaultCSRFCookieName))
+		Expect(cookie.HttpOnly).To(BeTrue())
+		Expect(cookie.Secure).To(BeTrue())
+		Expect(cookie.SameSite).To(Equal(http.SameSiteStrictMode))
+		Expect(cookie.MaxAge).To(Equal(3600))
+
+		m := metaRe.FindStringSubmatch(w.Body.String())
+		Expect(m).NotTo(BeNil())
+		Expect(m[1]).To(Equal(cookie.Value))
+		Expect(seen).To(Equal(cookie.Value))
+		Expect(w.Body.String()).To(ContainSubstring(`t="` + cookie.Value + `"`))
+		Expect(w.Header().Get("Cache-Control")).To(Equal("private, no-cache"))
+		Expect(w.Header().Values("Vary")).To(ContainElement("Cookie"))
+		Expect(w.Header().Get("ETag")).To(BeEmpty())
+
+		By("reusing the token of the session")
+		w = get(h, &http.Cookie{Name: DefaultCSRFCookieName, Value: cookie.Value})
+		Expect(w.Result().Cookies()).To(BeEmpty())
+		Expect(metaRe.FindStringSubmatch(w.Body.String())[1]).To(Equal(cookie.Value))
+
+		By("replacing invalid tokens")
+		w = get(h, &http.Cookie{Name: DefaultCSRFCookieName, Value: "forged"})
+		Expect(w.Result().Cookies()).To(HaveLen(1))
+		Expect(w.Result().Cookies()[0].Value).NotTo(Equal("forged"))
+	})
+
+	It("never rotates the token without serving the index", func() {
+		h := NewSPAHandler(fstest.MapFS{
+			"index.html": &fstest.MapFile{
+				Data:    spafs["index.html"].Data,
+				ModTime: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+			},
+		}, "index.html", WithCSRFToken("", 0))
+
+		r := httptest.NewRequest(http.MethodGet, "https://example.org/route", nil)
+		r.Header.Set("If-Modified-Since", time.Now().UTC().Format(http.TimeFormat))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("Last-Modified")).To(BeEmpty())
+		cookies := w.Result().Cookies()
+		Expect(cookies).To(HaveLen(1))
+		Expect(metaRe.FindStringSubmatch(w.Body.String())[1]).To(Equal(cookies[0].Value))
+
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "https://example.org/route", nil))
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Result().Cookies()).To(BeEmpty())
+	})
+
+	It("keeps a no-store index cache policy", func() {
+		h := NewSPAHandler(spafs, "index.html",
+			WithCSRFToken("xsrf", 0),
+			WithIndexCachePolicy(CachePolicy{NoStore: true}))
+		w := get(h)
+		Expect(w.Header().Get("Cache-Control")).To(Equal("no-store"))
+		Expect(w.Result().Cookies()[0].Name).To(Equal("xsrf"))
+		Expect(w.Result().Cookies()[0].MaxAge).To(BeZero())
+	})
+
+	It("validates double-submitted tokens", func() {
+		w := get(NewSPAHandler(spafs, "index.html", WithCSRFToken("", 0)))
+		token := w.Result().Cookies()[0].Value
+
+		r := httptest.NewRequest(http.MethodPost, "/api/things", nil)
+		Expect(ValidCSRFToken(r, "", "")).To(BeFalse())
+		r.AddCookie(&http.Cookie{Name: DefaultCSRFCookieName, Value: token})
+		Expect(ValidCSRFToken(r, "", "")).To(BeFalse())
+		r.Header.Set(DefaultCSRFHeaderName, "wrong")
+		Expect(ValidCSRFToken(r, "", "")).To(BeFalse())
+		r.Header.Set(DefaultCSRFHeaderName, token)
+		Expect(ValidCSRFToken(r, "", "")).To(BeTrue())
+	})
+
+})
@@ -0,0 +1,200 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// Description describes the effective configuration of an SPAHandler, as
+// returned by SPAHandler.Describe. It is intended for admin and debug
+// endpoints, and thus can be directly encoded into JSON.
+type Description struct {
+	Index             string             `json:"index"`               // (unrooted) path and name of the index/SPA file.
+	RoutingMode       string             `json:"routingMode"`         // current RoutingMode.
+	Methods           []string           `json:"methods"`             // request methods served.
+	IndexCacheControl string             `json:"indexCacheControl"`   // Cache-Control header value of the index.
+	IndexContentType  string             `json:"indexContentType"`    // Content-Type of the index.
+	IndexRewriters    int                `json:"indexRewriters"`      // number of index rewriters, including one set at runtime.
+	Hosts             []string           `json:"hosts,omitempty"`     // sorted virtual host names of WithHostSPAs.
+	Options           []string           `json:"options"`             // sorted names of the options applied, without "With".
+	Headers           []string           `json:"headers,omitempty"`   // sorted names of the additional response headers.
+	Observers         []string           `json:"observers,omitempty"` // types of the observers, such as "*metrics.Metrics".
+	Caching           CachingDescription `json:"caching"`             // caching state of the bundle currently served.
+}
+
+// CachingDescription describes the caching state of the bundle currently
+// served by an SPAHandler.
+type CachingDescription struct {
+	IndexFiles      int  `json:"indexFiles"`      // number of cached, pre-split index files.
+	IndexMetas      int  `json:"indexMetas"`      // number of cached rewritten index metadata.
+	IndexETags      bool `json:"indexETags"`      // rewritten index metadata can be cached, including ETags.
+	AssetETags      int  `json:"assetETags"`      // number of cached strong asset ETags.
+	IntegrityHashes int  `json:"integrityHashes"` // number of cached SRI hashes of assets.
+}
+
+// Index returns the (unrooted) path and name of the index/SPA file served
+// from the fs.FS currently served, see also WithIndexCandidates.
+func (h *SPAHandler) Index() string {
+	return h.bundleIndex(h.current())
+}
+
+// Describe returns a Description of the effective configuration of this
+// SPAHandler, including the options applied when creating it and the caching
+// state of the bundle currently served.
+func (h *SPAHandler) Describe() Description {
+	d := Description{
+		Index:             h.Index(),
+		RoutingMode:       h.RoutingMode().String(),
+		Methods:           append([]string(nil), h.allowedMethods...),
+		IndexCacheControl: h.indexCache,
+		IndexContentType:  h.indexContentType,
+		IndexRewriters:    len(h.indexRewriters),
+		Options:           h.appliedOptions(),
+	}
+	if h.indexRewriter.Load() != nil {
+		d.IndexRewriters++
+	}
+	for host := range h.hosts {
+		d.Hosts = append(d.Hosts, host)
+	}
+	sort.Strings(d.Hosts)
+	for _, header := range []http.Header{h.header, h.assetHeader} {
+		for name := range header {
+			if !slices.Contains(d.Headers, name) {
+				d.Headers = append(d.Headers, name)
+			}
+		}
+	}
+	sort.Strings(d.Headers)
+	for _, observer := range h.observers {
+		d.Observers = append(d.Observers, fmt.Sprintf("%T", observer))
+	}
+	b := h.current()
+	d.Caching = CachingDescription{
+		IndexFiles:      syncMapLen(&b.segments),
+		IndexMetas:      syncMapLen(&b.indexMetas),
+		IndexETags:      h.hasDeterministicIndex(),
+		AssetETags:      syncMapLen(&b.assetETags),
+		IntegrityHashes: syncMapLen(&b.integrityHashes),
+	}
+	return d
+}
+
+// appliedOptions returns the sorted names of the options applied, without
+// their "With" prefixes, as far as these options can be told from the
+// configuration. Options disabling features, such as WithoutBaseRewrite, keep
+// their full names. Options setting headers are recorded explicitly, and
+// WithExpvar is told apart from other observers.
+func (h *SPAHandler) appliedOptions() []string {
+	var expvars, observers int
+	for _, observer := range h.observers {
+		if _, ok := observer.(*expvarObserver); ok {
+			expvars++
+		} else {
+			observers++
+		}
+	}
+	applied := []struct {
+		name string
+		ok   bool
+	}{
+		{"AccessLog", h.accessLogger != nil},
+		{"AllowedExtensions", len(h.allowedExts) > 0},
+		{"AssetCachePolicy", len(h.assetCache) > 0},
+		{"AssetETags", h.assetETags},
+		{"AssetNotFound", h.assetNotFound},
+		{"AssetResponseWrapper", len(h.assetWrappers) > 0},
+		{"Authenticator", h.authenticator != nil},
+		{"BlockedDotfiles", h.blockDotfiles},
+		{"CORSPreflight", h.cors != nil},
+		{"CSPNonce", h.cspPolicy != ""},
+		{"CSRFToken", h.csrf != nil},
+		{"CacheInvalidation", h.invalidation != nil},
+		{"CachePrimer", h.primer != nil},
+		{"Canary", h.canary != nil},
+		{"CanonicalIndexRedirect", h.indexRedirect != 0},
+		{"CleanURLs", h.cleanURLs},
+		{"DeniedFiles", len(h.deniedFiles) > 0},
+		{"DevServer", h.devServer != nil},
+		{"DirectoryPolicy", h.directories != DirectoryFallback},
+		{"EarlyHints", h.earlyHints},
+		{"Expvar", expvars > 0},
+		{"Entry", len(h.entries) > 0},
+		{"ErrorLogger", h.errorLogger != nil},
+		{"ErrorPage", len(h.errorPages) > 0},
+		{"ErrorResponder", h.errorResponder != nil},
+		{"HTTPSRedirect", h.httpsRedirect},
+		{"HealthProbes", h.probes != nil},
+		{"HostSPAs", h.hosts != nil},
+		{"IndexCandidates", len(h.indexCandidates) > 0},
+		{"IndexSelector", h.indexSelector != nil},
+		{"IndexTemplate", h.indexTemplate != nil},
+		{"Integrity", h.integrityMode != IntegrityKeep},
+		{"LegacyIndex", h.legacyIndex != ""},
+		{"LiveReload", h.liveReload != nil},
+		{"LocalizedIndex", len(h.indexLanguages) > 0},
+		{"MIMETypes", len(h.mimeTypes) > 0},
+		{"MaxIndexSize", h.maxIndexSize > 0},
+		{"MetaTags", h.metaProvider != nil},
+		{"NotFoundHandler", h.notFoundHandler != nil},
+		{"Observer", observers > 0},
+		{"OnError", len(h.onError) > 0},
+		{"OnIndex", len(h.onIndex) > 0},
+		{"OnStatic", len(h.onStatic) > 0},
+		{"OptionsResponse", h.answerOptions},
+		{"OverlayFS", h.overlay != nil},
+		{"Placeholders", h.placeholders != nil},
+		{"PreloadLinks", len(h.preloadLinks) > 0 || h.discoverPreloads},
+		{"PrerenderFS", h.prerender != nil},
+		{"RPCPassthrough", h.rpcHandler != nil},
+		{"RequestID", h.requestID != nil},
+		{"SPAPathPredicate", h.spaPathPredicate != nil},
+		{"ServiceWorker", h.serviceWorker != nil},
+		{"SharedAssets", len(h.shared) > 0},
+		{"SourceMaps", h.sourceMaps != nil},
+		{"Title", h.titleFunc != nil},
+		{"TrailingSlashPolicy", h.trailingSlash != TrailingSlashLeave},
+		{"VersionMeta", h.versionMeta != nil},
+		{"VirtualFile", len(h.virtualFiles) > 0},
+		{"WebSocketHandler", h.webSocketHandler != nil},
+		{"WithoutBaseRewrite", h.keepBase},
+	}
+	options := []string{}
+	for _, option := range applied {
+		if option.ok {
+			options = append(options, option.name)
+		}
+	}
+	for name := range h.headerOptions {
+		options = append(options, name)
+	}
+	sort.Strings(options)
+	return options
+}
+
+// syncMapLen returns the number of entries in the specified sync.Map.
+func syncMapLen(m *sync.Map) int {
+	n := 0
+	m.Range(func(any, any) bool {
+		n++
+		return true
+	})
+	return n
+}
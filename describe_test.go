@@ -0,0 +1,89 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("describing handlers", func() {
+
+	It("returns the index and FS", func() {
+		fsys := fstest.MapFS{"app/index.html": {Data: []byte("<html></html>")}}
+		h := NewSPAHandler(fsys, "/app/./index.html")
+		Expect(h.Index()).To(Equal("app/index.html"))
+		Expect(h.FS()).To(Equal(fs.FS(fsys)))
+	})
+
+	It("describes the default configuration", func() {
+		h := NewSPAHandler(embStaticFs, "index.html")
+		d := h.Describe()
+		Expect(d.Index).To(Equal("index.html"))
+		Expect(d.RoutingMode).To(Equal(HistoryRouting.String()))
+		Expect(d.Methods).To(ConsistOf(http.MethodGet, http.MethodHead))
+		Expect(d.IndexCacheControl).To(Equal(DefaultIndexCachePolicy().String()))
+		Expect(d.IndexContentType).To(Equal(DefaultIndexContentType))
+		Expect(d.IndexRewriters).To(BeZero())
+		Expect(d.Hosts).To(BeEmpty())
+		Expect(d.Options).To(BeEmpty())
+		Expect(d.Caching).To(Equal(CachingDescription{IndexETags: true}))
+	})
+
+	It("describes the options applied and the caching state", func() {
+		h := NewSPAHandler(embStaticFs, "index.html",
+			WithIndexRewriter(func(r *http.Request, index string) string { return index }),
+			WithAssetETags(),
+			WithCleanURLs(),
+			WithHostSPAs(map[string]fs.FS{"b.example.com": embStaticFs, "a.example.com": embStaticFs}))
+		h.SetIndexRewriter(func(r *http.Request, index string) string { return index })
+
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+		d := h.Describe()
+		Expect(d.IndexRewriters).To(Equal(2))
+		Expect(d.Hosts).To(Equal([]string{"a.example.com", "b.example.com"}))
+		Expect(d.Options).To(Equal([]string{"AssetETags", "CleanURLs", "HostSPAs"}))
+		Expect(d.Caching.IndexETags).To(BeFalse())
+		Expect(d.Caching.IndexFiles).To(Equal(1))
+
+		Expect(string(Successful(json.Marshal(d)))).To(ContainSubstring(`"options":["AssetETags","CleanURLs","HostSPAs"]`))
+	})
+
+	It("describes options setting headers and observers", func() {
+		h := NewSPAHandler(embStaticFs, "index.html",
+			WithSecurityHeaders(DefaultSecurityHeaders()),
+			WithCrossOriginIsolation(CrossOriginIsolation{ResourcePolicy: "same-origin"}),
+			WithAltSvc(HTTP3AltSvc(443, 0)),
+			WithExpvar("spaserve-describe-test"),
+			WithObserver(&rewriteCountingObserver{}))
+		d := h.Describe()
+		Expect(d.Options).To(Equal([]string{
+			"AltSvc", "CrossOriginIsolation", "Expvar", "Observer", "SecurityHeaders"}))
+		Expect(d.Headers).To(ContainElements("Alt-Svc", "Cross-Origin-Resource-Policy", "X-Content-Type-Options"))
+		Expect(d.Observers).To(Equal([]string{"*spaserve.expvarObserver", "*spaserve.rewriteCountingObserver"}))
+
+		d = NewSPAHandler(embStaticFs, "index.html", WithExpvar("spaserve-describe-test")).Describe()
+		Expect(d.Options).To(Equal([]string{"Expvar"}))
+	})
+
+})
@@ -0,0 +1,132 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxDevServerFileSize limits the size of files fetched from a dev server.
+const maxDevServerFileSize = 16 << 20
+
+// devServer is the configuration for proxying to a frontend dev server.
+type devServer struct {
+	target *url.URL
+	proxy  *httputil.ReverseProxy
+}
+
+// WithDevServer enables a development mode where the SPA is served from the
+// frontend dev server at the specified URL, such as Vite's or webpack's dev
+// server. This replaces the fs.FS passed to NewSPAHandler, which thus can be
+// nil. Index requests still get the full treatment of base rewriting, and so
+// on, with the index fetched from the dev server on each request. All other
+// requests, such as for assets and hot module replacement including WebSocket
+// upgrades, are reverse-proxied to the dev server as is. For instance:
+//
+//	h := NewSPAHandler(bundle, "index.html")
+//	if devMode {
+//	    h = NewSPAHandler(nil, "index.html",
+//	        WithDevServer(&url.URL{Scheme: "http", Host: "localhost:5173"}))
+//	}
+//
+// Requests are considered to be index requests when they are navigation
+// requests, that is, GET requests accepting “text/html”. The dev server
+// should use a relative base path, such as Vite's “base: './'”.
+//
+// WithDevServer is not intended for production use.
+func WithDevServer(target *url.URL) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.devServer = &devServer{
+			target: target,
+			proxy: &httputil.ReverseProxy{
+				Rewrite: func(pr *httputil.ProxyRequest) {
+					// Dev servers might check the Host header, so better
+					// pretend to be the dev server's host.
+					pr.SetURL(target)
+					pr.SetXForwarded()
+				},
+			},
+		}
+		h.bundle.Store(newBundle(&devServerFS{target: target, client: http.DefaultClient}))
+	}
+}
+
+// serveDevServer serves the specified request in development mode, returning
+// the outcome and true. For navigation requests, it serves the rewritten
+// index, otherwise it proxies the request to the dev server. If development
+// mode isn't enabled, it returns false without having served anything.
+//
+// IMPORTANT: the passed r.URL.Path must have already been sanitized.
+func (h *SPAHandler) serveDevServer(w http.ResponseWriter, r *http.Request) (Outcome, bool) {
+	if h.devServer == nil {
+		return 0, false
+	}
+	if r.Method == http.MethodGet && r.Header.Get("Upgrade") == "" &&
+		strings.Contains(r.Header.Get("Accept"), "text/html") {
+		h.serveRewrittenIndex(w, r)
+		return OutcomeIndex, true
+	}
+	h.devServer.proxy.ServeHTTP(w, r)
+	return OutcomeStatic, true
+}
+
+// devServerFS is an fs.FS fetching files from a dev server. As dev servers
+// might change files any time, the files always have the current time as
+// their modification time.
+type devServerFS struct {
+	target *url.URL
+	client *http.Client
+}
+
+var _ fs.FS = (*devServerFS)(nil)
+
+// Open fetches the named file from the dev server.
+func (d *devServerFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	resp, err := d.client.Get(d.target.JoinPath(name).String())
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	defer func() { _ = resp.Body.Close() }()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	case resp.StatusCode != http.StatusOK:
+		return nil, &fs.PathError{Op: "open", Path: name,
+			Err: fmt.Errorf("dev server responded with %s", resp.Status)}
+	}
+	contents, err := io.ReadAll(io.LimitReader(resp.Body, maxDevServerFileSize))
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &memFile{
+		Reader: bytes.NewReader(contents),
+		info: memFileInfo{
+			name:    name[strings.LastIndex(name, "/")+1:],
+			size:    int64(len(contents)),
+			modTime: time.Now(),
+		},
+	}, nil
+}
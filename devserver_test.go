@@ -0,0 +1,111 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"bufio"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("dev server mode", func() {
+
+	var devsrv *httptest.Server
+	var spasrv *httptest.Server
+
+	BeforeEach(func() {
+		devsrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/index.html":
+				_, _ = w.Write([]byte(`<base href="./" /><script type="module" src="./@vite/client"></script>`))
+			case "/src/main.ts":
+				w.Header().Set("Content-Type", "text/javascript")
+				_, _ = w.Write([]byte(`main(` + r.URL.RawQuery + `);`))
+			case "/hmr":
+				conn, brw := Successful2R(http.NewResponseController(w).Hijack())
+				defer conn.Close()
+				_, _ = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+					"Connection: Upgrade\r\nUpgrade: websocket\r\n\r\nHELLO")
+				_ = brw.Flush()
+			case "/broken.html":
+				w.WriteHeader(http.StatusBadGateway)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		DeferCleanup(devsrv.Close)
+		spasrv = httptest.NewServer(NewSPAHandler(nil, "index.html",
+			WithDevServer(Successful(url.Parse(devsrv.URL)))))
+		DeferCleanup(spasrv.Close)
+	})
+
+	get := func(path string, accept string) *http.Response {
+		req := Successful(http.NewRequest(http.MethodGet, spasrv.URL+path, nil))
+		req.Header.Set(ForwardedPrefixHeader, "/app")
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp := Successful(http.DefaultClient.Do(req))
+		DeferCleanup(resp.Body.Close)
+		return resp
+	}
+
+	It("serves the rewritten index from the dev server", func() {
+		resp := get("/some/route", "text/html,application/xhtml+xml")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(string(Successful(io.ReadAll(resp.Body)))).To(Equal(
+			`<base href="/app/" /><script type="module" src="./@vite/client"></script>`))
+	})
+
+	It("proxies assets to the dev server", func() {
+		resp := get("/src/main.ts?t=42", "*/*")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(string(Successful(io.ReadAll(resp.Body)))).To(Equal(`main(t=42);`))
+
+		Expect(get("/src/missing.ts", "").StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("proxies WebSocket upgrades", func() {
+		conn := Successful(net.Dial("tcp", spasrv.Listener.Addr().String()))
+		defer conn.Close()
+		_, err := conn.Write([]byte("GET /hmr HTTP/1.1\r\nHost: localhost\r\n" +
+			"Connection: Upgrade\r\nUpgrade: websocket\r\nAccept: text/html\r\n\r\n"))
+		Expect(err).NotTo(HaveOccurred())
+		br := bufio.NewReader(conn)
+		resp := Successful(http.ReadResponse(br, nil))
+		Expect(resp.StatusCode).To(Equal(http.StatusSwitchingProtocols))
+		Expect(string(Successful(io.ReadAll(br)))).To(Equal("HELLO"))
+	})
+
+	It("fetches files from the dev server", func() {
+		dfs := &devServerFS{target: Successful(url.Parse(devsrv.URL)), client: http.DefaultClient}
+		Expect(fs.ReadFile(dfs, "src/main.ts")).To(Equal([]byte(`main();`)))
+		info := Successful(fs.Stat(dfs, "src/main.ts"))
+		Expect(info.Name()).To(Equal("main.ts"))
+		Expect(info.Size()).To(Equal(int64(7)))
+		Expect(fs.ReadFile(dfs, "missing")).Error().To(MatchError(fs.ErrNotExist))
+		Expect(fs.ReadFile(dfs, "broken.html")).Error().To(MatchError(ContainSubstring("502")))
+		Expect(fs.ReadFile(dfs, "../etc/passwd")).Error().To(MatchError(fs.ErrInvalid))
+	})
+
+})
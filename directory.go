@@ -0,0 +1,106 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// DirectoryPolicy specifies how an SPAHandler treats request paths matching
+// directories in its fs.FS.
+type DirectoryPolicy int
+
+const (
+	// DirectoryFallback treats directories like missing files, so requests
+	// fall back to the index. This is the default.
+	DirectoryFallback DirectoryPolicy = iota
+	// DirectoryIndex serves the “index.html” inside a directory, if present,
+	// with its base element rewritten to refer to the directory. Directories
+	// without an “index.html” fall back to the SPA's index.
+	DirectoryIndex
+	// DirectoryNotFound answers requests for directories with 404.
+	DirectoryNotFound
+)
+
+// String returns the textual representation of a DirectoryPolicy, such as
+// "fallback", "index", or "notfound".
+func (p DirectoryPolicy) String() string {
+	switch p {
+	case DirectoryFallback:
+		return "fallback"
+	case DirectoryIndex:
+		return "index"
+	case DirectoryNotFound:
+		return "notfound"
+	}
+	return fmt.Sprintf("DirectoryPolicy(%d)", int(p))
+}
+
+// WithDirectoryPolicy sets the policy for request paths matching directories
+// in the fs.FS, except for the root directory which always serves the index.
+// For instance, to serve nested micro-frontends from subdirectories of the
+// bundle, each with its own index.html:
+//
+//	h := NewSPAHandler(fsys, "index.html", WithDirectoryPolicy(DirectoryIndex))
+//
+// With DirectoryIndex, a request for “/app/checkout” then serves
+// “checkout/index.html” with its base element rewritten to “/app/checkout/”.
+func WithDirectoryPolicy(p DirectoryPolicy) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.directories = p
+	}
+}
+
+// serveDirectory serves the request for a directory according to the
+// directory policy, returning the outcome and true. Otherwise, if the request
+// isn't for a directory or the policy is to fall back to the index, nothing is
+// served and false is returned.
+//
+// IMPORTANT: the passed r.URL.Path must have already been sanitized.
+func (h *SPAHandler) serveDirectory(w http.ResponseWriter, r *http.Request) (Outcome, bool) {
+	if h.directories == DirectoryFallback || r.URL.Path == "/" {
+		return 0, false
+	}
+	fsys := h.bundleFor(r).fs
+	dir := r.URL.Path[1:]
+	if info, err := fs.Stat(fsys, dir); err != nil || !info.IsDir() {
+		return 0, false
+	}
+	if h.directories == DirectoryNotFound {
+		h.serveNotFound(w, r)
+		return OutcomeNotFound, true
+	}
+	name := path.Join(dir, "index.html")
+	info, err := fs.Stat(fsys, name)
+	if err != nil || !info.Mode().IsRegular() {
+		return 0, false
+	}
+	contents, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		h.serveError(w, r, err)
+		return OutcomeIndex, true
+	}
+	h.callHooks(h.onIndex, r, name, false)
+	base := strings.ReplaceAll(escapedPath(path.Join(h.basename(r), dir)+"/"), "$", "")
+	h.setIndexCacheControl(w)
+	h.setIndexContentType(w)
+	http.ServeContent(w, r, "index.html", info.ModTime(),
+		strings.NewReader(h.replaceBase(string(contents), base)))
+	return OutcomeIndex, true
+}
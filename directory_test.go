@@ -0,0 +1,75 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("directory policies", func() {
+
+	spafs := fstest.MapFS{
+		"index.html":          &fstest.MapFile{Data: []byte(`<base href="./" />SPA`)},
+		"checkout/index.html": &fstest.MapFile{Data: []byte(`<base href="./" />checkout`)},
+		"assets/main.js":      &fstest.MapFile{Data: []byte(`main()`)},
+	}
+
+	It("returns textual representations", func() {
+		Expect(DirectoryFallback.String()).To(Equal("fallback"))
+		Expect(DirectoryIndex.String()).To(Equal("index"))
+		Expect(DirectoryNotFound.String()).To(Equal("notfound"))
+		Expect(DirectoryPolicy(42).String()).To(Equal("DirectoryPolicy(42)"))
+	})
+
+	DescribeTable("serves directories",
+		func(policy DirectoryPolicy, path string, expectedCode int, expectedBody string) {
+			h := NewSPAHandler(spafs, "index.html", WithDirectoryPolicy(policy))
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, path, nil)
+			r.Header.Set(ForwardedPrefixHeader, "/app")
+			h.ServeHTTP(w, r)
+			Expect(w.Code).To(Equal(expectedCode))
+			if expectedBody != "" {
+				Expect(w.Body.String()).To(Equal(expectedBody))
+			}
+		},
+		Entry("fallback", DirectoryFallback, "/checkout", http.StatusOK, `<base href="/app/" />SPA`),
+		Entry("directory index", DirectoryIndex, "/checkout", http.StatusOK, `<base href="/app/checkout/" />checkout`),
+		Entry("directory index with trailing slash", DirectoryIndex, "/checkout/", http.StatusOK, `<base href="/app/checkout/" />checkout`),
+		Entry("missing directory index", DirectoryIndex, "/assets", http.StatusOK, `<base href="/app/" />SPA`),
+		Entry("no directory", DirectoryIndex, "/foo", http.StatusOK, `<base href="/app/" />SPA`),
+		Entry("root", DirectoryNotFound, "/", http.StatusOK, `<base href="/app/" />SPA`),
+		Entry("not found", DirectoryNotFound, "/assets", http.StatusNotFound, ""),
+		Entry("not found, but files", DirectoryNotFound, "/assets/main.js", http.StatusOK, `main()`),
+		Entry("not found, but routes", DirectoryNotFound, "/foo", http.StatusOK, `<base href="/app/" />SPA`),
+	)
+
+	It("serves directory indices as is without base rewriting", func() {
+		h := NewSPAHandler(spafs, "index.html",
+			WithDirectoryPolicy(DirectoryIndex), WithoutBaseRewrite())
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/checkout/", nil)
+		r.Header.Set(ForwardedPrefixHeader, "/app")
+		h.ServeHTTP(w, r)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(Equal(`<base href="./" />checkout`))
+	})
+
+})
@@ -0,0 +1,139 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"html"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// preloadLink is a resource referenced by the index that clients should
+// preload.
+type preloadLink struct {
+	href        string // href as found in the index, relative or rooted.
+	rel         string // "preload" or "modulepreload".
+	as          string // destination, such as "script" or "style".
+	crossorigin string // CORS mode, such as "anonymous" as required for fonts; empty for no CORS.
+}
+
+var (
+	// preloadElementRe matches script and link elements, capturing their
+	// element names and attributes.
+	preloadElementRe = regexp.MustCompile(`(?is)<(script|link)(\s[^>]*)?>`)
+	// elementAttrRe matches attributes with quoted or unquoted values, as
+	// well as boolean attributes without any value, such as “crossorigin”.
+	elementAttrRe = regexp.MustCompile(`(?i)\s([a-z-]+)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+)))?`)
+)
+
+// WithEarlyHints sends a “103 Early Hints” informational response before the
+// index, with “Link” headers telling clients to preload the scripts and
+// stylesheets referenced by the index, so that clients can start fetching
+// them while the index is still on its way. The final index response carries
+// the same Link headers. The referenced scripts and stylesheets are extracted
+// from the index only when (re)loading it, with relative URLs resolved
+// against the base path.
+//
+// As some HTTP/1.1 clients cannot cope with informational responses, the
+// 103 Early Hints response is only sent to HTTP/2 and later clients.
+func WithEarlyHints() SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.earlyHints = true
+	}
+}
+
+// extractPreloads returns the same-origin scripts and stylesheets referenced
+// by the specified HTML document contents, in document order.
+func extractPreloads(contents string) []preloadLink {
+	var links []preloadLink
+	for _, element := range preloadElementRe.FindAllStringSubmatch(contents, -1) {
+		attrs := map[string]string{}
+		for _, attr := range elementAttrRe.FindAllStringSubmatch(element[2], -1) {
+			attrs[strings.ToLower(attr[1])] = html.UnescapeString(attr[2] + attr[3] + attr[4])
+		}
+		var link preloadLink
+		switch strings.ToLower(element[1]) {
+		case "script":
+			link = preloadLink{href: attrs["src"], rel: "preload", as: "script"}
+			if strings.EqualFold(attrs["type"], "module") {
+				link.rel = "modulepreload"
+			}
+		case "link":
+			if !strings.EqualFold(attrs["rel"], "stylesheet") {
+				continue
+			}
+			link = preloadLink{href: attrs["href"], rel: "preload", as: "style"}
+		}
+		// Preloads must use the same CORS mode as the actual requests, as
+		// otherwise browsers download the resources twice.
+		if cors, ok := attrs["crossorigin"]; ok {
+			link.crossorigin = "anonymous"
+			if strings.EqualFold(cors, "use-credentials") {
+				link.crossorigin = "use-credentials"
+			}
+		}
+		if link.href == "" || strings.HasPrefix(link.href, "//") ||
+			strings.Contains(link.href, ":") || strings.ContainsAny(link.href, "<>,; \t\r\n") {
+			continue // not same-origin or unfit for a Link header
+		}
+		links = append(links, link)
+	}
+	return links
+}
+
+// header returns the Link header value for the preload link, with relative
+// URLs resolved against the specified (escaped) base path.
+func (l preloadLink) header(base string) string {
+	href := l.href
+	if !strings.HasPrefix(href, "/") {
+		href = base + strings.TrimPrefix(href, "./")
+	}
+	value := "<" + href + ">; rel=" + l.rel + "; as=" + l.as
+	switch l.crossorigin {
+	case "":
+	case "use-credentials":
+		value += "; crossorigin=use-credentials"
+	default:
+		value += "; crossorigin"
+	}
+	return value
+}
+
+// discoversPreloads returns true if the resources to preload are to be
+// extracted from the index.
+func (h *SPAHandler) discoversPreloads() bool {
+	return h.earlyHints || h.discoverPreloads
+}
+
+// sendEarlyHints sets the Link headers for the configured preload links as
+// well as the preload links of the index segments, and sends them in a 103
+// Early Hints response, if enabled.
+func (h *SPAHandler) sendEarlyHints(w http.ResponseWriter, r *http.Request, segs *indexSegments) {
+	if len(h.preloadLinks) == 0 && len(segs.preloads) == 0 {
+		return
+	}
+	base := h.escapedBase(r)
+	header := w.Header()
+	for _, link := range h.preloadLinks {
+		header.Add("Link", link.header(base))
+	}
+	for _, link := range segs.preloads {
+		header.Add("Link", link.header(base))
+	}
+	if h.earlyHints && r.ProtoMajor >= 2 && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusEarlyHints)
+	}
+}
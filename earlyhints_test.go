@@ -0,0 +1,118 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("early hints", func() {
+
+	spafs := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<html><head><base href="./" />` +
+			`<script type="module" crossorigin src="./assets/index.js"></script>` +
+			`<link rel="stylesheet" href=assets/index.css>` +
+			`<link rel="icon" href="favicon.ico">` +
+			`</head><body></body></html>`)},
+	}
+
+	DescribeTable("extracts preload links",
+		func(html string, expected []preloadLink) {
+			Expect(extractPreloads(html)).To(Equal(expected))
+		},
+		Entry("none", `<html></html>`, nil),
+		Entry("classic and module scripts",
+			`<script src="a.js"></script><SCRIPT TYPE='module' SRC='/b.js'></SCRIPT><script>inline()</script>`,
+			[]preloadLink{
+				{href: "a.js", rel: "preload", as: "script"},
+				{href: "/b.js", rel: "modulepreload", as: "script"},
+			}),
+		Entry("CORS modes",
+			`<script type="module" crossorigin src="a.js"></script><link rel="stylesheet" crossorigin href="b.css"/>`+
+				`<script src="c.js" CROSSORIGIN="use-credentials" defer></script><link crossorigin='anonymous' rel=stylesheet href=d.css>`,
+			[]preloadLink{
+				{href: "a.js", rel: "modulepreload", as: "script", crossorigin: "anonymous"},
+				{href: "b.css", rel: "preload", as: "style", crossorigin: "anonymous"},
+				{href: "c.js", rel: "preload", as: "script", crossorigin: "use-credentials"},
+				{href: "d.css", rel: "preload", as: "style", crossorigin: "anonymous"},
+			}),
+		Entry("stylesheets only",
+			`<link rel="stylesheet" href="a&amp;b.css"><link rel="preconnect" href="/c">`,
+			[]preloadLink{{href: "a&b.css", rel: "preload", as: "style"}}),
+		Entry("skips cross-origin and unfit",
+			`<script src="https://cdn.example.com/a.js"></script><script src="//cdn.example.com/b.js"></script>`+
+				`<script src="c>d.js"></script>`,
+			nil),
+	)
+
+	It("adds Link headers to the index without 103 for HTTP/1.1", func() {
+		h := NewSPAHandler(spafs, "index.html", WithEarlyHints())
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/foo", nil)
+		r.Header.Set(ForwardedPrefixHeader, "/app")
+		h.ServeHTTP(w, r)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Values("Link")).To(Equal([]string{
+			"</app/assets/index.js>; rel=modulepreload; as=script; crossorigin",
+			"</app/assets/index.css>; rel=preload; as=style",
+		}))
+	})
+
+	It("doesn't add Link headers by default", func() {
+		h := NewSPAHandler(spafs, "index.html")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/foo", nil))
+		Expect(w.Header().Values("Link")).To(BeEmpty())
+	})
+
+	It("sends 103 Early Hints to HTTP/2 clients", func() {
+		srv := httptest.NewUnstartedServer(NewSPAHandler(spafs, "index.html", WithEarlyHints()))
+		srv.EnableHTTP2 = true
+		srv.StartTLS()
+		defer srv.Close()
+
+		var hints []textproto.MIMEHeader
+		ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+			Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+				if code == http.StatusEarlyHints {
+					hints = append(hints, header)
+				}
+				return nil
+			},
+		})
+		req := Successful(http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/foo", nil))
+		resp := Successful(srv.Client().Do(req))
+		defer resp.Body.Close()
+		Expect(resp.ProtoMajor).To(Equal(2))
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(Successful(io.ReadAll(resp.Body))).To(ContainSubstring(`<base href="/" />`))
+		Expect(hints).To(HaveLen(1))
+		Expect(hints[0].Values("Link")).To(ConsistOf(
+			"</assets/index.js>; rel=modulepreload; as=script; crossorigin",
+			"</assets/index.css>; rel=preload; as=style"))
+		Expect(resp.Header.Values("Link")).To(HaveLen(2))
+	})
+
+})
@@ -0,0 +1,74 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+// entry maps the SPA route paths below a prefix to an entry document.
+type entry struct {
+	prefix string // rooted and cleaned route path prefix, without trailing slash.
+	index  string // (unrooted) path and name of the entry document.
+}
+
+// WithEntry serves the specified entry document instead of the index passed to
+// NewSPAHandler for all SPA route paths matching the specified prefix, that is,
+// the prefix itself as well as all paths below it. This supports multi-page
+// builds, such as Vite's, with several HTML entry points that share the same
+// assets. WithEntry can be passed multiple times, with the longest matching
+// prefix winning. For instance:
+//
+//	h := NewSPAHandler(fsys, "index.html",
+//	    WithEntry("/admin", "admin/index.html"),
+//	    WithEntry("/help", "help.html"))
+//
+// Here, “/admin” and “/admin/users/42” are served “admin/index.html”, whereas
+// “/administrator” is still served “index.html”. Route path prefixes are
+// relative to the SPA's base path. Entry documents get their base elements
+// rewritten to the SPA's base path, the same way as the index. An index file
+// chosen by an IndexSelector takes precedence over entry documents; legacy and
+// localized index files only apply to the index passed to NewSPAHandler.
+func WithEntry(prefix string, index string) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		prefix = path.Clean("/" + prefix)
+		if prefix == "/" {
+			prefix = ""
+		}
+		h.entries = append(h.entries, entry{
+			prefix: prefix,
+			index:  path.Clean("/" + index)[1:],
+		})
+		sort.SliceStable(h.entries, func(i, j int) bool {
+			return len(h.entries[i].prefix) > len(h.entries[j].prefix)
+		})
+	}
+}
+
+// entryFor returns the (unrooted) path and name of the entry document to serve
+// for the specified request, or an empty string if there is no matching entry.
+//
+// IMPORTANT: the passed r.URL.Path must have already been sanitized.
+func (h *SPAHandler) entryFor(r *http.Request) string {
+	for _, e := range h.entries {
+		if r.URL.Path == e.prefix || strings.HasPrefix(r.URL.Path, e.prefix+"/") {
+			return e.index
+		}
+	}
+	return ""
+}
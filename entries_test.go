@@ -0,0 +1,78 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("entry documents", func() {
+
+	spafs := fstest.MapFS{
+		"index.html":       {Data: []byte(`<html><head><base href="./" /></head><body>main</body></html>`)},
+		"admin/index.html": {Data: []byte(`<html><head><base href="./" /></head><body>admin</body></html>`)},
+		"admin-users.html": {Data: []byte(`<html><head><base href="./" /></head><body>users</body></html>`)},
+		"assets/app.js":    {Data: []byte(`// app`)},
+	}
+
+	DescribeTable("serves entry documents by route path prefix",
+		func(path string, expected string) {
+			h := NewSPAHandler(spafs, "index.html",
+				WithEntry("admin/", "/admin/index.html"),
+				WithEntry("/admin/users", "admin-users.html"))
+			r := httptest.NewRequest("GET", path, nil)
+			r.Header.Set(ForwardedPrefixHeader, "/app/")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(Equal(
+				`<html><head><base href="/app/" /></head><body>` + expected + `</body></html>`))
+		},
+		Entry(nil, "/", "main"),
+		Entry(nil, "/administrator", "main"),
+		Entry(nil, "/admin", "admin"),
+		Entry(nil, "/admin/settings", "admin"),
+		Entry(nil, "/admin/users", "users"),
+		Entry(nil, "/admin/users/42", "users"),
+	)
+
+	It("gives an index selector precedence", func() {
+		h := NewSPAHandler(spafs, "index.html",
+			WithEntry("/admin", "admin/index.html"),
+			WithIndexSelector(func(r *http.Request) string { return "admin-users.html" }))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/admin/", nil))
+		Expect(w.Body.String()).To(ContainSubstring("users"))
+	})
+
+	It("still serves assets", func() {
+		h := NewSPAHandler(spafs, "index.html",
+			WithEntry("/assets", "admin/index.html"))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/assets/app.js", nil))
+		Expect(w.Body.String()).To(Equal("// app"))
+	})
+
+	It("rejects missing entry documents", func() {
+		Expect(NewSPAHandlerE(spafs, "index.html",
+			WithEntry("/help", "help.html"))).Error().To(MatchError(ContainSubstring("entry /help")))
+	})
+
+})
@@ -0,0 +1,115 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+)
+
+// WithErrorPage serves the specified error document from the SPAHandler's fs
+// for the specified HTTP status codes, instead of a plain text error message.
+// The error document gets the same base element rewriting as the index, so it
+// can safely reference the SPA's assets, such as stylesheets and logos. The
+// name must be an unrooted, slash-separated path+name inside the fs; similar
+// to the index, it will be sanitized anyway.
+//
+// For instance, to serve branded error documents:
+//
+//	h := NewSPAHandler(fsys, "index.html",
+//	    WithErrorPage("404.html", http.StatusNotFound),
+//	    WithErrorPage("50x.html", http.StatusInternalServerError, http.StatusServiceUnavailable))
+//
+// If an error document cannot be served, the plain text error message gets
+// served instead.
+func WithErrorPage(name string, statuses ...int) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		if h.errorPages == nil {
+			h.errorPages = map[int]string{}
+		}
+		name = path.Clean("/" + name)[1:]
+		for _, status := range statuses {
+			h.errorPages[status] = name
+		}
+	}
+}
+
+// WithErrorResponder sets the ErrorResponder to write error responses, such as
+// NegotiatedHttpError. Without an ErrorResponder, error responses are always
+// plain text, as written by NormalizedHttpError.
+//
+// Error documents configured using WithErrorPage take precedence, except for
+// requests preferring JSON.
+func WithErrorResponder(responder ErrorResponder) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.errorResponder = responder
+	}
+}
+
+// serveError serves the normalized status code and error message for the
+// specified error, using an error document if configured for the status code
+// and the client doesn't prefer JSON. The original error gets logged, if an
+// error logger has been set, and passed to the error hooks, if any.
+func (h *SPAHandler) serveError(w http.ResponseWriter, r *http.Request, err error) {
+	h.logError(r, err)
+	h.callErrorHooks(r, err)
+	h.writeError(w, r, err)
+}
+
+// writeError writes the normalized status code and error message for the
+// specified error without logging it, using an error document if configured
+// for the status code and the client doesn't prefer JSON.
+func (h *SPAHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status, _, header := normalizedError(err)
+	if !prefersJSON(r) && h.serveErrorPage(w, r, status, header) {
+		return
+	}
+	if h.errorResponder != nil {
+		h.errorResponder(w, r, err)
+		return
+	}
+	NormalizedHttpError(w, err)
+}
+
+// serveErrorPage serves the error document for the specified status code with
+// its base element rewritten and the specified additional response headers
+// set, returning true. If there is no error document configured for the status
+// code or it cannot be read, nothing is served and false is returned instead.
+func (h *SPAHandler) serveErrorPage(w http.ResponseWriter, r *http.Request, status int, header http.Header) bool {
+	name, ok := h.errorPages[status]
+	if !ok {
+		return false
+	}
+	fsys := h.bundleFor(r).fs
+	if fsys == nil {
+		return false
+	}
+	contents, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		h.logError(r, err)
+		return false
+	}
+	html := h.rewriteBase(r, string(contents))
+	setHeader(w, header)
+	header = w.Header()
+	header.Set("Content-Type", "text/html; charset=utf-8")
+	header.Set("Content-Length", strconv.Itoa(len(html)))
+	header.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(html))
+	return true
+}
@@ -0,0 +1,128 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/PuerkitoBio/goquery"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+// failingFS is an fs.FS failing to open the specified file with the specified
+// error, while passing all other operations on to the embedded fs.FS.
+type failingFS struct {
+	fs.FS
+	name string
+	err  error
+}
+
+func (f failingFS) Open(name string) (fs.File, error) {
+	if name == f.name {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: f.err}
+	}
+	return f.FS.Open(name)
+}
+
+var _ = Describe("error pages", func() {
+
+	serve := func(h http.Handler, path string) *httptest.ResponseRecorder {
+		r := &http.Request{
+			Method: "GET",
+			URL:    Successful(url.Parse("http://foo.bar:12345" + path)),
+			Header: http.Header{
+				ForwardedPrefixHeader: []string{"/app"},
+			},
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	It("serves a 404 error page with rewritten base", func() {
+		h := NewSPAHandler(embStaticFs, "index.html",
+			WithAssetNotFound(),
+			WithErrorPage("/errors/404.html", http.StatusNotFound))
+		w := serve(h, "/chunk-abc.js")
+		Expect(w.Code).To(Equal(http.StatusNotFound))
+		Expect(w.Header().Get("Content-Type")).To(HavePrefix("text/html"))
+		Expect(w.Body.String()).To(ContainSubstring("CANARY 404"))
+		doc := Successful(goquery.NewDocumentFromReader(w.Body))
+		href, _ := doc.Find("base").First().Attr("href")
+		Expect(href).To(Equal("/app/"))
+	})
+
+	It("serves a server error page", func() {
+		h := NewSPAHandler(failingFS{FS: embStaticFs, name: "index.html", err: errors.New("D'OH!")},
+			"index.html",
+			WithErrorPage("errors/404.html", http.StatusInternalServerError))
+		w := serve(h, "/")
+		Expect(w.Code).To(Equal(http.StatusInternalServerError))
+		Expect(w.Body.String()).To(ContainSubstring("CANARY 404"))
+		Expect(w.Body.String()).NotTo(ContainSubstring("D'OH!"))
+	})
+
+	It("falls back to plain text when the error page is missing", func() {
+		h := NewSPAHandler(embStaticFs, "index.html",
+			WithAssetNotFound(),
+			WithErrorPage("errors/missing.html", http.StatusNotFound))
+		w := serve(h, "/chunk-abc.js")
+		Expect(w.Code).To(Equal(http.StatusNotFound))
+		Expect(w.Header().Get("Content-Type")).To(HavePrefix("text/plain"))
+	})
+
+	It("uses the error responder, skipping error pages for JSON", func() {
+		h := NewSPAHandler(embStaticFs, "index.html",
+			WithAssetNotFound(),
+			WithErrorPage("errors/404.html", http.StatusNotFound),
+			WithErrorResponder(NegotiatedHttpError))
+		r := &http.Request{
+			Method: "GET",
+			URL:    Successful(url.Parse("http://foo.bar:12345/chunk-abc.js")),
+			Header: http.Header{"Accept": []string{"application/json"}},
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		Expect(w.Code).To(Equal(http.StatusNotFound))
+		Expect(w.Header().Get("Content-Type")).To(Equal("application/problem+json"))
+
+		r.Header.Set("Accept", "text/html")
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		Expect(w.Code).To(Equal(http.StatusNotFound))
+		Expect(w.Body.String()).To(ContainSubstring("CANARY 404"))
+	})
+
+	It("uses registered error matchers", func() {
+		errBundleUnavailable := errors.New("bundle unavailable")
+		defer RegisterErrorStatus(errBundleUnavailable, http.StatusServiceUnavailable,
+			http.Header{"Retry-After": []string{"42"}})()
+		h := NewSPAHandler(failingFS{FS: embStaticFs, name: "index.html", err: errBundleUnavailable},
+			"index.html",
+			WithErrorPage("errors/404.html", http.StatusServiceUnavailable))
+		w := serve(h, "/")
+		Expect(w.Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(w.Header().Get("Retry-After")).To(Equal("42"))
+		Expect(w.Body.String()).To(ContainSubstring("CANARY 404"))
+	})
+
+})
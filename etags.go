@@ -0,0 +1,117 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"crypto/sha256"
+	"io"
+	"io/fs"
+	"net/http"
+	"time"
+)
+
+// assetETag is the strong ETag of a particular version of an asset.
+type assetETag struct {
+	etag    string
+	size    int64
+	modTime time.Time
+}
+
+// WithAssetETags serves static assets with strong ETags computed from their
+// contents, answering conditional requests with “If-None-Match” accordingly.
+// This gives clients usable validators regardless of the fs.FS
+// implementation, such as for embedded bundles lacking modification times.
+//
+// All files get hashed when creating the SPAHandler and when swapping the
+// served fs.FS using SwapFS, so that first requests don't have to wait for
+// hashing. Files changing later get hashed again on their next request.
+func WithAssetETags() SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.assetETags = true
+	}
+}
+
+// hashAssets computes the strong ETags of all files in the specified bundle,
+// if enabled.
+func (h *SPAHandler) hashAssets(b *bundle) {
+	if !h.assetETags || b == nil || b.fs == nil {
+		return
+	}
+	_ = fs.WalkDir(b.fs, ".", func(name string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			_, _ = b.assetETag(name)
+		}
+		return nil
+	})
+}
+
+// hashBundles computes the strong ETags of all files in all bundles, if
+// enabled.
+func (h *SPAHandler) hashBundles() {
+	if !h.assetETags {
+		return
+	}
+	h.hashAssets(h.current())
+	if h.canary != nil {
+		h.hashAssets(h.canary.bundle)
+	}
+	for _, b := range h.hosts {
+		h.hashAssets(b)
+	}
+}
+
+// assetETagHandler returns an http.Handler setting the strong ETag of the
+// requested asset of the specified bundle before passing the request on to
+// the specified handler, if enabled.
+func (h *SPAHandler) assetETagHandler(b *bundle, next http.Handler) http.Handler {
+	if !h.assetETags {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if etag, ok := b.assetETag(r.URL.Path[1:]); ok {
+			w.Header().Set("ETag", etag)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// assetETag returns the strong ETag of the named asset and true, hashing the
+// asset if it hasn't been hashed yet or has changed since. If the asset
+// cannot be hashed, false is returned instead.
+func (b *bundle) assetETag(name string) (string, bool) {
+	info, err := fs.Stat(b.fs, name)
+	if err != nil {
+		return "", false
+	}
+	if v, ok := b.assetETags.Load(name); ok {
+		if e := v.(*assetETag); e.size == info.Size() && e.modTime.Equal(info.ModTime()) {
+			return e.etag, true
+		}
+	}
+	f, err := b.fs.Open(name)
+	if err != nil {
+		return "", false
+	}
+	defer func() { _ = f.Close() }()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", false
+	}
+	var sum [sha256.Size]byte
+	hasher.Sum(sum[:0])
+	e := &assetETag{etag: strongETag(sum), size: info.Size(), modTime: info.ModTime()}
+	b.assetETags.Store(name, e)
+	return e.etag, true
+}
@@ -0,0 +1,83 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("asset ETags", func() {
+
+	newFS := func() fstest.MapFS {
+		return fstest.MapFS{
+			"index.html":    &fstest.MapFile{Data: []byte(`<base href="./" />`)},
+			"assets/app.js": &fstest.MapFile{Data: []byte(`app();`)},
+		}
+	}
+
+	get := func(h http.Handler, path string, etag string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	It("doesn't set ETags by default", func() {
+		h := NewSPAHandler(newFS(), "index.html")
+		Expect(get(h, "/assets/app.js", "").Header().Get("ETag")).To(BeEmpty())
+	})
+
+	It("hashes all assets at construction", func() {
+		h := NewSPAHandler(newFS(), "index.html", WithAssetETags())
+		_, ok := h.current().assetETags.Load("assets/app.js")
+		Expect(ok).To(BeTrue())
+
+		mfs := newFS()
+		h.SwapFS(mfs)
+		_, ok = h.current().assetETags.Load("index.html")
+		Expect(ok).To(BeTrue())
+	})
+
+	It("serves strong ETags and honors If-None-Match", func() {
+		mfs := newFS()
+		h := NewSPAHandler(mfs, "index.html", WithAssetETags())
+		w := get(h, "/assets/app.js", "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		etag := w.Header().Get("ETag")
+		Expect(etag).To(MatchRegexp(`^"[A-Za-z0-9_-]+"$`))
+
+		w = get(h, "/assets/app.js", etag)
+		Expect(w.Code).To(Equal(http.StatusNotModified))
+		Expect(w.Body.Len()).To(BeZero())
+
+		Expect(get(h, "/assets/app.js", `"foo"`).Code).To(Equal(http.StatusOK))
+
+		By("rehashing changed assets")
+		mfs["assets/app.js"] = &fstest.MapFile{Data: []byte(`app(42);`)}
+		w = get(h, "/assets/app.js", etag)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("ETag")).NotTo(Equal(etag))
+		Expect(w.Body.String()).To(Equal(`app(42);`))
+	})
+
+})
@@ -0,0 +1,76 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"expvar"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// expvarObserver is an Observer counting served requests in an expvar.Map.
+type expvarObserver struct {
+	counters *expvar.Map
+}
+
+// expvarMu serializes looking up and publishing the expvar.Maps of counters.
+var expvarMu sync.Mutex
+
+// WithExpvar publishes basic counters of the served requests via expvar under
+// the specified name, such as "spaserve". The counters are "index", "static",
+// "notfound", "redirect", and "options" for the different ways requests were
+// served, as well as "errors" for all responses with 4xx and 5xx status codes
+// other than rejected index fallbacks. With the expvar handler registered
+// (importing package expvar registers it with http.DefaultServeMux), “GET
+// /debug/vars” thus instantly shows whether the index fallback works as
+// expected.
+//
+// Multiple SPAHandlers configured with the same name share the same counters.
+// If the name has already been published as some other kind of variable, such
+// as the built-in "memstats", no counters get published and NewSPAHandlerE
+// reports the conflict.
+func WithExpvar(name string) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		expvarMu.Lock()
+		defer expvarMu.Unlock()
+		var counters *expvar.Map
+		switch v := expvar.Get(name).(type) {
+		case nil:
+			counters = expvar.NewMap(name)
+		case *expvar.Map:
+			counters = v
+		default:
+			h.invalidOption("WithExpvar", "%q already published as %T", name, v)
+			return
+		}
+		for _, key := range []string{"index", "static", "notfound", "redirect", "options", "errors"} {
+			counters.Add(key, 0)
+		}
+		h.observers = append(h.observers, &expvarObserver{counters: counters})
+	}
+}
+
+// ObserveRequest implements Observer.
+func (o *expvarObserver) ObserveRequest(r *http.Request, info ServeInfo) {
+	if info.Outcome != OutcomeNotFound && info.Status >= http.StatusBadRequest {
+		o.counters.Add("errors", 1)
+		return
+	}
+	o.counters.Add(info.Outcome.String(), 1)
+}
+
+// ObserveIndexRewrite implements Observer.
+func (o *expvarObserver) ObserveIndexRewrite(*http.Request, time.Duration) {}
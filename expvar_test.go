@@ -0,0 +1,56 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"errors"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("expvar counters", func() {
+
+	It("counts served requests", func() {
+		h := NewSPAHandler(embStaticFs, "index.html",
+			WithAssetNotFound(),
+			WithExpvar("spaserve-test"))
+		for _, path := range []string{"/", "/route", "/static/js/some.js", "/missing.js"} {
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		}
+		h2 := NewSPAHandler(failingFS{FS: embStaticFs, name: "index.html", err: errors.New("D'OH!")},
+			"index.html", WithExpvar("spaserve-test"))
+		h2.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		counters := expvar.Get("spaserve-test").(*expvar.Map)
+		Expect(counters.Get("index").String()).To(Equal("2"))
+		Expect(counters.Get("static").String()).To(Equal("1"))
+		Expect(counters.Get("notfound").String()).To(Equal("1"))
+		Expect(counters.Get("redirect").String()).To(Equal("0"))
+		Expect(counters.Get("errors").String()).To(Equal("1"))
+	})
+
+	It("reports conflicting variables instead of panicking", func() {
+		var h *SPAHandler
+		Expect(func() { h = NewSPAHandler(embStaticFs, "index.html", WithExpvar("memstats")) }).NotTo(Panic())
+		Expect(h.observers).To(BeEmpty())
+		Expect(NewSPAHandlerE(embStaticFs, "index.html", WithExpvar("memstats"))).Error().To(
+			MatchError(ContainSubstring("WithExpvar")))
+	})
+
+})
@@ -0,0 +1,125 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// WithAssetNotFound answers requests for missing asset-like resources with 404
+// instead of the index. A request is considered to be asset-like when the last
+// element of its path has a file extension, such as “chunk-abc.js”. If exts is
+// specified, then only the listed file extensions (such as ".js", ".css")
+// count as asset-like, otherwise any extension does.
+//
+// Without this option a missing JavaScript chunk gets served the index instead,
+// with a status code of 200, resulting in rather cryptic “Unexpected token <”
+// module loading errors in browsers.
+func WithAssetNotFound(exts ...string) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.assetNotFound = true
+		h.assetExts = nil
+		for _, ext := range exts {
+			if ext == "" {
+				continue
+			}
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			h.assetExts = append(h.assetExts, strings.ToLower(ext))
+		}
+	}
+}
+
+// SPAPathPredicate decides whether a request for a non-existing resource should
+// fall back to the index, returning true; otherwise, returning false rejects
+// the request with 404. The request URL path passed to the predicate has
+// already been sanitized and is relative to the SPA's base path.
+type SPAPathPredicate func(r *http.Request) bool
+
+// WithSPAPathPredicate sets the SPAPathPredicate deciding whether requests for
+// non-existing resources fall back to the index or get rejected with 404
+// instead. For instance, to fall back only for routes beneath “/ui”:
+//
+//	WithSPAPathPredicate(func(r *http.Request) bool {
+//	    return r.URL.Path == "/" || r.URL.Path == "/ui" ||
+//	        strings.HasPrefix(r.URL.Path, "/ui/")
+//	})
+func WithSPAPathPredicate(predicate SPAPathPredicate) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.spaPathPredicate = predicate
+	}
+}
+
+// fallsBackToIndex returns true if a request for a non-existing resource should
+// be served the index, otherwise false.
+//
+// IMPORTANT: the passed r.URL.Path must have already been sanitized.
+func (h *SPAHandler) fallsBackToIndex(r *http.Request) bool {
+	if h.isMissingAsset(r) {
+		return false
+	}
+	if h.spaPathPredicate != nil {
+		return h.spaPathPredicate(r)
+	}
+	return true
+}
+
+// WithNotFoundHandler sets the handler to be invoked instead of the index
+// fallback whenever a request for a non-existing resource must not fall back
+// to the index, such as when the SPAPathPredicate rejects the request path or
+// for missing asset-like resources. Without a NotFound handler, such requests
+// are answered with a 404 error.
+func WithNotFoundHandler(handler http.Handler) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.notFoundHandler = handler
+	}
+}
+
+// serveNotFound serves a request for a non-existing resource that must not fall
+// back to the index.
+func (h *SPAHandler) serveNotFound(w http.ResponseWriter, r *http.Request) {
+	if h.notFoundHandler != nil {
+		h.notFoundHandler.ServeHTTP(w, r)
+		return
+	}
+	h.writeError(w, r, fs.ErrNotExist)
+}
+
+// isMissingAsset returns true if the request (for a resource that is already
+// known to be missing) is asset-like and thus must not fall back to the index.
+//
+// IMPORTANT: the passed r.URL.Path must have already been sanitized.
+func (h *SPAHandler) isMissingAsset(r *http.Request) bool {
+	if !h.assetNotFound {
+		return false
+	}
+	ext := strings.ToLower(path.Ext(r.URL.Path))
+	if ext == "" {
+		return false
+	}
+	if len(h.assetExts) == 0 {
+		return true
+	}
+	for _, assetExt := range h.assetExts {
+		if ext == assetExt {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("index fallback", func() {
+
+	DescribeTable("answers missing asset-like resources with 404",
+		func(path string, opts []SPAHandlerOption, expectedStatus int) {
+			r := &http.Request{
+				Method: "GET",
+				URL:    Successful(url.Parse("http://foo.bar:12345" + path)),
+			}
+			h := NewSPAHandler(embStaticFs, "index.html", opts...)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			Expect(w.Result().StatusCode).To(Equal(expectedStatus))
+		},
+		Entry("missing chunk without option", "/chunk-abc.js", nil, http.StatusOK),
+		Entry("missing chunk", "/chunk-abc.js",
+			[]SPAHandlerOption{WithAssetNotFound()}, http.StatusNotFound),
+		Entry("missing chunk with matching extension", "/static/CHUNK-abc.JS",
+			[]SPAHandlerOption{WithAssetNotFound(".css", "js")}, http.StatusNotFound),
+		Entry("missing asset with non-matching extension", "/users/john.doe",
+			[]SPAHandlerOption{WithAssetNotFound(".css", ".js")}, http.StatusOK),
+		Entry("route without extension", "/users/42",
+			[]SPAHandlerOption{WithAssetNotFound()}, http.StatusOK),
+		Entry("existing asset", "/static/js/some.js",
+			[]SPAHandlerOption{WithAssetNotFound()}, http.StatusOK),
+	)
+
+	DescribeTable("falls back only where the SPA path predicate allows",
+		func(path string, expectedStatus int) {
+			r := &http.Request{
+				Method: "GET",
+				URL:    Successful(url.Parse("http://foo.bar:12345" + path)),
+			}
+			h := NewSPAHandler(embStaticFs, "index.html",
+				WithSPAPathPredicate(func(r *http.Request) bool {
+					return r.URL.Path == "/" || r.URL.Path == "/ui" ||
+						strings.HasPrefix(r.URL.Path, "/ui/")
+				}))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			Expect(w.Result().StatusCode).To(Equal(expectedStatus))
+		},
+		Entry("root", "/", http.StatusOK),
+		Entry("ui route", "/ui/users/42", http.StatusOK),
+		Entry("sanitized ui route", "/foo/../ui", http.StatusOK),
+		Entry("static asset", "/static/js/some.js", http.StatusOK),
+		Entry("elsewhere", "/api/users", http.StatusNotFound),
+		Entry("ui lookalike", "/uifoo", http.StatusNotFound),
+	)
+
+	It("invokes the NotFound handler instead of falling back", func() {
+		h := NewSPAHandler(embStaticFs, "index.html",
+			WithAssetNotFound(".js"),
+			WithSPAPathPredicate(func(r *http.Request) bool {
+				return !strings.HasPrefix(r.URL.Path, "/api/")
+			}),
+			WithNotFoundHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+			})))
+		for path, expectedStatus := range map[string]int{
+			"/api/users":    http.StatusTeapot,
+			"/chunk-abc.js": http.StatusTeapot,
+			"/users/42":     http.StatusOK,
+		} {
+			r := &http.Request{
+				Method: "GET",
+				URL:    Successful(url.Parse("http://foo.bar:12345" + path)),
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			Expect(w.Result().StatusCode).To(Equal(expectedStatus), "path %s", path)
+		}
+	})
+
+})
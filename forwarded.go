@@ -0,0 +1,135 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// forwardedPrefix returns the sanitized and decoded path from the specified
+// X-Forwarded-Prefix header value and true. If the header value is empty or
+// malformed, it returns false instead, so the header should be ignored.
+//
+// In case of multiple comma-separated values, only the first value is used.
+func forwardedPrefix(value string) (string, bool) {
+	value = firstHeaderValue(value)
+	if value == "" {
+		return "", false
+	}
+	prefix, err := url.PathUnescape(value)
+	if err != nil {
+		return "", false
+	}
+	return sanitizedForwardedPath(prefix)
+}
+
+// forwardedURIPath returns the sanitized and decoded path from the specified
+// X-Forwarded-Uri header value and true. The header value can be either just
+// a (percent-encoded) request URI, with or without query, or a full URL. If
+// the header value is empty or malformed, it returns false instead, so the
+// header should be ignored.
+//
+// In case of multiple comma-separated values, only the first value is used.
+func forwardedURIPath(value string) (string, bool) {
+	value = firstHeaderValue(value)
+	if value == "" {
+		return "", false
+	}
+	if strings.HasPrefix(value, "/") {
+		// Assume it's just the request URI, so don't let url.Parse mistake
+		// multiple leading slashes for a host.
+		value, _, _ = strings.Cut(value, "#")
+		value, _, _ = strings.Cut(value, "?")
+		uripath, err := url.PathUnescape(value)
+		if err != nil {
+			return "", false
+		}
+		return sanitizedForwardedPath(uripath)
+	}
+	// Attempt to parse it as a URI, erm, URL, which may also contain userinfo
+	// and what not; if that fails, just ignore it.
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" {
+		return "", false
+	}
+	return sanitizedForwardedPath(u.Path)
+}
+
+// firstHeaderValue returns the first, trimmed value of a potentially
+// comma-separated list of header values.
+func firstHeaderValue(value string) string {
+	value, _, _ = strings.Cut(value, ",")
+	return strings.TrimSpace(value)
+}
+
+// sanitizedForwardedPath returns the sanitized (decoded) path and true, or
+// false if the path contains control characters, backslashes, or “..”
+// elements.
+func sanitizedForwardedPath(uripath string) (string, bool) {
+	if hasInvalidPathChars(uripath) || hasTraversal(uripath) {
+		return "", false
+	}
+	return path.Clean("/" + uripath), true
+}
+
+// escapedPath returns the specified (decoded) path in escaped form, suitable
+// for use in URLs and HTML attributes.
+func escapedPath(uripath string) string {
+	return (&url.URL{Path: uripath}).EscapedPath()
+}
+
+// ForwardedProtoHeader and ForwardedHostHeader, if present, specify the
+// original scheme and host of a request when hitting the first proxy.
+const (
+	ForwardedProtoHeader = "X-Forwarded-Proto"
+	ForwardedHostHeader  = "X-Forwarded-Host"
+)
+
+// originalOrigin returns the origin (scheme and host) of the specified request
+// when it hit the first proxy, based on the X-Forwarded-Proto and
+// X-Forwarded-Host headers, if present and well-formed. Otherwise, the origin
+// is taken from the request itself.
+func originalOrigin(r *http.Request) string {
+	return originalScheme(r) + "://" + originalHost(r)
+}
+
+// originalScheme returns the scheme, either "http" or "https", of the
+// specified request when it hit the first proxy, based on the
+// X-Forwarded-Proto header, if present and well-formed. Otherwise, the scheme
+// is taken from the request itself.
+func originalScheme(r *http.Request) string {
+	if proto := strings.ToLower(firstHeaderValue(r.Header.Get(ForwardedProtoHeader))); proto == "http" || proto == "https" {
+		return proto
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// originalHost returns the host, with optional port, of the specified request
+// when it hit the first proxy, based on the X-Forwarded-Host header, if
+// present and well-formed. Otherwise, the host is taken from the request
+// itself.
+func originalHost(r *http.Request) string {
+	if fwhost := firstHeaderValue(r.Header.Get(ForwardedHostHeader)); fwhost != "" &&
+		!strings.ContainsAny(fwhost, "/\\@?#%<>\"' \t") {
+		return fwhost
+	}
+	return r.Host
+}
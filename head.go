@@ -0,0 +1,156 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// indexMeta describes the rewritten index for a particular base, so HEAD and
+// conditional requests can be answered without reading and rewriting the
+// index.
+type indexMeta struct {
+	modTime time.Time // modification time of the index file.
+	size    int64     // size of the rewritten index.
+	etag    string    // strong ETag of the rewritten index.
+}
+
+// indexMetaKey identifies the rewritten metadata of a particular index file
+// for a particular base.
+type indexMetaKey struct {
+	index string
+	base  string
+}
+
+// hasDeterministicIndex returns true if the rewritten index depends only on the
+// base and the index file itself, but neither on anything else in the request
+// nor on other assets, such as when recomputing integrity attributes. Only then
+// the rewritten index metadata can be cached.
+func (h *SPAHandler) hasDeterministicIndex() bool {
+	return len(h.indexRewriters) == 0 && h.cspPolicy == "" && h.indexTemplate == nil &&
+		h.metaProvider == nil && h.titleFunc == nil && h.csrf == nil &&
+		h.requestID == nil && h.indexRewriter.Load() == nil &&
+		h.integrityMode != IntegrityRecompute
+}
+
+// rememberIndex caches the metadata of the specified rewritten contents of the
+// named index of the specified bundle for the specified base, returning the
+// metadata. If the rewritten index isn't deterministic, it returns nil instead.
+func (h *SPAHandler) rememberIndex(b *bundle, index string, base string, modTime time.Time, contents []byte) *indexMeta {
+	if !h.hasDeterministicIndex() {
+		return nil
+	}
+	if meta := b.cachedIndexMeta(index, base, modTime); meta != nil {
+		return meta
+	}
+	return b.storeIndexMeta(index, base, modTime, int64(len(contents)), sha256.Sum256(contents))
+}
+
+// cachedIndexMeta returns the cached metadata of the named rewritten index for
+// the specified base, or nil if there is none or it is stale.
+func (b *bundle) cachedIndexMeta(index string, base string, modTime time.Time) *indexMeta {
+	if v, ok := b.indexMetas.Load(indexMetaKey{index: index, base: base}); ok {
+		if meta := v.(*indexMeta); meta.modTime.Equal(modTime) {
+			return meta
+		}
+	}
+	return nil
+}
+
+// storeIndexMeta caches the metadata of the named rewritten index for the
+// specified base, given its size and SHA256 digest, returning the metadata.
+func (b *bundle) storeIndexMeta(index string, base string, modTime time.Time, size int64, sum [sha256.Size]byte) *indexMeta {
+	meta := &indexMeta{
+		modTime: modTime,
+		size:    size,
+		etag:    strongETag(sum),
+	}
+	b.indexMetas.Store(indexMetaKey{index: index, base: base}, meta)
+	return meta
+}
+
+// strongETag returns a strong ETag for contents with the specified SHA256
+// digest.
+func strongETag(sum [sha256.Size]byte) string {
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:18]) + `"`
+}
+
+// serveIndexFromMeta answers a HEAD request or a conditional GET request for
+// the named index from the cached metadata of the rewritten index, if
+// available, returning true. Conditional requests whose validators match the
+// cached metadata are answered with 304 without rewriting the index. Otherwise,
+// it returns false and the index needs to be rewritten the usual way.
+func (h *SPAHandler) serveIndexFromMeta(w http.ResponseWriter, r *http.Request, index string) bool {
+	if !h.hasDeterministicIndex() || (r.Method != http.MethodHead && !hasValidators(r)) {
+		return false
+	}
+	b := h.bundleFor(r)
+	fileInfo, err := fs.Stat(b.fs, index)
+	if err != nil {
+		return false
+	}
+	meta := b.cachedIndexMeta(index, h.basename(r), fileInfo.ModTime())
+	if meta == nil {
+		return false
+	}
+	header := w.Header()
+	header.Set("ETag", meta.etag)
+	if !meta.modTime.IsZero() {
+		header.Set("Last-Modified", meta.modTime.UTC().Format(http.TimeFormat))
+	}
+	if meta.notModified(r) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	if r.Method != http.MethodHead {
+		return false
+	}
+	h.setIndexContentType(w)
+	header.Set("Content-Length", strconv.FormatInt(meta.size, 10))
+	w.WriteHeader(http.StatusOK)
+	return true
+}
+
+// hasValidators returns true if the specified request is a conditional
+// request with validators to check against the rewritten index.
+func hasValidators(r *http.Request) bool {
+	return r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != ""
+}
+
+// notModified returns true if the validators of the specified conditional
+// request match the rewritten index. Following RFC 9110, If-Modified-Since is
+// only evaluated in the absence of If-None-Match.
+func (m *indexMeta) notModified(r *http.Request) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, etag := range strings.Split(inm, ",") {
+			etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+			if etag == "*" || etag == m.etag {
+				return true
+			}
+		}
+		return false
+	}
+	ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || m.modTime.IsZero() {
+		return false
+	}
+	return !m.modTime.Truncate(time.Second).After(ims)
+}
@@ -0,0 +1,224 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// readCountingFS counts how often files get read from.
+type readCountingFS struct {
+	fs.FS
+	reads int
+}
+
+func (f *readCountingFS) Open(name string) (fs.File, error) {
+	file, err := f.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &readCountingFile{File: file, fsys: f}, nil
+}
+
+type readCountingFile struct {
+	fs.File
+	fsys *readCountingFS
+}
+
+func (f *readCountingFile) Read(b []byte) (int, error) {
+	f.fsys.reads++
+	return f.File.Read(b)
+}
+
+var _ = Describe("HEAD requests for the index", func() {
+
+	var mfs fstest.MapFS
+
+	BeforeEach(func() {
+		mfs = fstest.MapFS{
+			"index.html": &fstest.MapFile{
+				Data:    []byte(`<html><head><base href="./" /></head></html>`),
+				ModTime: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+			},
+		}
+	})
+
+	serve := func(h http.Handler, method string, prefix string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/foo", nil)
+		r.Header.Set(ForwardedPrefixHeader, prefix)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	It("answers from the cached index metadata without reading the index", func() {
+		cfs := &readCountingFS{FS: mfs}
+		h := NewSPAHandler(cfs, "index.html")
+
+		get := serve(h, http.MethodGet, "/app")
+		Expect(get.Code).To(Equal(http.StatusOK))
+		Expect(get.Header().Get("ETag")).NotTo(BeEmpty())
+
+		reads := cfs.reads
+		head := serve(h, http.MethodHead, "/app")
+		Expect(head.Code).To(Equal(http.StatusOK))
+		Expect(cfs.reads).To(Equal(reads), "index must not be read")
+		Expect(head.Body.Len()).To(BeZero())
+		Expect(head.Header().Get("Content-Length")).To(Equal(get.Header().Get("Content-Length")))
+		Expect(head.Header().Get("ETag")).To(Equal(get.Header().Get("ETag")))
+		Expect(head.Header().Get("Last-Modified")).To(Equal("Mon, 02 Jan 2023 03:04:05 GMT"))
+		Expect(head.Header().Get("Content-Type")).To(Equal("text/html; charset=utf-8"))
+
+		other := serve(h, http.MethodHead, "/other")
+		Expect(other.Code).To(Equal(http.StatusOK))
+		Expect(other.Header().Get("ETag")).NotTo(Equal(get.Header().Get("ETag")))
+	})
+
+	It("rewrites the index again after it has changed", func() {
+		h := NewSPAHandler(mfs, "index.html")
+		get := serve(h, http.MethodGet, "/app")
+
+		mfs["index.html"] = &fstest.MapFile{
+			Data:    []byte(`<html><head><base href="./" /></head><body>new</body></html>`),
+			ModTime: time.Date(2023, 1, 2, 3, 4, 6, 0, time.UTC),
+		}
+		head := serve(h, http.MethodHead, "/app")
+		Expect(head.Header().Get("ETag")).NotTo(Equal(get.Header().Get("ETag")))
+		Expect(head.Header().Get("Content-Length")).NotTo(Equal(get.Header().Get("Content-Length")))
+		Expect(head.Header().Get("Last-Modified")).To(Equal("Mon, 02 Jan 2023 03:04:06 GMT"))
+	})
+
+	It("doesn't cache request-specific index rewrites", func() {
+		h := NewSPAHandler(mfs, "index.html",
+			WithIndexRewriter(func(r *http.Request, index string) string {
+				return index + r.Header.Get("X-Extra")
+			}))
+		get := serve(h, http.MethodGet, "/app")
+		Expect(get.Header().Get("ETag")).To(BeEmpty())
+		head := serve(h, http.MethodHead, "/app")
+		Expect(head.Code).To(Equal(http.StatusOK))
+		Expect(head.Header().Get("ETag")).To(BeEmpty())
+	})
+
+	It("doesn't cache indices with recomputed integrity attributes", func() {
+		mfs["index.html"] = &fstest.MapFile{
+			Data:    []byte(`<html><head><script src="a.js" integrity="sha256-stale"></script></head></html>`),
+			ModTime: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		}
+		mfs["a.js"] = &fstest.MapFile{Data: []byte("old")}
+		h := NewSPAHandler(mfs, "index.html", WithIntegrity(IntegrityRecompute))
+		get := serve(h, http.MethodGet, "/app")
+		Expect(get.Header().Get("ETag")).To(BeEmpty())
+		Expect(get.Header().Get("Last-Modified")).To(BeEmpty())
+
+		mfs["a.js"] = &fstest.MapFile{Data: []byte("renewed")}
+		r := httptest.NewRequest(http.MethodGet, "/foo", nil)
+		r.Header.Set("If-Modified-Since", "Mon, 02 Jan 2023 03:04:05 GMT")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).NotTo(Equal(get.Body.String()))
+	})
+
+})
+
+// rewriteCountingObserver counts index rewrites.
+type rewriteCountingObserver struct {
+	rewrites int
+}
+
+func (o *rewriteCountingObserver) ObserveRequest(*http.Request, ServeInfo) {}
+
+func (o *rewriteCountingObserver) ObserveIndexRewrite(*http.Request, time.Duration) {
+	o.rewrites++
+}
+
+var _ = Describe("conditional GET requests for the index", func() {
+
+	mfs := fstest.MapFS{
+		"index.html": &fstest.MapFile{
+			Data:    []byte(`<html><head><base href="./" /></head></html>`),
+			ModTime: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+	}
+
+	get := func(h http.Handler, header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/foo", nil)
+		for name, values := range header {
+			r.Header[name] = values
+		}
+		r.Header.Set(ForwardedPrefixHeader, "/app")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	DescribeTable("answers from the cached index metadata without rewriting",
+		func(validators func(etag string) http.Header, expectedStatus int, expectedRewrites int) {
+			obs := &rewriteCountingObserver{}
+			h := NewSPAHandler(mfs, "index.html",
+				WithIntegrity(IntegrityStrip), // ...disables streaming
+				WithObserver(obs))
+			first := get(h, nil)
+			Expect(first.Code).To(Equal(http.StatusOK))
+			Expect(obs.rewrites).To(Equal(1))
+			etag := first.Header().Get("ETag")
+			Expect(etag).NotTo(BeEmpty())
+
+			w := get(h, validators(etag))
+			Expect(w.Code).To(Equal(expectedStatus))
+			Expect(w.Header().Get("ETag")).To(Equal(etag))
+			Expect(obs.rewrites).To(Equal(1 + expectedRewrites))
+			if expectedStatus == http.StatusNotModified {
+				Expect(w.Body.Len()).To(BeZero())
+			}
+		},
+		Entry("matching ETag", func(etag string) http.Header {
+			return http.Header{"If-None-Match": {etag}}
+		}, http.StatusNotModified, 0),
+		Entry("matching weak ETag in list", func(etag string) http.Header {
+			return http.Header{"If-None-Match": {`"foo", W/` + etag}}
+		}, http.StatusNotModified, 0),
+		Entry("wildcard", func(string) http.Header {
+			return http.Header{"If-None-Match": {"*"}}
+		}, http.StatusNotModified, 0),
+		Entry("mismatching ETag", func(string) http.Header {
+			return http.Header{"If-None-Match": {`"foo"`}}
+		}, http.StatusOK, 1),
+		Entry("mismatching ETag takes precedence", func(string) http.Header {
+			return http.Header{
+				"If-None-Match":     {`"foo"`},
+				"If-Modified-Since": {"Mon, 02 Jan 2023 03:04:05 GMT"},
+			}
+		}, http.StatusOK, 1),
+		Entry("not modified since", func(string) http.Header {
+			return http.Header{"If-Modified-Since": {"Mon, 02 Jan 2023 03:04:05 GMT"}}
+		}, http.StatusNotModified, 0),
+		Entry("modified since", func(string) http.Header {
+			return http.Header{"If-Modified-Since": {"Mon, 02 Jan 2023 03:04:04 GMT"}}
+		}, http.StatusOK, 1),
+		Entry("malformed date", func(string) http.Header {
+			return http.Header{"If-Modified-Since": {"yesterday"}}
+		}, http.StatusOK, 1),
+	)
+
+})
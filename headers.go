@@ -0,0 +1,180 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SecurityHeaders configures the security-related response headers set by
+// WithSecurityHeaders. Empty fields leave the corresponding headers unset.
+type SecurityHeaders struct {
+	ContentTypeOptions string // X-Content-Type-Options, such as "nosniff".
+	ReferrerPolicy     string // Referrer-Policy, such as "strict-origin-when-cross-origin".
+	FrameOptions       string // X-Frame-Options, such as "DENY".
+	FrameAncestors     string // frame-ancestors CSP directive value, such as "'none'".
+	PermissionsPolicy  string // Permissions-Policy, such as "camera=(), microphone=()".
+}
+
+// DefaultSecurityHeaders returns sane default security headers, forbidding
+// MIME sniffing, leaking full referrer URLs to other origins, embedding the SPA
+// in frames, and accessing camera, microphone, and geolocation.
+func DefaultSecurityHeaders() SecurityHeaders {
+	return SecurityHeaders{
+		ContentTypeOptions: "nosniff",
+		ReferrerPolicy:     "strict-origin-when-cross-origin",
+		FrameOptions:       "DENY",
+		FrameAncestors:     "'none'",
+		PermissionsPolicy:  "camera=(), microphone=(), geolocation=()",
+	}
+}
+
+// WithSecurityHeaders sets the specified security headers on all responses.
+// Use DefaultSecurityHeaders as a starting point and adapt as necessary:
+//
+//	sh := DefaultSecurityHeaders()
+//	sh.FrameOptions = "SAMEORIGIN"
+//	sh.FrameAncestors = "'self'"
+//	h := NewSPAHandler(fsys, "index.html", WithSecurityHeaders(sh))
+//
+// The frame-ancestors directive is sent in its own Content-Security-Policy
+// header, so it doesn't interfere with any other Content-Security-Policy, such
+// as the one set by WithCSPNonce; browsers enforce all policies.
+func WithSecurityHeaders(sh SecurityHeaders) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.markHeaderOption("SecurityHeaders")
+		for name, value := range map[string]string{
+			"X-Content-Type-Options": sh.ContentTypeOptions,
+			"Referrer-Policy":        sh.ReferrerPolicy,
+			"X-Frame-Options":        sh.FrameOptions,
+			"Permissions-Policy":     sh.PermissionsPolicy,
+		} {
+			if value != "" {
+				h.setResponseHeader(name, value)
+			}
+		}
+		if sh.FrameAncestors != "" {
+			h.setResponseHeader("Content-Security-Policy", "frame-ancestors "+sh.FrameAncestors)
+		}
+	}
+}
+
+// markHeaderOption records the named option (without "With") as applied, as
+// options setting headers cannot be told from the headers alone.
+func (h *SPAHandler) markHeaderOption(name string) {
+	if h.headerOptions == nil {
+		h.headerOptions = map[string]bool{}
+	}
+	h.headerOptions[name] = true
+}
+
+// setResponseHeader sets the named header to be sent on all responses.
+func (h *SPAHandler) setResponseHeader(name string, value string) {
+	if h.header == nil {
+		h.header = http.Header{}
+	}
+	h.header.Set(name, value)
+}
+
+// CrossOriginIsolation configures the cross-origin isolation headers set by
+// WithCrossOriginIsolation. Empty fields leave the corresponding headers unset.
+type CrossOriginIsolation struct {
+	OpenerPolicy   string // Cross-Origin-Opener-Policy, such as "same-origin".
+	EmbedderPolicy string // Cross-Origin-Embedder-Policy, such as "require-corp".
+	ResourcePolicy string // Cross-Origin-Resource-Policy of static assets, such as "same-origin".
+}
+
+// DefaultCrossOriginIsolation returns the cross-origin isolation headers
+// required for SPAs using SharedArrayBuffer and WASM threads, with the SPA's
+// static assets usable only by the same origin.
+func DefaultCrossOriginIsolation() CrossOriginIsolation {
+	return CrossOriginIsolation{
+		OpenerPolicy:   "same-origin",
+		EmbedderPolicy: "require-corp",
+		ResourcePolicy: "same-origin",
+	}
+}
+
+// WithCrossOriginIsolation sets the specified cross-origin isolation headers.
+// The opener and embedder policies are set on all responses, as they do not
+// only apply to the index document, but also to workers and their scripts. In
+// contrast, the resource policy applies to static assets only. If the static
+// assets are to be used by other origins, then set the ResourcePolicy to
+// "cross-origin":
+//
+//	coi := DefaultCrossOriginIsolation()
+//	coi.ResourcePolicy = "cross-origin"
+//	h := NewSPAHandler(fsys, "index.html", WithCrossOriginIsolation(coi))
+//
+// Please note that with an embedder policy of "require-corp" all cross-origin
+// resources loaded by the SPA, such as from CDNs, must be served with a
+// suitable Cross-Origin-Resource-Policy header or use CORS.
+func WithCrossOriginIsolation(coi CrossOriginIsolation) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.markHeaderOption("CrossOriginIsolation")
+		if coi.OpenerPolicy != "" {
+			h.setResponseHeader("Cross-Origin-Opener-Policy", coi.OpenerPolicy)
+		}
+		if coi.EmbedderPolicy != "" {
+			h.setResponseHeader("Cross-Origin-Embedder-Policy", coi.EmbedderPolicy)
+		}
+		if coi.ResourcePolicy != "" {
+			h.setAssetHeader("Cross-Origin-Resource-Policy", coi.ResourcePolicy)
+		}
+	}
+}
+
+// setAssetHeader sets the named header to be sent on static asset responses.
+func (h *SPAHandler) setAssetHeader(name string, value string) {
+	if h.assetHeader == nil {
+		h.assetHeader = http.Header{}
+	}
+	h.assetHeader.Set(name, value)
+}
+
+// WithAltSvc sets the Alt-Svc header on all responses, advertising the
+// specified alternative services, such as HTTP/3 on a QUIC listener running
+// next to the SPAHandler:
+//
+//	h := NewSPAHandler(fsys, "index.html",
+//	    WithAltSvc(HTTP3AltSvc(443, 24*time.Hour)))
+//
+// Multiple alternative services are sent as a comma-separated list, in the
+// order specified. Specifying no alternative services leaves the Alt-Svc
+// header unset.
+func WithAltSvc(altSvcs ...string) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		if len(altSvcs) == 0 {
+			return
+		}
+		h.markHeaderOption("AltSvc")
+		h.setResponseHeader("Alt-Svc", strings.Join(altSvcs, ", "))
+	}
+}
+
+// HTTP3AltSvc returns the Alt-Svc value advertising HTTP/3 on the specified
+// UDP port of the same host, with the specified maximum age in whole seconds,
+// such as `h3=":443"; ma=86400`. A maximum age of zero or less omits the
+// maximum age, so browsers use their default of 24 hours.
+func HTTP3AltSvc(port int, maxAge time.Duration) string {
+	altSvc := `h3=":` + strconv.Itoa(port) + `"`
+	if maxAge > 0 {
+		altSvc += "; ma=" + strconv.FormatInt(int64(maxAge/time.Second), 10)
+	}
+	return altSvc
+}
@@ -0,0 +1,126 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("response headers", func() {
+
+	DescribeTable("sets security headers on all responses",
+		func(path string, expectedStatus int) {
+			h := NewSPAHandler(embStaticFs, "index.html",
+				WithAssetNotFound(),
+				WithSecurityHeaders(DefaultSecurityHeaders()),
+				WithCSPNonce(""))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			Expect(w.Code).To(Equal(expectedStatus))
+			Expect(w.Header().Get("X-Content-Type-Options")).To(Equal("nosniff"))
+			Expect(w.Header().Get("Referrer-Policy")).To(Equal("strict-origin-when-cross-origin"))
+			Expect(w.Header().Get("X-Frame-Options")).To(Equal("DENY"))
+			Expect(w.Header().Get("Permissions-Policy")).To(ContainSubstring("camera=()"))
+			Expect(w.Header().Values("Content-Security-Policy")).To(ContainElement("frame-ancestors 'none'"))
+		},
+		Entry("index", "/", http.StatusOK),
+		Entry("static asset", "/static/js/some.js", http.StatusOK),
+		Entry("missing asset", "/missing.js", http.StatusNotFound),
+	)
+
+	It("keeps the frame-ancestors and nonce policies separate", func() {
+		h := NewSPAHandler(embStaticFs, "index.html",
+			WithSecurityHeaders(DefaultSecurityHeaders()),
+			WithCSPNonce(""))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		Expect(w.Header().Values("Content-Security-Policy")).To(ConsistOf(
+			"frame-ancestors 'none'",
+			ContainSubstring("'nonce-")))
+	})
+
+	It("leaves empty security headers unset", func() {
+		h := NewSPAHandler(embStaticFs, "index.html",
+			WithSecurityHeaders(SecurityHeaders{ReferrerPolicy: "no-referrer"}))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		Expect(w.Header().Get("Referrer-Policy")).To(Equal("no-referrer"))
+		Expect(w.Header()).NotTo(HaveKey("X-Frame-Options"))
+		Expect(w.Header()).NotTo(HaveKey("Content-Security-Policy"))
+	})
+
+	DescribeTable("sets cross-origin isolation headers",
+		func(path string, expectedCORP string) {
+			h := NewSPAHandler(embStaticFs, "index.html",
+				WithSharedAssets(embStaticFs, "multi/vendor"),
+				WithCrossOriginIsolation(DefaultCrossOriginIsolation()))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Header().Get("Cross-Origin-Opener-Policy")).To(Equal("same-origin"))
+			Expect(w.Header().Get("Cross-Origin-Embedder-Policy")).To(Equal("require-corp"))
+			Expect(w.Header().Get("Cross-Origin-Resource-Policy")).To(Equal(expectedCORP))
+		},
+		Entry("index", "/", ""),
+		Entry("static asset", "/static/js/some.js", "same-origin"),
+		Entry("shared asset", "/multi/vendor/chunk.js", "same-origin"),
+	)
+
+	It("sets only the configured cross-origin isolation headers", func() {
+		h := NewSPAHandler(embStaticFs, "index.html",
+			WithCrossOriginIsolation(CrossOriginIsolation{ResourcePolicy: "cross-origin"}))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/static/js/some.js", nil))
+		Expect(w.Header().Get("Cross-Origin-Resource-Policy")).To(Equal("cross-origin"))
+		Expect(w.Header()).NotTo(HaveKey("Cross-Origin-Opener-Policy"))
+		Expect(w.Header()).NotTo(HaveKey("Cross-Origin-Embedder-Policy"))
+	})
+
+	DescribeTable("HTTP/3 Alt-Svc values",
+		func(port int, maxAge time.Duration, expected string) {
+			Expect(HTTP3AltSvc(port, maxAge)).To(Equal(expected))
+		},
+		Entry("with max age", 443, 24*time.Hour, `h3=":443"; ma=86400`),
+		Entry("without max age", 8443, time.Duration(0), `h3=":8443"`),
+	)
+
+	DescribeTable("advertises alternative services",
+		func(path string, expectedCode int) {
+			h := NewSPAHandler(embStaticFs, "index.html",
+				WithAssetNotFound(),
+				WithAltSvc(HTTP3AltSvc(443, time.Hour), `h3=":8443"`))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			Expect(w.Code).To(Equal(expectedCode))
+			Expect(w.Header().Get("Alt-Svc")).To(Equal(`h3=":443"; ma=3600, h3=":8443"`))
+		},
+		Entry("index", "/", http.StatusOK),
+		Entry("static asset", "/static/js/some.js", http.StatusOK),
+		Entry("missing asset", "/static/js/missing.js", http.StatusNotFound),
+	)
+
+	It("doesn't advertise without alternative services", func() {
+		h := NewSPAHandler(embStaticFs, "index.html", WithAltSvc())
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		Expect(w.Header()).NotTo(HaveKey("Alt-Svc"))
+	})
+
+})
@@ -0,0 +1,96 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"net/http"
+)
+
+// Default paths of the liveness and readiness probe endpoints.
+const (
+	DefaultLivenessPath  = "/healthz"
+	DefaultReadinessPath = "/readyz"
+)
+
+// healthProbes are the (rooted) paths of the liveness and readiness probe
+// endpoints.
+type healthProbes struct {
+	liveness  string
+	readiness string
+}
+
+// WithHealthProbes answers liveness and readiness probes, such as from
+// Kubernetes, on the specified paths, so that probes don't need to fetch the
+// SPA index, polluting access logs and analytics. An empty path disables the
+// particular probe endpoint. Usually, the paths are [DefaultLivenessPath] and
+// [DefaultReadinessPath]:
+//
+//	h := NewSPAHandler(bundle, "index.html",
+//	    WithHealthProbes(DefaultLivenessPath, DefaultReadinessPath))
+//
+// The liveness endpoint always answers with 200 OK as long as the handler
+// serves requests at all. The readiness endpoint answers with 200 OK only if
+// the index can be opened from the currently served fs.FS and successfully
+// loaded, including parsing it as a template if enabled; otherwise, it answers
+// with 503 Service Unavailable and logs the original error, if an error logger
+// has been set. Probe endpoints take precedence over any files of the same
+// paths, are not subject to HTTPS redirection, and only answer GET and HEAD
+// requests; their responses are never cached.
+func WithHealthProbes(livenessPath, readinessPath string) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.probes = &healthProbes{}
+		if livenessPath != "" {
+			h.probes.liveness = cleanVirtualPath(livenessPath)
+		}
+		if readinessPath != "" {
+			h.probes.readiness = cleanVirtualPath(readinessPath)
+		}
+	}
+}
+
+// serveProbe answers a liveness or readiness probe request, returning true.
+// Otherwise, nothing is served and false is returned.
+//
+// IMPORTANT: the passed r.URL.Path must have already been sanitized.
+func (h *SPAHandler) serveProbe(w http.ResponseWriter, r *http.Request) bool {
+	if h.probes == nil || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		return false
+	}
+	switch r.URL.Path {
+	case h.probes.liveness:
+		writeProbe(w, r, http.StatusOK)
+	case h.probes.readiness:
+		if _, err := h.loadIndex(h.current(), h.bundleIndex(h.current())); err != nil {
+			h.logError(r, err)
+			writeProbe(w, r, http.StatusServiceUnavailable)
+			return true
+		}
+		writeProbe(w, r, http.StatusOK)
+	default:
+		return false
+	}
+	return true
+}
+
+// writeProbe writes a probe response with the specified status code.
+func writeProbe(w http.ResponseWriter, r *http.Request, status int) {
+	header := w.Header()
+	header.Set("Content-Type", "text/plain; charset=utf-8")
+	header.Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if r.Method == http.MethodGet {
+		_, _ = w.Write([]byte(http.StatusText(status) + "\n"))
+	}
+}
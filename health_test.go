@@ -0,0 +1,79 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("health probes", func() {
+
+	spafs := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<base href="./" />INDEX`)},
+		"healthz":    &fstest.MapFile{Data: []byte(`static`)},
+	}
+
+	probe := func(h *SPAHandler, method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, "http://example.org"+path, nil))
+		return w
+	}
+
+	DescribeTable("answering probes",
+		func(method, path string, expectedStatus int, expectedBody string) {
+			h := NewSPAHandler(spafs, "index.html",
+				WithHealthProbes(DefaultLivenessPath, "/probes/../readyz/"),
+				WithHTTPSRedirect(0))
+			w := probe(h, method, path)
+			Expect(w.Code).To(Equal(expectedStatus))
+			Expect(w.Body.String()).To(Equal(expectedBody))
+			Expect(w.Header().Get("Cache-Control")).To(Equal("no-store"))
+		},
+		Entry(nil, http.MethodGet, "/healthz", http.StatusOK, "OK\n"),
+		Entry(nil, http.MethodGet, "/readyz", http.StatusOK, "OK\n"),
+		Entry(nil, http.MethodHead, "/readyz", http.StatusOK, ""),
+	)
+
+	It("doesn't answer disabled or non-GET probes", func() {
+		h := NewSPAHandler(spafs, "index.html", WithHealthProbes("", DefaultReadinessPath))
+		Expect(probe(h, http.MethodGet, "/healthz").Body.String()).To(Equal("static"))
+		Expect(probe(h, http.MethodPost, "/readyz").Code).To(Equal(http.StatusMethodNotAllowed))
+		h = NewSPAHandler(spafs, "index.html")
+		Expect(probe(h, http.MethodGet, "/readyz").Body.String()).To(HaveSuffix("INDEX"))
+	})
+
+	It("isn't ready without a loadable index", func() {
+		h := NewSPAHandler(spafs, "index.html",
+			WithHealthProbes(DefaultLivenessPath, DefaultReadinessPath))
+		h.SwapFS(fstest.MapFS{})
+		Expect(probe(h, http.MethodGet, "/healthz").Code).To(Equal(http.StatusOK))
+		w := probe(h, http.MethodGet, "/readyz")
+		Expect(w.Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(w.Body.String()).To(Equal("Service Unavailable\n"))
+	})
+
+	It("reports the probe outcome", func() {
+		h := NewSPAHandler(spafs, "index.html",
+			WithHealthProbes(DefaultLivenessPath, DefaultReadinessPath))
+		Expect(h.serve(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/readyz", nil))).
+			To(Equal(OutcomeProbe))
+	})
+
+})
@@ -0,0 +1,95 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"net/http"
+)
+
+// Decision describes an SPAHandler's decision how to serve a request.
+type Decision struct {
+	Path   string // sanitized request path, relative to the SPA's base path.
+	Base   string // resolved base path of the SPA.
+	Name   string // (unrooted) path and name of the file to be served.
+	Shared bool   // true if the file is served from a shared FS.
+}
+
+// ServeHook gets called with the request and decision metadata when an
+// SPAHandler has decided how to serve a request, but before serving it.
+type ServeHook func(r *http.Request, d Decision)
+
+// ErrorHook gets called with the request and the original, non-normalized
+// error as well as the normalized HTTP status code when an SPAHandler
+// encounters an error while serving a request.
+type ErrorHook func(r *http.Request, err error, status int)
+
+// WithOnIndex adds a ServeHook to be called when the SPAHandler decides to
+// serve the index.
+func WithOnIndex(hook ServeHook) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		if hook != nil {
+			h.onIndex = append(h.onIndex, hook)
+		}
+	}
+}
+
+// WithOnStatic adds a ServeHook to be called when the SPAHandler decides to
+// serve a static asset.
+func WithOnStatic(hook ServeHook) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		if hook != nil {
+			h.onStatic = append(h.onStatic, hook)
+		}
+	}
+}
+
+// WithOnError adds an ErrorHook to be called when the SPAHandler encounters an
+// error while serving a request. Please note that the hook doesn't get called
+// for rejected index fallbacks.
+func WithOnError(hook ErrorHook) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		if hook != nil {
+			h.onError = append(h.onError, hook)
+		}
+	}
+}
+
+// callHooks calls the specified serve hooks, if any, with the decision to serve
+// the named file.
+func (h *SPAHandler) callHooks(hooks []ServeHook, r *http.Request, name string, shared bool) {
+	if len(hooks) == 0 {
+		return
+	}
+	d := Decision{
+		Path:   r.URL.Path,
+		Base:   h.basename(r),
+		Name:   name,
+		Shared: shared,
+	}
+	for _, hook := range hooks {
+		hook(r, d)
+	}
+}
+
+// callErrorHooks calls the error hooks, if any, with the specified error.
+func (h *SPAHandler) callErrorHooks(r *http.Request, err error) {
+	if len(h.onError) == 0 {
+		return
+	}
+	status, _, _ := normalizedError(err)
+	for _, hook := range h.onError {
+		hook(r, err, status)
+	}
+}
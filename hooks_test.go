@@ -0,0 +1,53 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("serve hooks", func() {
+
+	It("calls hooks with decision metadata", func() {
+		var indexDecisions, staticDecisions []Decision
+		var errs []int
+		h := NewSPAHandler(embStaticFs, "bonkers.html",
+			WithSharedAssets(Successful(fs.Sub(embStaticFs, "multi")), "vendor"),
+			WithOnIndex(func(r *http.Request, d Decision) { indexDecisions = append(indexDecisions, d) }),
+			WithOnStatic(func(r *http.Request, d Decision) { staticDecisions = append(staticDecisions, d) }),
+			WithOnError(func(r *http.Request, err error, status int) { errs = append(errs, status) }),
+			WithOnIndex(nil))
+		for _, path := range []string{"/static/js/some.js", "/vendor/chunk.js", "/some/route"} {
+			r := httptest.NewRequest(http.MethodGet, path, nil)
+			r.Header.Set(ForwardedPrefixHeader, "/app")
+			h.ServeHTTP(httptest.NewRecorder(), r)
+		}
+		Expect(staticDecisions).To(Equal([]Decision{
+			{Path: "/static/js/some.js", Base: "/app/", Name: "static/js/some.js"},
+			{Path: "/vendor/chunk.js", Base: "/app/", Name: "vendor/chunk.js", Shared: true},
+		}))
+		Expect(indexDecisions).To(Equal([]Decision{
+			{Path: "/some/route", Base: "/app/", Name: "bonkers.html"},
+		}))
+		Expect(errs).To(Equal([]int{http.StatusNotFound}))
+	})
+
+})
@@ -0,0 +1,69 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"context"
+	"io/fs"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// WithHostSPAs serves different SPAs depending on the virtual host requested,
+// with the map keys specifying the host names, such as "tenant1.example.com",
+// and the map values the fs.FSes to serve the SPAs from. The SPAs share the
+// index name, options, and base rewriting, but each SPA gets its own caches.
+// Requests for hosts not in the map are served from the fs.FS passed to
+// NewSPAHandler; if that fs.FS is nil, such requests are answered with 404
+// instead.
+//
+//	h := NewSPAHandler(nil, "index.html", WithHostSPAs(map[string]fs.FS{
+//	    "tenant1.example.com": tenant1,
+//	    "tenant2.example.com": tenant2,
+//	}))
+//
+// Please note that SwapFS swaps only the fs.FS passed to NewSPAHandler, and
+// canary mode applies only to requests served from this fs.FS.
+func WithHostSPAs(spas map[string]fs.FS) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.hosts = map[string]*bundle{}
+		for host, fsys := range spas {
+			h.hosts[normalizedHost(host)] = newBundle(fsys)
+		}
+	}
+}
+
+// selectHost selects the bundle to serve the specified request from based on
+// the requested host, returning the request with the selected bundle. It
+// returns false if there is no bundle to serve the requested host from.
+func (h *SPAHandler) selectHost(r *http.Request) (*http.Request, bool) {
+	if h.hosts == nil {
+		return r, true
+	}
+	if b, ok := h.hosts[normalizedHost(r.Host)]; ok {
+		return r.WithContext(context.WithValue(r.Context(), bundleCtxKey{}, b)), true
+	}
+	return r, h.current().fs != nil
+}
+
+// normalizedHost returns the specified host without any port and trailing
+// dot, in lower case.
+func normalizedHost(host string) string {
+	if hostonly, _, err := net.SplitHostPort(host); err == nil {
+		host = hostonly
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
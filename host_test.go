@@ -0,0 +1,80 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("host-based SPAs", func() {
+
+	tenant1 := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<base href="./" />TENANT1`)},
+		"t1.js":      &fstest.MapFile{Data: []byte(`t1();`)},
+	}
+	tenant2 := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<base href="./" />TENANT2`)},
+	}
+	defaultfs := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<base href="./" />DEFAULT`)},
+	}
+
+	get := func(h http.Handler, host string, path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Host = host
+		r.Header.Set(ForwardedPrefixHeader, "/app")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	DescribeTable("serves per host",
+		func(fsys fs.FS, host string, path string, expectedStatus int, expectedBody string) {
+			h := NewSPAHandler(fsys, "index.html", WithHostSPAs(map[string]fs.FS{
+				"Tenant1.example.com": tenant1,
+				"tenant2.example.com": tenant2,
+			}))
+			w := get(h, host, path)
+			Expect(w.Code).To(Equal(expectedStatus))
+			Expect(w.Body.String()).To(Equal(expectedBody))
+		},
+		Entry("tenant 1 index", nil, "tenant1.example.com", "/foo", http.StatusOK, `<base href="/app/" />TENANT1`),
+		Entry("tenant 1 with port and different case", nil, "TENANT1.example.com:8080", "/", http.StatusOK, `<base href="/app/" />TENANT1`),
+		Entry("tenant 1 with trailing dot", nil, "tenant1.example.com.", "/", http.StatusOK, `<base href="/app/" />TENANT1`),
+		Entry("tenant 1 asset", nil, "tenant1.example.com", "/t1.js", http.StatusOK, `t1();`),
+		Entry("tenant 2 index", nil, "tenant2.example.com", "/foo", http.StatusOK, `<base href="/app/" />TENANT2`),
+		Entry("no tenant 1 asset on tenant 2", defaultfs, "tenant2.example.com", "/t1.js", http.StatusOK, `<base href="/app/" />TENANT2`),
+		Entry("unknown host without default", nil, "tenant3.example.com", "/", http.StatusNotFound, "404 page not found\n"),
+		Entry("unknown host with default", defaultfs, "tenant3.example.com", "/", http.StatusOK, `<base href="/app/" />DEFAULT`),
+	)
+
+	It("keeps caches per host", func() {
+		h := NewSPAHandler(nil, "index.html", WithHostSPAs(map[string]fs.FS{
+			"tenant1.example.com": tenant1,
+			"tenant2.example.com": tenant2,
+		}))
+		for i := 0; i < 2; i++ {
+			Expect(get(h, "tenant1.example.com", "/").Body.String()).To(HaveSuffix("TENANT1"))
+			Expect(get(h, "tenant2.example.com", "/").Body.String()).To(HaveSuffix("TENANT2"))
+		}
+	})
+
+})
@@ -0,0 +1,50 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"regexp"
+)
+
+var (
+	// importMapRe matches import map script elements, capturing their
+	// contents.
+	importMapRe = regexp.MustCompile(`(?is)<script\s[^>]*type\s*=\s*["']?importmap["']?[^>]*>(.*?)</script\s*>`)
+	// importMapAddressRe matches the beginning of JSON string values, that
+	// is, addresses in import maps, capturing rooted or relative address
+	// prefixes.
+	importMapAddressRe = regexp.MustCompile(`:\s*"(\./|/)(.)`)
+)
+
+// importMapCuts returns the start and end positions of the “/” and “./”
+// prefixes of rooted and relative addresses in the import maps of the
+// specified HTML document contents. Replacing these prefixes with the base
+// path makes the addresses refer to the correct locations regardless of how
+// browsers resolve them, as rooted addresses in particular ignore the base
+// element. Protocol-relative addresses, such as “//cdn.example.com/lib.js”,
+// are left alone.
+func importMapCuts(html string) [][2]int {
+	var cuts [][2]int
+	for _, importMap := range importMapRe.FindAllStringSubmatchIndex(html, -1) {
+		contents := html[importMap[2]:importMap[3]]
+		for _, match := range importMapAddressRe.FindAllStringSubmatchIndex(contents, -1) {
+			if contents[match[2]:match[3]] == "/" && contents[match[4]:match[5]] == "/" {
+				continue // protocol-relative
+			}
+			cuts = append(cuts, [2]int{importMap[2] + match[2], importMap[2] + match[3]})
+		}
+	}
+	return cuts
+}
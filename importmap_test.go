@@ -0,0 +1,64 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("import maps", func() {
+
+	DescribeTable("rewrites import map addresses",
+		func(html string, expected string) {
+			h := NewSPAHandler(fstest.MapFS{
+				"index.html": &fstest.MapFile{Data: []byte(html)},
+			}, "index.html")
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/foo", nil)
+			r.Header.Set(ForwardedPrefixHeader, "/app")
+			h.ServeHTTP(w, r)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(Equal(expected))
+		},
+		Entry("no import map",
+			`<base href="./" /><script type="module">import "/x.js";</script>`,
+			`<base href="/app/" /><script type="module">import "/x.js";</script>`),
+		Entry("rooted and relative addresses",
+			`<base href="./" /><script type="importmap">{"imports": {`+
+				`"vue": "/vendor/vue.js", "lib/": "./lib/", "up": "../up.js",`+
+				`"cdn": "//cdn.example.com/cdn.js", "abs": "https://example.com/abs.js"}}</script>`,
+			`<base href="/app/" /><script type="importmap">{"imports": {`+
+				`"vue": "/app/vendor/vue.js", "lib/": "/app/lib/", "up": "../up.js",`+
+				`"cdn": "//cdn.example.com/cdn.js", "abs": "https://example.com/abs.js"}}</script>`),
+		Entry("scopes without base element",
+			`<SCRIPT type='importmap'>{"scopes": {"/legacy/": {"vue": "/vendor/vue2.js"}}}</SCRIPT>`,
+			`<SCRIPT type='importmap'>{"scopes": {"/legacy/": {"vue": "/app/vendor/vue2.js"}}}</SCRIPT>`),
+	)
+
+	It("splits at import map addresses", func() {
+		Expect(splitIndex(`<base href="./" /><script type="importmap">{"imports":{"a":"/a.js"}}</script>`)).
+			To(Equal([]string{
+				`<base href="`,
+				`" /><script type="importmap">{"imports":{"a":"`,
+				`a.js"}}</script>`,
+			}))
+	})
+
+})
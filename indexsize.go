@@ -0,0 +1,67 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// ErrIndexTooLarge signals an index file exceeding the maximum index size set
+// using WithMaxIndexSize. It is answered with the HTTP status code 500
+// (Internal Server Error), as it is a server misconfiguration.
+var ErrIndexTooLarge = errors.New("index file too large")
+
+// WithMaxIndexSize limits the size of the index file to the specified number
+// of bytes. Larger index files are refused with 500 (Internal Server Error)
+// instead of being read into memory, such as when a misconfigured index path
+// points at a huge file. A size of zero or less means no limit, which is the
+// default.
+func WithMaxIndexSize(size int64) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.maxIndexSize = size
+	}
+}
+
+// checkIndexSize returns ErrIndexTooLarge if the named index file described by
+// the specified file info exceeds the maximum index size.
+func (h *SPAHandler) checkIndexSize(name string, fileInfo fs.FileInfo) error {
+	if h.maxIndexSize > 0 && fileInfo.Size() > h.maxIndexSize {
+		return fmt.Errorf("%w: %s has %d bytes, limit is %d bytes",
+			ErrIndexTooLarge, name, fileInfo.Size(), h.maxIndexSize)
+	}
+	return nil
+}
+
+// readIndex reads the contents of the named index file into the specified
+// buffer, but never more than the maximum index size. This guards against file
+// infos lying about the real file size.
+func (h *SPAHandler) readIndex(dst *bytes.Buffer, name string, r io.Reader) error {
+	if h.maxIndexSize <= 0 {
+		_, err := dst.ReadFrom(r)
+		return err
+	}
+	if _, err := dst.ReadFrom(io.LimitReader(r, h.maxIndexSize+1)); err != nil {
+		return err
+	}
+	if int64(dst.Len()) > h.maxIndexSize {
+		return fmt.Errorf("%w: %s exceeds limit of %d bytes",
+			ErrIndexTooLarge, name, h.maxIndexSize)
+	}
+	return nil
+}
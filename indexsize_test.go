@@ -0,0 +1,78 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// understatingFS understates the sizes of the files it opens.
+type understatingFS struct{ fs.FS }
+
+func (f understatingFS) Open(name string) (fs.File, error) {
+	file, err := f.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return understatingFile{file}, nil
+}
+
+type understatingFile struct{ fs.File }
+
+func (f understatingFile) Stat() (fs.FileInfo, error) {
+	info, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return understatingFileInfo{info}, nil
+}
+
+type understatingFileInfo struct{ fs.FileInfo }
+
+func (understatingFileInfo) Size() int64 { return 1 }
+
+var _ = Describe("index size limit", func() {
+
+	index := `<html><head><base href="./" /></head>` + strings.Repeat("x", 1000) + `</html>`
+	mfs := fstest.MapFS{"index.html": &fstest.MapFile{Data: []byte(index)}}
+
+	noopRewriter := WithIndexRewriter(func(_ *http.Request, index string) string { return index })
+
+	DescribeTable("refuses oversized index files",
+		func(fsys fs.FS, maxSize int, opts []SPAHandlerOption, expectedStatus int) {
+			h := NewSPAHandler(fsys, "index.html", append(opts, WithMaxIndexSize(int64(maxSize)))...)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/foo", nil))
+			Expect(w.Code).To(Equal(expectedStatus))
+			if expectedStatus == http.StatusInternalServerError {
+				Expect(w.Body.String()).NotTo(ContainSubstring("xxx"))
+			}
+		},
+		Entry("unlimited", mfs, 0, nil, http.StatusOK),
+		Entry("at the limit", mfs, len(index), nil, http.StatusOK),
+		Entry("too large", mfs, len(index)-1, nil, http.StatusInternalServerError),
+		Entry("too large, buffered", mfs, len(index)-1, []SPAHandlerOption{noopRewriter}, http.StatusInternalServerError),
+		Entry("understated size", understatingFS{mfs}, len(index)-1, []SPAHandlerOption{noopRewriter}, http.StatusInternalServerError),
+		Entry("understated size, at the limit", understatingFS{mfs}, len(index), []SPAHandlerOption{noopRewriter}, http.StatusOK),
+	)
+
+})
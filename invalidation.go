@@ -0,0 +1,100 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"context"
+	"time"
+)
+
+// invalidation is the configuration for watching the served fs.FS for changes
+// in order to invalidate all cached information.
+type invalidation struct {
+	ctx      context.Context
+	interval time.Duration
+}
+
+// WithCacheInvalidation watches the fs.FS the SPA is served from for changes,
+// dropping all cached information about the SPA bundle when files have
+// changed, such as the pre-split index files, rewritten index metadata, and
+// SRI hashes. This allows operators to hot-patch a bundle directory served
+// using os.DirFS without restarting the server. If the cache primer has been
+// enabled using WithCachePrimer, the caches then get primed again.
+//
+// While cached information gets validated against the modification times and
+// sizes of files anyway, the caches would otherwise keep outdated entries
+// around, such as the SRI hashes of replaced assets. Additionally, dropping
+// the caches on any change also refreshes cached information for files that
+// were replaced without changing their modification times and sizes.
+//
+// The fs.FS is polled in the specified interval until the specified context
+// gets cancelled. Only the fs.FS passed to NewSPAHandler (or swapped in using
+// SwapFS) is watched; watching embedded file systems is pointless.
+func WithCacheInvalidation(ctx context.Context, interval time.Duration) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.invalidation = &invalidation{
+			ctx:      ctx,
+			interval: interval,
+		}
+	}
+}
+
+// startInvalidation starts watching the served fs.FS for changes in the
+// background, if enabled.
+func (h *SPAHandler) startInvalidation() {
+	if h.invalidation == nil {
+		return
+	}
+	go h.watchForInvalidation(h.invalidation.ctx, h.invalidation.interval)
+}
+
+// watchForInvalidation polls the served fs.FS for changes in the specified
+// interval, until the context gets cancelled. On changes, it replaces the
+// current bundle with a fresh bundle for the same fs.FS, thus dropping all
+// cached information.
+func (h *SPAHandler) watchForInvalidation(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	b := h.current()
+	last := fingerprint(b.fs)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		current := h.current()
+		if current != b {
+			// The bundle has been swapped (or refreshed), so start over with
+			// the new bundle.
+			b = current
+			last = fingerprint(b.fs)
+			continue
+		}
+		fp := fingerprint(b.fs)
+		if fp == last {
+			continue
+		}
+		last = fp
+		fresh := newBundle(b.fs)
+		if !h.bundle.CompareAndSwap(b, fresh) {
+			continue // ...lost a race with SwapFS.
+		}
+		b = fresh
+		if h.primer != nil {
+			_ = h.Prime(ctx)
+		}
+	}
+}
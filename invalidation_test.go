@@ -0,0 +1,82 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("cache invalidation", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "index.html"), []byte(`<base href="./" />OLD`), 0644)).To(Succeed())
+	})
+
+	get := func(h http.Handler) string {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/foo", nil))
+		return w.Body.String()
+	}
+
+	// sneakyPatch replaces the index contents while keeping its size and
+	// modification time.
+	sneakyPatch := func() {
+		name := filepath.Join(dir, "index.html")
+		info := Successful(os.Stat(name))
+		Expect(os.WriteFile(name, []byte(`<base href="./" />NEW`), 0644)).To(Succeed())
+		Expect(os.Chtimes(name, info.ModTime(), info.ModTime())).To(Succeed())
+	}
+
+	It("serves stale contents without invalidation", func() {
+		h := NewSPAHandler(os.DirFS(dir), "index.html")
+		Expect(get(h)).To(HaveSuffix("OLD"))
+		sneakyPatch()
+		Consistently(func() string { return get(h) }).
+			WithTimeout(100 * time.Millisecond).Should(HaveSuffix("OLD"))
+	})
+
+	It("invalidates caches on changes", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		h := NewSPAHandler(os.DirFS(dir), "index.html",
+			WithCacheInvalidation(ctx, 10*time.Millisecond))
+		Expect(get(h)).To(HaveSuffix("OLD"))
+		b := h.current()
+		// Ensure that the watcher took its initial fingerprint.
+		time.Sleep(50 * time.Millisecond)
+		Expect(h.current()).To(BeIdenticalTo(b))
+		Expect(os.WriteFile(filepath.Join(dir, "app.js"), []byte(`app();`), 0644)).To(Succeed())
+		Eventually(h.current).ShouldNot(BeIdenticalTo(b))
+		Expect(get(h)).To(HaveSuffix("OLD"))
+		sneakyPatch()
+		// The fingerprint covers only names, sizes, and modification
+		// times, so touch another file to make the change noticeable.
+		Expect(os.WriteFile(filepath.Join(dir, "app.js"), []byte(`app(42);`), 0644)).To(Succeed())
+		Eventually(func() string { return get(h) }).Should(HaveSuffix("NEW"))
+	})
+
+})
@@ -0,0 +1,114 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WithLocalizedIndex serves localized index files for the specified languages
+// based on the client's Accept-Language preferences, falling back to the
+// default index file for all other languages. The localized index files are
+// named after the default index file with the language tag inserted before the
+// extension, such as “index.de.html” and “index.pt-BR.html” for the languages
+// "de" and "pt-BR". For instance:
+//
+//	h := NewSPAHandler(bundle, "index.html", WithLocalizedIndex("de", "fr"))
+//
+// Clients preferring “de-AT” thus get “index.de.html”, unless “de-AT” has been
+// specified as a language too. Index responses carry a “Vary: Accept-Language”
+// header. Index files chosen by an IndexSelector take precedence over
+// localized index files. When using WithIndexCandidates, the localized index
+// files are named after the index file candidate found instead.
+func WithLocalizedIndex(languages ...string) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.indexLanguages = map[string]string{}
+		for _, lang := range languages {
+			h.indexLanguages[strings.ToLower(lang)] = lang
+		}
+	}
+}
+
+// localizedName returns the name of the localized variant of the specified
+// index file for the specified language tag, such as “index.de.html”.
+func localizedName(index string, tag string) string {
+	ext := path.Ext(index)
+	return strings.TrimSuffix(index, ext) + "." + tag + ext
+}
+
+// localizedIndex returns the (unrooted) path and name of the localized index
+// file to serve for the specified request, or "" if the default index file is
+// to be served.
+func (h *SPAHandler) localizedIndex(r *http.Request) string {
+	if len(h.indexLanguages) == 0 {
+		return ""
+	}
+	index := h.bundleIndex(h.bundleFor(r))
+	for _, lang := range acceptedLanguages(r.Header.Get("Accept-Language")) {
+		for {
+			if tag, ok := h.indexLanguages[lang]; ok {
+				return localizedName(index, tag)
+			}
+			// Try the more general language tag, such as "de" for "de-at".
+			idx := strings.LastIndex(lang, "-")
+			if idx < 0 {
+				break
+			}
+			lang = lang[:idx]
+		}
+	}
+	return ""
+}
+
+// acceptedLanguages returns the lower-case language tags from the specified
+// Accept-Language header value in order of decreasing preference, leaving out
+// languages with a quality of zero and the wildcard.
+func acceptedLanguages(value string) []string {
+	type language struct {
+		tag     string
+		quality float64
+	}
+	var languages []language
+	for _, entry := range strings.Split(value, ",") {
+		tag, params, _ := strings.Cut(entry, ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+		languages = append(languages, language{tag: tag, quality: quality})
+	}
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+	tags := make([]string, len(languages))
+	for idx, lang := range languages {
+		tags[idx] = lang.tag
+	}
+	return tags
+}
@@ -0,0 +1,83 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("localized index files", func() {
+
+	spafs := fstest.MapFS{
+		"index.html":       &fstest.MapFile{Data: []byte(`<base href="./" />EN`)},
+		"index.de.html":    &fstest.MapFile{Data: []byte(`<base href="./" />DE`)},
+		"index.fr.html":    &fstest.MapFile{Data: []byte(`<base href="./" />FR`)},
+		"index.pt-BR.html": &fstest.MapFile{Data: []byte(`<base href="./" />PT-BR`)},
+	}
+
+	DescribeTable("negotiates the index language",
+		func(acceptLanguage string, expected string) {
+			h := NewSPAHandler(spafs, "index.html", WithLocalizedIndex("de", "fr", "pt-BR"))
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/foo", nil)
+			if acceptLanguage != "" {
+				r.Header.Set("Accept-Language", acceptLanguage)
+			}
+			r.Header.Set(ForwardedPrefixHeader, "/app")
+			h.ServeHTTP(w, r)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Header().Values("Vary")).To(ContainElement("Accept-Language"))
+			Expect(w.Body.String()).To(Equal(`<base href="/app/" />` + expected))
+		},
+		Entry("no preference", "", "EN"),
+		Entry("unsupported language", "ja", "EN"),
+		Entry("exact language", "fr", "FR"),
+		Entry("regional language", "de-AT", "DE"),
+		Entry("case-insensitive region", "PT-br", "PT-BR"),
+		Entry("unsupported region", "pt-PT", "EN"),
+		Entry("quality ordering", "en;q=0.3, de;q=0.5, fr;q=0.9", "FR"),
+		Entry("stable ordering", "de, fr", "DE"),
+		Entry("excluded language", "fr;q=0, de;q=0.1", "DE"),
+		Entry("wildcard", "*", "EN"),
+		Entry("malformed quality", "fr;q=foo, de", "DE"),
+	)
+
+	It("doesn't vary without localized index files", func() {
+		h := NewSPAHandler(spafs, "index.html")
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/foo", nil)
+		r.Header.Set("Accept-Language", "de")
+		h.ServeHTTP(w, r)
+		Expect(w.Header().Values("Vary")).NotTo(ContainElement("Accept-Language"))
+		Expect(w.Body.String()).To(Equal(`<base href="/" />EN`))
+	})
+
+	It("prefers selected index files", func() {
+		h := NewSPAHandler(spafs, "index.html",
+			WithLocalizedIndex("de"),
+			WithIndexSelector(func(r *http.Request) string { return "index.fr.html" }))
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/foo", nil)
+		r.Header.Set("Accept-Language", "de")
+		h.ServeHTTP(w, r)
+		Expect(w.Body.String()).To(Equal(`<base href="/" />FR`))
+	})
+
+})
@@ -0,0 +1,76 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"path"
+	"regexp"
+)
+
+// RequestMatcher returns true if the specified request matches, such as when
+// it comes from a legacy browser.
+type RequestMatcher func(r *http.Request) bool
+
+// MatchUserAgent returns a RequestMatcher matching requests by their user
+// agent using the specified UserAgentMatcher.
+func MatchUserAgent(matcher UserAgentMatcher) RequestMatcher {
+	return func(r *http.Request) bool {
+		return matcher(r.UserAgent())
+	}
+}
+
+// legacyBrowserRe matches the user agents of browsers not supporting ES
+// modules: Internet Explorer, legacy Edge before 16, Chrome before 61,
+// Firefox before 60, and Safari before 11.
+var legacyBrowserRe = regexp.MustCompile(`MSIE |Trident/|Edge/1[2-5]\.|` +
+	`Chrome/([1-5]?[0-9]|60)\.|Firefox/([1-5]?[0-9])\.|` +
+	`Version/([0-9]|10)(\.[0-9]+)* (Mobile/\S+ )?Safari/`)
+
+// IsLegacyBrowser is a UserAgentMatcher matching the user agents of browsers
+// not supporting ES modules.
+func IsLegacyBrowser(userAgent string) bool {
+	return legacyBrowserRe.MatchString(userAgent)
+}
+
+// WithLegacyIndex serves the specified legacy index file instead of the
+// default (modern) index file to requests matched by isLegacy, so that
+// differential builds can be served without client-side sniffing scripts. If
+// isLegacy is nil, requests are matched by their user agent using
+// IsLegacyBrowser. For instance:
+//
+//	h := NewSPAHandler(bundle, "index.html", WithLegacyIndex("index-legacy.html", nil))
+//
+// Index responses carry a “Vary: User-Agent” header. Index files chosen by an
+// IndexSelector take precedence over the legacy index file, which in turn
+// takes precedence over localized index files.
+func WithLegacyIndex(index string, isLegacy RequestMatcher) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		if isLegacy == nil {
+			isLegacy = MatchUserAgent(IsLegacyBrowser)
+		}
+		h.legacyIndex = path.Clean("/" + index)[1:]
+		h.isLegacy = isLegacy
+	}
+}
+
+// legacyIndexFor returns the (unrooted) path and name of the legacy index file
+// if the specified request is to be served the legacy index, otherwise "".
+func (h *SPAHandler) legacyIndexFor(r *http.Request) string {
+	if h.isLegacy == nil || !h.isLegacy(r) {
+		return ""
+	}
+	return h.legacyIndex
+}
@@ -0,0 +1,81 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("legacy index files", func() {
+
+	const (
+		modernChrome = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+		oldChrome    = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/58.0.3029.110 Safari/537.36"
+		ie11         = "Mozilla/5.0 (Windows NT 10.0; Trident/7.0; rv:11.0) like Gecko"
+		oldSafari    = "Mozilla/5.0 (iPhone; CPU iPhone OS 10_3 like Mac OS X) AppleWebKit/603.1.30 (KHTML, like Gecko) Version/10.0 Mobile/14E277 Safari/602.1"
+		modernSafari = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Safari/605.1.15"
+		oldFirefox   = "Mozilla/5.0 (X11; Linux x86_64; rv:52.0) Gecko/20100101 Firefox/52.0"
+		modernEdge   = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0"
+		legacyEdge   = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/52.0.2743.116 Safari/537.36 Edge/15.15063"
+	)
+
+	spafs := fstest.MapFS{
+		"index.html":        &fstest.MapFile{Data: []byte(`<base href="./" />MODERN`)},
+		"index-legacy.html": &fstest.MapFile{Data: []byte(`<base href="./" />LEGACY`)},
+	}
+
+	get := func(h http.Handler, userAgent string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/foo", nil)
+		r.Header.Set("User-Agent", userAgent)
+		h.ServeHTTP(w, r)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		return w
+	}
+
+	DescribeTable("detects legacy browsers",
+		func(userAgent string, expected bool) {
+			Expect(IsLegacyBrowser(userAgent)).To(Equal(expected))
+		},
+		Entry("modern Chrome", modernChrome, false),
+		Entry("old Chrome", oldChrome, true),
+		Entry("IE11", ie11, true),
+		Entry("old Safari", oldSafari, true),
+		Entry("modern Safari", modernSafari, false),
+		Entry("old Firefox", oldFirefox, true),
+		Entry("modern Edge", modernEdge, false),
+		Entry("legacy Edge", legacyEdge, true),
+	)
+
+	It("serves the legacy index to legacy browsers", func() {
+		h := NewSPAHandler(spafs, "index.html", WithLegacyIndex("/index-legacy.html", nil))
+		w := get(h, ie11)
+		Expect(w.Body.String()).To(Equal(`<base href="/" />LEGACY`))
+		Expect(w.Header().Values("Vary")).To(ContainElement("User-Agent"))
+		Expect(get(h, modernChrome).Body.String()).To(Equal(`<base href="/" />MODERN`))
+	})
+
+	It("uses a custom matcher", func() {
+		h := NewSPAHandler(spafs, "index.html", WithLegacyIndex("index-legacy.html",
+			func(r *http.Request) bool { return r.Header.Get("Sec-CH-UA") == "" }))
+		Expect(get(h, modernChrome).Body.String()).To(Equal(`<base href="/" />LEGACY`))
+	})
+
+})
@@ -0,0 +1,190 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"hash/fnv"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LiveReloadPath is the path, relative to the base path, of the server-sent
+// events endpoint notifying browsers about changed files.
+const LiveReloadPath = "/__spaserve/livereload"
+
+// liveReloadScript is injected into the index, reloading the page when the
+// server sends a reload event. It refers to LiveReloadPath relative to the
+// base.
+const liveReloadScript = `<script>new EventSource("__spaserve/livereload")` +
+	`.addEventListener("reload",()=>location.reload())</script>`
+
+// liveReload watches the served fs.FS for changes and notifies subscribed
+// browsers about them.
+type liveReload struct {
+	interval time.Duration
+	mu       sync.Mutex
+	subs     map[chan struct{}]struct{}
+	stop     chan struct{} // closed to stop the running watcher, if any.
+}
+
+// WithLiveReload enables a development feature that watches the fs.FS the SPA
+// is served from for changes and tells browsers to reload the SPA when files
+// have changed. For this, a tiny script gets injected into the index that
+// listens for reload events from a server-sent events endpoint at
+// LiveReloadPath below the base path.
+//
+// Instead of relying on OS-specific file system notifications, the fs.FS gets
+// polled in the specified interval, and only while at least one browser is
+// listening. Thus, live reloading works with any fs.FS, and also when swapping
+// fs.FSes using SwapFS.
+//
+// WithLiveReload is not intended for production use.
+func WithLiveReload(interval time.Duration) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.liveReload = &liveReload{
+			interval: interval,
+			subs:     map[chan struct{}]struct{}{},
+		}
+	}
+}
+
+// injectLiveReload returns the specified index contents with the live reload
+// script injected at the end of the head element, if live reloading is
+// enabled. Without a head element, the script gets appended.
+func (h *SPAHandler) injectLiveReload(html string) string {
+	if h.liveReload == nil {
+		return html
+	}
+	if loc := headEndRe.FindStringIndex(html); loc != nil {
+		return html[:loc[0]] + liveReloadScript + html[loc[0]:]
+	}
+	return html + liveReloadScript
+}
+
+// serveLiveReload serves the live reload events endpoint, if enabled and
+// requested, returning true. Otherwise, nothing is served and false is
+// returned.
+//
+// IMPORTANT: the passed r.URL.Path must have already been sanitized.
+func (h *SPAHandler) serveLiveReload(w http.ResponseWriter, r *http.Request) bool {
+	if h.liveReload == nil || r.URL.Path != LiveReloadPath {
+		return false
+	}
+	rc := http.NewResponseController(w)
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(": live reload\n\n"))
+	_ = rc.Flush()
+	changes := h.liveReload.subscribe(h)
+	defer h.liveReload.unsubscribe(changes)
+	for {
+		select {
+		case <-r.Context().Done():
+			return true
+		case <-changes:
+			if _, err := w.Write([]byte("event: reload\ndata: reload\n\n")); err != nil {
+				return true
+			}
+			_ = rc.Flush()
+		}
+	}
+}
+
+// subscribe returns a new channel notified about changes, starting the
+// watcher for the fs.FS currently served by the specified SPAHandler if
+// necessary.
+func (l *liveReload) subscribe(h *SPAHandler) chan struct{} {
+	ch := make(chan struct{}, 1)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.subs[ch] = struct{}{}
+	if l.stop == nil {
+		l.stop = make(chan struct{})
+		go l.watch(h, l.stop)
+	}
+	return ch
+}
+
+// unsubscribe removes the specified channel from the subscribers, stopping
+// the watcher when the last subscriber has gone.
+func (l *liveReload) unsubscribe(ch chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.subs, ch)
+	if len(l.subs) == 0 && l.stop != nil {
+		close(l.stop)
+		l.stop = nil
+	}
+}
+
+// watch polls the fs.FS currently served by the specified SPAHandler for
+// changes until stopped, notifying all subscribers about changes.
+func (l *liveReload) watch(h *SPAHandler, stop chan struct{}) {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+	last := fingerprint(h.FS())
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		if current := fingerprint(h.FS()); current != last {
+			last = current
+			l.notify()
+		}
+	}
+}
+
+// notify notifies all subscribers about a change, without blocking on
+// subscribers that haven't picked up a previous notification yet.
+func (l *liveReload) notify() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for ch := range l.subs {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// fingerprint returns a fingerprint of the names, sizes, and modification
+// times of all files in the specified fs.FS.
+func fingerprint(fsys fs.FS) uint64 {
+	hash := fnv.New64a()
+	_ = fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		_, _ = hash.Write([]byte(strings.Join([]string{
+			path,
+			strconv.FormatInt(info.Size(), 10),
+			strconv.FormatInt(info.ModTime().UnixNano(), 10),
+		}, "\x00") + "\x00"))
+		return nil
+	})
+	return hash.Sum64()
+}
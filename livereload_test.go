@@ -0,0 +1,108 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"bufio"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing/fstest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+// lockedFS guards a MapFS against concurrent modifications. It deliberately
+// doesn't embed the MapFS, so that only Open is available.
+type lockedFS struct {
+	mu  sync.Mutex
+	mfs fstest.MapFS
+}
+
+func (f *lockedFS) Open(name string) (fs.File, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.mfs.Open(name)
+}
+
+func (f *lockedFS) set(name string, contents string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mfs[name] = &fstest.MapFile{Data: []byte(contents), ModTime: time.Now()}
+}
+
+var _ = Describe("live reloading", func() {
+
+	It("injects the reload script", func() {
+		h := NewSPAHandler(fstest.MapFS{
+			"index.html": &fstest.MapFile{Data: []byte(`<head><base href="./" /></head>`)},
+		}, "index.html", WithLiveReload(time.Second))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/foo", nil))
+		Expect(w.Body.String()).To(Equal(`<head><base href="/" />` + liveReloadScript + `</head>`))
+	})
+
+	It("fingerprints file systems", func() {
+		mfs := fstest.MapFS{
+			"index.html":  &fstest.MapFile{Data: []byte(`index`)},
+			"assets/a.js": &fstest.MapFile{Data: []byte(`a`)},
+		}
+		fp := fingerprint(mfs)
+		Expect(fingerprint(mfs)).To(Equal(fp))
+		mfs["assets/a.js"] = &fstest.MapFile{Data: []byte(`aa`)}
+		Expect(fingerprint(mfs)).NotTo(Equal(fp))
+	})
+
+	It("notifies browsers about changes", func() {
+		lfs := &lockedFS{mfs: fstest.MapFS{
+			"index.html": &fstest.MapFile{Data: []byte(`<base href="./" />`)},
+		}}
+		h := NewSPAHandler(lfs, "index.html", WithLiveReload(10*time.Millisecond))
+		srv := httptest.NewServer(h)
+		defer srv.Close()
+
+		resp := Successful(http.Get(srv.URL + LiveReloadPath))
+		defer resp.Body.Close()
+		Expect(resp.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+		events := make(chan string)
+		go func() {
+			defer GinkgoRecover()
+			defer close(events)
+			scanner := bufio.NewScanner(resp.Body)
+			for scanner.Scan() {
+				if event, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+					events <- event
+				}
+			}
+		}()
+
+		Consistently(events).WithTimeout(100 * time.Millisecond).ShouldNot(Receive())
+		lfs.set("app.js", `app();`)
+		Eventually(events).Should(Receive(Equal("reload")))
+
+		resp.Body.Close()
+		Eventually(func() bool {
+			h.liveReload.mu.Lock()
+			defer h.liveReload.mu.Unlock()
+			return h.liveReload.stop == nil
+		}).Should(BeTrue())
+	})
+
+})
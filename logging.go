@@ -0,0 +1,76 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// WithErrorLogger sets the logger for the original errors encountered while
+// serving requests, such as errors from opening, stat'ing, and reading
+// resources from the SPAHandler's fs. While clients only get to see normalized
+// errors, the logged errors include all the internal details necessary to
+// diagnose misconfigurations, such as a wrong embedded FS root. Errors leading
+// to 5xx status codes are logged at error level, all others at warning level.
+func WithErrorLogger(logger *slog.Logger) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.errorLogger = logger
+	}
+}
+
+// logError logs the specified original error encountered while serving the
+// specified request, if an error logger has been set.
+func (h *SPAHandler) logError(r *http.Request, err error) {
+	if h.errorLogger == nil {
+		return
+	}
+	status, _, _ := normalizedError(err)
+	level := slog.LevelWarn
+	if status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	h.errorLogger.LogAttrs(r.Context(), level, "serving SPA request failed",
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.Int("status", status),
+		slog.String("error", err.Error()))
+}
+
+// WithAccessLog sets the logger for logging every request served, including
+// the method, request path, the resolved base path, how the request was served
+// (index fallback, static asset, et cetera), the response status code, and the
+// number of response body bytes written.
+func WithAccessLog(logger *slog.Logger) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.accessLogger = logger
+	}
+}
+
+// logAccess logs the served request.
+func (h *SPAHandler) logAccess(r *http.Request, info ServeInfo) {
+	if h.accessLogger == nil {
+		return
+	}
+	h.accessLogger.LogAttrs(r.Context(), slog.LevelInfo, "served SPA request",
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("base", info.Base),
+		slog.String("outcome", info.Outcome.String()),
+		slog.Bool("index", info.Outcome == OutcomeIndex),
+		slog.Int("status", info.Status),
+		slog.Int64("bytes", info.Bytes),
+		slog.Duration("duration", info.Duration))
+}
@@ -0,0 +1,114 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("logging", func() {
+
+	var logbuff *bytes.Buffer
+	var logger *slog.Logger
+
+	BeforeEach(func() {
+		logbuff = &bytes.Buffer{}
+		logger = slog.New(slog.NewTextHandler(logbuff, nil))
+	})
+
+	serve := func(h http.Handler, path string) *httptest.ResponseRecorder {
+		r := &http.Request{
+			Method: "GET",
+			URL:    Successful(url.Parse("http://foo.bar:12345" + path)),
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	It("logs original errors", func() {
+		h := NewSPAHandler(failingFS{FS: embStaticFs, name: "index.html", err: errors.New("D'OH!")},
+			"index.html", WithErrorLogger(logger))
+		w := serve(h, "/")
+		Expect(w.Code).To(Equal(http.StatusInternalServerError))
+		Expect(w.Body.String()).NotTo(ContainSubstring("D'OH!"))
+		Expect(logbuff.String()).To(And(
+			ContainSubstring("level=ERROR"),
+			ContainSubstring("status=500"),
+			ContainSubstring(`error="open index.html: D'OH!"`)))
+	})
+
+	It("logs a missing index as a warning", func() {
+		h := NewSPAHandler(embStaticFs, "bonkers.html", WithErrorLogger(logger))
+		Expect(serve(h, "/").Code).To(Equal(http.StatusNotFound))
+		Expect(logbuff.String()).To(And(
+			ContainSubstring("level=WARN"),
+			ContainSubstring("bonkers.html")))
+	})
+
+	It("doesn't log rejected index fallbacks", func() {
+		h := NewSPAHandler(embStaticFs, "index.html",
+			WithAssetNotFound(),
+			WithErrorLogger(logger))
+		Expect(serve(h, "/chunk-abc.js").Code).To(Equal(http.StatusNotFound))
+		Expect(logbuff.String()).To(BeEmpty())
+	})
+
+	It("logs accesses", func() {
+		h := NewSPAHandler(embStaticFs, "index.html",
+			WithAssetNotFound(),
+			WithAccessLog(logger))
+		r := &http.Request{
+			Method: "GET",
+			URL:    Successful(url.Parse("http://foo.bar:12345/some/route")),
+			Header: http.Header{ForwardedPrefixHeader: []string{"/app"}},
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(logbuff.String()).To(And(
+			ContainSubstring("level=INFO"),
+			ContainSubstring("method=GET"),
+			ContainSubstring("path=/some/route"),
+			ContainSubstring("base=/app/"),
+			ContainSubstring("outcome=index"),
+			ContainSubstring("index=true"),
+			ContainSubstring("status=200"),
+			ContainSubstring(fmt.Sprintf("bytes=%d", w.Body.Len()))))
+
+		logbuff.Reset()
+		Expect(serve(h, "/static/js/some.js").Code).To(Equal(http.StatusOK))
+		Expect(logbuff.String()).To(And(
+			ContainSubstring("outcome=static"),
+			ContainSubstring("index=false")))
+
+		logbuff.Reset()
+		Expect(serve(h, "/chunk-abc.js").Code).To(Equal(http.StatusNotFound))
+		Expect(logbuff.String()).To(And(
+			ContainSubstring("outcome=notfound"),
+			ContainSubstring("status=404")))
+	})
+
+})
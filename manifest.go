@@ -0,0 +1,119 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// manifestNames are the names of web app manifests whose URLs get rewritten to
+// the base path.
+var manifestNames = map[string]string{
+	"manifest.webmanifest": "application/manifest+json",
+	"manifest.json":        "application/json",
+}
+
+// serveManifest serves a web app manifest named “manifest.webmanifest” or
+// “manifest.json” with its rooted start_url, scope, id, and icon (as well as
+// screenshot and shortcut) URLs rewritten to the base path, returning true. As
+// manifests resolve URLs relative to the manifest's own URL instead of a base
+// element, rooted URLs would otherwise break the installed PWA's scope when
+// the SPA is served from a prefix. If the request isn't for a manifest or the
+// manifest cannot be parsed, nothing is served and false is returned.
+//
+// IMPORTANT: the passed r.URL.Path must have already been sanitized.
+func (h *SPAHandler) serveManifest(w http.ResponseWriter, r *http.Request) bool {
+	contentType, ok := manifestNames[path.Base(r.URL.Path)]
+	if !ok {
+		return false
+	}
+	name := r.URL.Path[1:]
+	if !h.isAllowedExtension(name) {
+		return false
+	}
+	fsys := h.bundleFor(r).fs
+	info, err := fs.Stat(fsys, name)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	contents, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return false
+	}
+	contents, err = rewriteManifest(contents, h.escapedBase(r))
+	if err != nil {
+		return false
+	}
+	h.callHooks(h.onStatic, r, name, false)
+	setHeader(w, h.assetHeader)
+	w.Header().Set("Content-Type", contentType)
+	http.ServeContent(w, r, name, info.ModTime(), bytes.NewReader(contents))
+	return true
+}
+
+// rewriteManifest returns the specified web app manifest with its rooted URLs
+// rewritten to the specified base path.
+func rewriteManifest(contents []byte, base string) ([]byte, error) {
+	var manifest map[string]any
+	dec := json.NewDecoder(bytes.NewReader(contents))
+	dec.UseNumber()
+	if err := dec.Decode(&manifest); err != nil {
+		return nil, err
+	}
+	for _, key := range []string{"start_url", "scope", "id"} {
+		rebaseManifestURL(manifest, key, base)
+	}
+	for _, key := range []string{"icons", "screenshots"} {
+		rebaseManifestImages(manifest[key], base)
+	}
+	if shortcuts, ok := manifest["shortcuts"].([]any); ok {
+		for _, shortcut := range shortcuts {
+			if shortcut, ok := shortcut.(map[string]any); ok {
+				rebaseManifestURL(shortcut, "url", base)
+				rebaseManifestImages(shortcut["icons"], base)
+			}
+		}
+	}
+	return json.Marshal(manifest)
+}
+
+// rebaseManifestImages rewrites the rooted src URLs of the specified image
+// resources to the specified base path.
+func rebaseManifestImages(images any, base string) {
+	list, ok := images.([]any)
+	if !ok {
+		return
+	}
+	for _, image := range list {
+		if image, ok := image.(map[string]any); ok {
+			rebaseManifestURL(image, "src", base)
+		}
+	}
+}
+
+// rebaseManifestURL rewrites the rooted URL of the specified member to the
+// specified base path. Protocol-relative URLs are left alone.
+func rebaseManifestURL(member map[string]any, key string, base string) {
+	url, ok := member[key].(string)
+	if !ok || !strings.HasPrefix(url, "/") || strings.HasPrefix(url, "//") {
+		return
+	}
+	member[key] = base + url[1:]
+}
@@ -0,0 +1,94 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("web app manifests", func() {
+
+	spafs := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<base href="./" />`)},
+		"manifest.webmanifest": &fstest.MapFile{Data: []byte(`{
+			"name": "ACME",
+			"start_url": "/",
+			"scope": "/",
+			"id": "./?app",
+			"theme_color": "#123456",
+			"version": 1.50,
+			"icons": [
+				{"src": "/icons/192.png", "sizes": "192x192"},
+				{"src": "icons/512.png", "sizes": "512x512"},
+				{"src": "//cdn.example.com/1024.png", "sizes": "1024x1024"}
+			],
+			"shortcuts": [
+				{"name": "Cart", "url": "/cart", "icons": [{"src": "/icons/cart.png"}]}
+			]
+		}`)},
+		"sub/manifest.json":    &fstest.MapFile{Data: []byte(`{"start_url": "/sub/"}`)},
+		"broken/manifest.json": &fstest.MapFile{Data: []byte(`{"start_url": `)},
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set(ForwardedPrefixHeader, "/app")
+		NewSPAHandler(spafs, "index.html").ServeHTTP(w, r)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		return w
+	}
+
+	It("rewrites rooted URLs", func() {
+		w := get("/manifest.webmanifest")
+		Expect(w.Header().Get("Content-Type")).To(Equal("application/manifest+json"))
+		Expect(w.Body.Bytes()).To(MatchJSON(`{
+			"name": "ACME",
+			"start_url": "/app/",
+			"scope": "/app/",
+			"id": "./?app",
+			"theme_color": "#123456",
+			"version": 1.50,
+			"icons": [
+				{"src": "/app/icons/192.png", "sizes": "192x192"},
+				{"src": "icons/512.png", "sizes": "512x512"},
+				{"src": "//cdn.example.com/1024.png", "sizes": "1024x1024"}
+			],
+			"shortcuts": [
+				{"name": "Cart", "url": "/app/cart", "icons": [{"src": "/app/icons/cart.png"}]}
+			]
+		}`))
+		var manifest map[string]json.RawMessage
+		Expect(json.Unmarshal(w.Body.Bytes(), &manifest)).To(Succeed())
+		Expect(string(manifest["version"])).To(Equal("1.50"))
+	})
+
+	It("rewrites manifest.json in subdirectories", func() {
+		w := get("/sub/manifest.json")
+		Expect(w.Header().Get("Content-Type")).To(Equal("application/json"))
+		Expect(w.Body.Bytes()).To(MatchJSON(`{"start_url": "/app/sub/"}`))
+	})
+
+	It("serves unparseable manifests as is", func() {
+		Expect(get("/broken/manifest.json").Body.String()).To(Equal(`{"start_url": `))
+	})
+
+})
@@ -0,0 +1,168 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// memFS is a read-only fs.FS with its files in memory, keyed by their
+// (unrooted) paths; the root directory has the path ".". Parent directories of
+// files are always present.
+type memFS map[string]*memEntry
+
+// memEntry is a file or directory of a memFS.
+type memEntry struct {
+	contents []byte    // file contents; nil for directories.
+	modTime  time.Time // modification time.
+	dir      bool      // true for directories.
+	children []string  // sorted base names of directory entries.
+}
+
+var _ fs.FS = (memFS)(nil)
+
+// newMemFS returns a new memFS with only the root directory.
+func newMemFS() memFS {
+	return memFS{".": {dir: true}}
+}
+
+// add adds the file or directory with the specified path, creating its parent
+// directories as necessary. Adding an existing file or directory replaces it,
+// keeping any directory entries.
+func (m memFS) add(name string, contents []byte, modTime time.Time, dir bool) {
+	if e, ok := m[name]; ok {
+		e.contents, e.modTime, e.dir = contents, modTime, dir
+		return
+	}
+	m[name] = &memEntry{contents: contents, modTime: modTime, dir: dir}
+	parent := path.Dir(name)
+	if _, ok := m[parent]; !ok {
+		m.add(parent, nil, time.Time{}, true)
+	}
+	p := m[parent]
+	base := path.Base(name)
+	i := sort.SearchStrings(p.children, base)
+	p.children = append(p.children, "")
+	copy(p.children[i+1:], p.children[i:])
+	p.children[i] = base
+}
+
+// Open opens the named file or directory.
+func (m memFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	e, ok := m[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	info := memFileInfo{
+		name:    path.Base(name),
+		size:    int64(len(e.contents)),
+		modTime: e.modTime,
+		dir:     e.dir,
+	}
+	if !e.dir {
+		return &memFile{Reader: bytes.NewReader(e.contents), info: info}, nil
+	}
+	entries := make([]fs.DirEntry, 0, len(e.children))
+	for _, child := range e.children {
+		c := m[path.Join(name, child)]
+		entries = append(entries, fs.FileInfoToDirEntry(memFileInfo{
+			name:    child,
+			size:    int64(len(c.contents)),
+			modTime: c.modTime,
+			dir:     c.dir,
+		}))
+	}
+	return &memDir{info: info, entries: entries}, nil
+}
+
+// memFile is an fs.File with its contents in memory.
+type memFile struct {
+	*bytes.Reader
+	info memFileInfo
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memFile) Close() error               { return nil }
+
+// memDir is an fs.ReadDirFile of a memFS directory.
+type memDir struct {
+	info    memFileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *memDir) Close() error               { return nil }
+
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+// ReadDir returns the next n directory entries, or all remaining entries if n
+// is zero or less.
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(remaining))
+	d.offset += n
+	return remaining[:n], nil
+}
+
+// memFileInfo is the fs.FileInfo of a memFile or memDir.
+type memFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return i.size }
+func (i memFileInfo) ModTime() time.Time { return i.modTime }
+func (i memFileInfo) IsDir() bool        { return i.dir }
+func (i memFileInfo) Sys() any           { return nil }
+
+func (i memFileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// cleanArchivePath returns the specified path of an archive entry as a valid
+// fs.FS path and true, or false if the path is invalid, such as when trying to
+// escape the archive's root.
+func cleanArchivePath(name string) (string, bool) {
+	name = path.Clean(strings.TrimLeft(name, "/"))
+	if name == "." || !fs.ValidPath(name) {
+		return "", false
+	}
+	return name, true
+}
@@ -0,0 +1,78 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"io/fs"
+	"testing/fstest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("in-memory fs", func() {
+
+	modTime := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	newFS := func() memFS {
+		m := newMemFS()
+		m.add("index.html", []byte("<base href=\"/\">"), modTime, false)
+		m.add("assets/app.js", []byte("app"), modTime, false)
+		m.add("assets/css/app.css", []byte("css"), modTime, false)
+		m.add("assets", nil, modTime, true)
+		return m
+	}
+
+	It("passes the fs conformance tests", func() {
+		Expect(fstest.TestFS(newFS(),
+			"index.html", "assets/app.js", "assets/css/app.css")).To(Succeed())
+	})
+
+	It("implicitly creates parent directories in order", func() {
+		entries := Successful(fs.ReadDir(newFS(), "assets"))
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].Name()).To(Equal("app.js"))
+		Expect(entries[1].Name()).To(Equal("css"))
+		Expect(entries[1].IsDir()).To(BeTrue())
+	})
+
+	It("keeps modification times", func() {
+		info := Successful(fs.Stat(newFS(), "assets/app.js"))
+		Expect(info.ModTime()).To(Equal(modTime))
+		Expect(info.Size()).To(Equal(int64(3)))
+		Expect(info.Mode()).To(Equal(fs.FileMode(0444)))
+	})
+
+	It("rejects invalid and non-existing paths", func() {
+		Expect(newFS().Open("/index.html")).Error().To(MatchError(fs.ErrInvalid))
+		Expect(newFS().Open("missing.js")).Error().To(MatchError(fs.ErrNotExist))
+	})
+
+	DescribeTable("cleaning archive paths",
+		func(name, expected string, ok bool) {
+			cleaned, valid := cleanArchivePath(name)
+			Expect(valid).To(Equal(ok))
+			Expect(cleaned).To(Equal(expected))
+		},
+		Entry(nil, "index.html", "index.html", true),
+		Entry(nil, "./assets/app.js", "assets/app.js", true),
+		Entry(nil, "/assets/", "assets", true),
+		Entry(nil, "../escape.js", "", false),
+		Entry(nil, "./", "", false),
+	)
+
+})
@@ -0,0 +1,139 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"html"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// MetaTags are the meta tags to inject into the index served for a particular
+// request, such as “og:title” and “description”, so that link previews show
+// route-specific information even for client-rendered SPAs. Meta tags already
+// present in the index with the same name or property get replaced.
+type MetaTags struct {
+	Canonical  string            // canonical URL of the page, if any.
+	Names      map[string]string // meta tag contents by name, such as "description".
+	Properties map[string]string // meta tag contents by property, such as "og:title".
+}
+
+// MetaProvider returns the meta tags to inject into the index served for the
+// specified request, or nil if the index is to be served as is.
+type MetaProvider func(r *http.Request) *MetaTags
+
+// WithMetaTags injects the meta tags returned by the specified provider into
+// the index, right before the end of the head element. For instance:
+//
+//	h := NewSPAHandler(bundle, "index.html",
+//	    WithMetaTags(func(r *http.Request) *MetaTags {
+//	        product, ok := lookupProduct(r.URL.Path)
+//	        if !ok {
+//	            return nil
+//	        }
+//	        return &MetaTags{
+//	            Canonical:  "https://shop.example.com/products/" + product.ID,
+//	            Names:      map[string]string{"description": product.Summary},
+//	            Properties: map[string]string{"og:title": product.Name},
+//	        }
+//	    }))
+//
+// The contents are properly escaped. Index files without a head end tag are
+// served without any meta tags injected.
+func WithMetaTags(provider MetaProvider) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.metaProvider = provider
+	}
+}
+
+var (
+	// metaTagRe matches meta and link elements, where we're interested in
+	// their name, property, and rel attributes.
+	metaTagRe = regexp.MustCompile(`(?i)<(?:meta|link)\s[^>]*>`)
+	// metaAttrRe matches the name, property, and rel attributes with quoted
+	// values.
+	metaAttrRe = regexp.MustCompile(`(?i)\s(name|property|rel)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	// headEndRe matches the end tag of the head element.
+	headEndRe = regexp.MustCompile(`(?i)</head\s*>`)
+)
+
+// injectMetaTags returns the specified index contents with the meta tags for
+// the specified request injected, replacing any existing meta tags with the
+// same name or property, as well as any existing canonical link.
+func (h *SPAHandler) injectMetaTags(r *http.Request, index []byte) []byte {
+	if h.metaProvider == nil {
+		return index
+	}
+	return injectMeta(index, h.metaProvider(r))
+}
+
+// injectMeta returns the specified index contents with the specified meta tags
+// injected, replacing any existing meta tags with the same name or property,
+// as well as any existing canonical link.
+func injectMeta(index []byte, tags *MetaTags) []byte {
+	if tags == nil {
+		return index
+	}
+	headEnd := headEndRe.FindIndex(index)
+	if headEnd == nil {
+		return index
+	}
+	var injected strings.Builder
+	if tags.Canonical != "" {
+		injected.WriteString(`<link rel="canonical" href="` + html.EscapeString(tags.Canonical) + `">`)
+	}
+	writeMetaTags(&injected, "name", tags.Names)
+	writeMetaTags(&injected, "property", tags.Properties)
+	head := metaTagRe.ReplaceAllFunc(index[:headEnd[0]], func(tag []byte) []byte {
+		for _, attr := range metaAttrRe.FindAllStringSubmatch(string(tag), -1) {
+			value := attr[2] + attr[3]
+			switch strings.ToLower(attr[1]) {
+			case "name":
+				if _, ok := tags.Names[value]; ok {
+					return nil
+				}
+			case "property":
+				if _, ok := tags.Properties[value]; ok {
+					return nil
+				}
+			case "rel":
+				if tags.Canonical != "" && strings.EqualFold(value, "canonical") {
+					return nil
+				}
+			}
+		}
+		return tag
+	})
+	injectedIndex := make([]byte, 0, len(head)+injected.Len()+len(index)-headEnd[0])
+	injectedIndex = append(injectedIndex, head...)
+	injectedIndex = append(injectedIndex, injected.String()...)
+	return append(injectedIndex, index[headEnd[0]:]...)
+}
+
+// writeMetaTags writes meta elements for the specified contents by attribute
+// value, sorted by the attribute values.
+func writeMetaTags(b *strings.Builder, attr string, contents map[string]string) {
+	keys := make([]string, 0, len(contents))
+	for key := range contents {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		b.WriteString(`<meta ` + attr + `="` + html.EscapeString(key) +
+			`" content="` + html.EscapeString(contents[key]) + `">`)
+	}
+}
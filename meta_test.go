@@ -0,0 +1,82 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("meta tags", func() {
+
+	spafs := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<html><head><base href="./" />` +
+			`<meta name="description" content="generic">` +
+			`<META PROPERTY='og:title' CONTENT='generic'>` +
+			`<meta property="og:type" content="website">` +
+			`<link rel="canonical" href="https://example.com/">` +
+			`<link rel="icon" href="favicon.ico">` +
+			`</HEAD><body></body></html>`)},
+		"headless.html": &fstest.MapFile{Data: []byte(`<base href="./" />HEADLESS`)},
+	}
+
+	provider := func(r *http.Request) *MetaTags {
+		if r.URL.Path != "/products/42" {
+			return nil
+		}
+		return &MetaTags{
+			Canonical: "https://example.com/products/42?a=1&b=2",
+			Names:     map[string]string{"description": `The "answer"`},
+			Properties: map[string]string{
+				"og:title": "<42>",
+				"og:image": "https://example.com/42.png",
+			},
+		}
+	}
+
+	get := func(h http.Handler, path string) string {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		Expect(w.Code).To(Equal(http.StatusOK))
+		return w.Body.String()
+	}
+
+	It("injects and replaces meta tags", func() {
+		h := NewSPAHandler(spafs, "index.html", WithMetaTags(provider))
+		Expect(get(h, "/products/42")).To(Equal(`<html><head><base href="/" />` +
+			`<meta property="og:type" content="website">` +
+			`<link rel="icon" href="favicon.ico">` +
+			`<link rel="canonical" href="https://example.com/products/42?a=1&amp;b=2">` +
+			`<meta name="description" content="The &#34;answer&#34;">` +
+			`<meta property="og:image" content="https://example.com/42.png">` +
+			`<meta property="og:title" content="&lt;42&gt;">` +
+			`</HEAD><body></body></html>`))
+	})
+
+	It("leaves the index alone without meta tags", func() {
+		h := NewSPAHandler(spafs, "index.html", WithMetaTags(provider))
+		Expect(get(h, "/products/1")).To(ContainSubstring(`<meta name="description" content="generic">`))
+	})
+
+	It("leaves indices without head alone", func() {
+		h := NewSPAHandler(spafs, "headless.html", WithMetaTags(provider))
+		Expect(get(h, "/products/42")).To(Equal(`<base href="/" />HEADLESS`))
+	})
+
+})
@@ -0,0 +1,151 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrMethodNotAllowed signals a request method not allowed by an SPAHandler,
+// and gets mapped to the HTTP status code 405 (Method Not Allowed).
+var ErrMethodNotAllowed = errors.New("method not allowed")
+
+// WithAllowedMethods sets the request methods served, replacing the default
+// GET and HEAD methods. Requests with any other method are answered with 405
+// (Method Not Allowed) and an “Allow” header listing the allowed methods,
+// instead of happily serving the index to, say, a misrouted POST and thus
+// hiding routing bugs.
+//
+// Please note that SPAHandler never does anything different for methods other
+// than GET and HEAD; allowing additional methods is thus only useful when these
+// are intercepted before reaching the SPAHandler, for instance, by middleware.
+func WithAllowedMethods(methods ...string) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.allowedMethods = h.allowedMethods[:0:0]
+		for _, method := range methods {
+			if method == "" {
+				continue
+			}
+			h.allowedMethods = append(h.allowedMethods, strings.ToUpper(method))
+		}
+	}
+}
+
+// isAllowedMethod returns true if the specified request method is allowed.
+func (h *SPAHandler) isAllowedMethod(method string) bool {
+	for _, allowed := range h.allowedMethods {
+		if method == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectMethod answers a request with a method not allowed with 405, telling
+// the client the allowed methods.
+func (h *SPAHandler) rejectMethod(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Allow", h.allow())
+	h.writeError(w, r, ErrMethodNotAllowed)
+}
+
+// allow returns the value of the Allow header, listing the allowed methods.
+func (h *SPAHandler) allow() string {
+	methods := h.allowedMethods
+	if h.answerOptions {
+		methods = append(methods[:len(methods):len(methods)], http.MethodOptions)
+	}
+	return strings.Join(methods, ", ")
+}
+
+// CORSPreflight configures the answers to CORS preflight requests, as set by
+// WithCORSPreflight. When allowing credentialed requests, the “*” origin is
+// ignored, so that only explicitly allowed origins get credentialed approval;
+// NewSPAHandlerE rejects this combination altogether.
+type CORSPreflight struct {
+	AllowedOrigins   []string      // allowed origins, such as "https://example.org", or "*" for any.
+	AllowedHeaders   []string      // allowed request headers, such as "Authorization".
+	AllowCredentials bool          // allow credentialed requests.
+	MaxAge           time.Duration // how long the preflight result can be cached; zero leaves it unset.
+}
+
+// WithOptionsResponse answers OPTIONS requests with 204 (No Content) and an
+// “Allow” header listing the allowed methods, instead of 405 (Method Not
+// Allowed).
+func WithOptionsResponse() SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.answerOptions = true
+	}
+}
+
+// WithCORSPreflight answers OPTIONS requests the same as WithOptionsResponse
+// does, but additionally answers CORS preflight requests from the specified
+// allowed origins with the corresponding Access-Control-Allow-* headers.
+// Preflight requests from other origins get no such headers, so browsers will
+// refuse the actual cross-origin requests.
+func WithCORSPreflight(cors CORSPreflight) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		if cors.AllowCredentials && slices.Contains(cors.AllowedOrigins, "*") {
+			h.invalidOption("WithCORSPreflight", `credentials allowed for any origin "*"`)
+		}
+		h.answerOptions = true
+		h.cors = &cors
+	}
+}
+
+// serveOptions answers an OPTIONS request, including CORS preflight requests
+// if configured.
+func (h *SPAHandler) serveOptions(w http.ResponseWriter, r *http.Request) {
+	header := w.Header()
+	header.Set("Allow", h.allow())
+	if h.cors != nil && r.Header.Get("Access-Control-Request-Method") != "" {
+		header.Add("Vary", "Origin")
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+		if origin := r.Header.Get("Origin"); origin != "" && h.cors.allowsOrigin(origin) {
+			if h.cors.AllowCredentials {
+				header.Set("Access-Control-Allow-Origin", origin)
+				header.Set("Access-Control-Allow-Credentials", "true")
+			} else if h.cors.allowsOrigin("*") {
+				header.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				header.Set("Access-Control-Allow-Origin", origin)
+			}
+			header.Set("Access-Control-Allow-Methods", strings.Join(h.allowedMethods, ", "))
+			if len(h.cors.AllowedHeaders) > 0 {
+				header.Set("Access-Control-Allow-Headers", strings.Join(h.cors.AllowedHeaders, ", "))
+			}
+			if h.cors.MaxAge > 0 {
+				header.Set("Access-Control-Max-Age", strconv.Itoa(int(h.cors.MaxAge.Seconds())))
+			}
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// allowsOrigin returns true if the specified origin is allowed. The “*” origin
+// allows any origin, unless credentials are allowed.
+func (c *CORSPreflight) allowsOrigin(origin string) bool {
+	for _, allowed := range c.AllowedOrigins {
+		if (allowed == "*" && !c.AllowCredentials) || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("request methods", func() {
+
+	DescribeTable("rejects methods other than GET and HEAD by default",
+		func(method string, path string, expectedStatus int) {
+			h := NewSPAHandler(embStaticFs, "index.html")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+			Expect(w.Code).To(Equal(expectedStatus))
+			if expectedStatus == http.StatusMethodNotAllowed {
+				Expect(w.Header().Get("Allow")).To(Equal("GET, HEAD"))
+				Expect(w.Body.String()).NotTo(ContainSubstring("<html"))
+			}
+		},
+		Entry("GET index", http.MethodGet, "/", http.StatusOK),
+		Entry("HEAD index", http.MethodHead, "/foo", http.StatusOK),
+		Entry("GET asset", http.MethodGet, "/static/js/some.js", http.StatusOK),
+		Entry("POST SPA path", http.MethodPost, "/foo", http.StatusMethodNotAllowed),
+		Entry("PUT asset", http.MethodPut, "/static/js/some.js", http.StatusMethodNotAllowed),
+		Entry("DELETE index", http.MethodDelete, "/", http.StatusMethodNotAllowed),
+	)
+
+	It("allows configuring the allowed methods", func() {
+		h := NewSPAHandler(embStaticFs, "index.html",
+			WithAllowedMethods("get", "", http.MethodPost))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/foo", nil))
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/foo", nil))
+		Expect(w.Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(w.Header().Get("Allow")).To(Equal("GET, POST"))
+	})
+
+	It("logs rejected requests", func() {
+		var logbuff bytes.Buffer
+		h := NewSPAHandler(embStaticFs, "index.html",
+			WithAccessLog(slog.New(slog.NewTextHandler(&logbuff, nil))))
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPatch, "/", nil))
+		Expect(logbuff.String()).To(And(
+			ContainSubstring("method=PATCH"),
+			ContainSubstring("outcome=rejected"),
+			ContainSubstring("status=405")))
+	})
+
+})
+
+var _ = Describe("OPTIONS requests", func() {
+
+	options := func(h http.Handler, header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodOptions, "/foo", nil)
+		for name, values := range header {
+			r.Header[name] = values
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	preflight := func(origin string) http.Header {
+		return http.Header{
+			"Origin":                        []string{origin},
+			"Access-Control-Request-Method": []string{http.MethodGet},
+		}
+	}
+
+	It("rejects OPTIONS by default", func() {
+		h := NewSPAHandler(embStaticFs, "index.html")
+		w := options(h, nil)
+		Expect(w.Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(w.Header().Get("Allow")).To(Equal("GET, HEAD"))
+	})
+
+	It("answers OPTIONS", func() {
+		h := NewSPAHandler(embStaticFs, "index.html", WithOptionsResponse())
+		w := options(h, preflight("https://example.org"))
+		Expect(w.Code).To(Equal(http.StatusNoContent))
+		Expect(w.Body.Len()).To(BeZero())
+		Expect(w.Header().Get("Allow")).To(Equal("GET, HEAD, OPTIONS"))
+		Expect(w.Header().Get("Access-Control-Allow-Origin")).To(BeEmpty())
+
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/foo", nil))
+		Expect(w.Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(w.Header().Get("Allow")).To(Equal("GET, HEAD, OPTIONS"))
+	})
+
+	It("answers CORS preflight requests from allowed origins", func() {
+		h := NewSPAHandler(embStaticFs, "index.html", WithCORSPreflight(CORSPreflight{
+			AllowedOrigins:   []string{"https://example.org"},
+			AllowedHeaders:   []string{"Authorization", "X-Requested-With"},
+			AllowCredentials: true,
+			MaxAge:           10 * time.Minute,
+		}))
+		w := options(h, preflight("https://example.org"))
+		Expect(w.Code).To(Equal(http.StatusNoContent))
+		Expect(w.Header()).To(And(
+			HaveKeyWithValue("Allow", []string{"GET, HEAD, OPTIONS"}),
+			HaveKeyWithValue("Access-Control-Allow-Origin", []string{"https://example.org"}),
+			HaveKeyWithValue("Access-Control-Allow-Credentials", []string{"true"}),
+			HaveKeyWithValue("Access-Control-Allow-Methods", []string{"GET, HEAD"}),
+			HaveKeyWithValue("Access-Control-Allow-Headers", []string{"Authorization, X-Requested-With"}),
+			HaveKeyWithValue("Access-Control-Max-Age", []string{"600"}),
+			HaveKeyWithValue("Vary", ContainElement("Origin")),
+		))
+
+		w = options(h, preflight("https://evil.example.com"))
+		Expect(w.Code).To(Equal(http.StatusNoContent))
+		Expect(w.Header()).NotTo(HaveKey("Access-Control-Allow-Origin"))
+
+		w = options(h, nil)
+		Expect(w.Code).To(Equal(http.StatusNoContent))
+		Expect(w.Header()).NotTo(HaveKey("Access-Control-Allow-Origin"))
+	})
+
+	It("ignores any origin when allowing credentials", func() {
+		h := NewSPAHandler(embStaticFs, "index.html", WithCORSPreflight(CORSPreflight{
+			AllowedOrigins:   []string{"*", "https://example.org"},
+			AllowCredentials: true,
+		}))
+		w := options(h, preflight("https://evil.example.com"))
+		Expect(w.Header()).NotTo(HaveKey("Access-Control-Allow-Origin"))
+		Expect(w.Header()).NotTo(HaveKey("Access-Control-Allow-Credentials"))
+
+		w = options(h, preflight("https://example.org"))
+		Expect(w.Header()).To(And(
+			HaveKeyWithValue("Access-Control-Allow-Origin", []string{"https://example.org"}),
+			HaveKeyWithValue("Access-Control-Allow-Credentials", []string{"true"}),
+		))
+	})
+
+	It("answers CORS preflight requests from any origin", func() {
+		h := NewSPAHandler(embStaticFs, "index.html", WithCORSPreflight(CORSPreflight{
+			AllowedOrigins: []string{"*"},
+		}))
+		w := options(h, preflight("https://example.org"))
+		Expect(w.Header()).To(And(
+			HaveKeyWithValue("Access-Control-Allow-Origin", []string{"*"}),
+			Not(HaveKey("Access-Control-Allow-Credentials")),
+			Not(HaveKey("Access-Control-Allow-Headers")),
+			Not(HaveKey("Access-Control-Max-Age")),
+		))
+	})
+
+})
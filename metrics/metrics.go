@@ -0,0 +1,126 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package metrics provides Prometheus metrics for spaserve's SPAHandler.
+
+Create a new Metrics collector, register it with a prometheus.Registerer, and
+then pass it to the SPAHandler as an observer:
+
+	m := metrics.New("myapp")
+	prometheus.MustRegister(m)
+	h := spaserve.NewSPAHandler(fsys, "index.html", spaserve.WithObserver(m))
+
+The following metrics are collected, prefixed by the optional namespace:
+
+  - spaserve_requests_total: counter of served requests, by outcome (index,
+    static, notfound, redirect) and status code.
+  - spaserve_request_duration_seconds: histogram of request latencies, by
+    outcome.
+  - spaserve_index_rewrite_duration_seconds: histogram of the index rewrite
+    durations.
+  - spaserve_cache_hits_total: counter of requests answered with “304 Not
+    Modified”, that is, client cache hits.
+*/
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/thediveo/spaserve"
+)
+
+// subsystem of all spaserve metrics.
+const subsystem = "spaserve"
+
+// Metrics is a prometheus.Collector for SPAHandler metrics, as well as a
+// spaserve.Observer.
+type Metrics struct {
+	requests  *prometheus.CounterVec
+	latency   *prometheus.HistogramVec
+	rewrite   prometheus.Histogram
+	cacheHits prometheus.Counter
+}
+
+var (
+	_ prometheus.Collector = (*Metrics)(nil)
+	_ spaserve.Observer    = (*Metrics)(nil)
+)
+
+// New returns a new Metrics collector with its metrics placed in the
+// specified (optional) namespace.
+func New(namespace string) *Metrics {
+	return &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "requests_total",
+			Help:      "Number of requests served, by outcome and status code.",
+		}, []string{"outcome", "code"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "request_duration_seconds",
+			Help:      "Latencies of served requests, by outcome.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"outcome"}),
+		rewrite: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "index_rewrite_duration_seconds",
+			Help:      "Durations of rewriting the index.",
+			Buckets:   prometheus.ExponentialBuckets(0.00001, 4, 10),
+		}),
+		cacheHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "cache_hits_total",
+			Help:      "Number of requests answered with 304 Not Modified.",
+		}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.requests.Describe(ch)
+	m.latency.Describe(ch)
+	m.rewrite.Describe(ch)
+	m.cacheHits.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.requests.Collect(ch)
+	m.latency.Collect(ch)
+	m.rewrite.Collect(ch)
+	m.cacheHits.Collect(ch)
+}
+
+// ObserveRequest implements spaserve.Observer.
+func (m *Metrics) ObserveRequest(r *http.Request, info spaserve.ServeInfo) {
+	outcome := info.Outcome.String()
+	m.requests.WithLabelValues(outcome, strconv.Itoa(info.Status)).Inc()
+	m.latency.WithLabelValues(outcome).Observe(info.Duration.Seconds())
+	if info.Status == http.StatusNotModified {
+		m.cacheHits.Inc()
+	}
+}
+
+// ObserveIndexRewrite implements spaserve.Observer.
+func (m *Metrics) ObserveIndexRewrite(r *http.Request, duration time.Duration) {
+	m.rewrite.Observe(duration.Seconds())
+}
@@ -0,0 +1,74 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/thediveo/spaserve"
+	"github.com/thediveo/spaserve/test/fixture"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("SPA metrics", func() {
+
+	It("collects request metrics", func() {
+		m := New("test")
+		reg := prometheus.NewPedanticRegistry()
+		Expect(reg.Register(m)).To(Succeed())
+
+		bundle := fixture.New(fixture.WithModTime(time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)))
+		h := spaserve.NewSPAHandler(bundle.FS, bundle.Index,
+			spaserve.WithAssetNotFound(),
+			spaserve.WithObserver(m))
+		for _, path := range []string{"/", "/route", "/" + bundle.Chunks[0], "/missing.js"} {
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		}
+		r := httptest.NewRequest(http.MethodGet, "/"+bundle.Chunks[0], nil)
+		r.Header.Set("If-Modified-Since", "Mon, 02 Jan 2006 15:04:05 GMT")
+		h.ServeHTTP(httptest.NewRecorder(), r)
+
+		Expect(testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP test_spaserve_requests_total Number of requests served, by outcome and status code.
+# TYPE test_spaserve_requests_total counter
+test_spaserve_requests_total{code="200",outcome="index"} 2
+test_spaserve_requests_total{code="200",outcome="static"} 1
+test_spaserve_requests_total{code="304",outcome="static"} 1
+test_spaserve_requests_total{code="404",outcome="notfound"} 1
+# HELP test_spaserve_cache_hits_total Number of requests answered with 304 Not Modified.
+# TYPE test_spaserve_cache_hits_total counter
+test_spaserve_cache_hits_total 1
+`), "test_spaserve_requests_total", "test_spaserve_cache_hits_total")).To(Succeed())
+
+		Expect(testutil.CollectAndCount(m, "test_spaserve_request_duration_seconds")).To(Equal(3))
+		families := Successful(reg.Gather())
+		for _, family := range families {
+			if family.GetName() == "test_spaserve_index_rewrite_duration_seconds" {
+				Expect(family.GetMetric()[0].GetHistogram().GetSampleCount()).To(Equal(uint64(2)))
+				return
+			}
+		}
+		Fail("missing index rewrite duration metric")
+	})
+
+})
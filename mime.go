@@ -0,0 +1,93 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// DefaultMIMETypes returns the media types of file extensions commonly found in
+// SPA bundles that must be served correctly for browsers to accept them, such
+// as module scripts and WASM modules for streaming compilation.
+func DefaultMIMETypes() map[string]string {
+	return map[string]string{
+		".js":          "text/javascript; charset=utf-8",
+		".mjs":         "text/javascript; charset=utf-8",
+		".css":         "text/css; charset=utf-8",
+		".json":        "application/json",
+		".map":         "application/json",
+		".wasm":        "application/wasm",
+		".webmanifest": "application/manifest+json",
+		".svg":         "image/svg+xml",
+		".avif":        "image/avif",
+		".webp":        "image/webp",
+		".woff2":       "font/woff2",
+	}
+}
+
+// WithMIMETypes serves static assets and virtual files with the specified
+// media types, by file extension. The media types take precedence over the
+// ones known to the system, which might be incomplete or even wrong on systems
+// with broken /etc/mime.types or Windows registries. For instance:
+//
+//	h := NewSPAHandler(fsys, "index.html", WithMIMETypes(DefaultMIMETypes()))
+//
+// File extensions are matched case-insensitively and must include the leading
+// dot. Specifying WithMIMETypes multiple times adds to the media types, with
+// later media types replacing earlier ones of the same file extension.
+func WithMIMETypes(types map[string]string) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		if h.mimeTypes == nil {
+			h.mimeTypes = map[string]string{}
+		}
+		for ext, mediaType := range types {
+			h.mimeTypes[strings.ToLower(ext)] = mediaType
+		}
+	}
+}
+
+// setContentType sets the Content-Type header for the file with the specified
+// name if its file extension has a configured media type. Otherwise, the
+// Content-Type is left to the file serving machinery.
+func (h *SPAHandler) setContentType(w http.ResponseWriter, name string) {
+	if mediaType, ok := h.mimeTypes[strings.ToLower(path.Ext(name))]; ok {
+		w.Header().Set("Content-Type", mediaType)
+	}
+}
+
+// DefaultIndexContentType is the default Content-Type of the index.
+const DefaultIndexContentType = "text/html; charset=utf-8"
+
+// WithIndexContentType sets the Content-Type of the index, overriding the
+// DefaultIndexContentType with its explicit UTF-8 charset. Passing an empty
+// contentType leaves detecting the Content-Type to http.ServeContent instead.
+//
+// Explicitly stating the charset is important as some older proxies otherwise
+// downgrade the charset, breaking SPAs with non-ASCII index contents.
+func WithIndexContentType(contentType string) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.indexContentType = contentType
+	}
+}
+
+// setIndexContentType sets the Content-Type header of index responses, if
+// configured.
+func (h *SPAHandler) setIndexContentType(w http.ResponseWriter) {
+	if h.indexContentType != "" {
+		w.Header().Set("Content-Type", h.indexContentType)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("custom MIME types", func() {
+
+	spafs := fstest.MapFS{
+		"index.html":     &fstest.MapFile{Data: []byte(`<base href="./" />`)},
+		"app.wasm":       &fstest.MapFile{Data: []byte("\x00asm")},
+		"chunk.MJS":      &fstest.MapFile{Data: []byte(`export {}`)},
+		"data.foo":       &fstest.MapFile{Data: []byte(`foo`)},
+		"styles.css":     &fstest.MapFile{Data: []byte(`body {}`)},
+		"unknown.xyzzy1": &fstest.MapFile{Data: []byte(`plugh`)},
+	}
+
+	DescribeTable("serves assets with configured media types",
+		func(path string, expected string) {
+			h := NewSPAHandler(spafs, "index.html",
+				WithMIMETypes(DefaultMIMETypes()),
+				WithMIMETypes(map[string]string{
+					".FOO": "application/x-foo",
+					".css": "text/css",
+				}),
+				WithVirtualFile("env.foo", func(*http.Request) ([]byte, time.Time, error) {
+					return []byte("foo"), time.Time{}, nil
+				}))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Header().Get("Content-Type")).To(Equal(expected))
+		},
+		Entry("wasm", "/app.wasm", "application/wasm"),
+		Entry("module script with upper-case extension", "/chunk.MJS", "text/javascript; charset=utf-8"),
+		Entry("custom extension", "/data.foo", "application/x-foo"),
+		Entry("replaced media type", "/styles.css", "text/css"),
+		Entry("virtual file", "/env.foo", "application/x-foo"),
+		Entry("index unaffected", "/foo", "text/html; charset=utf-8"),
+	)
+
+	DescribeTable("serves the index with an explicit Content-Type",
+		func(opts []SPAHandlerOption, expected string) {
+			h := NewSPAHandler(fstest.MapFS{
+				"index.html": &fstest.MapFile{Data: []byte(`<base href="./" /><p>Grüße</p>`)},
+			}, "index.html", opts...)
+			for _, method := range []string{http.MethodGet, http.MethodHead} {
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, httptest.NewRequest(method, "/foo", nil))
+				Expect(w.Code).To(Equal(http.StatusOK))
+				Expect(w.Header().Get("Content-Type")).To(Equal(expected), method)
+			}
+		},
+		Entry("default", nil, "text/html; charset=utf-8"),
+		Entry("configured", []SPAHandlerOption{WithIndexContentType("text/html; charset=UTF-8")}, "text/html; charset=UTF-8"),
+	)
+
+})
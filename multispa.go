@@ -0,0 +1,117 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// SPAConfig describes an individual SPA served by a MultiSPAHandler.
+type SPAConfig struct {
+	FS      fs.FS              // the FS to serve the SPA's static resources from.
+	Index   string             // (unrooted) path and name of the index/SPA file inside FS.
+	Options []SPAHandlerOption // optional options specific to this SPA.
+}
+
+// MultiSPAHandler implements an http.Handler that serves multiple SPAs, each
+// mounted on its own path prefix, such as “/admin” and “/shop”. Each SPA gets
+// its own index fallback and base rewriting. Mount prefixes can be nested, such
+// as “/shop” and “/shop/admin”, with the longest matching prefix winning.
+type MultiSPAHandler struct {
+	mounts map[string]*SPAHandler // SPA handlers indexed by their mount name.
+}
+
+// NewMultiSPAHandler returns a new HTTP handler serving the SPAs specified in
+// apps, where the map keys specify the names the SPAs are mounted on, such as
+// "admin" (mounted on “/admin/”) or "shop/admin" (mounted on “/shop/admin/”).
+// An SPA mounted on "" or "/" serves all requests not matching any other
+// mounted SPA. The common opts are applied to all SPAs before their individual
+// options.
+//
+// The following example serves two SPAs from the same embedded FS, with both
+// SPAs sharing the same vendor chunks:
+//
+//	admin, _ := fs.Sub(embedded, "admin")
+//	shop, _ := fs.Sub(embedded, "shop")
+//	h := NewMultiSPAHandler(map[string]SPAConfig{
+//	    "admin": {FS: admin, Index: "index.html"},
+//	    "shop":  {FS: shop, Index: "index.html"},
+//	}, WithSharedAssets(embedded, "vendor"))
+func NewMultiSPAHandler(apps map[string]SPAConfig, opts ...SPAHandlerOption) *MultiSPAHandler {
+	h := &MultiSPAHandler{
+		mounts: map[string]*SPAHandler{},
+	}
+	for name, app := range apps {
+		name = strings.Trim(path.Clean("/"+name), "/")
+		h.mounts[name] = NewSPAHandler(app.FS, app.Index,
+			append(append([]SPAHandlerOption{}, opts...), app.Options...)...)
+	}
+	return h
+}
+
+// ServeHTTP serves the SPA mounted on the longest matching prefix of the
+// request URL path, with the mount prefix stripped from the request path.
+// Requests not matching any mounted SPA are answered with 404.
+func (h *MultiSPAHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reqPath, err := SanitizePath(r.URL.Path)
+	if err != nil {
+		NormalizedHttpError(w, err)
+		return
+	}
+	for prefix := reqPath; ; prefix = path.Dir(prefix) {
+		if spa, ok := h.mounts[prefix[1:]]; ok {
+			rest := strings.TrimPrefix(reqPath, prefix)
+			if rest != "" && strings.HasSuffix(r.URL.Path, "/") {
+				rest += "/" // ...for trailing slash policies.
+			}
+			if prefix == "/" {
+				prefix = ""
+			}
+			spa.ServeHTTP(w, mountedRequest(r, prefix, "/"+strings.TrimPrefix(rest, "/")))
+			return
+		}
+		if prefix == "/" {
+			break
+		}
+	}
+	NormalizedHttpError(w, fs.ErrNotExist)
+}
+
+// mountedRequest returns a shallow copy of the specified request with its URL
+// path set to the specified path below the mount prefix. Additionally, the
+// mount prefix gets added to the forwarded prefix, so that the SPA handler
+// correctly determines the SPA's base path. If an outer proxy only passed the
+// original request URI, then the outer prefix is derived from it.
+func mountedRequest(r *http.Request, prefix string, reqPath string) *http.Request {
+	r2 := withURLPath(r, reqPath)
+	r2.Header = r.Header.Clone()
+	if r2.Header == nil {
+		r2.Header = http.Header{}
+	}
+	if fwprefix, ok := forwardedPrefix(r.Header.Get(ForwardedPrefixHeader)); ok {
+		prefix = path.Join(fwprefix, prefix)
+	} else if fwuri, ok := forwardedURIPath(r.Header.Get(ForwardedUriHeader)); ok {
+		// The outer prefix is what precedes the request path we've got in the
+		// original request path.
+		if outer, ok := strings.CutSuffix(fwuri, path.Clean("/"+r.URL.Path)); ok {
+			prefix = path.Join("/", outer, prefix)
+		}
+	}
+	r2.Header.Set(ForwardedPrefixHeader, escapedPath(path.Join("/", prefix)))
+	return r2
+}
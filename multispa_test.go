@@ -0,0 +1,99 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/PuerkitoBio/goquery"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("multiple SPAs", func() {
+
+	var multiFs fs.FS
+
+	BeforeEach(func() {
+		multiFs = Successful(fs.Sub(embStaticFs, "multi"))
+	})
+
+	newMultiHandler := func() *MultiSPAHandler {
+		return NewMultiSPAHandler(map[string]SPAConfig{
+			"admin":            {FS: Successful(fs.Sub(multiFs, "admin")), Index: "index.html"},
+			"/shop/":           {FS: Successful(fs.Sub(multiFs, "shop")), Index: "index.html"},
+			"shop/backoffice/": {FS: Successful(fs.Sub(multiFs, "admin")), Index: "index.html"},
+		}, WithSharedAssets(multiFs, "vendor"))
+	}
+
+	DescribeTable("serves per-app index with correct base",
+		func(path string, header http.Header, expectedCanary string, expectedBase string) {
+			r := &http.Request{
+				Method: "GET",
+				URL:    Successful(url.Parse("http://foo.bar:12345" + path)),
+				Header: header,
+			}
+			w := httptest.NewRecorder()
+			newMultiHandler().ServeHTTP(w, r)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(ContainSubstring(expectedCanary))
+			doc := Successful(goquery.NewDocumentFromReader(w.Body))
+			href, _ := doc.Find("base").First().Attr("href")
+			Expect(href).To(Equal(expectedBase))
+		},
+		Entry("admin root", "/admin/", nil, "CANARY ADMIN", "/admin/"),
+		Entry("admin without trailing slash", "/admin", nil, "CANARY ADMIN", "/admin/"),
+		Entry("admin route", "/admin/users/42", nil, "CANARY ADMIN", "/admin/"),
+		Entry("shop route", "/shop/cart", nil, "CANARY SHOP", "/shop/"),
+		Entry("shop route behind prefixing proxy", "/shop/cart", http.Header{
+			ForwardedPrefixHeader: []string{"/outer"},
+		}, "CANARY SHOP", "/outer/shop/"),
+		Entry("shop route behind URI-forwarding proxy", "/shop/cart", http.Header{
+			ForwardedUriHeader: []string{"/outer/shop/cart?foo=bar"},
+		}, "CANARY SHOP", "/outer/shop/"),
+		Entry("nested mount", "/shop/backoffice/users", nil, "CANARY ADMIN", "/shop/backoffice/"),
+		Entry("nested mount root", "/shop/backoffice", nil, "CANARY ADMIN", "/shop/backoffice/"),
+		Entry("nested mount behind prefixing proxy", "/shop/backoffice/users", http.Header{
+			ForwardedPrefixHeader: []string{"/outer"},
+		}, "CANARY ADMIN", "/outer/shop/backoffice/"),
+		Entry("outer mount with similar name", "/shop/backofficer", nil, "CANARY SHOP", "/shop/"),
+	)
+
+	DescribeTable("serves own and shared assets",
+		func(path string, expectedStatus int, expectedCanary string) {
+			r := &http.Request{
+				Method: "GET",
+				URL:    Successful(url.Parse("http://foo.bar:12345" + path)),
+			}
+			w := httptest.NewRecorder()
+			newMultiHandler().ServeHTTP(w, r)
+			Expect(w.Result().StatusCode).To(Equal(expectedStatus))
+			Expect(w.Body.String()).To(ContainSubstring(expectedCanary))
+		},
+		Entry("own asset", "/admin/admin.js", http.StatusOK, "CANARY ADMIN JS"),
+		Entry("shared asset via admin", "/admin/vendor/chunk.js", http.StatusOK, "CANARY VENDOR"),
+		Entry("shared asset via shop", "/shop/vendor/chunk.js", http.StatusOK, "CANARY VENDOR"),
+		Entry("missing shared asset", "/shop/vendor/missing.js", http.StatusNotFound, ""),
+		Entry("unknown app", "/foo/bar", http.StatusNotFound, ""),
+		Entry("root", "/", http.StatusNotFound, ""),
+		Entry("encoded traversal", "/shop/%252e%252e/etc/passwd", http.StatusBadRequest, ""),
+	)
+
+})
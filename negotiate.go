@@ -0,0 +1,91 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// acceptSpec is a single element of an Accept-style header, such as
+// “text/html;q=0.9”.
+type acceptSpec struct {
+	value string  // lower-cased value, such as "text/html".
+	q     float64 // quality value, 1.0 if not specified.
+}
+
+// parseAccept parses an Accept-style header value into its elements, sorted in
+// descending order of their quality values, keeping the original order of
+// equal quality values. Elements with a quality of zero are dropped.
+func parseAccept(header string) []acceptSpec {
+	var specs []acceptSpec
+	for _, element := range strings.Split(header, ",") {
+		value, params, _ := strings.Cut(element, ";")
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, val, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(name) != "q" {
+				continue
+			}
+			if qval, err := strconv.ParseFloat(strings.TrimSpace(val), 64); err == nil {
+				q = qval
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		specs = append(specs, acceptSpec{value: value, q: q})
+	}
+	sort.SliceStable(specs, func(i, j int) bool { return specs[i].q > specs[j].q })
+	return specs
+}
+
+// negotiateMediaType returns the offered media type best matching the specified
+// Accept header value, or the first offer if the header is empty. If none of
+// the offers is acceptable, an empty string is returned.
+func negotiateMediaType(accept string, offers ...string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	specs := parseAccept(accept)
+	if len(specs) == 0 {
+		return offers[0]
+	}
+	for _, spec := range specs {
+		for _, offer := range offers {
+			if mediaTypeMatches(spec.value, offer) {
+				return offer
+			}
+		}
+	}
+	return ""
+}
+
+// mediaTypeMatches returns true if the offered media type matches the
+// (potentially wildcarded) accepted media type.
+func mediaTypeMatches(accepted string, offer string) bool {
+	if accepted == "*/*" || accepted == offer {
+		return true
+	}
+	if strings.HasSuffix(accepted, "/*") {
+		return strings.HasPrefix(offer, accepted[:len(accepted)-1])
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("content negotiation", func() {
+
+	It("parses Accept headers", func() {
+		Expect(parseAccept("text/html;q=0.5, Application/JSON , */*;q=0.1, image/png;q=0, ,text/plain;foo=bar;q=abc")).
+			To(Equal([]acceptSpec{
+				{value: "application/json", q: 1},
+				{value: "text/plain", q: 1},
+				{value: "text/html", q: 0.5},
+				{value: "*/*", q: 0.1},
+			}))
+	})
+
+	DescribeTable("negotiates media types",
+		func(accept string, expected string) {
+			Expect(negotiateMediaType(accept, "text/plain", "text/html", "application/json")).
+				To(Equal(expected))
+		},
+		Entry("no Accept header", "", "text/plain"),
+		Entry("exact match", "application/json", "application/json"),
+		Entry("wildcard", "*/*", "text/plain"),
+		Entry("major type wildcard", "application/*", "application/json"),
+		Entry("preferred", "text/html;q=0.9, application/json", "application/json"),
+		Entry("unacceptable", "image/png", ""),
+	)
+
+	It("returns nothing without offers", func() {
+		Expect(negotiateMediaType("*/*")).To(BeEmpty())
+	})
+
+})
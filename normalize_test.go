@@ -20,7 +20,7 @@ import (
 	"io/fs"
 	"net/http"
 
-	"github.com/thediveo/spaserve/test/httptest"
+	"github.com/thediveo/spaserve/spaservetest"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -30,7 +30,7 @@ var _ = Describe("normalize errors into HTTP status codes", func() {
 
 	DescribeTable("normalize errors",
 		func(err error, expected int) {
-			w := httptest.NewRecorder()
+			w := spaservetest.NewRecorder()
 			NormalizedHttpError(w, err)
 			Expect(w.Result().StatusCode).To(Equal(expected))
 		},
@@ -38,8 +38,60 @@ var _ = Describe("normalize errors into HTTP status codes", func() {
 			http.StatusNotFound),
 		Entry("something's out of reach", fmt.Errorf("finger wech! %w", fs.ErrPermission),
 			http.StatusForbidden),
+		Entry("who are you?", fmt.Errorf("no session, %w", ErrUnauthorized),
+			http.StatusUnauthorized),
+		Entry("nobody's listening", fmt.Errorf("no sockets, %w", ErrUpgradeRequired),
+			http.StatusUpgradeRequired),
 		Entry("else it's a server error", errors.New("foobar"),
 			http.StatusInternalServerError),
 	)
 
+	DescribeTable("negotiates error representations",
+		func(accept string, expectedContentType string, expectedBody string) {
+			w := spaservetest.NewRecorder()
+			r := &http.Request{Header: http.Header{}}
+			if accept != "" {
+				r.Header.Set("Accept", accept)
+			}
+			NegotiatedHttpError(w, r, fmt.Errorf("foobar mistake %w", fs.ErrNotExist))
+			Expect(w.Result().StatusCode).To(Equal(http.StatusNotFound))
+			Expect(w.Result().Header.Get("Content-Type")).To(Equal(expectedContentType))
+			Expect(w.Body.String()).To(ContainSubstring(expectedBody))
+			Expect(w.Body.String()).NotTo(ContainSubstring("foobar"))
+		},
+		Entry("without Accept header", "",
+			"text/plain; charset=utf-8", "404 page not found"),
+		Entry("browser navigation", "text/html,application/xhtml+xml,*/*;q=0.8",
+			"text/html; charset=utf-8", "<h1>404 page not found</h1>"),
+		Entry("fetch() preferring JSON", "application/json, text/plain;q=0.5",
+			"application/problem+json", `"status":404`),
+		Entry("problem details", "application/problem+json",
+			"application/problem+json", `"title":"Not Found"`),
+	)
+
+	It("uses registered error matchers", func() {
+		errBundleUnavailable := errors.New("bundle unavailable")
+		unregister := RegisterErrorStatus(errBundleUnavailable, http.StatusServiceUnavailable,
+			http.Header{"retry-after": []string{"30"}})
+		defer unregister()
+		unregisterTeapot := RegisterErrorMatcher(func(err error) (int, http.Header, bool) {
+			return http.StatusTeapot, nil, errors.Is(err, fs.ErrPermission)
+		})
+
+		w := spaservetest.NewRecorder()
+		NormalizedHttpError(w, fmt.Errorf("nope: %w", errBundleUnavailable))
+		Expect(w.Result().StatusCode).To(Equal(http.StatusServiceUnavailable))
+		Expect(w.Result().Header.Get("Retry-After")).To(Equal("30"))
+		Expect(w.Body.String()).To(Equal("503 Service Unavailable\n"))
+
+		w = spaservetest.NewRecorder()
+		NormalizedHttpError(w, fs.ErrPermission)
+		Expect(w.Result().StatusCode).To(Equal(http.StatusTeapot))
+
+		unregisterTeapot()
+		w = spaservetest.NewRecorder()
+		NormalizedHttpError(w, fs.ErrPermission)
+		Expect(w.Result().StatusCode).To(Equal(http.StatusForbidden))
+	})
+
 })
@@ -15,22 +15,190 @@
 package spaserve
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"html"
 	"io/fs"
 	"net/http"
+	"strconv"
+	"sync"
 )
 
+// Media types of the different error response representations.
+const (
+	problemJSONMediaType = "application/problem+json"
+	jsonMediaType        = "application/json"
+	htmlMediaType        = "text/html"
+	plainMediaType       = "text/plain"
+)
+
+// ErrorResponder writes an error response for the specified request, based on
+// the specified error. ErrorResponders must never leak internal server details
+// from the error to clients. NegotiatedHttpError is an ErrorResponder, and
+// NormalizedHttpError can be easily adapted to become an ErrorResponder.
+type ErrorResponder func(w http.ResponseWriter, r *http.Request, err error)
+
 // NormalizedHttpError writes a normalized HTTP error message and HTTP status
 // code based on the specified error, but not leaking any interesting internal
 // server details from this specified error.
+//
+// Additional mappings of errors to HTTP status codes can be registered using
+// RegisterErrorMatcher and RegisterErrorStatus.
 func NormalizedHttpError(w http.ResponseWriter, err error) {
+	status, msg, header := normalizedError(err)
+	setHeader(w, header)
+	http.Error(w, msg, status)
+}
+
+// ErrorMatcher returns the HTTP status code and optional additional response
+// headers for the specified error, and true if it matches the error. Otherwise,
+// it returns false.
+type ErrorMatcher func(err error) (status int, header http.Header, ok bool)
+
+// errorMatchers is the registry of additional error matchers, in the order of
+// their registration.
+var (
+	errorMatchersMu sync.RWMutex
+	errorMatchers   []*ErrorMatcher
+)
+
+// RegisterErrorMatcher registers an additional ErrorMatcher used when
+// normalizing errors into HTTP status codes, such as by NormalizedHttpError,
+// NegotiatedHttpError, and SPAHandler. Matchers registered later take
+// precedence over matchers registered earlier, and all registered matchers take
+// precedence over the built-in mappings of fs.ErrNotExist (404),
+// ErrInvalidPath (400), ErrUnauthorized (401), fs.ErrPermission (403),
+// ErrMethodNotAllowed (405), ErrUpgradeRequired (426), and everything else
+// (500).
+//
+// RegisterErrorMatcher returns a function to unregister the matcher again.
+func RegisterErrorMatcher(matcher ErrorMatcher) (unregister func()) {
+	m := &matcher
+	errorMatchersMu.Lock()
+	errorMatchers = append(errorMatchers, m)
+	errorMatchersMu.Unlock()
+	return func() {
+		errorMatchersMu.Lock()
+		defer errorMatchersMu.Unlock()
+		for idx, matcher := range errorMatchers {
+			if matcher == m {
+				errorMatchers = append(errorMatchers[:idx:idx], errorMatchers[idx+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// RegisterErrorStatus registers an additional mapping of errors matching the
+// specified target error (as determined by errors.Is) to the specified HTTP
+// status code and optional additional response headers. For instance:
+//
+//	RegisterErrorStatus(ErrBundleUnavailable, http.StatusServiceUnavailable,
+//	    http.Header{"Retry-After": []string{"30"}})
+//
+// RegisterErrorStatus returns a function to unregister the mapping again.
+func RegisterErrorStatus(target error, status int, header http.Header) (unregister func()) {
+	return RegisterErrorMatcher(func(err error) (int, http.Header, bool) {
+		if errors.Is(err, target) {
+			return status, header, true
+		}
+		return 0, nil, false
+	})
+}
+
+// normalizedError returns the HTTP status code, the normalized error message,
+// and optional additional response headers for the specified error.
+func normalizedError(err error) (status int, msg string, header http.Header) {
+	errorMatchersMu.RLock()
+	defer errorMatchersMu.RUnlock()
+	for idx := len(errorMatchers) - 1; idx >= 0; idx-- {
+		if status, header, ok := (*errorMatchers[idx])(err); ok {
+			return status, fmt.Sprintf("%d %s", status, http.StatusText(status)), header
+		}
+	}
 	if errors.Is(err, fs.ErrNotExist) {
-		http.Error(w, "404 page not found", http.StatusNotFound)
-		return
+		return http.StatusNotFound, "404 page not found", nil
+	}
+	if errors.Is(err, ErrInvalidPath) {
+		return http.StatusBadRequest, "400 Bad Request", nil
+	}
+	if errors.Is(err, ErrUnauthorized) {
+		return http.StatusUnauthorized, "401 Unauthorized", nil
 	}
 	if errors.Is(err, fs.ErrPermission) {
-		http.Error(w, "403 Forbidden", http.StatusForbidden)
-		return
+		return http.StatusForbidden, "403 Forbidden", nil
+	}
+	if errors.Is(err, ErrMethodNotAllowed) {
+		return http.StatusMethodNotAllowed, "405 Method Not Allowed", nil
+	}
+	if errors.Is(err, ErrUpgradeRequired) {
+		return http.StatusUpgradeRequired, "426 Upgrade Required", nil
+	}
+	return http.StatusInternalServerError, "500 Internal Server Error", nil
+}
+
+// setHeader sets the specified additional response headers, if any.
+func setHeader(w http.ResponseWriter, header http.Header) {
+	for name, values := range header {
+		w.Header()[http.CanonicalHeaderKey(name)] = append([]string(nil), values...)
+	}
+}
+
+// NegotiatedHttpError writes a normalized HTTP error response and HTTP status
+// code based on the specified error, similar to NormalizedHttpError. However,
+// the response representation depends on the request's Accept header: clients
+// preferring JSON get an “application/problem+json” response (RFC 9457),
+// clients preferring HTML get a minimal HTML document, and all other clients
+// get plain text.
+func NegotiatedHttpError(w http.ResponseWriter, r *http.Request, err error) {
+	status, msg, header := normalizedError(err)
+	setHeader(w, header)
+	writeNegotiatedError(w, r, status, msg)
+}
+
+// writeNegotiatedError writes the specified HTTP status code and error message
+// in the representation best matching the request's Accept header.
+func writeNegotiatedError(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	var body []byte
+	header := w.Header()
+	header.Del("Content-Length")
+	header.Set("X-Content-Type-Options", "nosniff")
+	switch negotiateMediaType(r.Header.Get("Accept"),
+		plainMediaType, htmlMediaType, problemJSONMediaType, jsonMediaType) {
+	case problemJSONMediaType, jsonMediaType:
+		header.Set("Content-Type", problemJSONMediaType)
+		body, _ = json.Marshal(struct {
+			Type   string `json:"type"`
+			Title  string `json:"title"`
+			Status int    `json:"status"`
+			Detail string `json:"detail,omitempty"`
+		}{
+			Type:   "about:blank",
+			Title:  http.StatusText(status),
+			Status: status,
+			Detail: msg,
+		})
+	case htmlMediaType:
+		header.Set("Content-Type", "text/html; charset=utf-8")
+		body = []byte(fmt.Sprintf("<!doctype html>\n<title>%[1]s</title>\n<h1>%[1]s</h1>\n",
+			html.EscapeString(msg)))
+	default:
+		header.Set("Content-Type", "text/plain; charset=utf-8")
+		body = []byte(msg + "\n")
+	}
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// prefersJSON returns true if the request prefers a JSON representation over
+// HTML and plain text representations.
+func prefersJSON(r *http.Request) bool {
+	switch negotiateMediaType(r.Header.Get("Accept"),
+		plainMediaType, htmlMediaType, problemJSONMediaType, jsonMediaType) {
+	case problemJSONMediaType, jsonMediaType:
+		return true
 	}
-	http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+	return false
 }
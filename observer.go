@@ -0,0 +1,49 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"time"
+)
+
+// ServeInfo describes how an SPAHandler served a particular request.
+type ServeInfo struct {
+	Outcome  Outcome       // how the request was served.
+	Base     string        // resolved base path of the SPA.
+	Status   int           // response status code.
+	Bytes    int64         // number of response body bytes written.
+	Duration time.Duration // time taken to serve the request.
+}
+
+// Observer gets notified about the requests served by an SPAHandler, such as
+// for gathering metrics. Observers must be safe for concurrent use.
+type Observer interface {
+	// ObserveRequest gets called after the specified request has been served.
+	ObserveRequest(r *http.Request, info ServeInfo)
+	// ObserveIndexRewrite gets called after the index for the specified
+	// request has been rewritten, passing the time taken to rewrite it.
+	ObserveIndexRewrite(r *http.Request, duration time.Duration)
+}
+
+// WithObserver adds an Observer to be notified about the requests served.
+func WithObserver(observer Observer) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		if observer == nil {
+			return
+		}
+		h.observers = append(h.observers, observer)
+	}
+}
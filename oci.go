@@ -0,0 +1,466 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OCI manifest media types accepted when pulling SPA bundles.
+const (
+	ociManifestMediaType        = "application/vnd.oci.image.manifest.v1+json"
+	ociIndexMediaType           = "application/vnd.oci.image.index.v1+json"
+	dockerManifestMediaType     = "application/vnd.docker.distribution.manifest.v2+json"
+	dockerManifestListMediaType = "application/vnd.docker.distribution.manifest.list.v2+json"
+)
+
+// maxOCIManifestSize limits the size of manifests fetched from a registry.
+const maxOCIManifestSize = 4 << 20
+
+// OCISource pulls an SPA bundle published as an OCI artifact or image layer
+// from an OCI distribution registry, exposing the bundle as an fs.FS. The
+// bundle layer is a zip, tar, or gzip'ed tar archive, as supported by
+// [ArchiveFS]; its digest is verified when pulling.
+//
+// References are of the form "registry/repository:tag" or
+// "registry/repository@sha256:...", where references without a registry host
+// default to Docker Hub. References pinned to a digest always pull exactly the
+// specified manifest and thus never change.
+type OCISource struct {
+	registry   string
+	repository string
+	reference  string // tag or digest.
+	pinned     bool   // reference is a digest.
+	scheme     string
+	client     *http.Client
+	username   string
+	password   string
+	layerType  string
+	logger     *slog.Logger
+
+	mu    sync.Mutex
+	token string // bearer token obtained from the registry's auth service.
+}
+
+// OCIOption configures an OCISource.
+type OCIOption func(*OCISource)
+
+// WithOCIHTTPClient sets the HTTP client for talking to the registry, instead
+// of http.DefaultClient.
+func WithOCIHTTPClient(client *http.Client) OCIOption {
+	return func(s *OCISource) {
+		s.client = client
+	}
+}
+
+// WithOCIBasicAuth sets the credentials for authenticating with the registry,
+// either directly or with the registry's token service.
+func WithOCIBasicAuth(username, password string) OCIOption {
+	return func(s *OCISource) {
+		s.username = username
+		s.password = password
+	}
+}
+
+// WithOCIPlainHTTP talks to the registry using plain HTTP instead of HTTPS,
+// such as for local development registries.
+func WithOCIPlainHTTP() OCIOption {
+	return func(s *OCISource) {
+		s.scheme = "http"
+	}
+}
+
+// WithOCILayerMediaType selects the first layer with the specified media type
+// as the SPA bundle. Otherwise, the last layer of the manifest is used, which
+// is the only layer of artifacts and the topmost layer of images built FROM
+// scratch.
+func WithOCILayerMediaType(mediaType string) OCIOption {
+	return func(s *OCISource) {
+		s.layerType = mediaType
+	}
+}
+
+// WithOCILogger sets the logger for errors encountered while periodically
+// re-pulling the SPA bundle in the background.
+func WithOCILogger(logger *slog.Logger) OCIOption {
+	return func(s *OCISource) {
+		s.logger = logger
+	}
+}
+
+// NewOCISource returns a new OCISource for the specified reference, or an
+// error if the reference is invalid.
+func NewOCISource(ref string, opts ...OCIOption) (*OCISource, error) {
+	registry, repository, reference, pinned, err := parseOCIReference(ref)
+	if err != nil {
+		return nil, err
+	}
+	s := &OCISource{
+		registry:   registry,
+		repository: repository,
+		reference:  reference,
+		pinned:     pinned,
+		scheme:     "https",
+		client:     http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// parseOCIReference splits the specified reference into its registry,
+// repository, and tag or digest parts.
+func parseOCIReference(ref string) (registry, repository, reference string, pinned bool, err error) {
+	name := ref
+	if at := strings.LastIndex(name, "@"); at >= 0 {
+		name, reference, pinned = name[:at], name[at+1:], true
+		if !strings.HasPrefix(reference, "sha256:") || len(reference) != len("sha256:")+64 {
+			return "", "", "", false, fmt.Errorf("invalid OCI reference %q, unsupported digest", ref)
+		}
+	} else if colon := strings.LastIndex(name, ":"); colon > strings.LastIndex(name, "/") {
+		name, reference = name[:colon], name[colon+1:]
+	} else {
+		reference = "latest"
+	}
+	registry, repository, found := strings.Cut(name, "/")
+	if !found || (!strings.ContainsAny(registry, ".:") && registry != "localhost") {
+		registry, repository = "registry-1.docker.io", name
+		if !strings.Contains(repository, "/") {
+			repository = "library/" + repository
+		}
+	}
+	if repository == "" || reference == "" || strings.ContainsAny(repository, ":@") {
+		return "", "", "", false, fmt.Errorf("invalid OCI reference %q", ref)
+	}
+	return registry, repository, reference, pinned, nil
+}
+
+// ociDescriptor describes content referenced from a manifest.
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+// ociManifest is an image manifest or image index.
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Layers    []ociDescriptor `json:"layers"`
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+// Pull pulls the SPA bundle, returning it as an fs.FS together with the digest
+// of the manifest pulled.
+func (s *OCISource) Pull(ctx context.Context) (fs.FS, string, error) {
+	manifest, digest, err := s.manifest(ctx, s.reference)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(manifest.Manifests) > 0 {
+		// SPA bundles are platform-independent, so any manifest of an image
+		// index will do.
+		if manifest, _, err = s.manifest(ctx, manifest.Manifests[0].Digest); err != nil {
+			return nil, "", err
+		}
+	}
+	layer, err := s.bundleLayer(manifest)
+	if err != nil {
+		return nil, "", err
+	}
+	blob, err := s.fetch(ctx, "blobs/"+layer.Digest, "", layer.Size)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := verifyDigest(blob, layer.Digest); err != nil {
+		return nil, "", err
+	}
+	fsys, err := ArchiveFS(bytes.NewReader(blob), int64(len(blob)))
+	if err != nil {
+		return nil, "", err
+	}
+	return fsys, digest, nil
+}
+
+// Watch periodically checks the registry in the specified interval for a
+// changed manifest, pulling the new SPA bundle and swapping it into the
+// specified SPAHandler, until the context gets cancelled. The digest is the
+// digest of the manifest currently served, as returned by Pull. Errors are
+// logged, if a logger has been set, and the currently served SPA bundle is
+// kept. Watching a reference pinned to a digest returns immediately.
+func (s *OCISource) Watch(ctx context.Context, h *SPAHandler, digest string, interval time.Duration) {
+	if s.pinned {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		_, current, err := s.manifest(ctx, s.reference)
+		if err != nil {
+			s.logError(ctx, err)
+			continue
+		}
+		if current == digest {
+			continue
+		}
+		fsys, pulled, err := s.Pull(ctx)
+		if err != nil {
+			s.logError(ctx, err)
+			continue
+		}
+		h.SwapFSContext(ctx, fsys)
+		digest = pulled
+	}
+}
+
+// logError logs the specified error encountered while watching, if a logger
+// has been set.
+func (s *OCISource) logError(ctx context.Context, err error) {
+	if s.logger == nil || ctx.Err() != nil {
+		return
+	}
+	s.logger.LogAttrs(ctx, slog.LevelError, "re-pulling SPA bundle failed",
+		slog.String("registry", s.registry),
+		slog.String("repository", s.repository),
+		slog.String("reference", s.reference),
+		slog.String("error", err.Error()))
+}
+
+// NewSPAHandlerFromOCI returns a new SPA handler serving the SPA bundle pulled
+// from the specified OCISource. If interval is positive, the registry is
+// checked for a changed manifest in this interval until the context gets
+// cancelled, swapping in changed SPA bundles.
+func NewSPAHandlerFromOCI(ctx context.Context, src *OCISource, interval time.Duration, index string, opts ...SPAHandlerOption) (*SPAHandler, error) {
+	fsys, digest, err := src.Pull(ctx)
+	if err != nil {
+		return nil, err
+	}
+	h := NewSPAHandler(fsys, index, opts...)
+	if interval > 0 {
+		go src.Watch(ctx, h, digest, interval)
+	}
+	return h, nil
+}
+
+// bundleLayer returns the descriptor of the layer containing the SPA bundle.
+func (s *OCISource) bundleLayer(manifest *ociManifest) (ociDescriptor, error) {
+	if len(manifest.Layers) == 0 {
+		return ociDescriptor{}, errors.New("OCI manifest without layers")
+	}
+	if s.layerType == "" {
+		return manifest.Layers[len(manifest.Layers)-1], nil
+	}
+	for _, layer := range manifest.Layers {
+		if layer.MediaType == s.layerType {
+			return layer, nil
+		}
+	}
+	return ociDescriptor{}, fmt.Errorf("OCI manifest without layer of media type %q", s.layerType)
+}
+
+// manifest fetches the manifest with the specified tag or digest, returning
+// the decoded manifest together with its digest.
+func (s *OCISource) manifest(ctx context.Context, reference string) (*ociManifest, string, error) {
+	body, err := s.fetch(ctx, "manifests/"+reference, strings.Join([]string{
+		ociManifestMediaType, ociIndexMediaType,
+		dockerManifestMediaType, dockerManifestListMediaType,
+	}, ", "), maxOCIManifestSize)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(body)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	if strings.HasPrefix(reference, "sha256:") && digest != reference {
+		return nil, "", fmt.Errorf("OCI manifest digest mismatch, expected %s, got %s", reference, digest)
+	}
+	var manifest ociManifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, "", fmt.Errorf("invalid OCI manifest, %w", err)
+	}
+	return &manifest, digest, nil
+}
+
+// verifyDigest returns an error if the specified content doesn't match the
+// specified digest.
+func verifyDigest(content []byte, digest string) error {
+	if !strings.HasPrefix(digest, "sha256:") {
+		return fmt.Errorf("unsupported OCI digest %q", digest)
+	}
+	sum := sha256.Sum256(content)
+	if actual := "sha256:" + hex.EncodeToString(sum[:]); actual != digest {
+		return fmt.Errorf("OCI blob digest mismatch, expected %s, got %s", digest, actual)
+	}
+	return nil
+}
+
+// fetch returns the contents of the specified manifest or blob of the
+// repository, limited to the specified maximum size. If the registry requests
+// authentication, fetch authenticates and then retries once.
+func (s *OCISource) fetch(ctx context.Context, resource string, accept string, maxSize int64) ([]byte, error) {
+	u := (&url.URL{
+		Scheme: s.scheme,
+		Host:   s.registry,
+		Path:   "/v2/" + s.repository + "/" + resource,
+	}).String()
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		s.authorize(req)
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			challenge := resp.Header.Get("WWW-Authenticate")
+			resp.Body.Close()
+			if err := s.authenticate(ctx, challenge); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("cannot fetch %s, status %d", u, resp.StatusCode)
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(body)) > maxSize {
+			return nil, fmt.Errorf("cannot fetch %s, too large", u)
+		}
+		return body, nil
+	}
+}
+
+// authorize adds the bearer token or basic auth credentials to the specified
+// registry request, if any.
+func (s *OCISource) authorize(req *http.Request) {
+	s.mu.Lock()
+	token := s.token
+	s.mu.Unlock()
+	switch {
+	case token != "":
+		req.Header.Set("Authorization", "Bearer "+token)
+	case s.username != "":
+		req.SetBasicAuth(s.username, s.password)
+	}
+}
+
+// authenticate answers the specified authentication challenge from the
+// registry. Bearer challenges are answered by fetching a token from the
+// indicated token service, passing the basic auth credentials, if any. Basic
+// challenges can only be answered with basic auth credentials.
+func (s *OCISource) authenticate(ctx context.Context, challenge string) error {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if s.username == "" {
+			return errors.New("OCI registry requires credentials")
+		}
+		return nil
+	case "bearer":
+	default:
+		return fmt.Errorf("unsupported OCI registry authentication challenge %q", challenge)
+	}
+	attrs := parseChallengeParams(params)
+	realm, err := url.Parse(attrs["realm"])
+	if err != nil || realm.Host == "" {
+		return fmt.Errorf("invalid OCI registry token realm %q", attrs["realm"])
+	}
+	query := realm.Query()
+	if service := attrs["service"]; service != "" {
+		query.Set("service", service)
+	}
+	scope := attrs["scope"]
+	if scope == "" {
+		scope = "repository:" + s.repository + ":pull"
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("cannot obtain OCI registry token, status %d", resp.StatusCode)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxOCIManifestSize)).Decode(&token); err != nil {
+		return fmt.Errorf("invalid OCI registry token, %w", err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	if token.Token == "" {
+		return errors.New("OCI registry token service returned no token")
+	}
+	s.mu.Lock()
+	s.token = token.Token
+	s.mu.Unlock()
+	return nil
+}
+
+// parseChallengeParams parses the comma-separated key="value" parameters of a
+// WWW-Authenticate challenge.
+func parseChallengeParams(params string) map[string]string {
+	attrs := map[string]string{}
+	for params != "" {
+		var key, value string
+		key, params, _ = strings.Cut(strings.TrimLeft(params, " ,"), "=")
+		if strings.HasPrefix(params, `"`) {
+			value, params, _ = strings.Cut(params[1:], `"`)
+		} else {
+			value, params, _ = strings.Cut(params, ",")
+		}
+		attrs[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	return attrs
+}
@@ -0,0 +1,188 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+func sha256Digest(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// fakeRegistry is a minimal OCI distribution registry serving a single
+// repository "spa/app", requiring a bearer token.
+type fakeRegistry struct {
+	mu        sync.Mutex
+	manifests map[string][]byte // by tag and digest.
+	blobs     map[string][]byte // by digest.
+}
+
+func (f *fakeRegistry) push(tag string, bundle []byte) string {
+	GinkgoHelper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	layer := sha256Digest(bundle)
+	f.blobs[layer] = bundle
+	manifest := Successful(json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     ociManifestMediaType,
+		"layers": []map[string]any{
+			{"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "digest": layer, "size": len(bundle)},
+		},
+	}))
+	digest := sha256Digest(manifest)
+	f.manifests[tag] = manifest
+	f.manifests[digest] = manifest
+	return digest
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/token" {
+		if r.URL.Query().Get("scope") != "repository:spa/app:pull" {
+			http.Error(w, "wrong scope", http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": "s3cr3t"})
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer s3cr3t" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="http://`+r.Host+`/token",service="fake"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var content []byte
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/v2/spa/app/manifests/"):
+		content = f.manifests[strings.TrimPrefix(r.URL.Path, "/v2/spa/app/manifests/")]
+	case strings.HasPrefix(r.URL.Path, "/v2/spa/app/blobs/"):
+		content = f.blobs[strings.TrimPrefix(r.URL.Path, "/v2/spa/app/blobs/")]
+	}
+	if content == nil {
+		http.NotFound(w, r)
+		return
+	}
+	_, _ = w.Write(content)
+}
+
+var _ = Describe("OCI bundles", func() {
+
+	var registry *fakeRegistry
+	var srv *httptest.Server
+	var host string
+
+	BeforeEach(func() {
+		registry = &fakeRegistry{manifests: map[string][]byte{}, blobs: map[string][]byte{}}
+		srv = httptest.NewServer(registry)
+		DeferCleanup(srv.Close)
+		host = strings.TrimPrefix(srv.URL, "http://")
+	})
+
+	DescribeTable("parsing references",
+		func(ref, registry, repository, reference string, pinned bool) {
+			reg, repo, r, p, err := parseOCIReference(ref)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reg).To(Equal(registry))
+			Expect(repo).To(Equal(repository))
+			Expect(r).To(Equal(reference))
+			Expect(p).To(Equal(pinned))
+		},
+		Entry(nil, "nginx", "registry-1.docker.io", "library/nginx", "latest", false),
+		Entry(nil, "org/app:1.2", "registry-1.docker.io", "org/app", "1.2", false),
+		Entry(nil, "localhost:5000/app", "localhost:5000", "app", "latest", false),
+		Entry(nil, "ghcr.io/org/spa/app:v1", "ghcr.io", "org/spa/app", "v1", false),
+		Entry(nil, "ghcr.io/org/app@sha256:"+strings.Repeat("0", 64),
+			"ghcr.io", "org/app", "sha256:"+strings.Repeat("0", 64), true),
+	)
+
+	It("rejects invalid references", func() {
+		Expect(NewOCISource("ghcr.io/org/app@md5:1234")).Error().To(HaveOccurred())
+		Expect(NewOCISource("ghcr.io/")).Error().To(HaveOccurred())
+	})
+
+	It("parses challenge parameters", func() {
+		Expect(parseChallengeParams(`realm="https://auth.io/token",service="registry.io", scope=repository:a:pull`)).
+			To(Equal(map[string]string{
+				"realm":   "https://auth.io/token",
+				"service": "registry.io",
+				"scope":   "repository:a:pull",
+			}))
+	})
+
+	It("pulls and serves a bundle, pinned by digest", func() {
+		digest := registry.push("v1", tarArchive(true))
+		for _, ref := range []string{host + "/spa/app:v1", host + "/spa/app@" + digest} {
+			src := Successful(NewOCISource(ref, WithOCIPlainHTTP()))
+			h := Successful(NewSPAHandlerFromOCI(context.Background(), src, 0, "index.html"))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/assets/app.js", nil))
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(Equal(archiveFiles["assets/app.js"]))
+		}
+	})
+
+	It("rejects corrupted blobs and missing layers", func() {
+		registry.push("v1", tarArchive(true))
+		registry.mu.Lock()
+		for digest := range registry.blobs {
+			registry.blobs[digest] = []byte("tampered")
+		}
+		registry.mu.Unlock()
+		src := Successful(NewOCISource(host+"/spa/app:v1", WithOCIPlainHTTP()))
+		Expect(src.Pull(context.Background())).Error().To(MatchError(ContainSubstring("digest mismatch")))
+
+		src = Successful(NewOCISource(host+"/spa/app:v1", WithOCIPlainHTTP(),
+			WithOCILayerMediaType("application/zip")))
+		Expect(src.Pull(context.Background())).Error().To(MatchError(ContainSubstring("without layer")))
+
+		src = Successful(NewOCISource(host+"/spa/app:v2", WithOCIPlainHTTP()))
+		Expect(src.Pull(context.Background())).Error().To(MatchError(ContainSubstring("status 404")))
+	})
+
+	It("re-pulls changed bundles", func() {
+		registry.push("latest", tarArchive(true))
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		src := Successful(NewOCISource(host+"/spa/app", WithOCIPlainHTTP()))
+		h := Successful(NewSPAHandlerFromOCI(ctx, src, 10*time.Millisecond, "index.html"))
+
+		fsys := func() uintptr { return reflect.ValueOf(h.FS()).Pointer() }
+		initial := fsys()
+		Consistently(fsys, "50ms", "10ms").Should(Equal(initial))
+
+		registry.push("latest", zipArchive())
+		Eventually(fsys).ShouldNot(Equal(initial))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/assets/app.js", nil))
+		Expect(rec.Body.String()).To(Equal(archiveFiles["assets/app.js"]))
+	})
+
+})
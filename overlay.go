@@ -0,0 +1,106 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"errors"
+	"io/fs"
+	"sort"
+)
+
+// WithOverlayFS layers the specified upper fs.FS on top of the fs.FS the SPA
+// is served from, so that files in the upper fs.FS take precedence. This
+// allows operators to drop in replacement files, such as a config.json or a
+// logo, without rebuilding the binary embedding the SPA bundle:
+//
+//	h := NewSPAHandler(bundle, "index.html",
+//	    WithOverlayFS(os.DirFS("/etc/myspa/overrides")))
+//
+// The overlay also applies to canary bundles and to bundles swapped in later
+// using SwapFS, but not to SPAs served per virtual host.
+func WithOverlayFS(upper fs.FS) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.overlay = upper
+	}
+}
+
+// applyOverlay layers the overlay fs.FS, if any, on top of the primary and
+// canary bundles.
+func (h *SPAHandler) applyOverlay() {
+	if h.overlay == nil {
+		return
+	}
+	h.bundle.Store(newBundle(h.overlaid(h.current().fs)))
+	if h.canary != nil {
+		h.canary.bundle = newBundle(h.overlaid(h.canary.bundle.fs))
+	}
+}
+
+// overlaid returns the specified fs.FS with the overlay fs.FS on top, if any.
+func (h *SPAHandler) overlaid(fsys fs.FS) fs.FS {
+	if h.overlay == nil || fsys == nil {
+		return fsys
+	}
+	return &overlayFS{upper: h.overlay, lower: fsys}
+}
+
+// overlayFS is a read-only union of two fs.FSes, where files in the upper
+// fs.FS shadow files with the same name in the lower fs.FS. Directory listings
+// are merged.
+type overlayFS struct {
+	upper fs.FS
+	lower fs.FS
+}
+
+var (
+	_ fs.FS        = (*overlayFS)(nil)
+	_ fs.ReadDirFS = (*overlayFS)(nil)
+)
+
+// Open opens the named file from the upper fs.FS, falling back to the lower
+// fs.FS only if the file doesn't exist in the upper fs.FS.
+func (o *overlayFS) Open(name string) (fs.File, error) {
+	f, err := o.upper.Open(name)
+	if err == nil || !errors.Is(err, fs.ErrNotExist) {
+		return f, err
+	}
+	return o.lower.Open(name)
+}
+
+// ReadDir returns the merged entries of the named directory in both fs.FSes,
+// sorted by name, with entries from the upper fs.FS taking precedence.
+func (o *overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	upperEntries, upperErr := fs.ReadDir(o.upper, name)
+	if upperErr != nil && !errors.Is(upperErr, fs.ErrNotExist) {
+		return nil, upperErr
+	}
+	lowerEntries, lowerErr := fs.ReadDir(o.lower, name)
+	if lowerErr != nil && (upperErr != nil || !errors.Is(lowerErr, fs.ErrNotExist)) {
+		return nil, lowerErr
+	}
+	entries := map[string]fs.DirEntry{}
+	for _, entry := range lowerEntries {
+		entries[entry.Name()] = entry
+	}
+	for _, entry := range upperEntries {
+		entries[entry.Name()] = entry
+	}
+	merged := make([]fs.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		merged = append(merged, entry)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Name() < merged[j].Name() })
+	return merged, nil
+}
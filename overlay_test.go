@@ -0,0 +1,100 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("overlay FS", func() {
+
+	basefs := fstest.MapFS{
+		"index.html":        &fstest.MapFile{Data: []byte(`<base href="./" />BASE`)},
+		"config.json":       &fstest.MapFile{Data: []byte(`{"base":true}`)},
+		"assets/logo.svg":   &fstest.MapFile{Data: []byte(`<svg>base</svg>`)},
+		"assets/script.js":  &fstest.MapFile{Data: []byte(`base();`)},
+		"assets/styles.css": &fstest.MapFile{Data: []byte(`base{}`)},
+	}
+	upperfs := fstest.MapFS{
+		"config.json":     &fstest.MapFile{Data: []byte(`{"upper":true}`)},
+		"assets/logo.svg": &fstest.MapFile{Data: []byte(`<svg>upper</svg>`)},
+		"assets/extra.js": &fstest.MapFile{Data: []byte(`extra();`)},
+	}
+
+	get := func(h http.Handler, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	DescribeTable("serves from the overlay first",
+		func(path string, expectedBody string) {
+			h := NewSPAHandler(basefs, "index.html", WithOverlayFS(upperfs))
+			w := get(h, path)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(Equal(expectedBody))
+		},
+		Entry("overridden file", "/config.json", `{"upper":true}`),
+		Entry("overridden file in directory", "/assets/logo.svg", `<svg>upper</svg>`),
+		Entry("added file", "/assets/extra.js", `extra();`),
+		Entry("base file", "/assets/script.js", `base();`),
+		Entry("index from base", "/foo", `<base href="/" />BASE`),
+	)
+
+	It("overlays index files", func() {
+		h := NewSPAHandler(basefs, "index.html", WithOverlayFS(fstest.MapFS{
+			"index.html": &fstest.MapFile{Data: []byte(`<base href="./" />UPPER`)},
+		}))
+		Expect(get(h, "/foo").Body.String()).To(Equal(`<base href="/" />UPPER`))
+	})
+
+	It("overlays swapped and canary bundles", func() {
+		canaryfs := fstest.MapFS{
+			"index.html": &fstest.MapFile{Data: []byte(`<base href="./" />CANARY`)},
+		}
+		h := NewSPAHandler(nil, "index.html",
+			WithOverlayFS(upperfs),
+			WithCanary(basefs, canaryfs, 100, "spa-canary"))
+		Expect(get(h, "/config.json").Body.String()).To(Equal(`{"upper":true}`))
+		Expect(h.canary.bundle.fs).To(BeAssignableToTypeOf(&overlayFS{}))
+
+		h.SwapFS(fstest.MapFS{
+			"config.json": &fstest.MapFile{Data: []byte(`{"swapped":true}`)},
+		})
+		Expect(Successful(fs.ReadFile(h.FS(), "config.json"))).To(Equal([]byte(`{"upper":true}`)))
+	})
+
+	It("merges directory listings", func() {
+		ofs := &overlayFS{upper: upperfs, lower: basefs}
+		entries := Successful(fs.ReadDir(ofs, "assets"))
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		Expect(names).To(Equal([]string{"extra.js", "logo.svg", "script.js", "styles.css"}))
+
+		Expect(fs.ReadDir(ofs, "nada")).Error().To(MatchError(fs.ErrNotExist))
+		Expect(Successful(fs.ReadDir(&overlayFS{upper: fstest.MapFS{}, lower: basefs}, "assets"))).
+			To(HaveLen(3))
+	})
+
+})
@@ -0,0 +1,70 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"regexp"
+)
+
+// PlaceholderLookup returns the value of the named placeholder and true, or
+// false if there is no such placeholder. For instance, os.LookupEnv is a
+// PlaceholderLookup substituting environment variables.
+type PlaceholderLookup func(name string) (string, bool)
+
+// placeholderRe matches placeholders in the “%NAME%” and “${NAME}” styles.
+var placeholderRe = regexp.MustCompile(`%([A-Za-z_][A-Za-z0-9_]*)%|\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// WithPlaceholders substitutes placeholders in the index file in the styles of
+// “%VITE_API_URL%” and “${API_URL}” with the values returned by the specified
+// lookup. Placeholders without a value are left untouched. For instance, to
+// substitute placeholders with environment variables, as is common for
+// dockerized SPAs:
+//
+//	h := NewSPAHandler(bundle, "index.html", WithPlaceholders(os.LookupEnv))
+//
+// Placeholders get substituted when the index file is (re)loaded, so later
+// value changes are picked up only with the next change of the index file.
+// Please note that values are substituted as is without any escaping, so they
+// must come from trusted sources.
+func WithPlaceholders(lookup PlaceholderLookup) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.placeholders = lookup
+	}
+}
+
+// PlaceholderMap returns a PlaceholderLookup for the specified map of
+// placeholder names to their values.
+func PlaceholderMap(values map[string]string) PlaceholderLookup {
+	return func(name string) (string, bool) {
+		value, ok := values[name]
+		return value, ok
+	}
+}
+
+// substitutePlaceholders returns the specified HTML document contents with
+// its placeholders substituted, if placeholder substitution is enabled.
+func (h *SPAHandler) substitutePlaceholders(html string) string {
+	if h.placeholders == nil {
+		return html
+	}
+	return placeholderRe.ReplaceAllStringFunc(html, func(placeholder string) string {
+		name := placeholderRe.FindStringSubmatch(placeholder)
+		value, ok := h.placeholders(name[1] + name[2])
+		if !ok {
+			return placeholder
+		}
+		return value
+	})
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("index placeholders", func() {
+
+	spafs := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(
+			`<base href="./" /><meta name="api" content="%VITE_API_URL%">` +
+				`<script>const flags = "${FLAGS}";</script>` +
+				`<style>.a{width:100%;height:50%}</style>%UNKNOWN%`)},
+	}
+
+	get := func(h http.Handler) string {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/foo/bar", nil)
+		r.Header.Set(ForwardedPrefixHeader, "/app")
+		h.ServeHTTP(w, r)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		return w.Body.String()
+	}
+
+	It("leaves placeholders alone by default", func() {
+		Expect(get(NewSPAHandler(spafs, "index.html"))).To(And(
+			ContainSubstring(`content="%VITE_API_URL%"`),
+			ContainSubstring(`"${FLAGS}"`)))
+	})
+
+	It("substitutes placeholders from a map", func() {
+		h := NewSPAHandler(spafs, "index.html", WithPlaceholders(PlaceholderMap(map[string]string{
+			"VITE_API_URL": "/api/v2",
+			"FLAGS":        "dark,beta",
+		})))
+		Expect(get(h)).To(Equal(
+			`<base href="/app/" /><meta name="api" content="/api/v2">` +
+				`<script>const flags = "dark,beta";</script>` +
+				`<style>.a{width:100%;height:50%}</style>%UNKNOWN%`))
+	})
+
+	It("substitutes placeholders from the environment", func() {
+		os.Setenv("VITE_API_URL", "https://api.example.org")
+		defer os.Unsetenv("VITE_API_URL")
+		h := NewSPAHandler(spafs, "index.html", WithPlaceholders(os.LookupEnv))
+		Expect(get(h)).To(And(
+			ContainSubstring(`content="https://api.example.org"`),
+			ContainSubstring(`"${FLAGS}"`)))
+	})
+
+})
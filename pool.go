@@ -0,0 +1,44 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBufferSize limits the capacity of buffers returned to the buffer
+// pool, so a few unusually large index files don't pin lots of memory.
+const maxPooledBufferSize = 1 << 20
+
+// bufferPool pools the buffers used when reading and rewriting index files.
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// getBuffer returns an empty buffer from the buffer pool.
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+// putBuffer returns the specified buffer to the buffer pool, unless it has
+// grown too large. The buffer must not be used anymore afterwards.
+func putBuffer(buff *bytes.Buffer) {
+	if buff.Cap() > maxPooledBufferSize {
+		return
+	}
+	buff.Reset()
+	bufferPool.Put(buff)
+}
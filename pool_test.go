@@ -0,0 +1,82 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("buffer pooling", func() {
+
+	It("doesn't pool overly large buffers", func() {
+		buff := getBuffer()
+		buff.Grow(2 * maxPooledBufferSize)
+		putBuffer(buff)
+		for i := 0; i < 10; i++ {
+			b := getBuffer()
+			Expect(b.Cap()).To(BeNumerically("<=", maxPooledBufferSize))
+			Expect(b.Len()).To(BeZero())
+		}
+	})
+
+})
+
+func BenchmarkIndexAllocs(b *testing.B) {
+	mfs := fstest.MapFS{
+		"index.html": &fstest.MapFile{
+			Data: []byte(`<html><head><base href="./" /></head><body>` +
+				strings.Repeat("<p>prerendered</p>\n", 10000) + `</body></html>`),
+		},
+	}
+	for _, bm := range []struct {
+		name string
+		opts []SPAHandlerOption
+	}{
+		{"streamed", nil},
+		{"buffered", []SPAHandlerOption{
+			WithIndexRewriter(func(_ *http.Request, index string) string { return index }),
+		}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			h := NewSPAHandler(mfs, "index.html", bm.opts...)
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					r := httptest.NewRequest(http.MethodGet, "/foo", nil)
+					r.Header.Set(ForwardedPrefixHeader, "/app")
+					h.ServeHTTP(&discardResponseWriter{header: http.Header{}}, r)
+				}
+			})
+		})
+	}
+}
+
+// discardResponseWriter is an http.ResponseWriter discarding everything
+// written to it.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header         { return w.header }
+func (w *discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardResponseWriter) WriteHeader(int)             {}
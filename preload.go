@@ -0,0 +1,69 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"path"
+	"strings"
+)
+
+// preloadDestinations maps file extensions to the rel and as attributes of
+// their preload links.
+var preloadDestinations = map[string]preloadLink{
+	".js":    {rel: "modulepreload", as: "script"},
+	".mjs":   {rel: "modulepreload", as: "script"},
+	".css":   {rel: "preload", as: "style"},
+	".woff2": {rel: "preload", as: "font", crossorigin: "anonymous"},
+	".woff":  {rel: "preload", as: "font", crossorigin: "anonymous"},
+	".ttf":   {rel: "preload", as: "font", crossorigin: "anonymous"},
+	".otf":   {rel: "preload", as: "font", crossorigin: "anonymous"},
+	".png":   {rel: "preload", as: "image"},
+	".jpg":   {rel: "preload", as: "image"},
+	".jpeg":  {rel: "preload", as: "image"},
+	".gif":   {rel: "preload", as: "image"},
+	".svg":   {rel: "preload", as: "image"},
+	".webp":  {rel: "preload", as: "image"},
+	".avif":  {rel: "preload", as: "image"},
+}
+
+// WithPreloadLinks adds “Link” headers to index responses telling clients,
+// CDNs, and HTTP/2 servers to preload the resources at the specified paths,
+// relative to the base path. The kind of preload link is derived from the
+// file extension, such as “modulepreload” for “.js” and “.mjs” files, and
+// “preload” with “as=style” for “.css” files; resources with unknown file
+// extensions are preloaded using “as=fetch”. For instance:
+//
+//	h := NewSPAHandler(bundle, "index.html",
+//	    WithPreloadLinks("assets/index.js", "assets/index.css", "fonts/inter.woff2"))
+//
+// Without any paths, the scripts and stylesheets referenced by the index are
+// preloaded instead, similar to WithEarlyHints but without sending 103 Early
+// Hints responses.
+func WithPreloadLinks(paths ...string) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		if len(paths) == 0 {
+			h.discoverPreloads = true
+			return
+		}
+		for _, p := range paths {
+			link, ok := preloadDestinations[strings.ToLower(path.Ext(p))]
+			if !ok {
+				link = preloadLink{rel: "preload", as: "fetch", crossorigin: "anonymous"}
+			}
+			link.href = escapedRoute(p)
+			h.preloadLinks = append(h.preloadLinks, link)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("preload links", func() {
+
+	spafs := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<head><base href="./" />` +
+			`<script src="assets/app.js"></script></head>`)},
+	}
+
+	links := func(h http.Handler) []string {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/foo", nil)
+		r.Header.Set(ForwardedPrefixHeader, "/app")
+		h.ServeHTTP(w, r)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		return w.Header().Values("Link")
+	}
+
+	It("adds Link headers for the specified paths", func() {
+		h := NewSPAHandler(spafs, "index.html", WithPreloadLinks(
+			"assets/index.js", "/assets/index.css", "fonts/Inter Bold.WOFF2", "data/init.bin"))
+		Expect(links(h)).To(Equal([]string{
+			"</app/assets/index.js>; rel=modulepreload; as=script",
+			"</app/assets/index.css>; rel=preload; as=style",
+			"</app/fonts/Inter%20Bold.WOFF2>; rel=preload; as=font; crossorigin",
+			"</app/data/init.bin>; rel=preload; as=fetch; crossorigin",
+		}))
+	})
+
+	It("discovers preload links from the index", func() {
+		h := NewSPAHandler(spafs, "index.html", WithPreloadLinks())
+		Expect(links(h)).To(Equal([]string{
+			"</app/assets/app.js>; rel=preload; as=script",
+		}))
+	})
+
+	It("doesn't discover preload links for explicit paths", func() {
+		h := NewSPAHandler(spafs, "index.html", WithPreloadLinks("foo.css"))
+		Expect(links(h)).To(Equal([]string{
+			"</app/foo.css>; rel=preload; as=style",
+		}))
+	})
+
+})
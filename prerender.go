@@ -0,0 +1,117 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// UserAgentMatcher returns true if the specified user agent is to be served
+// pre-rendered snapshots instead of the SPA.
+type UserAgentMatcher func(userAgent string) bool
+
+// botRe matches the user agents of common search engine crawlers and social
+// media link preview fetchers.
+var botRe = regexp.MustCompile(`(?i)bot|crawl|spider|slurp|facebookexternalhit|embedly|` +
+	`quora link preview|outbrain|pinterest|vkshare|whatsapp|skypeuripreview|` +
+	`slack|discord|telegram|linkedin|twitter`)
+
+// IsBot is the default UserAgentMatcher, matching the user agents of common
+// search engine crawlers and social media link preview fetchers.
+func IsBot(userAgent string) bool {
+	return botRe.MatchString(userAgent)
+}
+
+// prerender is the configuration for serving pre-rendered snapshots.
+type prerender struct {
+	fs    fs.FS            // FS with the pre-rendered snapshots.
+	isBot UserAgentMatcher // decides who gets the snapshots.
+}
+
+// WithPrerenderFS serves pre-rendered HTML snapshots from the specified fs.FS
+// to clients whose user agent the specified matcher matches, such as search
+// engine crawlers and social media link preview fetchers, while all other
+// clients keep getting the SPA. If isBot is nil, IsBot is used.
+//
+// Snapshots are looked up by route: the snapshot for “/products/42” is either
+// “products/42.html” or “products/42/index.html”, and the snapshot for the
+// SPA root is “index.html”. Routes without snapshot fall back to the SPA. The
+// base elements of the snapshots are rewritten the same as for the index.
+//
+// As the responses for routes then depend on the user agent, they carry a
+// “Vary: User-Agent” header.
+func WithPrerenderFS(fsys fs.FS, isBot UserAgentMatcher) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		if fsys == nil {
+			h.invalidOption("WithPrerenderFS", "nil fs.FS")
+		}
+		if isBot == nil {
+			isBot = IsBot
+		}
+		h.prerender = &prerender{
+			fs:    fsys,
+			isBot: isBot,
+		}
+	}
+}
+
+// servePrerendered serves the pre-rendered snapshot for the requested route,
+// if enabled, the client is a bot, and there is a snapshot for the route. It
+// then returns true, otherwise false without having served anything.
+//
+// IMPORTANT: the passed r.URL.Path must have already been sanitized.
+func (h *SPAHandler) servePrerendered(w http.ResponseWriter, r *http.Request) bool {
+	if h.prerender == nil {
+		return false
+	}
+	w.Header().Add("Vary", "User-Agent")
+	if !h.prerender.isBot(r.UserAgent()) {
+		return false
+	}
+	name, info, ok := h.prerender.snapshot(r.URL.Path)
+	if !ok {
+		return false
+	}
+	contents, err := fs.ReadFile(h.prerender.fs, name)
+	if err != nil {
+		h.logError(r, err)
+		return false
+	}
+	h.callHooks(h.onIndex, r, name, false)
+	h.setIndexCacheControl(w)
+	h.setIndexContentType(w)
+	http.ServeContent(w, r, name, info.ModTime(),
+		strings.NewReader(h.rewriteBase(r, string(contents))))
+	return true
+}
+
+// snapshot returns the name and file information of the snapshot for the
+// specified (sanitized) route, and true; otherwise, false.
+func (p *prerender) snapshot(route string) (string, fs.FileInfo, bool) {
+	candidates := []string{"index.html"}
+	if route != "/" {
+		candidates = []string{route[1:] + ".html", path.Join(route[1:], "index.html")}
+	}
+	for _, name := range candidates {
+		if info, err := fs.Stat(p.fs, name); err == nil && info.Mode().IsRegular() {
+			return name, info, true
+		}
+	}
+	return "", nil, false
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("pre-rendered snapshots", func() {
+
+	const (
+		firefox   = "Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/115.0"
+		googlebot = "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+	)
+
+	spafs := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<base href="./" />SHELL`)},
+		"app.js":     &fstest.MapFile{Data: []byte(`app();`)},
+	}
+	snapshots := fstest.MapFS{
+		"index.html":             &fstest.MapFile{Data: []byte(`<base href="./" />HOME`)},
+		"about.html":             &fstest.MapFile{Data: []byte(`<base href="./" />ABOUT`)},
+		"products/42/index.html": &fstest.MapFile{Data: []byte(`<base href="./" />PRODUCT 42`)},
+	}
+
+	DescribeTable("serves snapshots to bots only",
+		func(userAgent string, path string, expectedBody string, expectedVary bool) {
+			h := NewSPAHandler(spafs, "index.html", WithPrerenderFS(snapshots, nil))
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, path, nil)
+			r.Header.Set("User-Agent", userAgent)
+			r.Header.Set(ForwardedPrefixHeader, "/app")
+			h.ServeHTTP(w, r)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(Equal(expectedBody))
+			if expectedVary {
+				Expect(w.Header().Values("Vary")).To(ContainElement("User-Agent"))
+			} else {
+				Expect(w.Header().Values("Vary")).NotTo(ContainElement("User-Agent"))
+			}
+		},
+		Entry("human", firefox, "/about", `<base href="/app/" />SHELL`, true),
+		Entry("bot at root", googlebot, "/", `<base href="/app/" />HOME`, true),
+		Entry("bot with html snapshot", googlebot, "/about", `<base href="/app/" />ABOUT`, true),
+		Entry("bot with directory snapshot", googlebot, "/products/42", `<base href="/app/" />PRODUCT 42`, true),
+		Entry("bot without snapshot", googlebot, "/cart", `<base href="/app/" />SHELL`, true),
+		Entry("bot getting asset", googlebot, "/app.js", `app();`, false),
+	)
+
+	It("uses a custom matcher", func() {
+		h := NewSPAHandler(spafs, "index.html", WithPrerenderFS(snapshots, func(userAgent string) bool {
+			return strings.Contains(userAgent, "Firefox")
+		}))
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/about", nil)
+		r.Header.Set("User-Agent", firefox)
+		h.ServeHTTP(w, r)
+		Expect(w.Body.String()).To(Equal(`<base href="/" />ABOUT`))
+	})
+
+	DescribeTable("detects bots",
+		func(userAgent string, expected bool) {
+			Expect(IsBot(userAgent)).To(Equal(expected))
+		},
+		Entry("Firefox", firefox, false),
+		Entry("Googlebot", googlebot, true),
+		Entry("Facebook", "facebookexternalhit/1.1", true),
+		Entry("Slack", "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)", true),
+	)
+
+})
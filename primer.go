@@ -0,0 +1,269 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxPrimerEntriesFactor limits the number of distinct requests tracked by a
+// primer to this factor times the number of requests to replay.
+const maxPrimerEntriesFactor = 16
+
+// primerKey identifies a request to replay, including the forwarding proxy
+// headers that influence the base path.
+type primerKey struct {
+	host     string
+	path     string
+	fwprefix string
+	fwuri    string
+}
+
+// primer tracks the popularity of recent requests and replays the most popular
+// ones on demand, rate limited by a token bucket.
+type primer struct {
+	topN   int
+	rate   float64 // replayed requests per second.
+	burst  int
+	mu     sync.Mutex
+	counts map[primerKey]uint64
+	cancel context.CancelFunc // cancels priming in the background, if any.
+}
+
+// WithCachePrimer enables recording the popularity of recent GET requests,
+// allowing to later replay the topN most popular requests using
+// SPAHandler.Prime immediately after deploying a new SPA bundle. Replaying
+// primes caches and thus smoothes out post-deploy latency spikes. The replay
+// is rate limited by a token bucket with the specified rate (in requests per
+// second) and burst size.
+//
+// Both topN and rate must be positive.
+//
+// Replaying doesn't serve the requests, but instead directly warms the index
+// caches, so replayed requests neither get redirected nor authenticated, nor
+// show up in access logs, observers, or hooks.
+func WithCachePrimer(topN int, rate float64, burst int) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		if topN <= 0 {
+			h.invalidOption("WithCachePrimer", "non-positive topN %d", topN)
+			h.primer = nil
+			return
+		}
+		if rate <= 0 {
+			h.invalidOption("WithCachePrimer", "non-positive rate %g", rate)
+		}
+		if burst < 1 {
+			burst = 1
+		}
+		h.primer = &primer{
+			topN:   topN,
+			rate:   rate,
+			burst:  burst,
+			counts: map[primerKey]uint64{},
+		}
+	}
+}
+
+// Prime replays the most popular recent requests against this SPAHandler in
+// order to prime its caches, see WithCachePrimer. Prime blocks until all
+// requests have been replayed or the context gets cancelled, returning the
+// context's error in the latter case. Calling Prime without the cache primer
+// enabled using WithCachePrimer is a no-op.
+func (h *SPAHandler) Prime(ctx context.Context) error {
+	if h.primer == nil {
+		return nil
+	}
+	bucket := newTokenBucket(h.primer.rate, h.primer.burst)
+	for _, key := range h.primer.popular() {
+		if err := bucket.wait(ctx); err != nil {
+			return err
+		}
+		r := (&http.Request{
+			Method: http.MethodGet,
+			Host:   key.host,
+			URL:    &url.URL{Path: key.path},
+			Header: http.Header{},
+		}).WithContext(ctx)
+		if key.fwprefix != "" {
+			r.Header.Set(ForwardedPrefixHeader, key.fwprefix)
+		}
+		if key.fwuri != "" {
+			r.Header.Set(ForwardedUriHeader, key.fwuri)
+		}
+		h.warm(r)
+	}
+	return nil
+}
+
+// primeInBackground primes the caches in the background until done or the
+// specified context gets cancelled. Priming still running in the background
+// from a previous call gets cancelled, as there's no point in priming a bundle
+// that has been superseded.
+func (h *SPAHandler) primeInBackground(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	h.primer.mu.Lock()
+	if h.primer.cancel != nil {
+		h.primer.cancel()
+	}
+	h.primer.cancel = cancel
+	h.primer.mu.Unlock()
+	go func() {
+		defer cancel()
+		_ = h.Prime(ctx)
+	}()
+}
+
+// warm primes the caches of the bundle the specified request would be served
+// from, without actually serving the request: unless the request is for a
+// static asset, the index gets loaded and, if deterministic, the metadata of
+// the rewritten index cached.
+//
+// IMPORTANT: the passed r.URL.Path must have already been sanitized.
+func (h *SPAHandler) warm(r *http.Request) {
+	r, ok := h.selectHost(r)
+	if !ok {
+		return
+	}
+	b := h.bundleFor(r)
+	if b.fs == nil {
+		return
+	}
+	if name := r.URL.Path[1:]; name != "" {
+		if info, err := fs.Stat(b.fs, name); err == nil && info.Mode().IsRegular() {
+			return
+		}
+	}
+	index := h.indexFor(r)
+	segs, err := h.loadIndex(b, index)
+	if err != nil || !h.hasDeterministicIndex() {
+		return
+	}
+	contents := segs.join(h.escapedBase(r))
+	if h.integrityMode != IntegrityKeep {
+		contents = h.fixIntegrity(r, contents)
+	}
+	h.rememberIndex(b, index, h.basename(r), segs.modTime, contents)
+}
+
+// record counts the specified request. If the number of distinct tracked
+// requests grows too large, the least popular half gets forgotten.
+//
+// IMPORTANT: the passed r.URL.Path must have already been sanitized.
+func (p *primer) record(r *http.Request) {
+	if p == nil || r.Method != http.MethodGet {
+		return
+	}
+	key := primerKey{
+		host:     r.Host,
+		path:     r.URL.Path,
+		fwprefix: r.Header.Get(ForwardedPrefixHeader),
+		fwuri:    r.Header.Get(ForwardedUriHeader),
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.counts[key]++
+	if len(p.counts) <= p.topN*maxPrimerEntriesFactor {
+		return
+	}
+	keys := p.sortedKeys()
+	for _, key := range keys[len(keys)/2:] {
+		delete(p.counts, key)
+	}
+}
+
+// popular returns the keys of the topN most popular requests, in descending
+// order of popularity.
+func (p *primer) popular() []primerKey {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	keys := p.sortedKeys()
+	if len(keys) > p.topN {
+		keys = keys[:p.topN]
+	}
+	return keys
+}
+
+// sortedKeys returns the keys of the tracked requests in descending order of
+// popularity. The caller must hold the lock.
+func (p *primer) sortedKeys() []primerKey {
+	keys := make([]primerKey, 0, len(p.counts))
+	for key := range p.counts {
+		keys = append(keys, key)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		ci, cj := p.counts[keys[i]], p.counts[keys[j]]
+		if ci != cj {
+			return ci > cj
+		}
+		return keys[i].path < keys[j].path
+	})
+	return keys
+}
+
+// tokenBucket is a simple token bucket rate limiter for use by a single
+// goroutine.
+type tokenBucket struct {
+	rate   float64 // tokens per second; non-positive means unlimited.
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait blocks until a token becomes available and takes it, or until the
+// context gets cancelled.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if b.rate <= 0 {
+		return nil
+	}
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return nil
+	}
+	delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case now := <-timer.C:
+		b.tokens = 0
+		b.last = now
+		return nil
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("cache priming", func() {
+
+	serve := func(h http.Handler, path string, prefix string) {
+		r := &http.Request{
+			Method: "GET",
+			URL:    Successful(url.Parse("http://foo.bar:12345" + path)),
+			Header: http.Header{},
+		}
+		if prefix != "" {
+			r.Header.Set(ForwardedPrefixHeader, prefix)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	// redeploy replaces the current bundle by a new one with empty caches,
+	// without priming it.
+	redeploy := func(h *SPAHandler) {
+		h.bundle.Store(newBundle(embStaticFs))
+	}
+
+	// warmed returns the bases of the rewritten index metadata cached for the
+	// current bundle.
+	warmed := func(h *SPAHandler) []string {
+		bases := []string{}
+		h.current().indexMetas.Range(func(key, _ any) bool {
+			bases = append(bases, key.(indexMetaKey).base)
+			return true
+		})
+		return bases
+	}
+
+	It("is a no-op when not enabled", func() {
+		h := NewSPAHandler(embStaticFs, "index.html")
+		serve(h, "/foo", "")
+		redeploy(h)
+		Expect(h.Prime(context.Background())).To(Succeed())
+		Expect(warmed(h)).To(BeEmpty())
+	})
+
+	It("warms the caches for the most popular requests", func() {
+		obs := &rewriteCountingObserver{}
+		h := NewSPAHandler(embStaticFs, "index.html",
+			WithCachePrimer(2, 0, 1),
+			WithObserver(obs))
+		for i := 0; i < 3; i++ {
+			serve(h, "/foo", "")
+		}
+		serve(h, "/bar", "/app")
+		serve(h, "/bar", "/app")
+		serve(h, "/baz", "/other")
+		rewrites := obs.rewrites
+		redeploy(h)
+		Expect(h.Prime(context.Background())).To(Succeed())
+		Expect(warmed(h)).To(ConsistOf("/", "/app/"))
+		Expect(obs.rewrites).To(Equal(rewrites), "priming must not serve requests")
+		Expect(h.primer.popular()).To(HaveLen(2))
+	})
+
+	It("doesn't warm request-specific indices", func() {
+		h := NewSPAHandler(embStaticFs, "index.html",
+			WithCachePrimer(2, 0, 1),
+			WithIndexRewriter(func(r *http.Request, index string) string { return index }))
+		serve(h, "/foo", "")
+		redeploy(h)
+		Expect(h.Prime(context.Background())).To(Succeed())
+		Expect(warmed(h)).To(BeEmpty())
+	})
+
+	It("forgets unpopular requests", func() {
+		h := NewSPAHandler(embStaticFs, "index.html", WithCachePrimer(1, 0, 1))
+		serve(h, "/popular", "")
+		serve(h, "/popular", "")
+		for i := 0; i < maxPrimerEntriesFactor; i++ {
+			serve(h, "/route"+string(rune('a'+i)), "")
+		}
+		Expect(len(h.primer.counts)).To(BeNumerically("<=", maxPrimerEntriesFactor))
+		Expect(h.primer.popular()).To(ConsistOf(primerKey{path: "/popular"}))
+	})
+
+	It("rate limits replaying", func() {
+		h := NewSPAHandler(embStaticFs, "index.html", WithCachePrimer(10, 20, 1))
+		for _, path := range []string{"/a", "/b", "/c", "/d"} {
+			serve(h, path, "")
+		}
+		start := time.Now()
+		Expect(h.Prime(context.Background())).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically(">=", 100*time.Millisecond))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		Expect(h.Prime(ctx)).To(MatchError(context.Canceled))
+	})
+
+})
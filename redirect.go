@@ -0,0 +1,108 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// NewHTTPSRedirectHandler returns a handler redirecting all requests to HTTPS
+// on the specified port, using 308 Permanent Redirect. A port of zero or 443
+// redirects to the default HTTPS port. The handler is intended for serving on
+// the plain HTTP port next to the SPA served via HTTPS, such as on appliances
+// without a separate reverse proxy:
+//
+//	go spaserve.Serve(ctx, ":80", spaserve.NewHTTPSRedirectHandler(443))
+//	err := spaserve.Serve(ctx, ":443", spa, spaserve.WithTLSConfig(tlsConfig))
+//
+// The redirects preserve the original request URI as passed by forwarding
+// proxies via the X-Forwarded-Uri or X-Forwarded-Prefix headers, as well as
+// the original host passed via X-Forwarded-Host.
+func NewHTTPSRedirectHandler(port int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirectToHTTPS(w, r, port)
+	})
+}
+
+// WithHTTPSRedirect redirects plain HTTP requests to HTTPS on the specified
+// port, same as NewHTTPSRedirectHandler. Requests are considered to be plain
+// HTTP requests if they arrived without TLS and don't carry an
+// X-Forwarded-Proto header of "https". Use this option when the SPAHandler
+// serves both plain HTTP and HTTPS, or sits behind a proxy terminating TLS.
+func WithHTTPSRedirect(port int) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.httpsRedirect = true
+		h.httpsPort = port
+	}
+}
+
+// redirectToHTTPSIfPlain redirects plain HTTP requests to HTTPS, returning
+// true. Otherwise, nothing is served and false is returned.
+func (h *SPAHandler) redirectToHTTPSIfPlain(w http.ResponseWriter, r *http.Request) bool {
+	if !h.httpsRedirect || originalScheme(r) == "https" {
+		return false
+	}
+	redirectToHTTPS(w, r, h.httpsPort)
+	return true
+}
+
+// redirectToHTTPS redirects the specified request to its HTTPS equivalent on
+// the specified port.
+func redirectToHTTPS(w http.ResponseWriter, r *http.Request, port int) {
+	host := originalHost(r)
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	} else {
+		host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	}
+	if port != 0 && port != 443 {
+		host = net.JoinHostPort(host, strconv.Itoa(port))
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]" // ...IPv6 address without port.
+	}
+	http.Redirect(w, r, "https://"+host+originalRequestURI(r), http.StatusPermanentRedirect)
+}
+
+// originalRequestURI returns the (escaped) request URI, including any query,
+// of the specified request when it hit the first proxy, based on the
+// X-Forwarded-Uri and X-Forwarded-Prefix headers, if present and well-formed.
+// Otherwise, the request URI is taken from the request itself.
+func originalRequestURI(r *http.Request) string {
+	if fwuri := firstHeaderValue(r.Header.Get(ForwardedUriHeader)); fwuri != "" {
+		if uripath, ok := forwardedURIPath(fwuri); ok {
+			u := url.URL{Path: uripath}
+			if strings.HasSuffix(strings.SplitN(fwuri, "?", 2)[0], "/") && uripath != "/" {
+				u.Path += "/"
+			}
+			if _, query, ok := strings.Cut(fwuri, "?"); ok {
+				u.RawQuery, _, _ = strings.Cut(query, "#")
+			}
+			return u.RequestURI()
+		}
+	}
+	u := url.URL{Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+	if fwprefix, ok := forwardedPrefix(r.Header.Get(ForwardedPrefixHeader)); ok {
+		u.Path = path.Join(fwprefix, r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, "/") && u.Path != "/" {
+			u.Path += "/"
+		}
+	}
+	return u.RequestURI()
+}
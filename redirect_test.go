@@ -0,0 +1,92 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HTTPS redirects", func() {
+
+	DescribeTable("redirects plain HTTP requests to HTTPS",
+		func(port int, target string, header http.Header, expected string) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, target, nil)
+			for name, values := range header {
+				r.Header[name] = values
+			}
+			NewHTTPSRedirectHandler(port).ServeHTTP(w, r)
+			Expect(w.Code).To(Equal(http.StatusPermanentRedirect))
+			Expect(w.Header().Get("Location")).To(Equal(expected))
+		},
+		Entry("default port", 0, "http://example.org/foo?bar=baz", nil,
+			"https://example.org/foo?bar=baz"),
+		Entry("explicit default port", 443, "http://example.org:80/", nil,
+			"https://example.org/"),
+		Entry("other port", 8443, "http://example.org:8080/foo/", nil,
+			"https://example.org:8443/foo/"),
+		Entry("IPv6", 0, "http://[::1]:80/foo", nil,
+			"https://[::1]/foo"),
+		Entry("IPv6 with other port", 8443, "http://[::1]/foo", nil,
+			"https://[::1]:8443/foo"),
+		Entry("escaped path", 0, "http://example.org/a%20b", nil,
+			"https://example.org/a%20b"),
+		Entry("forwarded prefix", 0, "http://example.org/foo/?bar=baz", http.Header{
+			ForwardedPrefixHeader: {"/app"},
+			ForwardedHostHeader:   {"public.example.org"},
+		}, "https://public.example.org/app/foo/?bar=baz"),
+		Entry("forwarded URI", 0, "http://example.org/foo", http.Header{
+			ForwardedUriHeader: {"/app/foo/?bar=baz#frag"},
+		}, "https://example.org/app/foo/?bar=baz"),
+		Entry("forwarded URL", 0, "http://example.org/foo", http.Header{
+			ForwardedUriHeader: {"http://public.example.org/app/foo?bar=baz"},
+		}, "https://example.org/app/foo?bar=baz"),
+		Entry("malformed forwarded URI", 0, "http://example.org/foo", http.Header{
+			ForwardedUriHeader: {"/app/../../etc"},
+		}, "https://example.org/foo"),
+	)
+
+	spafs := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<base href="./" />`)},
+	}
+
+	DescribeTable("redirects only plain HTTP requests to the SPA",
+		func(tlsState *tls.ConnectionState, proto string, expectedCode int) {
+			h := NewSPAHandler(spafs, "index.html", WithHTTPSRedirect(0))
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://example.org/foo", nil)
+			r.TLS = tlsState
+			if proto != "" {
+				r.Header.Set(ForwardedProtoHeader, proto)
+			}
+			h.ServeHTTP(w, r)
+			Expect(w.Code).To(Equal(expectedCode))
+			if expectedCode == http.StatusPermanentRedirect {
+				Expect(w.Header().Get("Location")).To(Equal("https://example.org/foo"))
+			}
+		},
+		Entry("plain HTTP", nil, "", http.StatusPermanentRedirect),
+		Entry("forwarded plain HTTP", &tls.ConnectionState{}, "http", http.StatusPermanentRedirect),
+		Entry("HTTPS", &tls.ConnectionState{}, "", http.StatusOK),
+		Entry("forwarded HTTPS", nil, "https", http.StatusOK),
+	)
+
+})
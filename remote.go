@@ -0,0 +1,357 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRemoteBundleSize limits the size of SPA bundle archives downloaded.
+const maxRemoteBundleSize = 256 << 20
+
+// RemoteSource downloads an SPA bundle archive from an HTTP(S) URL, exposing
+// the bundle as an fs.FS. The archive is a zip, tar, or gzip'ed tar archive,
+// as supported by [ArchiveFS]. S3 objects are downloaded using their
+// (virtual-hosted style) HTTPS URLs, or presigned URLs for private buckets;
+// "s3://bucket/key" URLs are a shorthand for public objects in
+// "https://bucket.s3.amazonaws.com/key".
+//
+// Refreshing uses conditional requests based on the ETag and Last-Modified
+// validators of the previous download, so unchanged bundles aren't downloaded
+// again.
+type RemoteSource struct {
+	url      string
+	client   *http.Client
+	header   http.Header
+	cacheDir string
+	logger   *slog.Logger
+
+	mu        sync.Mutex
+	validator remoteValidator // validators of the bundle last downloaded.
+}
+
+// remoteValidator are the validators of a downloaded SPA bundle archive.
+type remoteValidator struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// RemoteOption configures a RemoteSource.
+type RemoteOption func(*RemoteSource)
+
+// WithRemoteHTTPClient sets the HTTP client for downloading, instead of
+// http.DefaultClient.
+func WithRemoteHTTPClient(client *http.Client) RemoteOption {
+	return func(s *RemoteSource) {
+		s.client = client
+	}
+}
+
+// WithRemoteHeader adds the specified header to all download requests, such as
+// an "Authorization" header.
+func WithRemoteHeader(name, value string) RemoteOption {
+	return func(s *RemoteSource) {
+		s.header.Add(name, value)
+	}
+}
+
+// WithRemoteCacheDir caches the downloaded SPA bundle archive in the specified
+// directory, which must exist. After restarts, the cached archive is only
+// downloaded again if it has changed; and if downloading fails, the cached
+// archive is used instead, so the service can start even when the remote is
+// temporarily unavailable. Without a cache directory, the bundle is only kept
+// in memory.
+func WithRemoteCacheDir(dir string) RemoteOption {
+	return func(s *RemoteSource) {
+		s.cacheDir = dir
+	}
+}
+
+// WithRemoteLogger sets the logger for errors encountered while periodically
+// refreshing the SPA bundle in the background.
+func WithRemoteLogger(logger *slog.Logger) RemoteOption {
+	return func(s *RemoteSource) {
+		s.logger = logger
+	}
+}
+
+// NewRemoteSource returns a new RemoteSource for the specified http, https, or
+// s3 URL, or an error if the URL is invalid.
+func NewRemoteSource(rawURL string, opts ...RemoteOption) (*RemoteSource, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote SPA bundle URL, %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+	case "s3":
+		u.Scheme, u.Host = "https", u.Host+".s3.amazonaws.com"
+	default:
+		return nil, fmt.Errorf("invalid remote SPA bundle URL %q, unsupported scheme", rawURL)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid remote SPA bundle URL %q, missing host", rawURL)
+	}
+	s := &RemoteSource{
+		url:    u.String(),
+		client: http.DefaultClient,
+		header: http.Header{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// Fetch downloads the SPA bundle, returning it as an fs.FS. If the bundle
+// hasn't changed since the previous download, Fetch returns a nil fs.FS and no
+// error. If a cache directory has been set, the downloaded archive gets
+// cached; failing to cache is logged, but not considered an error.
+func (s *RemoteSource) Fetch(ctx context.Context) (fs.FS, error) {
+	s.mu.Lock()
+	validator := s.validator
+	s.mu.Unlock()
+	archive, validator, err := s.download(ctx, validator)
+	if err != nil {
+		return nil, err
+	}
+	if archive == nil {
+		return nil, nil
+	}
+	fsys, err := ArchiveFS(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.validator = validator
+	s.mu.Unlock()
+	if s.cacheDir != "" {
+		if err := s.storeCache(archive, validator); err != nil {
+			s.logError(ctx, err)
+		}
+	}
+	return fsys, nil
+}
+
+// download downloads the SPA bundle archive using a conditional request with
+// the specified validators, returning the archive and its validators, or a nil
+// archive if it hasn't changed.
+func (s *RemoteSource) download(ctx context.Context, validator remoteValidator) ([]byte, remoteValidator, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, validator, err
+	}
+	for name, values := range s.header {
+		req.Header[name] = values
+	}
+	if validator.ETag != "" {
+		req.Header.Set("If-None-Match", validator.ETag)
+	}
+	if validator.LastModified != "" {
+		req.Header.Set("If-Modified-Since", validator.LastModified)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// Don't leak the URL's query with any credentials.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, validator, fmt.Errorf("cannot download SPA bundle from %s, %w", redactedURL(s.url), err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, validator, nil
+	default:
+		return nil, validator, fmt.Errorf("cannot download SPA bundle from %s, status %d", redactedURL(s.url), resp.StatusCode)
+	}
+	archive, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteBundleSize+1))
+	if err != nil {
+		return nil, validator, fmt.Errorf("cannot download SPA bundle from %s, %w", redactedURL(s.url), err)
+	}
+	if len(archive) > maxRemoteBundleSize {
+		return nil, validator, fmt.Errorf("cannot download SPA bundle from %s, too large", redactedURL(s.url))
+	}
+	return archive, remoteValidator{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}, nil
+}
+
+// Watch periodically refreshes the SPA bundle in the specified interval,
+// swapping changed bundles into the specified SPAHandler, until the context
+// gets cancelled. Errors are logged, if a logger has been set, and the
+// currently served SPA bundle is kept.
+func (s *RemoteSource) Watch(ctx context.Context, h *SPAHandler, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		fsys, err := s.Fetch(ctx)
+		if err != nil {
+			s.logError(ctx, err)
+			continue
+		}
+		if fsys != nil {
+			h.SwapFSContext(ctx, fsys)
+		}
+	}
+}
+
+// initial returns the SPA bundle to start serving with, using the cached
+// archive if it is still current or the remote isn't available.
+func (s *RemoteSource) initial(ctx context.Context) (fs.FS, error) {
+	var cached fs.FS
+	if s.cacheDir != "" {
+		if fsys, validator, err := s.loadCache(); err == nil {
+			cached = fsys
+			s.mu.Lock()
+			s.validator = validator
+			s.mu.Unlock()
+		}
+	}
+	fsys, err := s.Fetch(ctx)
+	switch {
+	case fsys != nil:
+		return fsys, nil
+	case cached == nil && err == nil:
+		return nil, fmt.Errorf("cannot download SPA bundle from %s, unexpectedly not modified", redactedURL(s.url))
+	case cached == nil:
+		return nil, err
+	}
+	if err != nil {
+		s.logError(ctx, err)
+	}
+	return cached, nil
+}
+
+// cachePath returns the path of the cached archive, derived from the URL.
+func (s *RemoteSource) cachePath() string {
+	sum := sha256.Sum256([]byte(s.url))
+	return filepath.Join(s.cacheDir, "spa-"+hex.EncodeToString(sum[:8]))
+}
+
+// loadCache returns the SPA bundle from the cached archive, together with the
+// validators of the archive.
+func (s *RemoteSource) loadCache() (fs.FS, remoteValidator, error) {
+	var validator remoteValidator
+	meta, err := os.ReadFile(s.cachePath() + ".json")
+	if err != nil {
+		return nil, validator, err
+	}
+	if err := json.Unmarshal(meta, &validator); err != nil {
+		return nil, validator, err
+	}
+	archive, err := os.ReadFile(s.cachePath())
+	if err != nil {
+		return nil, validator, err
+	}
+	fsys, err := ArchiveFS(bytes.NewReader(archive), int64(len(archive)))
+	return fsys, validator, err
+}
+
+// storeCache atomically replaces the cached archive and its validators.
+func (s *RemoteSource) storeCache(archive []byte, validator remoteValidator) error {
+	meta, err := json.Marshal(validator)
+	if err != nil {
+		return err
+	}
+	// Remove the validators first, so that a crash in between never pairs
+	// stale validators with a fresh archive, or vice versa.
+	if err := os.Remove(s.cachePath() + ".json"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := writeFileAtomically(s.cachePath(), archive); err != nil {
+		return err
+	}
+	return writeFileAtomically(s.cachePath()+".json", meta)
+}
+
+// writeFileAtomically writes the specified file by first writing a temporary
+// file and then renaming it.
+func writeFileAtomically(name string, contents []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+"-*")
+	if err != nil {
+		return err
+	}
+	tmpname := f.Name()
+	_, err = f.Write(contents)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpname, name)
+	}
+	if err != nil {
+		_ = os.Remove(tmpname)
+	}
+	return err
+}
+
+// logError logs the specified error encountered while refreshing, if a logger
+// has been set.
+func (s *RemoteSource) logError(ctx context.Context, err error) {
+	if s.logger == nil || ctx.Err() != nil {
+		return
+	}
+	s.logger.LogAttrs(ctx, slog.LevelError, "refreshing SPA bundle failed",
+		slog.String("url", redactedURL(s.url)),
+		slog.String("error", err.Error()))
+}
+
+// redactedURL returns the specified URL without its query, which might contain
+// credentials, such as with presigned S3 URLs.
+func redactedURL(rawURL string) string {
+	u, _, _ := strings.Cut(rawURL, "?")
+	return u
+}
+
+// NewSPAHandlerFromURL returns a new SPA handler serving the SPA bundle
+// downloaded from the specified RemoteSource. If interval is positive, the
+// SPA bundle is refreshed in this interval until the context gets cancelled,
+// swapping in changed SPA bundles.
+func NewSPAHandlerFromURL(ctx context.Context, src *RemoteSource, interval time.Duration, index string, opts ...SPAHandlerOption) (*SPAHandler, error) {
+	fsys, err := src.initial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	h := NewSPAHandler(fsys, index, opts...)
+	if interval > 0 {
+		go src.Watch(ctx, h, interval)
+	}
+	return h, nil
+}
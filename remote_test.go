@@ -0,0 +1,153 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+// fakeBundleServer serves an SPA bundle archive, supporting conditional
+// requests based on an ETag.
+type fakeBundleServer struct {
+	mu        sync.Mutex
+	archive   []byte
+	etag      string
+	downloads atomic.Int32
+	fail      atomic.Bool
+}
+
+func (f *fakeBundleServer) publish(archive []byte, etag string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.archive, f.etag = archive, etag
+}
+
+func (f *fakeBundleServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f.fail.Load() {
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer s3cr3t" {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("ETag", f.etag)
+	if r.Header.Get("If-None-Match") == f.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	f.downloads.Add(1)
+	_, _ = w.Write(f.archive)
+}
+
+var _ = Describe("remote bundles", func() {
+
+	var bundles *fakeBundleServer
+	var srv *httptest.Server
+
+	BeforeEach(func() {
+		bundles = &fakeBundleServer{}
+		bundles.publish(tarArchive(true), `"v1"`)
+		srv = httptest.NewServer(bundles)
+		DeferCleanup(srv.Close)
+	})
+
+	DescribeTable("validating URLs",
+		func(rawURL, expected string) {
+			src, err := NewRemoteSource(rawURL)
+			if expected == "" {
+				Expect(err).To(HaveOccurred())
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(src.url).To(Equal(expected))
+		},
+		Entry(nil, "https://cdn.example.com/spa.zip", "https://cdn.example.com/spa.zip"),
+		Entry(nil, "s3://bucket/spa/v1.tar.gz", "https://bucket.s3.amazonaws.com/spa/v1.tar.gz"),
+		Entry(nil, "ftp://example.com/spa.zip", ""),
+		Entry(nil, "https:///spa.zip", ""),
+		Entry(nil, "https://example.com/%zz", ""),
+	)
+
+	It("redacts URL queries", func() {
+		Expect(redactedURL("https://bucket/spa.zip?X-Amz-Signature=1234")).To(Equal("https://bucket/spa.zip"))
+	})
+
+	It("downloads, serves, and refreshes a bundle", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		src := Successful(NewRemoteSource(srv.URL+"/spa.tar.gz",
+			WithRemoteHeader("Authorization", "Bearer s3cr3t")))
+		h := Successful(NewSPAHandlerFromURL(ctx, src, 10*time.Millisecond, "index.html"))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/assets/app.js", nil))
+		Expect(rec.Body.String()).To(Equal(archiveFiles["assets/app.js"]))
+
+		fsys := func() uintptr { return reflect.ValueOf(h.FS()).Pointer() }
+		initial := fsys()
+		Consistently(fsys, "50ms", "10ms").Should(Equal(initial))
+		Expect(bundles.downloads.Load()).To(Equal(int32(1)))
+
+		bundles.publish(zipArchive(), `"v2"`)
+		Eventually(fsys).ShouldNot(Equal(initial))
+		Expect(bundles.downloads.Load()).To(Equal(int32(2)))
+	})
+
+	It("fails without a bundle", func() {
+		src := Successful(NewRemoteSource(srv.URL + "/spa.tar.gz"))
+		Expect(NewSPAHandlerFromURL(context.Background(), src, 0, "index.html")).
+			Error().To(MatchError(ContainSubstring("status 403")))
+	})
+
+	It("falls back to the cached bundle", func() {
+		dir := GinkgoT().TempDir()
+		src := Successful(NewRemoteSource(srv.URL+"/spa.tar.gz",
+			WithRemoteHeader("Authorization", "Bearer s3cr3t"),
+			WithRemoteCacheDir(dir)))
+		Expect(NewSPAHandlerFromURL(context.Background(), src, 0, "index.html")).Error().NotTo(HaveOccurred())
+		Expect(bundles.downloads.Load()).To(Equal(int32(1)))
+
+		By("not downloading an unchanged bundle again")
+		src = Successful(NewRemoteSource(srv.URL+"/spa.tar.gz",
+			WithRemoteHeader("Authorization", "Bearer s3cr3t"),
+			WithRemoteCacheDir(dir)))
+		h := Successful(NewSPAHandlerFromURL(context.Background(), src, 0, "index.html"))
+		Expect(bundles.downloads.Load()).To(Equal(int32(1)))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/assets/app.js", nil))
+		Expect(rec.Body.String()).To(Equal(archiveFiles["assets/app.js"]))
+
+		By("using the cached bundle when the remote is unavailable")
+		bundles.fail.Store(true)
+		src = Successful(NewRemoteSource(srv.URL+"/spa.tar.gz", WithRemoteCacheDir(dir)))
+		h = Successful(NewSPAHandlerFromURL(context.Background(), src, 0, "index.html"))
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/assets/app.js", nil))
+		Expect(rec.Body.String()).To(Equal(archiveFiles["assets/app.js"]))
+	})
+
+})
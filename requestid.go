@@ -0,0 +1,129 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// DefaultRequestIDHeader is the default request header carrying the request
+// ID, such as set by a reverse proxy.
+const DefaultRequestIDHeader = "X-Request-Id"
+
+// RequestIDMetaName is the name of the meta tag carrying the request ID.
+const RequestIDMetaName = "request-id"
+
+// RequestIDPlaceholder is replaced by the request ID in the index contents,
+// such as in “<script>window.__REQUEST_ID__="__SPA_REQUEST_ID__"</script>”.
+const RequestIDPlaceholder = "__SPA_REQUEST_ID__"
+
+// maxRequestIDLength limits the length of request IDs taken from requests.
+const maxRequestIDLength = 128
+
+// RequestIDGenerator returns a new request ID.
+type RequestIDGenerator func() string
+
+// requestID is the configuration for injecting request IDs into the index.
+type requestID struct {
+	header string
+	gen    RequestIDGenerator
+}
+
+// requestIDCtxKey is the context key for the request ID.
+type requestIDCtxKey struct{}
+
+// WithRequestID injects the ID of the index request into the index as a meta
+// tag named “request-id” (see RequestIDMetaName), as well as in place of any
+// RequestIDPlaceholder, so that frontend error reports can be correlated with
+// backend logs. For instance, to make the request ID available to scripts:
+//
+//	<script>window.__REQUEST_ID__ = "__SPA_REQUEST_ID__";</script>
+//
+// The request ID is taken from the specified request header, defaulting to
+// DefaultRequestIDHeader. If the request doesn't carry an ID, or the ID
+// contains characters other than ASCII letters, digits, “.”, “_”, “:”, and
+// “-”, or is longer than 128 characters, a new ID is generated using the
+// specified generator, or a random 128 bit hex ID if the generator is nil. The
+// request ID is also set in the same response header.
+//
+// The request ID is passed on to an IndexRewriter via the request context; use
+// RequestID to retrieve it.
+func WithRequestID(header string, gen RequestIDGenerator) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		if header == "" {
+			header = DefaultRequestIDHeader
+		}
+		if gen == nil {
+			gen = randomRequestID
+		}
+		h.requestID = &requestID{
+			header: header,
+			gen:    gen,
+		}
+	}
+}
+
+// RequestID returns the request ID from the specified context, or an empty
+// string if there is none. When enabled using WithRequestID, the request
+// contexts passed to IndexRewriters contain the request ID.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDCtxKey{}).(string)
+	return id
+}
+
+// randomRequestID returns a random 128 bit request ID in hex.
+func randomRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// isValidRequestID returns true if the specified request ID is non-empty, not
+// overly long, and only consists of characters that are safe to inject into
+// HTML attribute values and script strings without escaping.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, ch := range id {
+		switch {
+		case 'a' <= ch && ch <= 'z', 'A' <= ch && ch <= 'Z', '0' <= ch && ch <= '9':
+		case ch == '.' || ch == '_' || ch == ':' || ch == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// injectRequestID injects the ID of the specified request into the specified
+// index contents, generating a new ID if necessary. It returns the request
+// with the ID added to its context, and the updated index contents.
+func (h *SPAHandler) injectRequestID(w http.ResponseWriter, r *http.Request, index []byte) (*http.Request, []byte) {
+	id := r.Header.Get(h.requestID.header)
+	if !isValidRequestID(id) {
+		if id = h.requestID.gen(); !isValidRequestID(id) {
+			id = randomRequestID()
+		}
+	}
+	w.Header().Set(h.requestID.header, id)
+	index = injectMeta(index, &MetaTags{Names: map[string]string{RequestIDMetaName: id}})
+	index = bytes.ReplaceAll(index, []byte(RequestIDPlaceholder), []byte(id))
+	return r.WithContext(context.WithValue(r.Context(), requestIDCtxKey{}, id)), index
+}
@@ -0,0 +1,84 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("request IDs", func() {
+
+	spafs := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(
+			`<html><head><base href="./" /></head><script>window.__REQUEST_ID__="__SPA_REQUEST_ID__"</script></html>`)},
+	}
+
+	get := func(h *SPAHandler, header, id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://example.org/route", nil)
+		if id != "" {
+			r.Header.Set(header, id)
+		}
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	DescribeTable("validating request IDs",
+		func(id string, valid bool) {
+			Expect(isValidRequestID(id)).To(Equal(valid))
+		},
+		Entry(nil, "", false),
+		Entry(nil, "4bf92f3577b34da6a3ce929d0e0e4736", true),
+		Entry(nil, "req-1.2_3:4", true),
+		Entry(nil, `"><script>`, false),
+		Entry(nil, strings.Repeat("a", 129), false),
+	)
+
+	It("propagates the request ID", func() {
+		var seen string
+		h := NewSPAHandler(spafs, "index.html",
+			WithRequestID("", nil),
+			WithIndexRewriter(func(r *http.Request, index string) string {
+				seen = RequestID(r.Context())
+				return index
+			}))
+		w := get(h, DefaultRequestIDHeader, "req-42")
+		Expect(w.Body.String()).To(Equal(
+			`<html><head><base href="/" /><meta name="request-id" content="req-42"></head>` +
+				`<script>window.__REQUEST_ID__="req-42"</script></html>`))
+		Expect(w.Header().Get(DefaultRequestIDHeader)).To(Equal("req-42"))
+		Expect(seen).To(Equal("req-42"))
+	})
+
+	It("generates missing and replaces invalid request IDs", func() {
+		h := NewSPAHandler(spafs, "index.html",
+			WithRequestID("X-Trace-Id", func() string { return "gen-1" }))
+		Expect(get(h, "X-Trace-Id", "").Header().Get("X-Trace-Id")).To(Equal("gen-1"))
+		w := get(h, "X-Trace-Id", `"><script>`)
+		Expect(w.Header().Get("X-Trace-Id")).To(Equal("gen-1"))
+		Expect(w.Body.String()).NotTo(ContainSubstring("<script>\""))
+
+		h = NewSPAHandler(spafs, "index.html",
+			WithRequestID("", func() string { return "not valid" }))
+		Expect(get(h, "", "").Header().Get(DefaultRequestIDHeader)).To(MatchRegexp(`^[0-9a-f]{32}$`))
+	})
+
+})
@@ -0,0 +1,90 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SitemapRoutes returns the routes of the SPA to list in the sitemap for the
+// specified request, relative to the base path of the SPA, such as "" (the SPA
+// root) and "products/42".
+type SitemapRoutes func(r *http.Request) []string
+
+// WithRobotsTxt serves a generated “robots.txt” file for all user agents,
+// disallowing the specified paths relative to the base path of the SPA, such
+// as "admin/". If a sitemap has been enabled using WithSitemap, the
+// “robots.txt” additionally references the sitemap using its full URL.
+//
+// Please note that crawlers only look for “robots.txt” at the root of a host,
+// so this is most useful for SPAs either served from the root or with the
+// outer proxy mapping “/robots.txt” to the SPA.
+func WithRobotsTxt(disallow ...string) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		WithVirtualFile("robots.txt", func(r *http.Request) ([]byte, time.Time, error) {
+			base := h.escapedBase(r)
+			var robots bytes.Buffer
+			robots.WriteString("User-agent: *\n")
+			if len(disallow) == 0 {
+				robots.WriteString("Disallow:\n")
+			}
+			for _, path := range disallow {
+				robots.WriteString("Disallow: " + base + escapedRoute(path) + "\n")
+			}
+			if _, ok := h.virtualFiles["/sitemap.xml"]; ok {
+				robots.WriteString("Sitemap: " + originalOrigin(r) + base + "sitemap.xml\n")
+			}
+			return robots.Bytes(), time.Time{}, nil
+		})(h)
+	}
+}
+
+// WithSitemap serves a generated “sitemap.xml” file listing the routes
+// returned by the specified function, with their full URLs based on the
+// original scheme, host, and base path of the request. For instance:
+//
+//	h := NewSPAHandler(bundle, "index.html",
+//	    WithSitemap(func(*http.Request) []string {
+//	        return []string{"", "about", "products/42"}
+//	    }))
+func WithSitemap(routes SitemapRoutes) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		WithVirtualFile("sitemap.xml", func(r *http.Request) ([]byte, time.Time, error) {
+			prefix := originalOrigin(r) + h.escapedBase(r)
+			var sitemap bytes.Buffer
+			sitemap.WriteString(xml.Header)
+			sitemap.WriteString(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` + "\n")
+			for _, route := range routes(r) {
+				sitemap.WriteString("<url><loc>")
+				if err := xml.EscapeText(&sitemap, []byte(prefix+escapedRoute(route))); err != nil {
+					return nil, time.Time{}, err
+				}
+				sitemap.WriteString("</loc></url>\n")
+			}
+			sitemap.WriteString("</urlset>\n")
+			return sitemap.Bytes(), time.Time{}, nil
+		})(h)
+	}
+}
+
+// escapedRoute returns the specified route relative to the base path,
+// escaped.
+func escapedRoute(route string) string {
+	return strings.TrimPrefix(escapedPath("/"+strings.TrimPrefix(route, "/")), "/")
+}
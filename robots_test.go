@@ -0,0 +1,101 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("robots.txt and sitemaps", func() {
+
+	spafs := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<base href="./" />`)},
+	}
+
+	routes := func(*http.Request) []string {
+		return []string{"", "/about", "products/42&43", "süß"}
+	}
+
+	get := func(h http.Handler, path string, header http.Header) string {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://example.org"+path, nil)
+		for name, values := range header {
+			r.Header[name] = values
+		}
+		h.ServeHTTP(w, r)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		return w.Body.String()
+	}
+
+	It("generates robots.txt allowing everything", func() {
+		h := NewSPAHandler(spafs, "index.html", WithRobotsTxt())
+		Expect(get(h, "/robots.txt", nil)).To(Equal("User-agent: *\nDisallow:\n"))
+	})
+
+	It("generates robots.txt with base paths and sitemap", func() {
+		h := NewSPAHandler(spafs, "index.html",
+			WithRobotsTxt("admin/", "/private"),
+			WithSitemap(routes))
+		Expect(get(h, "/robots.txt", http.Header{
+			ForwardedPrefixHeader: {"/app"},
+			ForwardedProtoHeader:  {"https"},
+			ForwardedHostHeader:   {"shop.example.com, proxy.internal"},
+		})).To(Equal("User-agent: *\n" +
+			"Disallow: /app/admin/\n" +
+			"Disallow: /app/private\n" +
+			"Sitemap: https://shop.example.com/app/sitemap.xml\n"))
+	})
+
+	It("generates sitemap.xml", func() {
+		h := NewSPAHandler(spafs, "index.html", WithSitemap(routes))
+		Expect(get(h, "/sitemap.xml", http.Header{
+			ForwardedPrefixHeader: {"/app"},
+		})).To(Equal(`<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+			`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` + "\n" +
+			"<url><loc>http://example.org/app/</loc></url>\n" +
+			"<url><loc>http://example.org/app/about</loc></url>\n" +
+			"<url><loc>http://example.org/app/products/42&amp;43</loc></url>\n" +
+			"<url><loc>http://example.org/app/s%C3%BC%C3%9F</loc></url>\n" +
+			"</urlset>\n"))
+	})
+
+	DescribeTable("determines the original origin",
+		func(tls *tls.ConnectionState, header http.Header, expected string) {
+			r := httptest.NewRequest(http.MethodGet, "http://example.org/", nil)
+			r.TLS = tls
+			for name, values := range header {
+				r.Header[name] = values
+			}
+			Expect(originalOrigin(r)).To(Equal(expected))
+		},
+		Entry("plain", nil, nil, "http://example.org"),
+		Entry("TLS", &tls.ConnectionState{}, nil, "https://example.org"),
+		Entry("forwarded", nil, http.Header{
+			ForwardedProtoHeader: {"HTTPS"},
+			ForwardedHostHeader:  {"foo.example.com:8443"},
+		}, "https://foo.example.com:8443"),
+		Entry("invalid forwarded", &tls.ConnectionState{}, http.Header{
+			ForwardedProtoHeader: {"javascript"},
+			ForwardedHostHeader:  {"evil.example.com/<script>"},
+		}, "https://example.org"),
+	)
+
+})
@@ -0,0 +1,100 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// RoutingMode specifies how an SPAHandler falls back to the index for request
+// paths without any matching static asset.
+type RoutingMode int32
+
+const (
+	// HistoryRouting serves the index on all request paths without a matching
+	// static asset, as needed by client-side routers using the HTML5 history
+	// API. This is the default.
+	HistoryRouting RoutingMode = iota
+	// HashRouting redirects request paths without a matching static asset to
+	// the base path with the request path moved into the URL fragment, such as
+	// “/app/#/users/42”, as needed by client-side hash routers.
+	HashRouting
+)
+
+// String returns the textual representation of a RoutingMode, such as
+// "history" or "hash".
+func (m RoutingMode) String() string {
+	switch m {
+	case HistoryRouting:
+		return "history"
+	case HashRouting:
+		return "hash"
+	}
+	return fmt.Sprintf("RoutingMode(%d)", int32(m))
+}
+
+// ParseRoutingMode returns the RoutingMode for the textual representation
+// "history" or "hash", otherwise an error. This allows for easy switching the
+// routing mode when reloading configurations.
+func ParseRoutingMode(s string) (RoutingMode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "history":
+		return HistoryRouting, nil
+	case "hash":
+		return HashRouting, nil
+	}
+	return HistoryRouting, fmt.Errorf("invalid routing mode %q", s)
+}
+
+// WithRoutingMode sets the initial RoutingMode of a new SPAHandler.
+func WithRoutingMode(mode RoutingMode) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.routingMode.Store(int32(mode))
+	}
+}
+
+// RoutingMode returns the current RoutingMode of this SPAHandler.
+func (h *SPAHandler) RoutingMode() RoutingMode {
+	return RoutingMode(h.routingMode.Load())
+}
+
+// SetRoutingMode switches the RoutingMode of this SPAHandler at runtime,
+// without the need to recreate the handler. It is safe to call SetRoutingMode
+// concurrently while serving requests, such as from an admin API or a
+// configuration reload, in order to quickly mitigate broken deep links in
+// production.
+func (h *SPAHandler) SetRoutingMode(mode RoutingMode) {
+	h.routingMode.Store(int32(mode))
+}
+
+// redirectToHashRoute redirects a request path not matching any static asset to
+// the equivalent hash route, returning true. It doesn't redirect when not in
+// HashRouting mode or when the request path already is the SPA's root, and
+// returns false instead.
+//
+// IMPORTANT: the passed r.URL.Path must have already been sanitized.
+func (h *SPAHandler) redirectToHashRoute(w http.ResponseWriter, r *http.Request) bool {
+	if h.RoutingMode() != HashRouting || r.URL.Path == "/" {
+		return false
+	}
+	route := r.URL.Path
+	if r.URL.RawQuery != "" {
+		route += "?" + r.URL.RawQuery
+	}
+	http.Redirect(w, r, escapedPath(h.basename(r))+"#"+route, http.StatusFound)
+	return true
+}
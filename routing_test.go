@@ -0,0 +1,71 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("routing modes", func() {
+
+	DescribeTable("parses routing modes",
+		func(s string, expected RoutingMode) {
+			mode := Successful(ParseRoutingMode(s))
+			Expect(mode).To(Equal(expected))
+			Expect(mode.String()).To(Equal(s))
+		},
+		Entry(nil, "history", HistoryRouting),
+		Entry(nil, "hash", HashRouting),
+	)
+
+	It("rejects invalid routing modes", func() {
+		Expect(ParseRoutingMode("foo")).Error().To(HaveOccurred())
+		Expect(RoutingMode(42).String()).To(Equal("RoutingMode(42)"))
+	})
+
+	It("switches between history and hash routing at runtime", func() {
+		h := NewSPAHandler(embStaticFs, "index.html", WithRoutingMode(HashRouting))
+		Expect(h.RoutingMode()).To(Equal(HashRouting))
+
+		serve := func(path string) *http.Response {
+			r := &http.Request{
+				Method: "GET",
+				URL:    Successful(url.Parse("http://foo.bar:12345" + path)),
+				Header: http.Header{
+					ForwardedPrefixHeader: []string{"/app"},
+				},
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			return w.Result()
+		}
+
+		resp := serve("/users/42?tab=profile")
+		Expect(resp.StatusCode).To(Equal(http.StatusFound))
+		Expect(resp.Header.Get("Location")).To(Equal("/app/#/users/42?tab=profile"))
+		Expect(serve("/").StatusCode).To(Equal(http.StatusOK))
+		Expect(serve("/static/js/some.js").StatusCode).To(Equal(http.StatusOK))
+
+		h.SetRoutingMode(HistoryRouting)
+		Expect(serve("/users/42").StatusCode).To(Equal(http.StatusOK))
+	})
+
+})
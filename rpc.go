@@ -0,0 +1,78 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// WithRPCPassthrough passes gRPC, gRPC-Web, and Connect requests on to the
+// specified handler, instead of serving them from the SPA, so that a single
+// port can host both the SPA and its RPC API without a separate mux guessing
+// paths. See IsRPCRequest for how RPC requests are detected. RPC requests are
+// passed on before checking the request method, as they are POST requests;
+// however, plain HTTP requests still get redirected first when using
+// WithHTTPSRedirect.
+//
+// For instance, with a gRPC-Web wrapped gRPC server:
+//
+//	h := NewSPAHandler(bundle, "index.html",
+//	    WithRPCPassthrough(grpcweb.WrapServer(grpcServer)))
+func WithRPCPassthrough(handler http.Handler) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.rpcHandler = handler
+	}
+}
+
+// IsRPCRequest returns true if the specified request is a gRPC, gRPC-Web, or
+// Connect request, based on its content type. These are requests with content
+// types “application/grpc”, “application/grpc-web”, and
+// “application/grpc-web-text”, each with optional “+codec” suffixes, as well
+// as “application/connect+codec” streaming requests. Unary Connect requests,
+// which use plain codec content types such as “application/json”, are
+// detected by their “Connect-Protocol-Version” header instead.
+func IsRPCRequest(r *http.Request) bool {
+	if r.Header.Get("Connect-Protocol-Version") != "" {
+		return true
+	}
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	mediaType, _, _ = strings.Cut(mediaType, "+")
+	switch mediaType {
+	case "application/grpc", "application/grpc-web", "application/grpc-web-text":
+		return true
+	case "application/connect":
+		return strings.HasPrefix(strings.ToLower(contentType), "application/connect+")
+	}
+	return false
+}
+
+// passRPC passes the specified RPC request on to the RPC handler, returning
+// true. Otherwise, nothing is served and false is returned.
+func (h *SPAHandler) passRPC(w http.ResponseWriter, r *http.Request) bool {
+	if h.rpcHandler == nil || !IsRPCRequest(r) {
+		return false
+	}
+	h.rpcHandler.ServeHTTP(w, r)
+	return true
+}
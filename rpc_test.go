@@ -0,0 +1,85 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RPC passthrough", func() {
+
+	spafs := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<base href="./" />INDEX`)},
+	}
+
+	rpc := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/grpc-web+proto")
+		_, _ = w.Write([]byte("RPC " + r.URL.Path))
+	})
+
+	DescribeTable("detecting RPC requests",
+		func(contentType string, connectVersion string, expected bool) {
+			r := httptest.NewRequest(http.MethodPost, "/acme.v1.Service/Method", nil)
+			if contentType != "" {
+				r.Header.Set("Content-Type", contentType)
+			}
+			if connectVersion != "" {
+				r.Header.Set("Connect-Protocol-Version", connectVersion)
+			}
+			Expect(IsRPCRequest(r)).To(Equal(expected))
+		},
+		Entry(nil, "", "", false),
+		Entry(nil, "text/html", "", false),
+		Entry(nil, "application/json", "", false),
+		Entry(nil, "application/json", "1", true),
+		Entry(nil, "application/grpc", "", true),
+		Entry(nil, "application/grpc+proto", "", true),
+		Entry(nil, "application/grpc-web", "", true),
+		Entry(nil, "Application/gRPC-Web+json; charset=utf-8", "", true),
+		Entry(nil, "application/grpc-web-text", "", true),
+		Entry(nil, "application/grpc-websocket", "", false),
+		Entry(nil, "application/connect+proto", "", true),
+		Entry(nil, "application/connect", "", false),
+		Entry(nil, "application/;", "", false),
+	)
+
+	It("passes RPC requests on", func() {
+		h := NewSPAHandler(spafs, "index.html", WithRPCPassthrough(rpc))
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/acme.v1.Service/Method", nil)
+		r.Header.Set("Content-Type", "application/grpc-web+proto")
+		Expect(h.serve(w, r)).To(Equal(OutcomePassedOn))
+		Expect(w.Body.String()).To(Equal("RPC /acme.v1.Service/Method"))
+
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/acme.v1.Service/Method", nil))
+		Expect(w.Body.String()).To(HaveSuffix("INDEX"))
+	})
+
+	It("rejects RPC requests without passthrough", func() {
+		h := NewSPAHandler(spafs, "index.html")
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/acme.v1.Service/Method", nil)
+		r.Header.Set("Content-Type", "application/grpc-web+proto")
+		h.ServeHTTP(w, r)
+		Expect(w.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+})
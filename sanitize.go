@@ -0,0 +1,94 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"errors"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// ErrInvalidPath signals a request path that has been rejected because of
+// containing control characters, backslashes, or (multiply) encoded traversal
+// sequences. It gets normalized into 400 Bad Request.
+var ErrInvalidPath = errors.New("invalid request path")
+
+// maxUnescapeRounds limits the number of rounds of unescaping request paths
+// that are multiply encoded.
+const maxUnescapeRounds = 4
+
+// SanitizePath returns the specified (already once decoded) request URL path
+// as an absolute and cleaned path, so that it cannot traverse outside the root
+// directory. Slapping "/" onto the path ensures that path.Clean does NOT use
+// the current working dir for resolving the request path ... whichever current
+// working directory it might be at the moment is.
+//
+// Unlike path.Clean alone, SanitizePath rejects paths with ErrInvalidPath that
+// contain control characters, such as encoded NULs, or backslashes, as well as
+// paths that still contain such characters, “..” elements, or slashes after
+// further rounds of decoding. Such multiply encoded paths are typically
+// produced by misbehaving or mangling proxies, or are outright attacks.
+func SanitizePath(uripath string) (string, error) {
+	decoded := uripath
+	for round := 0; ; round++ {
+		if hasInvalidPathChars(decoded) {
+			return "", ErrInvalidPath
+		}
+		if round > 0 && hasTraversal(decoded) {
+			return "", ErrInvalidPath
+		}
+		if !strings.Contains(decoded, "%") {
+			break
+		}
+		if round == maxUnescapeRounds {
+			return "", ErrInvalidPath
+		}
+		unescaped, err := url.PathUnescape(decoded)
+		if err != nil || unescaped == decoded {
+			// Not a valid encoding (anymore), so these are just percent
+			// characters.
+			break
+		}
+		if strings.Count(unescaped, "/") != strings.Count(decoded, "/") {
+			// Multiply encoded slashes would turn into additional path
+			// elements whenever something downstream decodes once more.
+			return "", ErrInvalidPath
+		}
+		decoded = unescaped
+	}
+	return path.Clean("/" + uripath), nil
+}
+
+// hasInvalidPathChars returns true if the path contains control characters or
+// backslashes.
+func hasInvalidPathChars(p string) bool {
+	for i := 0; i < len(p); i++ {
+		if c := p[i]; c < 0x20 || c == 0x7f || c == '\\' {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTraversal returns true if the path contains a “..” element.
+func hasTraversal(p string) bool {
+	for _, element := range strings.Split(p, "/") {
+		if element == ".." {
+			return true
+		}
+	}
+	return false
+}
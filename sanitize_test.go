@@ -0,0 +1,94 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("request path sanitization", func() {
+
+	DescribeTable("sanitizes paths",
+		func(uripath string, expected string) {
+			Expect(SanitizePath(uripath)).To(Equal(expected))
+		},
+		Entry(nil, "", "/"),
+		Entry(nil, "/", "/"),
+		Entry(nil, "foo/bar", "/foo/bar"),
+		Entry(nil, "/foo/../../bar", "/bar"),
+		Entry(nil, "//foo//bar/", "/foo/bar"),
+		Entry(nil, "/100%.png", "/100%.png"),
+		Entry(nil, "/foo%20bar", "/foo%20bar"),
+	)
+
+	DescribeTable("rejects tricky paths",
+		func(uripath string) {
+			Expect(SanitizePath(uripath)).Error().To(MatchError(ErrInvalidPath))
+		},
+		Entry("NUL", "/foo\x00.js"),
+		Entry("control character", "/foo\n"),
+		Entry("backslash", "/..\\..\\etc\\passwd"),
+		Entry("double-encoded dots", "/%2e%2e/etc/passwd"),
+		Entry("double-encoded slash", "/..%2f..%2fetc"),
+		Entry("double-encoded NUL", "/foo%00.js"),
+		Entry("double-encoded backslash", "/%5c..%5cfoo"),
+		Entry("triple-encoded dots", "/%252e%252e/etc/passwd"),
+		Entry("excessively encoded", "/%2525252525252e"),
+	)
+
+	DescribeTable("rejects tricky requests with 400",
+		func(target string) {
+			h := NewSPAHandler(embStaticFs, "index.html")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+		},
+		Entry("encoded NUL", "/foo%00.js"),
+		Entry("encoded backslash", "/%5c..%5cfoo"),
+		Entry("double-encoded dots", "/%252e%252e/index.html"),
+	)
+
+})
+
+func FuzzSanitizePath(f *testing.F) {
+	for _, seed := range []string{
+		"", "/", "/foo/bar", "/../..", "/%2e%2e/", "/%252e%252e/", "/foo\x00",
+		"\\..\\", "/%c0%ae%c0%ae/", "/..%2f", "%", "/%zz",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, uripath string) {
+		sanitized, err := SanitizePath(uripath)
+		if err != nil {
+			return
+		}
+		if !strings.HasPrefix(sanitized, "/") {
+			t.Errorf("sanitized path %q of %q not absolute", sanitized, uripath)
+		}
+		if path.Clean(sanitized) != sanitized {
+			t.Errorf("sanitized path %q of %q not clean", sanitized, uripath)
+		}
+		if hasTraversal(sanitized) || hasInvalidPathChars(sanitized) {
+			t.Errorf("sanitized path %q of %q still tricky", sanitized, uripath)
+		}
+	})
+}
@@ -0,0 +1,172 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"html/template"
+	"io/fs"
+	"sort"
+	"time"
+)
+
+// indexSegments is the contents of an index file, pre-split at the href
+// values of its base elements and at its import map addresses. Rewriting the
+// base of the index then is simply joining the parts with the (escaped) base
+// path, instead of a regexp pass on each and every request.
+type indexSegments struct {
+	name    string    // (unrooted) path and name of the index file.
+	modTime time.Time // modification time of the index file.
+	size    int64     // size of the index file, as stated.
+	parts   []string  // index contents split at base href values.
+
+	tmpl     *template.Template // optional parsed index template.
+	preloads []preloadLink      // optional scripts and stylesheets to preload.
+}
+
+// splitAtBase splits the specified HTML document contents at the href values
+// of its base elements, dropping the href values.
+func splitAtBase(html string) []string {
+	return splitAtCuts(html, baseCuts(html))
+}
+
+// splitIndex splits the specified index contents at the href values of its
+// base elements, as well as at the rooted and relative addresses in its import
+// maps, so that joining the parts with the base path rewrites the base and
+// the import maps in one go.
+func splitIndex(html string) []string {
+	cuts := append(baseCuts(html), importMapCuts(html)...)
+	sort.Slice(cuts, func(i, j int) bool { return cuts[i][0] < cuts[j][0] })
+	return splitAtCuts(html, cuts)
+}
+
+// indexParts returns the parts of the specified index contents to be joined
+// with the base path. When base rewriting has been disabled using
+// WithoutBaseRewrite, it skips splitting the index contents and returns them
+// as a single part instead, so they get served as is.
+func (h *SPAHandler) indexParts(html string) []string {
+	if h.keepBase {
+		return []string{html}
+	}
+	return splitIndex(html)
+}
+
+// baseCuts returns the start and end positions of the href values of the base
+// elements in the specified HTML document contents.
+func baseCuts(html string) [][2]int {
+	matches := baseRe.FindAllStringSubmatchIndex(html, -1)
+	cuts := make([][2]int, 0, len(matches))
+	for _, match := range matches {
+		// ...from the end of "${1}" to the beginning of "${2}"
+		cuts = append(cuts, [2]int{match[3], match[4]})
+	}
+	return cuts
+}
+
+// splitAtCuts splits the specified HTML document contents at the specified
+// sorted, non-overlapping cuts, dropping the cut out contents.
+func splitAtCuts(html string, cuts [][2]int) []string {
+	parts := make([]string, 0, len(cuts)+1)
+	last := 0
+	for _, cut := range cuts {
+		parts = append(parts, html[last:cut[0]])
+		last = cut[1]
+	}
+	return append(parts, html[last:])
+}
+
+// join returns the index contents with the specified base path in the href
+// values of its base elements.
+func (s *indexSegments) join(base string) []byte {
+	joined := make([]byte, 0, s.len(base))
+	for idx, part := range s.parts {
+		if idx > 0 {
+			joined = append(joined, base...)
+		}
+		joined = append(joined, part...)
+	}
+	return joined
+}
+
+// len returns the length of the index contents with the specified base path
+// in the href values of its base elements.
+func (s *indexSegments) len(base string) int64 {
+	size := int64(len(base) * (len(s.parts) - 1))
+	for _, part := range s.parts {
+		size += int64(len(part))
+	}
+	return size
+}
+
+// loadIndex returns the named index file of the specified bundle pre-split
+// into its segments. The segments are cached and only get reloaded when the
+// index file changes its modification time or size. Concurrent requests
+// finding the cache stale, such as in a burst after a deployment, share a
+// single reload.
+func (h *SPAHandler) loadIndex(b *bundle, name string) (*indexSegments, error) {
+	fileInfo, err := fs.Stat(b.fs, name)
+	if err != nil {
+		return nil, err
+	}
+	if segs := b.cachedSegments(name, fileInfo); segs != nil {
+		return segs, nil
+	}
+	b.segmentsMu.Lock()
+	defer b.segmentsMu.Unlock()
+	// Some other request might have already reloaded the index while we were
+	// waiting for our turn...
+	if segs := b.cachedSegments(name, fileInfo); segs != nil {
+		return segs, nil
+	}
+	if err := h.checkIndexSize(name, fileInfo); err != nil {
+		return nil, err
+	}
+	f, err := b.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	buff := getBuffer()
+	defer putBuffer(buff)
+	if err := h.readIndex(buff, name, f); err != nil {
+		return nil, err
+	}
+	segs := &indexSegments{
+		name:    name,
+		modTime: fileInfo.ModTime(),
+		size:    fileInfo.Size(),
+		parts:   h.indexParts(h.injectLiveReload(h.injectVersionMeta(h.substitutePlaceholders(buff.String())))),
+	}
+	if h.discoversPreloads() {
+		segs.preloads = extractPreloads(buff.String())
+	}
+	if segs.tmpl, err = h.parseIndexTemplate(name, segs.parts); err != nil {
+		return nil, err
+	}
+	b.segments.Store(name, segs)
+	return segs, nil
+}
+
+// cachedSegments returns the cached segments of the named index if they are
+// still valid for the index file with the specified file info, otherwise nil.
+func (b *bundle) cachedSegments(name string, fileInfo fs.FileInfo) *indexSegments {
+	v, ok := b.segments.Load(name)
+	if !ok {
+		return nil
+	}
+	if segs := v.(*indexSegments); segs.modTime.Equal(fileInfo.ModTime()) && segs.size == fileInfo.Size() {
+		return segs
+	}
+	return nil
+}
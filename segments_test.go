@@ -0,0 +1,107 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing/fstest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+// slowOpeningFS counts how often the index file gets opened, taking its time to
+// open it, while stat'ing files is quick.
+type slowOpeningFS struct {
+	fstest.MapFS
+	opens atomic.Int32
+}
+
+func (f *slowOpeningFS) Open(name string) (fs.File, error) {
+	if name == "index.html" {
+		f.opens.Add(1)
+		time.Sleep(50 * time.Millisecond)
+	}
+	return f.MapFS.Open(name)
+}
+
+var _ = Describe("index segments", func() {
+
+	DescribeTable("splits the index at its base elements",
+		func(html string, expected []string) {
+			segs := &indexSegments{parts: splitAtBase(html)}
+			Expect(segs.parts).To(Equal(expected))
+
+			h := NewSPAHandler(embStaticFs, "index.html")
+			r := httptest.NewRequest(http.MethodGet, "/foo", nil)
+			r.Header.Set(ForwardedPrefixHeader, "/$app")
+			joined := segs.join(h.escapedBase(r))
+			Expect(string(joined)).To(Equal(h.rewriteBase(r, html)))
+			Expect(segs.len(h.escapedBase(r))).To(Equal(int64(len(joined))))
+		},
+		Entry("empty", "", []string{""}),
+		Entry("no base", "<html><head></head></html>", []string{"<html><head></head></html>"}),
+		Entry("single base", `<head><base href="./" /></head>`,
+			[]string{`<head><base href="`, `" /></head>`}),
+		Entry("multiple bases", `<base href="./" /><base href="/foo/"/>trailer`,
+			[]string{`<base href="`, `" /><base href="`, `"/>trailer`}),
+	)
+
+	It("caches the index until it changes", func() {
+		mfs := fstest.MapFS{
+			"index.html": &fstest.MapFile{
+				Data:    []byte(`<base href="./" />old`),
+				ModTime: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+			},
+		}
+		h := NewSPAHandler(mfs, "index.html")
+		segs := Successful(h.loadIndex(h.current(), "index.html"))
+		Expect(segs.parts).To(ConsistOf(`<base href="`, `" />old`))
+		Expect(h.loadIndex(h.current(), "index.html")).To(BeIdenticalTo(segs))
+
+		mfs["index.html"] = &fstest.MapFile{
+			Data:    []byte(`<base href="./" />new!`),
+			ModTime: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		}
+		Expect(Successful(h.loadIndex(h.current(), "index.html")).parts).To(ConsistOf(`<base href="`, `" />new!`))
+	})
+
+	It("shares a single index load among concurrent requests", func() {
+		sfs := &slowOpeningFS{MapFS: fstest.MapFS{
+			"index.html": &fstest.MapFile{Data: []byte(`<base href="./" />`)},
+		}}
+		h := NewSPAHandler(sfs, "index.html")
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/foo", nil))
+				Expect(w.Code).To(Equal(http.StatusOK))
+			}()
+		}
+		wg.Wait()
+		Expect(sfs.opens.Load()).To(Equal(int32(1)))
+	})
+
+})
@@ -0,0 +1,179 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// DefaultShutdownTimeout is the default time Serve waits for active requests
+// to finish when gracefully shutting down.
+const DefaultShutdownTimeout = 10 * time.Second
+
+// ServeOption sets optional properties of serving with Serve.
+type ServeOption func(*serveConfig)
+
+// serveConfig is the configuration of Serve.
+type serveConfig struct {
+	server          *http.Server  // the server, preconfigured with sane timeouts.
+	shutdownTimeout time.Duration // maximum time to wait for active requests to finish.
+	h2c             bool          // serve unencrypted HTTP/2.
+	listener        net.Listener  // optional listener instead of listening on the address.
+}
+
+// WithShutdownTimeout sets the maximum time to wait for active requests to
+// finish when gracefully shutting down, overriding the DefaultShutdownTimeout.
+func WithShutdownTimeout(d time.Duration) ServeOption {
+	return func(c *serveConfig) {
+		c.shutdownTimeout = d
+	}
+}
+
+// WithH2C additionally serves unencrypted HTTP/2 (“h2c”), such as to reverse
+// proxies and service meshes talking HTTP/2 to their upstreams without TLS.
+// H2C is ignored when serving HTTPS, as HTTP/2 is then negotiated anyway.
+//
+// Please note that upgraded h2c connections aren't tracked by the graceful
+// shutdown, so their requests might get cut short.
+func WithH2C() ServeOption {
+	return func(c *serveConfig) {
+		c.h2c = true
+	}
+}
+
+// WithTLSConfig serves HTTPS instead of HTTP, using the specified TLS
+// configuration. The TLS configuration must provide certificates, either
+// statically or via GetCertificate.
+func WithTLSConfig(cfg *tls.Config) ServeOption {
+	return func(c *serveConfig) {
+		c.server.TLSConfig = cfg
+	}
+}
+
+// WithAutocert serves HTTPS instead of HTTP, automatically obtaining and
+// renewing certificates for the specified domains from Let's Encrypt using the
+// ACME protocol, thereby accepting Let's Encrypt's terms of service.
+// Certificates are cached in the specified directory, which should be
+// persistent so certificates survive restarts without running into rate
+// limits. For instance:
+//
+//	err := spaserve.Serve(ctx, ":443", handler,
+//	    spaserve.WithAutocert([]string{"example.com"}, "/var/cache/spaserve"))
+//
+// Domain validation uses the “tls-alpn-01” challenge, so the server must be
+// reachable on port 443 from the outside.
+func WithAutocert(domains []string, cacheDir string) ServeOption {
+	return func(c *serveConfig) {
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(domains...),
+			Cache:      autocert.DirCache(cacheDir),
+		}
+		cfg := m.TLSConfig()
+		cfg.MinVersion = tls.VersionTLS12
+		c.server.TLSConfig = cfg
+	}
+}
+
+// WithListener serves on the specified listener instead of listening on the
+// address passed to Serve, such as on listeners passed by a service manager.
+func WithListener(l net.Listener) ServeOption {
+	return func(c *serveConfig) {
+		c.listener = l
+	}
+}
+
+// WithServer calls the specified function with the http.Server used by Serve
+// before serving, so its settings can be adjusted as necessary, such as its
+// timeouts or its error logger.
+func WithServer(fn func(*http.Server)) ServeOption {
+	return func(c *serveConfig) {
+		fn(c.server)
+	}
+}
+
+// Serve serves the specified handler on the specified TCP address, such as
+// ":8080", until the passed context gets cancelled. It then gracefully shuts
+// down, waiting for active requests to finish for at most the shutdown
+// timeout. Serve returns nil after a graceful shutdown, otherwise an error.
+// For instance:
+//
+//	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+//	defer cancel()
+//	err := spaserve.Serve(ctx, ":8080", spaserve.NewSPAHandler(fsys, "index.html"))
+//
+// The http.Server used by Serve has sane timeouts limiting the time for
+// reading request headers and bodies, as well as keeping idle connections,
+// guarding against slow or stuck clients. There's no write timeout, as this
+// would break streaming responses, such as server-sent events.
+func Serve(ctx context.Context, addr string, handler http.Handler, opts ...ServeOption) error {
+	c := &serveConfig{
+		server: &http.Server{
+			Addr:              addr,
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       time.Minute,
+			IdleTimeout:       2 * time.Minute,
+			MaxHeaderBytes:    64 << 10,
+		},
+		shutdownTimeout: DefaultShutdownTimeout,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	srv := c.server
+	if c.h2c && srv.TLSConfig == nil {
+		srv.Handler = h2c.NewHandler(srv.Handler, &http2.Server{IdleTimeout: srv.IdleTimeout})
+	}
+	l := c.listener
+	if l == nil {
+		var err error
+		if l, err = net.Listen("tcp", srv.Addr); err != nil {
+			return err
+		}
+	}
+	done := make(chan error, 1)
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stopped:
+			return // ...serving failed, so there's nothing to shut down.
+		}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), c.shutdownTimeout)
+		defer cancel()
+		done <- srv.Shutdown(shutdownCtx)
+	}()
+	var err error
+	if srv.TLSConfig != nil {
+		err = srv.ServeTLS(l, "", "")
+	} else {
+		err = srv.Serve(l)
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return <-done
+}
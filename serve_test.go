@@ -0,0 +1,141 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"golang.org/x/net/http2"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("serving", func() {
+
+	hello := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Proto)
+	})
+
+	// serve serves the specified handler on a new listener in the background,
+	// returning the listener's address and a channel receiving the result of
+	// Serve.
+	serve := func(ctx context.Context, handler http.Handler, opts ...ServeOption) (string, <-chan error) {
+		l := Successful(net.Listen("tcp", "127.0.0.1:0"))
+		result := make(chan error, 1)
+		go func() {
+			result <- Serve(ctx, "", handler, append([]ServeOption{WithListener(l)}, opts...)...)
+		}()
+		return l.Addr().String(), result
+	}
+
+	get := func(client *http.Client, url string) string {
+		GinkgoHelper()
+		resp := Successful(client.Get(url))
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		return string(Successful(io.ReadAll(resp.Body)))
+	}
+
+	It("serves until cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		servers := make(chan *http.Server, 1)
+		addr, result := serve(ctx, hello, WithServer(func(srv *http.Server) { servers <- srv }))
+		var server *http.Server
+		Eventually(servers).Should(Receive(&server))
+		Expect(server.ReadHeaderTimeout).NotTo(BeZero())
+		Expect(server.WriteTimeout).To(BeZero())
+		Expect(get(http.DefaultClient, "http://"+addr)).To(Equal("HTTP/1.1"))
+		cancel()
+		Eventually(result).Should(Receive(BeNil()))
+	})
+
+	It("reports listening errors", func() {
+		Expect(Serve(context.Background(), "127.0.0.1:-1", hello)).NotTo(Succeed())
+	})
+
+	It("serves unencrypted HTTP/2", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		addr, _ := serve(ctx, hello, WithH2C())
+		client := &http.Client{Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, addr)
+			},
+		}}
+		Expect(get(client, "http://"+addr)).To(Equal("HTTP/2.0"))
+		Expect(get(http.DefaultClient, "http://"+addr)).To(Equal("HTTP/1.1"))
+	})
+
+	It("serves HTTPS", func() {
+		// Borrow a TLS configuration and a client trusting it.
+		ts := httptest.NewUnstartedServer(nil)
+		ts.EnableHTTP2 = true
+		ts.StartTLS()
+		tlsConfig := ts.TLS.Clone()
+		client := ts.Client()
+		ts.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		addr, _ := serve(ctx, hello, WithTLSConfig(tlsConfig), WithH2C())
+		Expect(get(client, "https://"+addr)).To(Equal("HTTP/2.0"))
+	})
+
+	It("configures automatic certificates", func() {
+		servers := make(chan *http.Server, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		addr, result := serve(ctx, hello,
+			WithAutocert([]string{"example.com"}, GinkgoT().TempDir()),
+			WithServer(func(s *http.Server) { servers <- s }))
+		var srv *http.Server
+		Eventually(servers).Should(Receive(&srv))
+		Expect(srv.TLSConfig).NotTo(BeNil())
+		Expect(srv.TLSConfig.MinVersion).To(Equal(uint16(tls.VersionTLS12)))
+		Expect(srv.TLSConfig.NextProtos).To(ContainElement("acme-tls/1"))
+
+		// Handshakes for domains other than the configured ones must fail
+		// without ever contacting the ACME CA.
+		_, err := tls.Dial("tcp", addr, &tls.Config{ServerName: "example.org"})
+		Expect(err).To(HaveOccurred())
+
+		cancel()
+		Eventually(result).Should(Receive(BeNil()))
+	})
+
+	It("limits graceful shutdowns", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		blocked := make(chan struct{})
+		DeferCleanup(func() { close(blocked) })
+		addr, result := serve(ctx, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			http.NewResponseController(w).Flush()
+			<-blocked
+		}), WithShutdownTimeout(100*time.Millisecond))
+		resp := Successful(http.Get("http://" + addr))
+		defer resp.Body.Close()
+		cancel()
+		Eventually(result).Should(Receive(MatchError(context.DeadlineExceeded)))
+	})
+
+})
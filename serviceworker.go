@@ -0,0 +1,84 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"bytes"
+	"io/fs"
+	"net/http"
+)
+
+// serviceWorker is the configuration for serving a service worker script.
+type serviceWorker struct {
+	path        string // rooted and cleaned path of the service worker script.
+	placeholder string // optional placeholder to replace with the base path.
+}
+
+// WithServiceWorker serves the service worker script at the specified path
+// (relative to the fs.FS of the SPA) with a “Service-Worker-Allowed” header
+// allowing the base path as the service worker's scope, and with
+// “Cache-Control: no-cache” so that browsers always check for service worker
+// updates. If placeholder is non-empty, all occurrences of placeholder inside
+// the script get replaced with the base path, such as when the script needs to
+// know its scope. For instance:
+//
+//	h := NewSPAHandler(bundle, "index.html",
+//	    WithServiceWorker("sw.js", "__SPA_BASE__"))
+//
+// The SPA then registers its service worker using a URL relative to the base,
+// with the scope being the base path:
+//
+//	navigator.serviceWorker.register("sw.js", { scope: "./" })
+func WithServiceWorker(path string, placeholder string) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.serviceWorker = &serviceWorker{
+			path:        cleanVirtualPath(path),
+			placeholder: placeholder,
+		}
+	}
+}
+
+// serveServiceWorker serves the service worker script, if configured and
+// requested, returning true. Otherwise, nothing is served and false is
+// returned.
+//
+// IMPORTANT: the passed r.URL.Path must have already been sanitized.
+func (h *SPAHandler) serveServiceWorker(w http.ResponseWriter, r *http.Request) bool {
+	if h.serviceWorker == nil || r.URL.Path != h.serviceWorker.path {
+		return false
+	}
+	name := r.URL.Path[1:]
+	fsys := h.bundleFor(r).fs
+	info, err := fs.Stat(fsys, name)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	contents, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		h.serveError(w, r, err)
+		return true
+	}
+	if h.serviceWorker.placeholder != "" {
+		contents = bytes.ReplaceAll(contents,
+			[]byte(h.serviceWorker.placeholder), []byte(h.escapedBase(r)))
+	}
+	h.callHooks(h.onStatic, r, name, false)
+	header := w.Header()
+	header.Set("Content-Type", "text/javascript; charset=utf-8")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Service-Worker-Allowed", h.escapedBase(r))
+	http.ServeContent(w, r, name, info.ModTime(), bytes.NewReader(contents))
+	return true
+}
@@ -0,0 +1,70 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("service workers", func() {
+
+	spafs := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<base href="./" />`)},
+		"sw.js":      &fstest.MapFile{Data: []byte(`const scope = "__SPA_BASE__"; cache("__SPA_BASE__index.html");`)},
+		"other.js":   &fstest.MapFile{Data: []byte(`"__SPA_BASE__"`)},
+	}
+
+	get := func(h http.Handler, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set(ForwardedPrefixHeader, "/app")
+		h.ServeHTTP(w, r)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		return w
+	}
+
+	It("serves the service worker with its scope", func() {
+		h := NewSPAHandler(spafs, "index.html", WithServiceWorker("/sw.js", "__SPA_BASE__"))
+		w := get(h, "/sw.js")
+		Expect(w.Header().Get("Service-Worker-Allowed")).To(Equal("/app/"))
+		Expect(w.Header().Get("Cache-Control")).To(Equal("no-cache"))
+		Expect(w.Header().Get("Content-Type")).To(HavePrefix("text/javascript"))
+		Expect(w.Body.String()).To(Equal(`const scope = "/app/"; cache("/app/index.html");`))
+
+		w = get(h, "/other.js")
+		Expect(w.Header().Get("Service-Worker-Allowed")).To(BeEmpty())
+		Expect(w.Body.String()).To(Equal(`"__SPA_BASE__"`))
+	})
+
+	It("serves the service worker without placeholder", func() {
+		h := NewSPAHandler(spafs, "index.html", WithServiceWorker("sw.js", ""))
+		w := get(h, "/sw.js")
+		Expect(w.Header().Get("Service-Worker-Allowed")).To(Equal("/app/"))
+		Expect(w.Body.String()).To(ContainSubstring(`"__SPA_BASE__"`))
+	})
+
+	It("falls back to the index for a missing service worker", func() {
+		h := NewSPAHandler(spafs, "index.html", WithServiceWorker("service-worker.js", ""))
+		w := get(h, "/service-worker.js")
+		Expect(w.Header().Get("Service-Worker-Allowed")).To(BeEmpty())
+		Expect(w.Body.String()).To(Equal(`<base href="/app/" />`))
+	})
+
+})
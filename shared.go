@@ -0,0 +1,83 @@
+// Copyright 2022 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// sharedAssets describes a set of asset directories that are served from a
+// common (shared) fs.FS instead of an SPA's own fs.FS.
+type sharedAssets struct {
+	fs          fs.FS        // the common FS to serve the shared assets from.
+	dirs        []string     // rooted and cleaned directory paths, such as "/vendor".
+	fileHandler http.Handler // FS adapted to http's file serving handler needs.
+}
+
+// WithSharedAssets serves the specified asset directories from the specified
+// (common) fsys instead of the SPA's own fs.FS. The directories are
+// slash-separated paths that are looked up unchanged in fsys. For instance, in
+// an embedded layout with the SPAs in “admin/” and “shop/”, as well as the
+// vendor chunks they share in “vendor/”, a request for “/vendor/react.js” is
+// served from “vendor/react.js” in fsys.
+//
+// Requests for missing assets inside the shared directories never fall back to
+// the index, but instead are answered with 404.
+//
+// WithSharedAssets is especially useful when passed as a common option to
+// NewMultiSPAHandler, so that multiple SPAs share the same vendor chunks
+// without the need to embed the same bytes multiple times.
+func WithSharedAssets(fsys fs.FS, dirs ...string) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		if fsys == nil {
+			h.invalidOption("WithSharedAssets", "nil fs.FS")
+		}
+		shared := sharedAssets{
+			fs:          fsys,
+			fileHandler: newFileServer(fsys),
+		}
+		for _, dir := range dirs {
+			dir = path.Clean("/" + dir)
+			if dir == "/" {
+				continue // ...sharing everything would leave nothing for the SPA.
+			}
+			shared.dirs = append(shared.dirs, dir)
+		}
+		h.shared = append(h.shared, shared)
+	}
+}
+
+// serveSharedAsset serves the requested resource from a shared FS if the
+// request path lies inside one of the shared asset directories, returning true.
+// Otherwise, it returns false without having served anything.
+//
+// IMPORTANT: the passed r.URL.Path must have already been sanitized.
+func (h *SPAHandler) serveSharedAsset(w http.ResponseWriter, r *http.Request) bool {
+	for _, shared := range h.shared {
+		for _, dir := range shared.dirs {
+			if !strings.HasPrefix(r.URL.Path, dir+"/") {
+				continue
+			}
+			if !h.serveStaticAssetFrom(shared.fs, h.wrapAssetHandler(shared.fileHandler), true, w, r) {
+				h.serveNotFound(w, r)
+			}
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("shared assets", func() {
+
+	DescribeTable("serves own and shared assets",
+		func(path string, expectedStatus int, expectedCanary string) {
+			multiFs := Successful(fs.Sub(embStaticFs, "multi"))
+			h := NewSPAHandler(Successful(fs.Sub(multiFs, "admin")), "index.html",
+				WithSharedAssets(multiFs, "/vendor/", ""))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			Expect(w.Result().StatusCode).To(Equal(expectedStatus))
+			Expect(w.Body.String()).To(ContainSubstring(expectedCanary))
+		},
+		Entry(nil, "/admin.js", http.StatusOK, "CANARY ADMIN JS"),
+		Entry(nil, "/vendor/chunk.js", http.StatusOK, "CANARY VENDOR"),
+		Entry(nil, "/vendor/missing.js", http.StatusNotFound, ""),
+		Entry(nil, "/users/42", http.StatusOK, "CANARY ADMIN"),
+	)
+
+})
@@ -0,0 +1,110 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TrailingSlashPolicy specifies how an SPAHandler treats trailing slashes of
+// SPA route paths, that is, request paths falling back to the index.
+type TrailingSlashPolicy int
+
+const (
+	// TrailingSlashLeave serves SPA route paths with and without trailing
+	// slashes alike. This is the default.
+	TrailingSlashLeave TrailingSlashPolicy = iota
+	// TrailingSlashAdd redirects SPA route paths without a trailing slash to
+	// the same path with a trailing slash, such as “/app/users” to
+	// “/app/users/”.
+	TrailingSlashAdd
+	// TrailingSlashRemove redirects SPA route paths with a trailing slash to
+	// the same path without the trailing slash, such as “/app/users/” to
+	// “/app/users”.
+	TrailingSlashRemove
+)
+
+// String returns the textual representation of a TrailingSlashPolicy, such as
+// "leave", "add", or "remove".
+func (p TrailingSlashPolicy) String() string {
+	switch p {
+	case TrailingSlashLeave:
+		return "leave"
+	case TrailingSlashAdd:
+		return "add"
+	case TrailingSlashRemove:
+		return "remove"
+	}
+	return fmt.Sprintf("TrailingSlashPolicy(%d)", int(p))
+}
+
+// trailingSlashCtxKey is the context key for requests with original paths
+// having a trailing slash.
+type trailingSlashCtxKey struct{}
+
+// WithTrailingSlashPolicy sets the policy for trailing slashes of SPA route
+// paths, redirecting with 308 Permanent Redirect where necessary. The
+// redirects are base-aware, that is, they point at the externally visible
+// request path, including the prefix passed by forwarding proxies. The SPA's
+// base path itself always keeps its trailing slash, and requests for static
+// assets are never redirected.
+//
+// Make sure to configure any proxies in front of the SPAHandler with the same
+// trailing slash policy, as otherwise clients end up in redirect loops.
+func WithTrailingSlashPolicy(p TrailingSlashPolicy) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.trailingSlash = p
+	}
+}
+
+// markTrailingSlash returns the specified request marked as having a trailing
+// slash if the specified original, unsanitized request path has one, and the
+// trailing slash policy isn't to leave trailing slashes alone. Otherwise, the
+// request is returned as is.
+func (h *SPAHandler) markTrailingSlash(r *http.Request, uripath string) *http.Request {
+	if h.trailingSlash == TrailingSlashLeave || !strings.HasSuffix(uripath, "/") {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), trailingSlashCtxKey{}, true))
+}
+
+// redirectTrailingSlash redirects an SPA route path not conforming to the
+// trailing slash policy, returning true. Otherwise, nothing is served and false
+// is returned.
+//
+// IMPORTANT: the passed r.URL.Path must have already been sanitized.
+func (h *SPAHandler) redirectTrailingSlash(w http.ResponseWriter, r *http.Request) bool {
+	if h.trailingSlash == TrailingSlashLeave || r.URL.Path == "/" {
+		return false
+	}
+	slashed, _ := r.Context().Value(trailingSlashCtxKey{}).(bool)
+	var location string
+	switch {
+	case h.trailingSlash == TrailingSlashAdd && !slashed:
+		location = escapedPath(h.originalReqPath(r)) + "/"
+	case h.trailingSlash == TrailingSlashRemove && slashed:
+		location = escapedPath(h.originalReqPath(r))
+	default:
+		return false
+	}
+	if r.URL.RawQuery != "" {
+		location += "?" + r.URL.RawQuery
+	}
+	http.Redirect(w, r, location, http.StatusPermanentRedirect)
+	return true
+}
@@ -0,0 +1,75 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("trailing slash policies", func() {
+
+	spafs := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<base href="./" />`)},
+		"main.js":    &fstest.MapFile{Data: []byte(`main()`)},
+	}
+
+	It("returns textual representations", func() {
+		Expect(TrailingSlashLeave.String()).To(Equal("leave"))
+		Expect(TrailingSlashAdd.String()).To(Equal("add"))
+		Expect(TrailingSlashRemove.String()).To(Equal("remove"))
+		Expect(TrailingSlashPolicy(42).String()).To(Equal("TrailingSlashPolicy(42)"))
+	})
+
+	DescribeTable("redirects SPA route paths",
+		func(policy TrailingSlashPolicy, url string, expectedCode int, expectedLocation string) {
+			h := NewSPAHandler(spafs, "index.html", WithTrailingSlashPolicy(policy))
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, url, nil)
+			r.Header.Set(ForwardedPrefixHeader, "/app")
+			h.ServeHTTP(w, r)
+			Expect(w.Code).To(Equal(expectedCode))
+			Expect(w.Header().Get("Location")).To(Equal(expectedLocation))
+		},
+		Entry("leave without slash", TrailingSlashLeave, "/users", http.StatusOK, ""),
+		Entry("leave with slash", TrailingSlashLeave, "/users/", http.StatusOK, ""),
+		Entry("add", TrailingSlashAdd, "/users/42?tab=1", http.StatusPermanentRedirect, "/app/users/42/?tab=1"),
+		Entry("already added", TrailingSlashAdd, "/users/42/", http.StatusOK, ""),
+		Entry("remove", TrailingSlashRemove, "/users/42/?tab=1", http.StatusPermanentRedirect, "/app/users/42?tab=1"),
+		Entry("already removed", TrailingSlashRemove, "/users/42", http.StatusOK, ""),
+		Entry("never the base", TrailingSlashRemove, "/", http.StatusOK, ""),
+		Entry("never assets", TrailingSlashAdd, "/main.js", http.StatusOK, ""),
+		Entry("escaped", TrailingSlashAdd, "/a%20b", http.StatusPermanentRedirect, "/app/a%20b/"),
+	)
+
+	It("keeps trailing slashes of mounted SPAs", func() {
+		h := NewMultiSPAHandler(map[string]SPAConfig{
+			"app": {FS: spafs, Index: "index.html"},
+		}, WithTrailingSlashPolicy(TrailingSlashRemove))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/app/users/", nil))
+		Expect(w.Code).To(Equal(http.StatusPermanentRedirect))
+		Expect(w.Header().Get("Location")).To(Equal("/app/users"))
+
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/app/", nil))
+		Expect(w.Code).To(Equal(http.StatusOK))
+	})
+
+})
@@ -0,0 +1,77 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// sourceMapAccess restricts access to source maps.
+type sourceMapAccess struct {
+	header string // name of the request header carrying the secret, if any.
+	secret string // secret granting access to source maps.
+}
+
+// WithoutSourceMaps refuses to serve source maps, that is, files ending in
+// “.map”, answering with 404 instead. This way, production deployments don't
+// leak the full frontend sources even if the bundle contains source maps.
+func WithoutSourceMaps() SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.sourceMaps = &sourceMapAccess{}
+	}
+}
+
+// WithSourceMapsSecret serves source maps, that is, files ending in “.map”,
+// only to requests carrying the specified header with the specified secret
+// value, answering all other source map requests with 404. Developers can then
+// opt into source maps in production deployments, such as by configuring their
+// browsers to send the header:
+//
+//	h := NewSPAHandler(fsys, "index.html",
+//	    WithSourceMapsSecret("X-Sourcemap-Secret", os.Getenv("SOURCEMAP_SECRET")))
+//
+// An empty header name or secret refuses to serve source maps at all, same as
+// WithoutSourceMaps.
+func WithSourceMapsSecret(header string, secret string) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.sourceMaps = &sourceMapAccess{header: header, secret: secret}
+	}
+}
+
+// hidesSourceMap returns true if the request is for a source map that must not
+// be served to this request. As responses to source map requests then depend
+// on the secret header, it gets added to the Vary response header.
+//
+// IMPORTANT: the passed r.URL.Path must have already been sanitized.
+func (h *SPAHandler) hidesSourceMap(w http.ResponseWriter, r *http.Request) bool {
+	if h.sourceMaps == nil || !strings.HasSuffix(strings.ToLower(r.URL.Path), ".map") {
+		return false
+	}
+	if h.sourceMaps.header != "" {
+		w.Header().Add("Vary", h.sourceMaps.header)
+	}
+	return !h.sourceMaps.granted(r)
+}
+
+// granted returns true if the request carries the secret granting access to
+// source maps.
+func (a *sourceMapAccess) granted(r *http.Request) bool {
+	if a.header == "" || a.secret == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get(a.header)), []byte(a.secret)) == 1
+}
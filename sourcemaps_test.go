@@ -0,0 +1,66 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("source map exposure", func() {
+
+	spafs := fstest.MapFS{
+		"index.html":  &fstest.MapFile{Data: []byte(`<base href="./" />`)},
+		"main.js":     &fstest.MapFile{Data: []byte(`main()`)},
+		"main.js.map": &fstest.MapFile{Data: []byte(`{"version":3}`)},
+	}
+
+	DescribeTable("controls access to source maps",
+		func(opt SPAHandlerOption, path string, secret string, expectedStatus int) {
+			h := NewSPAHandler(spafs, "index.html", opt)
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, path, nil)
+			if secret != "" {
+				r.Header.Set("X-Sourcemap-Secret", secret)
+			}
+			h.ServeHTTP(w, r)
+			Expect(w.Code).To(Equal(expectedStatus))
+		},
+		Entry("blocked", WithoutSourceMaps(), "/main.js.map", "", http.StatusNotFound),
+		Entry("blocked, upper-case", WithoutSourceMaps(), "/main.js.MAP", "", http.StatusNotFound),
+		Entry("blocked, even with secret", WithoutSourceMaps(), "/main.js.map", "sesame", http.StatusNotFound),
+		Entry("other assets unaffected", WithoutSourceMaps(), "/main.js", "", http.StatusOK),
+		Entry("missing secret", WithSourceMapsSecret("X-Sourcemap-Secret", "sesame"), "/main.js.map", "", http.StatusNotFound),
+		Entry("wrong secret", WithSourceMapsSecret("X-Sourcemap-Secret", "sesame"), "/main.js.map", "sesam", http.StatusNotFound),
+		Entry("correct secret", WithSourceMapsSecret("X-Sourcemap-Secret", "sesame"), "/main.js.map", "sesame", http.StatusOK),
+		Entry("empty secret configured", WithSourceMapsSecret("X-Sourcemap-Secret", ""), "/main.js.map", "sesame", http.StatusNotFound),
+	)
+
+	It("varies source map responses on the secret header", func() {
+		h := NewSPAHandler(spafs, "index.html", WithSourceMapsSecret("X-Sourcemap-Secret", "sesame"))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/main.js.map", nil))
+		Expect(w.Header().Values("Vary")).To(ContainElement("X-Sourcemap-Secret"))
+
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/main.js", nil))
+		Expect(w.Header().Values("Vary")).NotTo(ContainElement("X-Sourcemap-Secret"))
+	})
+
+})
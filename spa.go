@@ -15,14 +15,17 @@
 package spaserve
 
 import (
-	"io"
+	"bytes"
+	"context"
 	"io/fs"
+	"log/slog"
 	"net/http"
-	"net/url"
 	"os"
 	"path"
 	"regexp"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 // ForwardedPrefixHeader, if present, specifies the prefix that need to be
@@ -52,10 +55,82 @@ var baseRe = regexp.MustCompile(`(<base href=").*?("\s*/>)`)
 // are automatically adjusted to the correct request base path, based on
 // forwarding proxy headers.
 type SPAHandler struct {
-	fs                fs.FS         // the FS to serve static resources from.
-	index             string        // (unrooted) path and name of the index/SPA file inside fs.
-	staticfileHandler http.Handler  // FS adapted to http's file serving handler needs.
-	indexRewriter     IndexRewriter // optional user function to rewrite the index/SPA file as necessary.
+	bundle            atomic.Pointer[bundle]          // the bundle currently served.
+	index             string                          // (unrooted) path and name of the index/SPA file inside fs.
+	indexRewriters    []IndexRewriterE                // optional user functions to rewrite the index/SPA file as necessary.
+	shared            []sharedAssets                  // optional shared asset directories served from a common FS.
+	assetNotFound     bool                            // answer missing asset-like paths with 404 instead of the index.
+	assetExts         []string                        // optional file extensions considered to be asset-like.
+	routingMode       atomic.Int32                    // RoutingMode for index fallbacks, settable at runtime.
+	indexRewriter     atomic.Pointer[IndexRewriter]   // optional IndexRewriter, settable at runtime.
+	assetWrappers     []ResponseWrapper               // optional wrappers of asset response writers.
+	spaPathPredicate  SPAPathPredicate                // optional decision whether to fall back to the index.
+	notFoundHandler   http.Handler                    // optional handler for rejected index fallbacks.
+	errorPages        map[int]string                  // optional error documents inside fs, by status code.
+	primer            *primer                         // optional request statistics for cache priming.
+	errorResponder    ErrorResponder                  // optional responder writing error responses.
+	errorLogger       *slog.Logger                    // optional logger for original, non-normalized errors.
+	accessLogger      *slog.Logger                    // optional logger for accessing requests.
+	observers         []Observer                      // optional observers of served requests.
+	onIndex           []ServeHook                     // optional hooks called before serving the index.
+	onStatic          []ServeHook                     // optional hooks called before serving static assets.
+	onError           []ErrorHook                     // optional hooks called on encountering errors.
+	cspPolicy         string                          // optional CSP policy with nonce placeholders.
+	header            http.Header                     // optional headers to set on all responses.
+	assetHeader       http.Header                     // optional headers to set on static asset responses.
+	integrityMode     IntegrityMode                   // optional post-pass fixing SRI attributes in the index.
+	deniedFiles       []string                        // optional glob patterns of files never to be served.
+	allowedExts       []string                        // optional file extensions of assets allowed to be served.
+	blockDotfiles     bool                            // refuse requests for dotfiles and hidden directories.
+	dotfileExceptions []string                        // optional dotfile or hidden directory names still served.
+	allowedMethods    []string                        // request methods served, GET and HEAD by default.
+	answerOptions     bool                            // answer OPTIONS requests instead of rejecting them.
+	cors              *CORSPreflight                  // optional CORS preflight configuration.
+	maxIndexSize      int64                           // optional maximum size of the index file.
+	canary            *canary                         // optional canary bundle.
+	indexSelector     IndexSelector                   // optional selection of index variants.
+	hosts             map[string]*bundle              // optional bundles by virtual host.
+	overlay           fs.FS                           // optional FS overlaying the bundle FS.
+	virtualFiles      map[string]VirtualFileGenerator // optional generated files, by rooted path.
+	placeholders      PlaceholderLookup               // optional placeholder values for the index.
+	indexTemplate     IndexTemplateData               // optional data for rendering the index as a template.
+	metaProvider      MetaProvider                    // optional meta tags to inject into the index.
+	titleFunc         TitleFunc                       // optional document titles by route.
+	serviceWorker     *serviceWorker                  // optional service worker script.
+	prerender         *prerender                      // optional pre-rendered snapshots for bots.
+	indexLanguages    map[string]string               // optional languages of localized index files, by lower-case tag.
+	legacyIndex       string                          // optional (unrooted) path and name of the legacy index file.
+	isLegacy          RequestMatcher                  // optional decision whether to serve the legacy index.
+	earlyHints        bool                            // send 103 Early Hints with preload links.
+	preloadLinks      []preloadLink                   // optional resources to preload.
+	discoverPreloads  bool                            // preload the resources referenced by the index.
+	devServer         *devServer                      // optional frontend dev server to proxy to.
+	liveReload        *liveReload                     // optional live reloading on file changes.
+	invalidation      *invalidation                   // optional cache invalidation on file changes.
+	assetETags        bool                            // serve assets with strong ETags from their contents.
+	indexCache        string                          // optional Cache-Control header value of the index.
+	assetCache        []cacheRule                     // optional cache policy rules of static assets.
+	mimeTypes         map[string]string               // optional media types by lower-case file extension.
+	indexContentType  string                          // optional Content-Type of the index.
+	sourceMaps        *sourceMapAccess                // optional restricted access to source maps.
+	cleanURLs         bool                            // serve “.html” pages for extensionless paths.
+	indexRedirect     int                             // optional status code of redirects from the index file.
+	trailingSlash     TrailingSlashPolicy             // policy for trailing slashes of SPA route paths.
+	directories       DirectoryPolicy                 // policy for request paths matching directories.
+	httpsRedirect     bool                            // redirect plain HTTP requests to HTTPS.
+	httpsPort         int                             // optional port to redirect plain HTTP requests to.
+	probes            *healthProbes                   // optional liveness and readiness probe endpoints.
+	versionMeta       *MetaTags                       // optional version meta tags to inject into the index.
+	authenticator     Authenticator                   // optional authentication of index requests.
+	csrf              *csrfToken                      // optional CSRF token injection into the index.
+	requestID         *requestID                      // optional request ID injection into the index.
+	rpcHandler        http.Handler                    // optional handler of gRPC(-Web) and Connect requests.
+	webSocketHandler  http.Handler                    // optional handler of WebSocket upgrade requests.
+	keepBase          bool                            // serve base elements and import maps as is.
+	entries           []entry                         // optional entry documents by route path prefix, longest first.
+	indexCandidates   []string                        // optional further index file candidates, in order.
+	optionErrs        []error                         // invalid option arguments, reported by NewSPAHandlerE.
+	headerOptions     map[string]bool                 // names of the options setting headers, without "With".
 }
 
 // NewSPAHandler returns a new HTTP handler serving static resources from the
@@ -72,14 +147,26 @@ type SPAHandler struct {
 //
 //	h := NewSPAHandler(os.DirFS("/opt/data/myspa"), "index.html")
 func NewSPAHandler(fs fs.FS, index string, opts ...SPAHandlerOption) *SPAHandler {
+	h := newSPAHandler(fs, index, opts...)
+	h.startInvalidation()
+	return h
+}
+
+// newSPAHandler returns a new SPAHandler with the specified options applied,
+// but without starting any background activities yet.
+func newSPAHandler(fs fs.FS, index string, opts ...SPAHandlerOption) *SPAHandler {
 	h := &SPAHandler{
-		fs:                fs,
-		staticfileHandler: http.FileServer(http.FS(fs)),
-		index:             path.Clean("/" + index)[1:],
+		index:            path.Clean("/" + index)[1:],
+		allowedMethods:   []string{http.MethodGet, http.MethodHead},
+		indexCache:       DefaultIndexCachePolicy().String(),
+		indexContentType: DefaultIndexContentType,
 	}
+	h.bundle.Store(newBundle(fs))
 	for _, opt := range opts {
 		opt(h)
 	}
+	h.applyOverlay()
+	h.hashBundles()
 	return h
 }
 
@@ -93,15 +180,146 @@ type SPAHandlerOption func(*SPAHandler)
 // SPAHandler.
 type IndexRewriter func(r *http.Request, index string) string
 
-// WithIndexRewriter sets the specified IndexRewriter that gets called before
+// WithIndexRewriter adds the specified IndexRewriter that gets called before
 // delivering the index/SPA file contents to requesting clients, allowing for
-// application-specific changes.
+// application-specific changes. WithIndexRewriter can be passed multiple
+// times in order to stack composable rewriters, such as for meta injection and
+// analytics snippets: the rewriters then get called in the order they were
+// added, each rewriter receiving the index as returned by its predecessor.
 func WithIndexRewriter(rewriter IndexRewriter) SPAHandlerOption {
+	return WithIndexRewriters(rewriter)
+}
+
+// WithIndexRewriters adds the specified IndexRewriters in the order given,
+// see WithIndexRewriter.
+func WithIndexRewriters(rewriters ...IndexRewriter) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		for _, rewriter := range rewriters {
+			if rewriter == nil {
+				continue
+			}
+			rewriter := rewriter
+			h.indexRewriters = append(h.indexRewriters,
+				func(r *http.Request, _ string, index []byte) ([]byte, error) {
+					return []byte(rewriter(r, string(index))), nil
+				})
+		}
+	}
+}
+
+// IndexBytesRewriter rewrites (parts) of an index/SPA file contents to be
+// delivered to a requesting client, similar to an IndexRewriter. In contrast
+// to an IndexRewriter, it works on byte slices, avoiding copying the index
+// contents into a string and back again on each index request.
+type IndexBytesRewriter func(r *http.Request, index []byte) []byte
+
+// WithIndexBytesRewriter adds the specified IndexBytesRewriter to the
+// rewriters called before delivering the index/SPA file contents to
+// requesting clients, see also WithIndexRewriter.
+func WithIndexBytesRewriter(rewriter IndexBytesRewriter) SPAHandlerOption {
+	return WithIndexBytesRewriters(rewriter)
+}
+
+// WithIndexBytesRewriters adds the specified IndexBytesRewriters in the order
+// given, see WithIndexBytesRewriter.
+func WithIndexBytesRewriters(rewriters ...IndexBytesRewriter) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		for _, rewriter := range rewriters {
+			if rewriter == nil {
+				continue
+			}
+			rewriter := rewriter
+			h.indexRewriters = append(h.indexRewriters,
+				func(r *http.Request, _ string, index []byte) ([]byte, error) {
+					return rewriter(r, index), nil
+				})
+		}
+	}
+}
+
+// IndexRewriterE rewrites (parts) of an index/SPA file contents to be
+// delivered to a requesting client, similar to an IndexRewriter. In contrast
+// to an IndexRewriter, it gets passed the base path of the SPA for the request
+// and can fail, returning an error instead of serving broken HTML.
+type IndexRewriterE func(r *http.Request, base string, index []byte) ([]byte, error)
+
+// WithIndexRewriterE adds the specified IndexRewriterE to the rewriters called
+// before delivering the index/SPA file contents to requesting clients, see
+// also WithIndexRewriter. If the rewriter returns an error, the error gets
+// normalized into an HTTP status code, as with NormalizedHttpError, and no
+// further rewriters are called. In order to map particular rewriter errors to
+// particular status codes, use RegisterErrorStatus.
+func WithIndexRewriterE(rewriter IndexRewriterE) SPAHandlerOption {
 	return func(h *SPAHandler) {
-		h.indexRewriter = rewriter
+		if rewriter == nil {
+			return
+		}
+		h.indexRewriters = append(h.indexRewriters, rewriter)
 	}
 }
 
+// IndexRewriterContext rewrites (parts) of an index/SPA file contents to be
+// delivered to a requesting client, similar to an IndexRewriterE. In contrast
+// to an IndexRewriterE, it gets passed the request context explicitly, so that
+// heavy rewriters, such as those calling out to template engines, can observe
+// the client disconnecting or the request deadline expiring and then bail out
+// early, returning the context's error.
+type IndexRewriterContext func(ctx context.Context, r *http.Request, base string, index []byte) ([]byte, error)
+
+// WithIndexRewriterContext adds the specified IndexRewriterContext to the
+// rewriters called before delivering the index/SPA file contents to
+// requesting clients, see also WithIndexRewriterE.
+func WithIndexRewriterContext(rewriter IndexRewriterContext) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		if rewriter == nil {
+			return
+		}
+		h.indexRewriters = append(h.indexRewriters,
+			func(r *http.Request, base string, index []byte) ([]byte, error) {
+				return rewriter(r.Context(), r, base, index)
+			})
+	}
+}
+
+// rewriteIndex passes the specified index contents through all index
+// rewriters in order, returning the final index contents, or the first error
+// returned by a rewriter. Rewriting is aborted with the context's error as
+// soon as the request context is done, such as when the client disconnected.
+func (h *SPAHandler) rewriteIndex(r *http.Request, index []byte) ([]byte, error) {
+	base := h.basename(r)
+	ctx := r.Context()
+	for _, rewriter := range h.indexRewriters {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var err error
+		if index, err = rewriter(r, base, index); err != nil {
+			return nil, err
+		}
+	}
+	if rewriter := h.indexRewriter.Load(); rewriter != nil {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		index = []byte((*rewriter)(r, string(index)))
+	}
+	return index, nil
+}
+
+// SetIndexRewriter sets the IndexRewriter of this SPAHandler at runtime,
+// without the need to recreate the handler; a nil rewriter removes a
+// previously set IndexRewriter. This IndexRewriter gets called after all the
+// rewriters added when creating the handler, such as using WithIndexRewriter.
+// It is safe to call SetIndexRewriter concurrently while serving requests, such
+// as to toggle a maintenance banner.
+func (h *SPAHandler) SetIndexRewriter(rewriter IndexRewriter) {
+	if rewriter == nil {
+		h.indexRewriter.Store(nil)
+		return
+	}
+	h.indexRewriter.Store(&rewriter)
+}
+
 // ServeHTTP either serves a static resource when available inside
 // SPAHandler.StaticAssetsPath or otherwise the specified Index asset inside the
 // static assets everywhere else. This behavior is required for SPAs with
@@ -110,14 +328,119 @@ func WithIndexRewriter(rewriter IndexRewriter) SPAHandlerOption {
 func (h *SPAHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Get the absolute and also cleaned path to the requested resource in order
 	// to prevent parent directory traversal outside the static assets
-	// directory. Slapping "/" ensures that path.Clean does NOT to use the
-	// current working dir for resolving the request path ... whichever current
-	// working directory it might be at the moment is.
-	r.URL.Path = path.Clean("/" + r.URL.Path)
-	if h.serveStaticAsset(w, r) {
+	// directory. Additionally, reject paths with encoded traversal tricks
+	// before they get anywhere near our fs.
+	setHeader(w, h.header)
+	sanitized, err := SanitizePath(r.URL.Path)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	r = h.markTrailingSlash(r, r.URL.Path)
+	r.URL.Path = sanitized
+	if h.accessLogger == nil && len(h.observers) == 0 {
+		h.serve(w, r)
 		return
 	}
+	start := time.Now()
+	tw := &trackingResponseWriter{ResponseWriter: w}
+	outcome := h.serve(tw, r)
+	info := ServeInfo{
+		Outcome:  outcome,
+		Base:     h.basename(r),
+		Status:   tw.Status(),
+		Bytes:    tw.written,
+		Duration: time.Since(start),
+	}
+	h.logAccess(r, info)
+	for _, observer := range h.observers {
+		observer.ObserveRequest(r, info)
+	}
+}
+
+// serve serves the request, returning the outcome.
+//
+// IMPORTANT: the passed r.URL.Path must have already been sanitized.
+func (h *SPAHandler) serve(w http.ResponseWriter, r *http.Request) Outcome {
+	if h.serveProbe(w, r) {
+		return OutcomeProbe
+	}
+	if h.redirectToHTTPSIfPlain(w, r) {
+		return OutcomeRedirect
+	}
+	if h.passRPC(w, r) {
+		return OutcomePassedOn
+	}
+	if r.Method == http.MethodOptions && h.answerOptions {
+		h.serveOptions(w, r)
+		return OutcomeOptions
+	}
+	if !h.isAllowedMethod(r.Method) {
+		h.rejectMethod(w, r)
+		return OutcomeRejected
+	}
+	r, ok := h.selectHost(r)
+	if !ok {
+		h.writeError(w, r, fs.ErrNotExist)
+		return OutcomeNotFound
+	}
+	r = h.selectBundle(r)
+	h.primer.record(r)
+	if h.isDenied(r.URL.Path) || h.hidesSourceMap(w, r) {
+		h.serveNotFound(w, r)
+		return OutcomeNotFound
+	}
+	if h.serveLiveReload(w, r) {
+		return OutcomeStatic
+	}
+	if h.redirectToCanonicalIndex(w, r) {
+		return OutcomeRedirect
+	}
+	if outcome, ok := h.serveDevServer(w, r); ok {
+		return outcome
+	}
+	if h.serveVirtualFile(w, r) {
+		return OutcomeStatic
+	}
+	if h.serveSharedAsset(w, r) {
+		return OutcomeStatic
+	}
+	if h.serveServiceWorker(w, r) {
+		return OutcomeStatic
+	}
+	if h.serveManifest(w, r) {
+		return OutcomeStatic
+	}
+	if h.serveStaticAsset(w, r) {
+		return OutcomeStatic
+	}
+	if h.serveCleanURL(w, r) {
+		return OutcomeStatic
+	}
+	if outcome, ok := h.serveDirectory(w, r); ok {
+		return outcome
+	}
+	if outcome, ok := h.serveWebSocket(w, r); ok {
+		return outcome
+	}
+	if !h.fallsBackToIndex(r) {
+		h.serveNotFound(w, r)
+		return OutcomeNotFound
+	}
+	if h.challengeUnauthenticated(w, r) {
+		return OutcomeRejected
+	}
+	if h.redirectTrailingSlash(w, r) {
+		return OutcomeRedirect
+	}
+	if h.servePrerendered(w, r) {
+		return OutcomeIndex
+	}
+	if h.redirectToHashRoute(w, r) {
+		return OutcomeRedirect
+	}
 	h.serveRewrittenIndex(w, r)
+	return OutcomeIndex
 }
 
 // serveRewrittenIndex serves the index file, rewriting its HTML base element if
@@ -125,35 +448,147 @@ func (h *SPAHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 func (h *SPAHandler) serveRewrittenIndex(w http.ResponseWriter, r *http.Request) {
 	var err error
 	defer func() {
-		if err != nil {
-			NormalizedHttpError(w, err)
+		// There's no point in serving an error to a client that has gone
+		// away while we were busy rewriting the index.
+		if err != nil && r.Context().Err() == nil {
+			h.serveError(w, r, err)
 		}
 	}()
-	// Sanitize the base path so it cannot interfere with our regexp replacement
-	// operations where we need to use "$1" and "$2" back references. As this
-	// ain't VMS (shudder), we don't need "$" in SPA paths anyway.
-	base := strings.ReplaceAll(h.basename(r), "$", "")
-	// Grab the index.html's contents into a string as we need to modify it
-	// on-the-fly based on where we deem the base path to be. And finally serve
-	// the updated contents.
-	f, err := h.fs.Open(h.index)
-	if err != nil {
+	h.varyIndex(w)
+	h.assignCanaryCookie(w, r)
+	h.setIndexCacheControl(w)
+	index := h.indexFor(r)
+	h.callHooks(h.onIndex, r, index, false)
+	if h.serveIndexFromMeta(w, r, index) {
 		return
 	}
-	defer func() { _ = f.Close() }()
-	fileInfo, err := f.Stat()
+	h.setIndexContentType(w)
+	// Get the index.html's contents pre-split at its base element, so we can
+	// modify it on-the-fly based on where we deem the base path to be. And
+	// finally serve the updated contents.
+	b := h.bundleFor(r)
+	segs, err := h.loadIndex(b, index)
 	if err != nil {
 		return
 	}
-	indexhtmlcontents, err := io.ReadAll(f)
-	if err != nil {
+	h.sendEarlyHints(w, r, segs)
+	if h.canStreamIndex() {
+		err = h.serveStreamedIndex(w, r, b, segs)
 		return
 	}
-	finalIndexhtml := baseRe.ReplaceAllString(string(indexhtmlcontents), "${1}"+base+"${2}")
-	if h.indexRewriter != nil {
-		finalIndexhtml = h.indexRewriter(r, finalIndexhtml)
+	start := time.Now()
+	var contents []byte
+	if segs.tmpl != nil {
+		contents, err = h.renderIndexTemplate(r, segs)
+		if err != nil {
+			return
+		}
+	} else {
+		contents = segs.join(h.escapedBase(r))
+	}
+	contents = h.injectMetaTags(r, contents)
+	contents = h.rewriteTitle(r, contents)
+	if h.requestID != nil {
+		r, contents = h.injectRequestID(w, r, contents)
+	}
+	if h.csrf != nil {
+		r, contents, err = h.injectCSRFToken(w, r, contents)
+		if err != nil {
+			return
+		}
+	}
+	if h.cspPolicy != "" {
+		r, contents, err = h.injectCSPNonce(w, r, contents)
+		if err != nil {
+			return
+		}
+	}
+	if contents, err = h.rewriteIndex(r, contents); err != nil {
+		return
+	}
+	if h.integrityMode != IntegrityKeep {
+		contents = h.fixIntegrity(r, contents)
+	}
+	for _, observer := range h.observers {
+		observer.ObserveIndexRewrite(r, time.Since(start))
+	}
+	// Request-specific indices, such as those carrying per-request CSP nonces
+	// or per-session CSRF tokens, must never be answered with 304, as
+	// otherwise clients would keep their cached index with stale nonces or
+	// tokens. So we don't send any validators then.
+	modTime := segs.modTime
+	if meta := h.rememberIndex(b, index, h.basename(r), segs.modTime, contents); meta != nil {
+		w.Header().Set("ETag", meta.etag)
+	} else {
+		modTime = time.Time{}
 	}
-	http.ServeContent(w, r, "index.html", fileInfo.ModTime(), strings.NewReader(finalIndexhtml))
+	http.ServeContent(w, r, "index.html", modTime, bytes.NewReader(contents))
+}
+
+// WithoutBaseRewrite disables rewriting the base elements and import maps of
+// the index, as well as of error documents and pre-rendered snapshots. This is
+// intended for SPAs built with relative (“./”) asset paths or using hash
+// routing, where documents need to be served byte-identical, so that stray
+// base-like strings don't get mangled. Other rewriting, such as injecting meta
+// tags or applying index rewriters, still takes place when configured.
+func WithoutBaseRewrite() SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.keepBase = true
+	}
+}
+
+// rewriteBase returns the specified HTML document contents with its base
+// element (if any) rewritten to refer to the correct base path of the SPA,
+// unless base rewriting has been disabled.
+func (h *SPAHandler) rewriteBase(r *http.Request, html string) string {
+	return h.replaceBase(html, h.escapedBase(r))
+}
+
+// replaceBase returns the specified HTML document contents with the href
+// values of its base elements (if any) replaced by the specified already
+// escaped base, unless base rewriting has been disabled.
+func (h *SPAHandler) replaceBase(html string, base string) string {
+	if h.keepBase {
+		return html
+	}
+	return baseRe.ReplaceAllString(html, "${1}"+base+"${2}")
+}
+
+// escapedBase returns the base path for the specified request, escaped so it
+// can neither break out of the href attribute of a base element, nor the
+// element itself. Additionally, the escaped base path is sanitized so it
+// cannot interfere with our regexp replacement operations where we need to use
+// "$1" and "$2" back references. As this ain't VMS (shudder), we don't need
+// "$" in SPA paths anyway.
+func (h *SPAHandler) escapedBase(r *http.Request) string {
+	return escapeBase(h.basename(r))
+}
+
+// escapeBase returns the specified base path escaped, see escapedBase.
+func escapeBase(base string) string {
+	return strings.ReplaceAll(escapedPath(base), "$", "")
+}
+
+// RewriteBase returns the specified index HTML document with the href values
+// of its base elements, as well as the rooted and relative addresses in its
+// import maps, rewritten to the specified base path, exactly as SPAHandler
+// rewrites the index it serves. This allows reusing the rewriting in
+// server-side rendering paths and unit tests without an SPAHandler and a
+// request. For instance:
+//
+//	html := spaserve.RewriteBase(indexHTML, "/app/")
+//
+// The base path gets cleaned and always ends in a slash; it is escaped so it
+// can neither break out of the href attribute nor the element. As with
+// SPAHandler, only base elements in the form “<base href="..." />” get
+// rewritten.
+func RewriteBase(indexHTML []byte, base string) []byte {
+	base = path.Clean("/" + base)
+	if base != "/" {
+		base += "/"
+	}
+	segs := &indexSegments{parts: splitIndex(string(indexHTML))}
+	return segs.join(escapeBase(base))
 }
 
 // serveStaticAsset tries to serve a static asset specified in uripath from the
@@ -162,6 +597,25 @@ func (h *SPAHandler) serveRewrittenIndex(w http.ResponseWriter, r *http.Request)
 //
 // IMPORTANT: the passed r.URL.Path must have already been sanitized.
 func (h *SPAHandler) serveStaticAsset(w http.ResponseWriter, r *http.Request) bool {
+	b := h.bundleFor(r)
+	if h.serveStaticAssetFrom(b.fs, h.wrapAssetHandler(h.assetETagHandler(b, b.fileServer)), false, w, r) {
+		return true
+	}
+	// In canary mode, assets of both bundles remain servable, as clients
+	// might still reference assets of the other bundle.
+	if other := h.otherBundle(b); other != nil {
+		return h.serveStaticAssetFrom(other.fs, h.wrapAssetHandler(h.assetETagHandler(other, other.fileServer)), false, w, r)
+	}
+	return false
+}
+
+// serveStaticAssetFrom tries to serve a static asset specified in uripath from
+// the specified fsys, using the specified fileHandler. The shared flag
+// indicates whether fsys is a shared FS instead of the SPA's own FS. It returns
+// true if successful, otherwise false without having served anything.
+//
+// IMPORTANT: the passed r.URL.Path must have already been sanitized.
+func (h *SPAHandler) serveStaticAssetFrom(fsys fs.FS, fileHandler http.Handler, shared bool, w http.ResponseWriter, r *http.Request) bool {
 	// Try to check that the requested resource in fact is a plain file.
 	// Thankfully, fs.State deals with fs.FS implementations that don't support
 	// fs.StatFS and works around this situation. Thus, we can rely on fs.Stat
@@ -171,18 +625,25 @@ func (h *SPAHandler) serveStaticAsset(w http.ResponseWriter, r *http.Request) bo
 	if path == "" {
 		return false // hitting root is always a case for index.html
 	}
-	info, err := fs.Stat(h.fs, r.URL.Path[1:])
+	if !h.isAllowedExtension(path) {
+		return false
+	}
+	info, err := fs.Stat(fsys, path)
 	// If we have a "regular" file then serve it using a regular
-	// http.FileServer. Fun fact: http.FileServer also sanitizes our already
-	// sanitized path.
+	// http.FileServer, unless the fs.FS is backed by a real directory. Fun
+	// fact: http.FileServer also sanitizes our already sanitized path.
 	if err == nil && info.Mode()&os.ModeType == 0 {
-		h.staticfileHandler.ServeHTTP(w, r)
+		h.callHooks(h.onStatic, r, path, shared)
+		setHeader(w, h.assetHeader)
+		h.setAssetCacheControl(w, path)
+		h.setContentType(w, path)
+		fileHandler.ServeHTTP(w, r)
 		return true
 	}
 	// If we got an error and it isn't a missing static asset, then normalize
 	// (or rather, sanitize) the error and send that back to the client.
 	if err != nil && !os.IsNotExist(err) {
-		NormalizedHttpError(w, err)
+		h.serveError(w, r, err)
 		return true
 	}
 	return false
@@ -196,23 +657,14 @@ func (h *SPAHandler) originalReqPath(r *http.Request) string {
 	// Was the request path rewritten? Then the original request path was the
 	// forwarded prefix, followed by the remaining part we now see in the
 	// request.
-	if fwprefix := r.Header.Get(ForwardedPrefixHeader); fwprefix != "" {
-		fwprefix = path.Clean("/" + fwprefix)
+	if fwprefix, ok := forwardedPrefix(r.Header.Get(ForwardedPrefixHeader)); ok {
 		return path.Join(fwprefix, r.URL.Path)
 	}
 	// Was the original HTTP request URL passed upon us? There seem to be
 	// different interpretations with some proxy implementations only passing
 	// the request path, but not the full original URI to us...
-	if fwurl := r.Header.Get(ForwardedUriHeader); fwurl != "" {
-		if strings.HasPrefix(fwurl, "/") {
-			// Assume it's just the request path: sani, sani, sanitize it!
-			return path.Clean(fwurl)
-		}
-		// Attempt to parse it as a URI, erm, URL, and sani, sani, sanitize it!;
-		// if that fails, just ignore it.
-		if u, err := url.Parse(fwurl); err == nil {
-			return path.Clean("/" + u.Path)
-		}
+	if fwurl, ok := forwardedURIPath(r.Header.Get(ForwardedUriHeader)); ok {
+		return fwurl
 	}
 	// If nothing else, go with just the request path we see.
 	return r.URL.Path
@@ -15,14 +15,19 @@
 package spaserve
 
 import (
+	"context"
 	"embed"
+	"errors"
 	"io/fs"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"strings"
+	"testing/fstest"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/thediveo/spaserve/test/fixture"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -198,6 +203,162 @@ var _ = Describe("", func() {
 		Expect(w.Body).To(HaveSuffix(canary))
 	})
 
+	It("stacks application-specific rewriters", func() {
+		url := Successful(url.Parse("http://foo.bar:12345"))
+		r := &http.Request{
+			Method: "GET",
+			URL:    url,
+		}
+		suffix := func(suffix string) IndexRewriter {
+			return func(r *http.Request, index string) string {
+				return index + suffix
+			}
+		}
+		h := NewSPAHandler(embStaticFs, "index.html",
+			WithIndexRewriter(suffix("<!-- A -->")),
+			WithIndexRewriter(nil),
+			WithIndexRewriters(suffix("<!-- B -->"), nil, suffix("<!-- C -->")))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		Expect(w.Body).To(HaveSuffix("<!-- A --><!-- B --><!-- C -->"))
+	})
+
+	It("stacks byte slice and string rewriters", func() {
+		h := NewSPAHandler(embStaticFs, "index.html",
+			WithIndexBytesRewriter(func(r *http.Request, index []byte) []byte {
+				return append(index, "<!-- A -->"...)
+			}),
+			WithIndexRewriter(func(r *http.Request, index string) string {
+				return index + "<!-- B -->"
+			}),
+			WithIndexBytesRewriters(nil, func(r *http.Request, index []byte) []byte {
+				return append(index, "<!-- C -->"...)
+			}))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+		Expect(w.Body).To(HaveSuffix("<!-- A --><!-- B --><!-- C -->"))
+	})
+
+	It("passes the base to error-aware rewriters and fails on errors", func() {
+		var bases []string
+		var calledAfterError bool
+		h := NewSPAHandler(embStaticFs, "index.html",
+			WithIndexRewriterE(nil),
+			WithIndexRewriterE(func(r *http.Request, base string, index []byte) ([]byte, error) {
+				bases = append(bases, base)
+				if r.URL.Query().Has("fail") {
+					return nil, errors.New("rewriter failure")
+				}
+				return append(index, "<!-- E -->"...), nil
+			}),
+			WithIndexRewriterE(func(r *http.Request, base string, index []byte) ([]byte, error) {
+				calledAfterError = r.URL.Query().Has("fail")
+				return index, nil
+			}))
+
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("X-Forwarded-Prefix", "/foo/")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+		Expect(w.Body).To(HaveSuffix("<!-- E -->"))
+		Expect(bases).To(ConsistOf("/foo/"))
+
+		r = httptest.NewRequest("GET", "/?fail", nil)
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		Expect(w.Result().StatusCode).To(Equal(http.StatusInternalServerError))
+		Expect(w.Body.String()).NotTo(ContainSubstring("<!-- E -->"))
+		Expect(calledAfterError).To(BeFalse())
+	})
+
+	It("passes the request context to rewriters and aborts when it is done", func() {
+		type ctxKey struct{}
+		var calledAfterCancel bool
+		ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "foo"))
+		defer cancel()
+		h := NewSPAHandler(embStaticFs, "index.html",
+			WithIndexRewriterContext(nil),
+			WithIndexRewriterContext(func(ctx context.Context, r *http.Request, base string, index []byte) ([]byte, error) {
+				Expect(ctx.Value(ctxKey{})).To(Equal("foo"))
+				if r.URL.Query().Has("disconnect") {
+					cancel()
+				}
+				return append(index, "<!-- ctx -->"...), nil
+			}),
+			WithIndexBytesRewriter(func(r *http.Request, index []byte) []byte {
+				calledAfterCancel = r.URL.Query().Has("disconnect")
+				return index
+			}))
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+		Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+		Expect(w.Body).To(HaveSuffix("<!-- ctx -->"))
+
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/?disconnect", nil).WithContext(ctx))
+		Expect(calledAfterCancel).To(BeFalse())
+		Expect(w.Body.Len()).To(BeZero())
+	})
+
+	It("replaces the index rewriter at runtime", func() {
+		h := NewSPAHandler(embStaticFs, "index.html")
+		serve := func(etag string) *httptest.ResponseRecorder {
+			r := httptest.NewRequest("GET", "/", nil)
+			if etag != "" {
+				r.Header.Set("If-None-Match", etag)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			return w
+		}
+		h.SetIndexRewriter(nil)
+		w := serve("")
+		etag := w.Header().Get("ETag")
+		Expect(etag).NotTo(BeEmpty())
+		Expect(serve(etag).Result().StatusCode).To(Equal(http.StatusNotModified))
+
+		h.SetIndexRewriter(func(r *http.Request, index string) string {
+			return index + "<!-- maintenance -->"
+		})
+		w = serve(etag)
+		Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+		Expect(w.Body).To(HaveSuffix("<!-- maintenance -->"))
+
+		h.SetIndexRewriter(nil)
+		w = serve("")
+		Expect(w.Body.String()).NotTo(ContainSubstring("<!-- maintenance -->"))
+		Expect(w.Header().Get("ETag")).To(Equal(etag))
+	})
+
+	It("serves the index byte-identical without base rewriting", func() {
+		index := `<html><head><base href="./" /><script type="importmap">{"imports":{"app":"./app.js"}}</script></head></html>`
+		h := NewSPAHandler(fstest.MapFS{"index.html": {Data: []byte(index)}}, "index.html",
+			WithoutBaseRewrite())
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set(ForwardedPrefixHeader, "/foo/")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(Equal(index))
+		Expect(h.Describe().Options).To(ContainElement("WithoutBaseRewrite"))
+	})
+
+	DescribeTable("rewrites the base of standalone index documents",
+		func(base string, expected string) {
+			index := `<base href="./" /><script type="importmap">{"imports":{"app":"./app.js"}}</script>`
+			Expect(string(RewriteBase([]byte(index), base))).To(Equal(expected))
+		},
+		Entry("root", "/",
+			`<base href="/" /><script type="importmap">{"imports":{"app":"/app.js"}}</script>`),
+		Entry("unclean base without trailing slash", "app//v1/..",
+			`<base href="/app/" /><script type="importmap">{"imports":{"app":"/app/app.js"}}</script>`),
+		Entry("escaped base", `/a"b$/`,
+			`<base href="/a%22b/" /><script type="importmap">{"imports":{"app":"/a%22b/app.js"}}</script>`),
+	)
+
 	It("returns a 500 when the index is missing", func() {
 		url := Successful(url.Parse("http://foo.bar:12345"))
 		r := &http.Request{
@@ -226,4 +387,19 @@ var _ = Describe("", func() {
 		Entry("from test dir fs", os.DirFS("./testdata")),
 	)
 
+	It("serves a generated bundle fixture", func() {
+		bundle := fixture.New()
+		h := NewSPAHandler(bundle.FS, bundle.Index)
+		for _, path := range append([]string{"/", "/some/route"}, bundle.Chunks...) {
+			url := Successful(url.Parse("http://foo.bar:12345/" + strings.TrimPrefix(path, "/")))
+			r := &http.Request{
+				Method: "GET",
+				URL:    url,
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK), "path %s", path)
+		}
+	})
+
 })
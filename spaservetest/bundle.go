@@ -0,0 +1,235 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaservetest
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Severity of a bundle Finding.
+type Severity int
+
+const (
+	SeverityWarning Severity = iota // bundle is servable, but likely misbehaves.
+	SeverityError                   // bundle is broken.
+)
+
+// String returns the textual representation of a Severity, such as "error".
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return "unknown"
+}
+
+// FindingKind identifies the kind of problem of a bundle Finding.
+type FindingKind string
+
+// The kinds of problems found by CheckBundle.
+const (
+	IndexMissing      FindingKind = "index-missing"       // index file doesn't exist or isn't a regular file.
+	IndexUnreadable   FindingKind = "index-unreadable"    // index file cannot be read.
+	BaseMissing       FindingKind = "base-missing"        // index has no base element.
+	BaseNotRewritable FindingKind = "base-not-rewritable" // base element not in the rewritable form.
+	AbsoluteAssetURL  FindingKind = "absolute-asset-url"  // asset URL ignores the base.
+	MissingAsset      FindingKind = "missing-asset"       // referenced asset isn't part of the bundle.
+	InvalidAssetURL   FindingKind = "invalid-asset-url"   // asset URL cannot be parsed.
+)
+
+// Finding is a problem of an SPA bundle found by CheckBundle.
+type Finding struct {
+	Severity Severity    // how severe the problem is.
+	Kind     FindingKind // kind of problem.
+	File     string      // bundle file with the problem.
+	Detail   string      // offending element or URL, if any.
+	Message  string      // human-readable description.
+}
+
+// String returns the finding in a human-readable form.
+func (f Finding) String() string {
+	s := f.Severity.String() + ": " + f.File + ": " + f.Message
+	if f.Detail != "" {
+		s += ": " + f.Detail
+	}
+	return s
+}
+
+// Findings are the problems of an SPA bundle found by CheckBundle.
+type Findings []Finding
+
+// HasErrors returns true if any finding has error severity.
+func (f Findings) HasErrors() bool {
+	for _, finding := range f {
+		if finding.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Err returns an error listing all findings with error severity, or nil if
+// there are none.
+func (f Findings) Err() error {
+	var msgs []string
+	for _, finding := range f {
+		if finding.Severity == SeverityError {
+			msgs = append(msgs, finding.String())
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("broken SPA bundle:\n%s", strings.Join(msgs, "\n"))
+}
+
+var (
+	// rewritableBaseRe mirrors how spaserve finds the base element it
+	// rewrites.
+	rewritableBaseRe = regexp.MustCompile(`(<base href=").*?("\s*/>)`)
+	// anyBaseRe matches any base element.
+	anyBaseRe = regexp.MustCompile(`(?i)<base[\s>/]`)
+	// assetElementRe matches elements referencing assets, capturing the
+	// element name and its attributes.
+	assetElementRe = regexp.MustCompile(`(?is)<(script|link|img|source|video|audio|iframe)(\s[^>]*)?>`)
+	// assetAttrRe matches the src and href attributes with quoted values.
+	assetAttrRe = regexp.MustCompile(`(?i)\s(src|href)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+)
+
+// CheckBundle checks that the specified fs.FS contains an SPA bundle servable
+// by spaserve with the specified index file, returning the problems found.
+// CheckBundle is intended to catch broken bundles in release pipelines before
+// deploying them, for instance:
+//
+//	if err := spaservetest.CheckBundle(os.DirFS("dist"), "index.html").Err(); err != nil {
+//	    log.Fatal(err)
+//	}
+//
+// It checks that:
+//   - the index exists and is readable,
+//   - the index has a base element in the form “<base href="..." />” that
+//     spaserve rewrites; without a base element, the SPA only works when
+//     served from the root,
+//   - scripts, stylesheets, images, and other assets are referenced relative
+//     to the base, as absolute paths such as “/assets/app.js” ignore the base,
+//   - relatively referenced assets are part of the bundle.
+func CheckBundle(fsys fs.FS, index string) Findings {
+	index = path.Clean("/" + index)[1:]
+	info, err := fs.Stat(fsys, index)
+	if err != nil || !info.Mode().IsRegular() {
+		return Findings{{
+			Severity: SeverityError,
+			Kind:     IndexMissing,
+			File:     index,
+			Message:  "index file missing",
+		}}
+	}
+	contents, err := fs.ReadFile(fsys, index)
+	if err != nil {
+		return Findings{{
+			Severity: SeverityError,
+			Kind:     IndexUnreadable,
+			File:     index,
+			Message:  "index file unreadable",
+			Detail:   err.Error(),
+		}}
+	}
+	document := string(contents)
+	var findings Findings
+	root := "." // ...where relative asset URLs get resolved against.
+	switch {
+	case rewritableBaseRe.MatchString(document):
+	case anyBaseRe.MatchString(document):
+		findings = append(findings, Finding{
+			Severity: SeverityError,
+			Kind:     BaseNotRewritable,
+			File:     index,
+			Message:  `base element not in the rewritable form <base href="..." />`,
+			Detail:   anyBaseRe.FindString(document),
+		})
+	default:
+		root = path.Dir(index)
+		findings = append(findings, Finding{
+			Severity: SeverityWarning,
+			Kind:     BaseMissing,
+			File:     index,
+			Message:  "no base element, so the SPA only works when served from the root",
+		})
+	}
+	for _, element := range assetElementRe.FindAllString(document, -1) {
+		for _, attr := range assetAttrRe.FindAllStringSubmatch(element, -1) {
+			findings = append(findings, checkAssetURL(fsys, index, root, attr[2]+attr[3])...)
+		}
+	}
+	return findings
+}
+
+// checkAssetURL checks the specified asset URL referenced from the specified
+// index, with relative URLs resolved against the specified root directory,
+// returning any problems found.
+func checkAssetURL(fsys fs.FS, index string, root string, assetURL string) Findings {
+	if assetURL == "" || strings.HasPrefix(assetURL, "#") {
+		return nil
+	}
+	u, err := url.Parse(assetURL)
+	if err != nil {
+		return Findings{{
+			Severity: SeverityError,
+			Kind:     InvalidAssetURL,
+			File:     index,
+			Message:  "invalid asset URL",
+			Detail:   assetURL,
+		}}
+	}
+	if u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return nil // ...not our business.
+	}
+	if strings.HasPrefix(u.Path, "/") {
+		return Findings{{
+			Severity: SeverityWarning,
+			Kind:     AbsoluteAssetURL,
+			File:     index,
+			Message:  "absolute asset URL ignores the base",
+			Detail:   assetURL,
+		}}
+	}
+	name := path.Join(root, u.Path)
+	if strings.HasPrefix(name, "../") || name == ".." {
+		return Findings{{
+			Severity: SeverityError,
+			Kind:     InvalidAssetURL,
+			File:     index,
+			Message:  "asset URL outside the bundle",
+			Detail:   assetURL,
+		}}
+	}
+	if _, err := fs.Stat(fsys, name); err != nil {
+		return Findings{{
+			Severity: SeverityError,
+			Kind:     MissingAsset,
+			File:     index,
+			Message:  "referenced asset missing from the bundle",
+			Detail:   assetURL,
+		}}
+	}
+	return nil
+}
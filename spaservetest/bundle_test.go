@@ -0,0 +1,93 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaservetest
+
+import (
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("bundle checks", func() {
+
+	bundle := func(index string) fstest.MapFS {
+		return fstest.MapFS{
+			"index.html":     &fstest.MapFile{Data: []byte(index)},
+			"assets/app.js":  &fstest.MapFile{Data: []byte(`app();`)},
+			"assets/app.css": &fstest.MapFile{Data: []byte(`body{}`)},
+			"sub/index.html": &fstest.MapFile{Data: []byte(`<script src="page.js"></script>`)},
+			"sub/page.js":    &fstest.MapFile{Data: []byte(`page();`)},
+		}
+	}
+
+	It("passes a good bundle", func() {
+		findings := CheckBundle(bundle(`<html><head><base href="./" />
+<link rel="stylesheet" href="./assets/app.css">
+<link rel="preconnect" href="https://fonts.example.com">
+<script type="module" src="assets/app.js?v=1"></script>
+</head><body><a href="/about">about</a><img src="data:image/png;base64,AAAA"></body></html>`), "/index.html")
+		Expect(findings).To(BeEmpty())
+		Expect(findings.HasErrors()).To(BeFalse())
+		Expect(findings.Err()).NotTo(HaveOccurred())
+	})
+
+	It("reports a missing index", func() {
+		findings := CheckBundle(bundle(""), "main.html")
+		Expect(findings).To(ConsistOf(HaveField("Kind", IndexMissing)))
+		Expect(findings.Err()).To(MatchError(ContainSubstring("error: main.html: index file missing")))
+		Expect(CheckBundle(bundle(""), "assets")).To(ConsistOf(HaveField("Kind", IndexMissing)))
+	})
+
+	It("reports base element problems", func() {
+		findings := CheckBundle(bundle(`<head><base href="./"></head>`), "index.html")
+		Expect(findings).To(ConsistOf(And(
+			HaveField("Kind", BaseNotRewritable),
+			HaveField("Severity", SeverityError))))
+
+		findings = CheckBundle(bundle(`<head></head>`), "index.html")
+		Expect(findings).To(ConsistOf(And(
+			HaveField("Kind", BaseMissing),
+			HaveField("Severity", SeverityWarning))))
+		Expect(findings.HasErrors()).To(BeFalse())
+
+		By("resolving relative assets against the index directory without base")
+		findings = CheckBundle(bundle(""), "sub/index.html")
+		Expect(findings).To(ConsistOf(HaveField("Kind", BaseMissing)))
+	})
+
+	It("reports asset URL problems", func() {
+		findings := CheckBundle(bundle(`<base href="./" />
+<script src="/assets/app.js"></script>
+<script src="assets/missing.js"></script>
+<script src="../outside.js"></script>
+<link href="%zz">`), "index.html")
+		Expect(findings).To(ConsistOf(
+			And(HaveField("Kind", AbsoluteAssetURL), HaveField("Detail", "/assets/app.js")),
+			And(HaveField("Kind", MissingAsset), HaveField("Detail", "assets/missing.js")),
+			And(HaveField("Kind", InvalidAssetURL), HaveField("Detail", "../outside.js")),
+			And(HaveField("Kind", InvalidAssetURL), HaveField("Detail", "%zz")),
+		))
+		Expect(findings.HasErrors()).To(BeTrue())
+		Expect(findings[0].String()).To(Equal("warning: index.html: absolute asset URL ignores the base: /assets/app.js"))
+	})
+
+	It("names severities", func() {
+		Expect(SeverityWarning.String()).To(Equal("warning"))
+		Expect(SeverityError.String()).To(Equal("error"))
+		Expect(Severity(42).String()).To(Equal("unknown"))
+	})
+
+})
@@ -0,0 +1,119 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaservetest
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+
+	"github.com/onsi/gomega/format"
+	"github.com/onsi/gomega/types"
+)
+
+// baseHrefRe matches the href attribute value of a base element.
+var baseHrefRe = regexp.MustCompile(`(?is)<base\s[^>]*?href\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+
+// BaseHref returns the (unescaped) href value of the first base element in the
+// specified HTML document and true, or false if there is no base element with
+// an href attribute.
+func BaseHref(document string) (string, bool) {
+	m := baseHrefRe.FindStringSubmatch(document)
+	if m == nil {
+		return "", false
+	}
+	return html.UnescapeString(m[1] + m[2]), true
+}
+
+// HaveBase succeeds if the actual HTML document has a base element with the
+// specified href value. The actual value can be a string, a []byte, an
+// *httptest.ResponseRecorder, or an *http.Response, where the latter's body
+// gets read and thus consumed.
+//
+//	Expect(GET("/foo").WithForwardedPrefix("/app").Serve(h)).To(HaveBase("/app/"))
+func HaveBase(href string) types.GomegaMatcher {
+	return &baseMatcher{href: href, want: true}
+}
+
+// HaveNoBase succeeds if the actual HTML document has no base element with an
+// href attribute. See HaveBase for the supported types of actual values.
+func HaveNoBase() types.GomegaMatcher {
+	return &baseMatcher{}
+}
+
+// baseMatcher matches the base href of HTML documents.
+type baseMatcher struct {
+	href     string // expected base href.
+	want     bool   // expect a base element, otherwise expect none.
+	document string // actual document, for failure messages.
+	base     string // actual base href, if any.
+	found    bool   // actual base element found.
+}
+
+// Match implements types.GomegaMatcher.
+func (m *baseMatcher) Match(actual any) (bool, error) {
+	var err error
+	if m.document, err = documentOf(actual); err != nil {
+		return false, err
+	}
+	m.base, m.found = BaseHref(m.document)
+	if !m.want {
+		return !m.found, nil
+	}
+	return m.found && m.base == m.href, nil
+}
+
+// FailureMessage implements types.GomegaMatcher.
+func (m *baseMatcher) FailureMessage(any) string {
+	return m.message("to")
+}
+
+// NegatedFailureMessage implements types.GomegaMatcher.
+func (m *baseMatcher) NegatedFailureMessage(any) string {
+	return m.message("not to")
+}
+
+// message returns the failure message for the specified expectation.
+func (m *baseMatcher) message(to string) string {
+	actual := "no base href"
+	if m.found {
+		actual = fmt.Sprintf("base href %q", m.base)
+	}
+	if !m.want {
+		return fmt.Sprintf("Expected HTML document with %s\n%s\n%s have no base href",
+			actual, format.IndentString(m.document, 1), to)
+	}
+	return fmt.Sprintf("Expected HTML document with %s\n%s\n%s have base href %q",
+		actual, format.IndentString(m.document, 1), to, m.href)
+}
+
+// documentOf returns the HTML document contents of the specified actual value.
+func documentOf(actual any) (string, error) {
+	switch actual := actual.(type) {
+	case string:
+		return actual, nil
+	case []byte:
+		return string(actual), nil
+	case *httptest.ResponseRecorder:
+		return actual.Body.String(), nil
+	case *http.Response:
+		b, err := io.ReadAll(actual.Body)
+		return string(b), err
+	}
+	return "", fmt.Errorf("expected a string, []byte, *httptest.ResponseRecorder, or *http.Response, got %T", actual)
+}
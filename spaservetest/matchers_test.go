@@ -0,0 +1,76 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaservetest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("base matchers", func() {
+
+	DescribeTable("extracting base hrefs",
+		func(document string, expected string, expectedOK bool) {
+			base, ok := BaseHref(document)
+			Expect(ok).To(Equal(expectedOK))
+			Expect(base).To(Equal(expected))
+		},
+		Entry(nil, `<html></html>`, "", false),
+		Entry(nil, `<base target="_blank">`, "", false),
+		Entry(nil, `<base href="/app/" />`, "/app/", true),
+		Entry(nil, `<BASE target="_top" HREF='/a&amp;b/'>`, "/a&b/", true),
+		Entry(nil, `<base href="/1/"><base href="/2/">`, "/1/", true),
+	)
+
+	It("matches various actual values", func() {
+		document := `<base href="/app/" />`
+		Expect(document).To(HaveBase("/app/"))
+		Expect([]byte(document)).To(HaveBase("/app/"))
+		w := httptest.NewRecorder()
+		_, _ = w.WriteString(document)
+		Expect(w).To(HaveBase("/app/"))
+		Expect(&http.Response{Body: io.NopCloser(strings.NewReader(document))}).To(HaveBase("/app/"))
+		Expect(document).NotTo(HaveBase("/"))
+		Expect(document).NotTo(HaveNoBase())
+		Expect(`<html></html>`).To(HaveNoBase())
+		Expect(`<html></html>`).NotTo(HaveBase("/"))
+	})
+
+	It("rejects unsupported actual values", func() {
+		Expect(HaveBase("/").Match(42)).Error().To(HaveOccurred())
+		Expect(HaveNoBase().Match(nil)).Error().To(HaveOccurred())
+	})
+
+	It("reports failures", func() {
+		m := HaveBase("/app/")
+		Expect(m.Match(`<base href="/">`)).To(BeFalse())
+		Expect(m.FailureMessage(nil)).To(And(
+			ContainSubstring(`with base href "/"`),
+			ContainSubstring(`to have base href "/app/"`)))
+		Expect(m.NegatedFailureMessage(nil)).To(ContainSubstring(`not to have base href "/app/"`))
+
+		m = HaveNoBase()
+		Expect(m.Match(`<html>`)).To(BeTrue())
+		Expect(m.NegatedFailureMessage(nil)).To(And(
+			ContainSubstring("with no base href"),
+			ContainSubstring("not to have no base href")))
+	})
+
+})
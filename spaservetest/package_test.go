@@ -12,7 +12,7 @@
 // License for the specific language governing permissions and limitations
 // under the License.
 
-package httptest
+package spaservetest
 
 import (
 	"testing"
@@ -23,5 +23,5 @@ import (
 
 func TestSPAServe(t *testing.T) {
 	RegisterFailHandler(Fail)
-	RunSpecs(t, "spaserve/test/httptest package")
+	RunSpecs(t, "spaserve/spaservetest package")
 }
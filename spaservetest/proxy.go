@@ -0,0 +1,228 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaservetest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Proxy simulates a path-rewriting reverse proxy in front of an HTTP handler,
+// mangling requests as the simulated proxy would do before passing them on.
+type Proxy struct {
+	// Name of the simulated proxy, for test reports.
+	Name string
+	// Prefix of the request paths routed to the handler, such as "/app". The
+	// prefix gets stripped from the request path if Strip is set. Requests not
+	// matching the prefix are answered with 404 by the simulated proxy.
+	Prefix string
+	// Strip the prefix from the request path before passing it on.
+	Strip bool
+	// Header is a function setting the forwarding headers for the specified
+	// original request URL and stripped prefix on the passed on request.
+	Header func(header http.Header, original *url.URL, prefix string)
+}
+
+// Nginx simulates nginx with a location block passing requests for the
+// specified prefix on to the handler with the prefix stripped, as in:
+//
+//	location /app/ {
+//	    proxy_pass http://backend/;
+//	    proxy_set_header Host $host;
+//	    proxy_set_header X-Real-IP $remote_addr;
+//	    proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+//	    proxy_set_header X-Forwarded-Proto $scheme;
+//	    proxy_set_header X-Forwarded-Prefix /app;
+//	}
+func Nginx(prefix string) *Proxy {
+	return &Proxy{
+		Name:   "nginx",
+		Prefix: prefix,
+		Strip:  true,
+		Header: func(header http.Header, original *url.URL, prefix string) {
+			header.Set("X-Real-IP", "192.0.2.1")
+			header.Set("X-Forwarded-For", "192.0.2.1")
+			header.Set("X-Forwarded-Proto", original.Scheme)
+			if prefix != "" {
+				header.Set("X-Forwarded-Prefix", prefix)
+			}
+		},
+	}
+}
+
+// Traefik simulates Traefik with a router for the specified path prefix and a
+// StripPrefix middleware, passing on the X-Forwarded-* headers Traefik sets
+// by default.
+func Traefik(prefix string) *Proxy {
+	return &Proxy{
+		Name:   "traefik",
+		Prefix: prefix,
+		Strip:  true,
+		Header: func(header http.Header, original *url.URL, prefix string) {
+			header.Set("X-Forwarded-For", "192.0.2.1")
+			header.Set("X-Forwarded-Host", original.Host)
+			header.Set("X-Forwarded-Port", originalPort(original))
+			header.Set("X-Forwarded-Proto", original.Scheme)
+			header.Set("X-Forwarded-Server", "traefik")
+			header.Set("X-Real-Ip", "192.0.2.1")
+			if prefix != "" {
+				header.Set("X-Forwarded-Prefix", prefix)
+			}
+		},
+	}
+}
+
+// IngressNginx simulates the Kubernetes ingress-nginx controller with an
+// ingress for the specified path prefix using the annotations:
+//
+//	nginx.ingress.kubernetes.io/use-regex: "true"
+//	nginx.ingress.kubernetes.io/rewrite-target: /$2
+//	nginx.ingress.kubernetes.io/x-forwarded-prefix: /app
+//
+// with the ingress path "/app(/|$)(.*)".
+func IngressNginx(prefix string) *Proxy {
+	return &Proxy{
+		Name:   "ingress-nginx",
+		Prefix: prefix,
+		Strip:  true,
+		Header: func(header http.Header, original *url.URL, prefix string) {
+			header.Set("X-Request-ID", "4bf92f3577b34da6a3ce929d0e0e4736")
+			header.Set("X-Real-IP", "192.0.2.1")
+			header.Set("X-Forwarded-For", "192.0.2.1")
+			header.Set("X-Forwarded-Host", original.Host)
+			header.Set("X-Forwarded-Port", originalPort(original))
+			header.Set("X-Forwarded-Proto", original.Scheme)
+			header.Set("X-Forwarded-Scheme", original.Scheme)
+			header.Set("X-Scheme", original.Scheme)
+			if prefix != "" {
+				header.Set("X-Forwarded-Prefix", prefix)
+			}
+		},
+	}
+}
+
+// ForwardedURI simulates a proxy passing the original request URI in an
+// X-Forwarded-Uri header instead of the stripped prefix, such as Traefik's
+// ForwardAuth middleware or Caddy with a corresponding header_up directive.
+func ForwardedURI(prefix string) *Proxy {
+	return &Proxy{
+		Name:   "forwarded-uri",
+		Prefix: prefix,
+		Strip:  true,
+		Header: func(header http.Header, original *url.URL, _ string) {
+			header.Set("X-Forwarded-Proto", original.Scheme)
+			header.Set("X-Forwarded-Host", original.Host)
+			header.Set("X-Forwarded-Uri", original.RequestURI())
+		},
+	}
+}
+
+// originalPort returns the port of the specified URL, defaulting to the
+// scheme's well-known port.
+func originalPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	if u.Scheme == "https" {
+		return "443"
+	}
+	return "80"
+}
+
+// Request returns the specified client request as passed on by the simulated
+// proxy, and true. If the request doesn't match the proxy's prefix, it returns
+// false instead.
+func (p *Proxy) Request(r *http.Request) (*http.Request, bool) {
+	original := *r.URL
+	if original.Scheme == "" {
+		original.Scheme = "http"
+		if r.TLS != nil {
+			original.Scheme = "https"
+		}
+	}
+	if original.Host == "" {
+		original.Host = r.Host
+	}
+	prefix := strings.TrimSuffix(p.Prefix, "/")
+	escapedPath := original.EscapedPath()
+	if prefix != "" && escapedPath != prefix && !strings.HasPrefix(escapedPath, prefix+"/") {
+		return nil, false
+	}
+	passed := r.Clone(r.Context())
+	if p.Strip && prefix != "" {
+		rest := strings.TrimPrefix(escapedPath, prefix)
+		if !strings.HasPrefix(rest, "/") {
+			rest = "/" + rest
+		}
+		u := *r.URL
+		u.Scheme, u.Host = "", ""
+		if err := setEscapedPath(&u, rest); err != nil {
+			return nil, false
+		}
+		passed.URL = &u
+		passed.RequestURI = u.RequestURI()
+	} else {
+		passed.URL.Scheme, passed.URL.Host = "", ""
+	}
+	passed.TLS = nil // ...proxies typically talk plain HTTP to their backends.
+	if p.Header != nil {
+		p.Header(passed.Header, &original, prefix)
+	}
+	return passed, true
+}
+
+// setEscapedPath sets the path of the specified URL from the specified
+// (percent-encoded) path.
+func setEscapedPath(u *url.URL, escapedPath string) error {
+	path, err := url.PathUnescape(escapedPath)
+	if err != nil {
+		return err
+	}
+	u.Path, u.RawPath = path, escapedPath
+	if u.EscapedPath() != escapedPath {
+		u.RawPath = ""
+	}
+	return nil
+}
+
+// Handler returns an HTTP handler simulating the proxy in front of the
+// specified handler. Proxies can be chained by wrapping handlers returned by
+// other proxies.
+func (p *Proxy) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		passed, ok := p.Request(r)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, passed)
+	})
+}
+
+// Get sends a GET request for the specified (absolute) client URL through the
+// simulated proxy to the specified handler, returning the recorded response.
+func (p *Proxy) Get(next http.Handler, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	p.Handler(next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+// String returns the name of the simulated proxy together with its prefix.
+func (p *Proxy) String() string {
+	return p.Name + " " + strconv.Quote(p.Prefix)
+}
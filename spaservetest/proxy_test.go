@@ -0,0 +1,91 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaservetest
+
+import (
+	"net/http"
+	stdhttptest "net/http/httptest"
+	"testing/fstest"
+
+	"github.com/thediveo/spaserve"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("proxy simulators", func() {
+
+	spafs := fstest.MapFS{
+		"index.html":    &fstest.MapFile{Data: []byte(`<base href="./" />INDEX`)},
+		"assets/app.js": &fstest.MapFile{Data: []byte(`app();`)},
+	}
+
+	DescribeTable("serving the SPA behind proxies",
+		func(proxy *Proxy) {
+			h := spaserve.NewSPAHandler(spafs, "index.html")
+
+			w := proxy.Get(h, "https://example.org/app/some/route")
+			Expect(w.Code).To(Equal(http.StatusOK), proxy.String())
+			Expect(w.Body.String()).To(Equal(`<base href="/app/" />INDEX`), proxy.String())
+
+			w = proxy.Get(h, "https://example.org/app/assets/app.js")
+			Expect(w.Body.String()).To(Equal(`app();`), proxy.String())
+
+			Expect(proxy.Get(h, "https://example.org/application").Code).To(Equal(http.StatusNotFound))
+		},
+		Entry(nil, Nginx("/app")),
+		Entry(nil, Traefik("/app/")),
+		Entry(nil, IngressNginx("/app")),
+		Entry(nil, ForwardedURI("/app")),
+	)
+
+	It("mangles requests", func() {
+		r, ok := Traefik("/app").Request(
+			httptestRequest("https://example.org/app/a%2Fb?q=1"))
+		Expect(ok).To(BeTrue())
+		Expect(r.URL.String()).To(Equal("/a%2Fb?q=1"))
+		Expect(r.RequestURI).To(Equal("/a%2Fb?q=1"))
+		Expect(r.TLS).To(BeNil())
+		Expect(r.Header.Get("X-Forwarded-Prefix")).To(Equal("/app"))
+		Expect(r.Header.Get("X-Forwarded-Proto")).To(Equal("https"))
+		Expect(r.Header.Get("X-Forwarded-Host")).To(Equal("example.org"))
+		Expect(r.Header.Get("X-Forwarded-Port")).To(Equal("443"))
+
+		r, ok = ForwardedURI("/app").Request(httptestRequest("http://example.org:8080/app/?q=1"))
+		Expect(ok).To(BeTrue())
+		Expect(r.URL.Path).To(Equal("/"))
+		Expect(r.Header.Get("X-Forwarded-Uri")).To(Equal("/app/?q=1"))
+		Expect(originalPort(r.URL)).To(Equal("80"))
+	})
+
+	It("doesn't strip without a prefix", func() {
+		r, ok := (&Proxy{Name: "transparent"}).Request(httptestRequest("http://example.org/foo"))
+		Expect(ok).To(BeTrue())
+		Expect(r.URL.String()).To(Equal("/foo"))
+	})
+
+	It("chains proxies", func() {
+		h := spaserve.NewSPAHandler(spafs, "index.html")
+		outer := Nginx("/app").Handler(Traefik("/").Handler(h))
+		w := NewRecorder()
+		outer.ServeHTTP(w, httptestRequest("https://example.org/app/route"))
+		Expect(w.Body.String()).To(Equal(`<base href="/app/" />INDEX`))
+	})
+
+})
+
+func httptestRequest(target string) *http.Request {
+	return stdhttptest.NewRequest(http.MethodGet, target, nil)
+}
@@ -13,10 +13,30 @@
 // limitations under the License.
 
 /*
-Package httptest wraps the standard library's httptest.ResponseRecorder in order
-to fail any test doing superfluous response.WriteHeader calls.
+Package spaservetest helps testing applications serving SPAs using spaserve.
+
+Its proxy simulators mangle requests the way reverse proxies such as nginx,
+Traefik, and ingress-nginx do, stripping path prefixes and passing on the
+original request details in their particular header styles. This allows
+applications to test their deployment topology against their SPA handler
+without any real proxies:
+
+	proxy := spaservetest.Traefik("/app")
+	w := proxy.Get(handler, "https://example.org/app/some/route")
+
+Its request builders and Gomega matchers remove the boilerplate of checking
+the base element of served index documents:
+
+	Expect(spaservetest.GET("/foo").WithForwardedPrefix("/app").Serve(handler)).
+	    To(spaservetest.HaveBase("/app/"))
+
+CheckBundle checks SPA bundles for problems before deploying them, such as a
+missing or non-rewritable base element and asset URLs ignoring the base.
+
+Additionally, its wrapped httptest.ResponseRecorder fails any test doing
+superfluous response.WriteHeader calls.
 */
-package httptest
+package spaservetest
 
 import (
 	stdhttptest "net/http/httptest"
@@ -12,7 +12,7 @@
 // License for the specific language governing permissions and limitations
 // under the License.
 
-package httptest
+package spaservetest
 
 import (
 	. "github.com/onsi/ginkgo/v2"
@@ -0,0 +1,99 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaservetest
+
+import (
+	"net/http"
+	"net/http/httptest"
+)
+
+// RequestBuilder builds client requests passed on by a proxy, for testing SPA
+// handlers. For instance:
+//
+//	w := spaservetest.GET("/foo").WithForwardedPrefix("/app").Serve(handler)
+type RequestBuilder struct {
+	r *http.Request
+}
+
+// NewRequest returns a new RequestBuilder for a request with the specified
+// method and target, which is either a request URI path or an absolute URL.
+func NewRequest(method, target string) *RequestBuilder {
+	return &RequestBuilder{r: httptest.NewRequest(method, target, nil)}
+}
+
+// GET returns a new RequestBuilder for a GET request for the specified target.
+func GET(target string) *RequestBuilder {
+	return NewRequest(http.MethodGet, target)
+}
+
+// HEAD returns a new RequestBuilder for a HEAD request for the specified
+// target.
+func HEAD(target string) *RequestBuilder {
+	return NewRequest(http.MethodHead, target)
+}
+
+// WithHeader sets the specified request header.
+func (b *RequestBuilder) WithHeader(name, value string) *RequestBuilder {
+	b.r.Header.Set(name, value)
+	return b
+}
+
+// WithForwardedPrefix sets the X-Forwarded-Prefix header to the specified
+// prefix, as set by proxies stripping the prefix from the request path.
+func (b *RequestBuilder) WithForwardedPrefix(prefix string) *RequestBuilder {
+	return b.WithHeader("X-Forwarded-Prefix", prefix)
+}
+
+// WithForwardedURI sets the X-Forwarded-Uri header to the specified original
+// request URI.
+func (b *RequestBuilder) WithForwardedURI(uri string) *RequestBuilder {
+	return b.WithHeader("X-Forwarded-Uri", uri)
+}
+
+// WithForwardedProto sets the X-Forwarded-Proto header to the specified
+// original scheme, such as "https".
+func (b *RequestBuilder) WithForwardedProto(proto string) *RequestBuilder {
+	return b.WithHeader("X-Forwarded-Proto", proto)
+}
+
+// WithForwardedHost sets the X-Forwarded-Host header to the specified original
+// host.
+func (b *RequestBuilder) WithForwardedHost(host string) *RequestBuilder {
+	return b.WithHeader("X-Forwarded-Host", host)
+}
+
+// WithAccept sets the Accept header to the specified media types.
+func (b *RequestBuilder) WithAccept(accept string) *RequestBuilder {
+	return b.WithHeader("Accept", accept)
+}
+
+// WithCookie adds the specified cookie.
+func (b *RequestBuilder) WithCookie(cookie *http.Cookie) *RequestBuilder {
+	b.r.AddCookie(cookie)
+	return b
+}
+
+// Request returns the built request.
+func (b *RequestBuilder) Request() *http.Request {
+	return b.r
+}
+
+// Serve serves the built request using the specified handler, returning the
+// recorded response.
+func (b *RequestBuilder) Serve(h http.Handler) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, b.r)
+	return w
+}
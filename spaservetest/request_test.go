@@ -0,0 +1,58 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaservetest
+
+import (
+	"net/http"
+	"testing/fstest"
+
+	"github.com/thediveo/spaserve"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("request builder", func() {
+
+	spafs := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<base href="./" />INDEX`)},
+	}
+
+	It("builds requests", func() {
+		r := HEAD("https://example.org/foo").
+			WithForwardedPrefix("/app").
+			WithForwardedURI("/app/foo").
+			WithForwardedProto("https").
+			WithForwardedHost("example.com").
+			WithAccept("text/html").
+			WithCookie(&http.Cookie{Name: "session", Value: "42"}).
+			Request()
+		Expect(r.Method).To(Equal(http.MethodHead))
+		Expect(r.URL.Path).To(Equal("/foo"))
+		Expect(r.Header.Get("X-Forwarded-Prefix")).To(Equal("/app"))
+		Expect(r.Header.Get("X-Forwarded-Uri")).To(Equal("/app/foo"))
+		Expect(r.Header.Get("X-Forwarded-Proto")).To(Equal("https"))
+		Expect(r.Header.Get("X-Forwarded-Host")).To(Equal("example.com"))
+		Expect(r.Header.Get("Accept")).To(Equal("text/html"))
+		Expect(r.Cookie("session")).To(HaveField("Value", "42"))
+	})
+
+	It("serves requests", func() {
+		h := spaserve.NewSPAHandler(spafs, "index.html")
+		Expect(GET("/foo/bar").WithForwardedPrefix("/app").Serve(h)).To(HaveBase("/app/"))
+		Expect(GET("/foo/bar").Serve(h)).To(HaveBase("/"))
+	})
+
+})
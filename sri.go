@@ -0,0 +1,201 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"hash"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// IntegrityMode specifies how to deal with Subresource Integrity (SRI)
+// attributes in the index after rewriting it.
+type IntegrityMode int
+
+const (
+	// IntegrityKeep leaves integrity attributes untouched. This is the default.
+	IntegrityKeep IntegrityMode = iota
+	// IntegrityRecompute recomputes the integrity attributes of script and
+	// link elements referencing assets in the SPAHandler's fs, using the
+	// strongest hash algorithm of the original integrity attribute. Integrity
+	// attributes of elements referencing missing assets are stripped, while
+	// elements referencing other origins are left untouched.
+	IntegrityRecompute
+	// IntegrityStrip strips all integrity attributes from script and link
+	// elements.
+	IntegrityStrip
+)
+
+// sriTagRe matches opening script and link elements with an integrity
+// attribute, capturing the element name and its attributes.
+var sriTagRe = regexp.MustCompile(`(?i)<(script|link)(\s[^>]*\sintegrity\s*=[^>]*)>`)
+
+// integrityAttrRe matches the integrity attribute, capturing its (quoted)
+// value.
+var integrityAttrRe = regexp.MustCompile(`(?i)\sintegrity\s*=\s*("[^"]*"|'[^']*'|[^\s"'>]+)`)
+
+// srcAttrRe matches the src or href attribute, capturing its (quoted) value.
+var srcAttrRe = regexp.MustCompile(`(?i)\s(?:src|href)\s*=\s*("[^"]*"|'[^']*'|[^\s"'>]+)`)
+
+// sriHashKey identifies a cached SRI hash of a particular asset version.
+type sriHashKey struct {
+	name    string
+	alg     string
+	size    int64
+	modTime time.Time
+}
+
+// WithIntegrity sets the IntegrityMode for dealing with Subresource Integrity
+// attributes in the index after it has been rewritten, including the changes
+// made by any IndexRewriter. This ensures that rewritten or injected script and
+// link elements don't end up with broken integrity attributes, which would
+// cause browsers to refuse loading the referenced resources.
+func WithIntegrity(mode IntegrityMode) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.integrityMode = mode
+	}
+}
+
+// fixIntegrity returns the index with its integrity attributes recomputed or
+// stripped, depending on the IntegrityMode.
+func (h *SPAHandler) fixIntegrity(r *http.Request, index []byte) []byte {
+	base := h.basename(r)
+	b := h.bundleFor(r)
+	return sriTagRe.ReplaceAllFunc(index, func(tag []byte) []byte {
+		m := sriTagRe.FindStringSubmatch(string(tag))
+		attrs := m[2]
+		if h.integrityMode == IntegrityRecompute {
+			integrity, foreign := b.recomputeIntegrity(base, attrs)
+			if foreign {
+				return tag
+			}
+			if integrity != "" {
+				attrs = integrityAttrRe.ReplaceAllLiteralString(attrs, ` integrity="`+integrity+`"`)
+				return []byte("<" + m[1] + attrs + ">")
+			}
+		}
+		return []byte("<" + m[1] + integrityAttrRe.ReplaceAllLiteralString(attrs, "") + ">")
+	})
+}
+
+// recomputeIntegrity returns the recomputed integrity value for the element
+// with the specified attributes. If the referenced asset is missing, it returns
+// an empty string instead, signalling to strip the integrity attribute. If the
+// element references another origin, it returns true for foreign, signalling to
+// leave the element untouched.
+func (b *bundle) recomputeIntegrity(base string, attrs string) (integrity string, foreign bool) {
+	ref := srcAttrRe.FindStringSubmatch(attrs)
+	if ref == nil {
+		return "", false
+	}
+	u, err := url.Parse(unquoteAttr(ref[1]))
+	if err != nil {
+		return "", false
+	}
+	if u.Scheme != "" || u.Host != "" {
+		return "", true
+	}
+	name := u.Path
+	if strings.HasPrefix(name, "/") {
+		if !strings.HasPrefix(name, base) {
+			return "", false
+		}
+		name = name[len(base):]
+	}
+	name = path.Clean("/" + name)[1:]
+	alg := strongestSRIAlgorithm(unquoteAttr(integrityAttrRe.FindStringSubmatch(attrs)[1]))
+	integrity, err = b.assetIntegrity(name, alg)
+	if err != nil {
+		return "", false
+	}
+	return integrity, false
+}
+
+// assetIntegrity returns the SRI integrity value for the named asset in the
+// bundle's fs, using the specified hash algorithm. The integrity values are
+// cached for the particular asset versions.
+func (b *bundle) assetIntegrity(name string, alg string) (string, error) {
+	f, err := b.fs.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() {
+		return "", fs.ErrNotExist
+	}
+	key := sriHashKey{name: name, alg: alg, size: info.Size(), modTime: info.ModTime()}
+	if integrity, ok := b.integrityHashes.Load(key); ok {
+		return integrity.(string), nil
+	}
+	var hasher hash.Hash
+	switch alg {
+	case "sha512":
+		hasher = sha512.New()
+	case "sha384":
+		hasher = sha512.New384()
+	default:
+		hasher = sha256.New()
+	}
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", err
+	}
+	integrity := alg + "-" + base64.StdEncoding.EncodeToString(hasher.Sum(nil))
+	b.integrityHashes.Store(key, integrity)
+	return integrity, nil
+}
+
+// strongestSRIAlgorithm returns the strongest hash algorithm used in the
+// specified integrity metadata, defaulting to "sha384".
+func strongestSRIAlgorithm(integrity string) string {
+	strongest := ""
+	for _, metadata := range strings.Fields(integrity) {
+		alg, _, _ := strings.Cut(metadata, "-")
+		switch alg = strings.ToLower(alg); alg {
+		case "sha512":
+			return alg
+		case "sha384":
+			strongest = alg
+		case "sha256":
+			if strongest == "" {
+				strongest = alg
+			}
+		}
+	}
+	if strongest == "" {
+		return "sha384"
+	}
+	return strongest
+}
+
+// unquoteAttr returns the specified attribute value with its quotes removed.
+func unquoteAttr(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
@@ -0,0 +1,109 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/thediveo/spaserve/test/fixture"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+const sriIndex = `<html><head>
+<base href="./" />
+<script id="rel" src="app.js" integrity="sha256-wrong"></script>
+<link id="abs" rel="stylesheet" href="/app/app.css" integrity='sha256-wrong sha384-wrong'>
+<script id="missing" src="missing.js" integrity="sha512-wrong"></script>
+<script id="outside" src="/elsewhere/app.js" integrity="sha256-wrong"></script>
+<script id="foreign" src="https://cdn.example.com/lib.js" integrity="sha384-cdn"></script>
+<script id="plain" src="app.js"></script>
+</head></html>`
+
+var _ = Describe("subresource integrity", func() {
+
+	serve := func(mode IntegrityMode) *goquery.Document {
+		bundle := fixture.New(
+			fixture.WithFile("index.html", sriIndex),
+			fixture.WithFile("app.js", "console.log('app');\n"),
+			fixture.WithFile("app.css", "body {}\n"))
+		h := NewSPAHandler(bundle.FS, "index.html",
+			WithIntegrity(mode),
+			WithIndexRewriter(func(r *http.Request, index string) string {
+				return strings.Replace(index, "</head>",
+					`<script id="injected" src="app.js" integrity="sha512-stale"></script></head>`, 1)
+			}))
+		var doc *goquery.Document
+		for i := 0; i < 2; i++ { // ...second round uses cached hashes.
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set(ForwardedPrefixHeader, "/app")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			doc = Successful(goquery.NewDocumentFromReader(w.Body))
+		}
+		return doc
+	}
+
+	integrity := func(doc *goquery.Document, id string) string {
+		return doc.Find("#"+id).AttrOr("integrity", "<none>")
+	}
+
+	It("recomputes integrity attributes", func() {
+		js256 := sha256.Sum256([]byte("console.log('app');\n"))
+		js512 := sha512.Sum512([]byte("console.log('app');\n"))
+		css384 := sha512.Sum384([]byte("body {}\n"))
+
+		doc := serve(IntegrityRecompute)
+		Expect(integrity(doc, "rel")).To(Equal("sha256-" + base64.StdEncoding.EncodeToString(js256[:])))
+		Expect(integrity(doc, "abs")).To(Equal("sha384-" + base64.StdEncoding.EncodeToString(css384[:])))
+		Expect(integrity(doc, "injected")).To(Equal("sha512-" + base64.StdEncoding.EncodeToString(js512[:])))
+		Expect(integrity(doc, "missing")).To(Equal("<none>"))
+		Expect(integrity(doc, "outside")).To(Equal("<none>"))
+		Expect(integrity(doc, "foreign")).To(Equal("sha384-cdn"))
+		Expect(integrity(doc, "plain")).To(Equal("<none>"))
+	})
+
+	It("strips integrity attributes", func() {
+		doc := serve(IntegrityStrip)
+		Expect(doc.Find("[integrity]").Length()).To(BeZero())
+		Expect(doc.Find("script").Length()).To(Equal(6))
+	})
+
+	It("keeps integrity attributes by default", func() {
+		doc := serve(IntegrityKeep)
+		Expect(integrity(doc, "rel")).To(Equal("sha256-wrong"))
+	})
+
+	DescribeTable("determines the strongest SRI algorithm",
+		func(integrity string, expected string) {
+			Expect(strongestSRIAlgorithm(integrity)).To(Equal(expected))
+		},
+		Entry(nil, "", "sha384"),
+		Entry(nil, "md5-foo", "sha384"),
+		Entry(nil, "sha256-foo", "sha256"),
+		Entry(nil, "sha384-foo sha256-bar", "sha384"),
+		Entry(nil, "sha256-bar SHA512-foo", "sha512"),
+	)
+
+})
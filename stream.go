@@ -0,0 +1,115 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// canStreamIndex returns true if rewriting the index involves only its base
+// element, so the index can be streamed instead of being fully buffered.
+func (h *SPAHandler) canStreamIndex() bool {
+	return h.hasDeterministicIndex() && h.integrityMode == IntegrityKeep
+}
+
+// serveStreamedIndex serves the index with its base element rewritten, but
+// without assembling the rewritten index in a per-request buffer. Instead, the
+// response is streamed directly from the cached index segments interleaved
+// with the base path. The index segments must have been loaded from the
+// specified bundle.
+func (h *SPAHandler) serveStreamedIndex(w http.ResponseWriter, r *http.Request, b *bundle, segs *indexSegments) error {
+	start := time.Now()
+	index := &segmentedIndex{
+		parts: segs.parts,
+		base:  h.escapedBase(r),
+	}
+	index.size = segs.len(index.base)
+	for _, observer := range h.observers {
+		observer.ObserveIndexRewrite(r, time.Since(start))
+	}
+	basename := h.basename(r)
+	meta := b.cachedIndexMeta(segs.name, basename, segs.modTime)
+	if meta == nil {
+		digest := sha256.New()
+		if _, err := io.Copy(digest, index); err != nil {
+			return err
+		}
+		if _, err := index.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		var sum [sha256.Size]byte
+		digest.Sum(sum[:0])
+		meta = b.storeIndexMeta(segs.name, basename, segs.modTime, index.size, sum)
+	}
+	w.Header().Set("ETag", meta.etag)
+	http.ServeContent(w, r, "index.html", segs.modTime, index)
+	return nil
+}
+
+// segmentedIndex is an io.ReadSeeker over index segments interleaved with a
+// base path.
+type segmentedIndex struct {
+	parts []string // index segments.
+	base  string   // base path to insert between the segments.
+	size  int64    // total size of the rewritten index.
+	pos   int64    // current read position.
+}
+
+// Read implements io.Reader.
+func (s *segmentedIndex) Read(p []byte) (int, error) {
+	if s.pos >= s.size {
+		return 0, io.EOF
+	}
+	offset := s.pos
+	for idx, part := range s.parts {
+		if idx > 0 {
+			if offset < int64(len(s.base)) {
+				n := copy(p, s.base[offset:])
+				s.pos += int64(n)
+				return n, nil
+			}
+			offset -= int64(len(s.base))
+		}
+		if offset < int64(len(part)) {
+			n := copy(p, part[offset:])
+			s.pos += int64(n)
+			return n, nil
+		}
+		offset -= int64(len(part))
+	}
+	return 0, io.EOF
+}
+
+// Seek implements io.Seeker.
+func (s *segmentedIndex) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.pos
+	case io.SeekEnd:
+		offset += s.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	s.pos = offset
+	return offset, nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing/fstest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("streaming index", func() {
+
+	const head = `<html><head><base href="./" /><title>SSR</title></head><body>`
+	var body string
+	var mfs fstest.MapFS
+
+	BeforeEach(func() {
+		body = strings.Repeat("<p>prerendered</p>\n", 100000)
+		mfs = fstest.MapFS{
+			"index.html": &fstest.MapFile{
+				Data:    []byte(head + body + "</body></html>"),
+				ModTime: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+			},
+		}
+	})
+
+	serve := func(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+		r.Header.Set(ForwardedPrefixHeader, "/app")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	It("streams a large index with only its head rewritten", func() {
+		h := NewSPAHandler(mfs, "index.html")
+		w := serve(h, httptest.NewRequest(http.MethodGet, "/foo", nil))
+		Expect(w.Code).To(Equal(http.StatusOK))
+		expected := strings.Replace(head, `href="./"`, `href="/app/"`, 1) + body + "</body></html>"
+		Expect(w.Body.String()).To(Equal(expected))
+		Expect(w.Header().Get("Content-Length")).To(Equal(strconv.Itoa(len(expected))))
+		Expect(w.Header().Get("ETag")).NotTo(BeEmpty())
+
+		head := serve(h, httptest.NewRequest(http.MethodHead, "/foo", nil))
+		Expect(head.Header().Get("ETag")).To(Equal(w.Header().Get("ETag")))
+		Expect(head.Header().Get("Content-Length")).To(Equal(w.Header().Get("Content-Length")))
+	})
+
+	It("yields the same ETag as the fully buffered rewrite", func() {
+		streamed := serve(NewSPAHandler(mfs, "index.html"),
+			httptest.NewRequest(http.MethodGet, "/foo", nil))
+		buffered := serve(NewSPAHandler(mfs, "index.html", WithIntegrity(IntegrityStrip)),
+			httptest.NewRequest(http.MethodGet, "/foo", nil))
+		Expect(buffered.Body.String()).To(Equal(streamed.Body.String()))
+		Expect(buffered.Header().Get("ETag")).To(Equal(streamed.Header().Get("ETag")))
+	})
+
+	It("serves ranges across the rewritten head", func() {
+		h := NewSPAHandler(mfs, "index.html")
+		r := httptest.NewRequest(http.MethodGet, "/foo", nil)
+		r.Header.Set("Range", "bytes=10-79")
+		w := serve(h, r)
+		Expect(w.Code).To(Equal(http.StatusPartialContent))
+		expected := strings.Replace(head, `href="./"`, `href="/app/"`, 1) + body
+		Expect(w.Body.String()).To(Equal(expected[10:80]))
+	})
+
+	It("reads and seeks a segmented index", func() {
+		s := &segmentedIndex{
+			parts: []string{"<a", "b>", "!"},
+			base:  "/",
+			size:  7,
+		}
+		Expect(Successful(io.ReadAll(s))).To(Equal([]byte("<a/b>/!")))
+		Expect(s.Seek(-5, io.SeekEnd)).To(Equal(int64(2)))
+		Expect(Successful(io.ReadAll(s))).To(Equal([]byte("/b>/!")))
+		Expect(s.Seek(-2, io.SeekCurrent)).To(Equal(int64(5)))
+		Expect(Successful(io.ReadAll(s))).To(Equal([]byte("/!")))
+		Expect(s.Seek(-1, io.SeekStart)).Error().To(HaveOccurred())
+		Expect(s.Seek(0, 42)).Error().To(HaveOccurred())
+	})
+
+})
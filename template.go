@@ -0,0 +1,86 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+// IndexTemplateData returns the data to render the index template with for
+// the specified request.
+type IndexTemplateData func(r *http.Request) any
+
+// baseTemplateFunc is the name of the template function returning the base
+// path, which gets called in place of the href values of base elements.
+const baseTemplateFunc = "spaserveBase"
+
+// WithIndexTemplate treats the index file as an html/template, rendering it
+// with the data returned by the specified function after the base has been
+// rewritten. As html/template escapes the data contextually, this is the safe
+// way to inject user- or tenant-derived data into the index, in contrast to
+// WithIndexRewriter. For instance, with an index file containing:
+//
+//	<title>{{.Tenant}}</title>
+//
+// the following handler renders the tenant name properly escaped:
+//
+//	h := NewSPAHandler(bundle, "index.html",
+//	    WithIndexTemplate(func(r *http.Request) any {
+//	        return struct{ Tenant string }{Tenant: tenantOf(r)}
+//	    }))
+//
+// The index template is parsed only when the index file is (re)loaded. Index
+// files failing to parse or render are answered with 500.
+func WithIndexTemplate(data IndexTemplateData) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.indexTemplate = data
+	}
+}
+
+// parseIndexTemplate returns the parsed index template for the specified
+// index parts as split at the href values of their base elements, or nil if
+// index templates aren't enabled. The base href values get rendered by
+// calling a template function, so the parsed template is independent of the
+// base.
+func (h *SPAHandler) parseIndexTemplate(name string, parts []string) (*template.Template, error) {
+	if h.indexTemplate == nil {
+		return nil, nil
+	}
+	return template.New(name).
+		Funcs(template.FuncMap{baseTemplateFunc: func() string { return "" }}).
+		Parse(strings.Join(parts, "{{"+baseTemplateFunc+"}}"))
+}
+
+// renderIndexTemplate returns the index rendered from the specified
+// segments' template for the specified request.
+func (h *SPAHandler) renderIndexTemplate(r *http.Request, segs *indexSegments) ([]byte, error) {
+	// As executing a template forbids cloning it afterwards, we never execute
+	// the cached template itself, but only clones of it, which additionally
+	// allows binding the base path per request.
+	tmpl, err := segs.tmpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+	base := h.escapedBase(r)
+	tmpl.Funcs(template.FuncMap{baseTemplateFunc: func() string { return base }})
+	var buff bytes.Buffer
+	if err := tmpl.Execute(&buff, h.indexTemplate(r)); err != nil {
+		return nil, err
+	}
+	return buff.Bytes(), nil
+}
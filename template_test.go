@@ -0,0 +1,74 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("index templates", func() {
+
+	spafs := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(
+			`<html><head><base href="./" /><title>{{.Tenant}}</title></head>` +
+				`<body><script>const tenant = {{.Tenant}};</script></body></html>`)},
+		"broken.html":  &fstest.MapFile{Data: []byte(`<base href="./" />{{.Tenant`)},
+		"failing.html": &fstest.MapFile{Data: []byte(`<base href="./" />{{.Tenant.Nada}}`)},
+	}
+
+	data := func(r *http.Request) any {
+		return struct{ Tenant string }{Tenant: r.URL.Query().Get("tenant")}
+	}
+
+	get := func(h http.Handler, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		r.Header.Set(ForwardedPrefixHeader, "/app")
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	It("renders the index with escaped data", func() {
+		h := NewSPAHandler(spafs, "index.html", WithIndexTemplate(data))
+		for i := 0; i < 2; i++ {
+			w := get(h, "/foo?tenant=</script><script>alert(1)</script>")
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(Equal(
+				`<html><head><base href="/app/" /><title>&lt;/script&gt;&lt;script&gt;alert(1)&lt;/script&gt;</title></head>` +
+					`<body><script>const tenant = "\u003c/script\u003e\u003cscript\u003ealert(1)\u003c/script\u003e";</script></body></html>`))
+		}
+		Expect(get(h, "/bar?tenant=ACME").Body.String()).To(ContainSubstring(`<title>ACME</title>`))
+	})
+
+	It("leaves template actions alone by default", func() {
+		h := NewSPAHandler(spafs, "index.html")
+		Expect(get(h, "/foo").Body.String()).To(ContainSubstring(`<title>{{.Tenant}}</title>`))
+	})
+
+	DescribeTable("reports template errors",
+		func(index string) {
+			h := NewSPAHandler(spafs, index, WithIndexTemplate(data))
+			Expect(get(h, "/foo").Code).To(Equal(http.StatusInternalServerError))
+		},
+		Entry("parse error", "broken.html"),
+		Entry("execution error", "failing.html"),
+	)
+
+})
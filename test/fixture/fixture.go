@@ -0,0 +1,199 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package fixture programmatically builds in-memory SPA bundles as fs.FS for use
+in tests, so that handler tests don't need to commit fixture file trees.
+
+A generated bundle resembles the typical output of SPA build tools: an index
+file with a base element, content-hashed JavaScript and CSS chunks, a web app
+manifest, and a service worker.
+
+	bundle := fixture.New(fixture.WithChunks("vendor"))
+	h := spaserve.NewSPAHandler(bundle.FS, bundle.Index)
+*/
+package fixture
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"testing/fstest"
+	"time"
+)
+
+// Bundle is a generated in-memory SPA bundle.
+type Bundle struct {
+	FS            fstest.MapFS // the in-memory FS with the generated files.
+	Index         string       // unrooted path of the index file.
+	Chunks        []string     // unrooted paths of the content-hashed chunks.
+	Manifest      string       // unrooted path of the manifest; empty if none.
+	ServiceWorker string       // unrooted path of the service worker; empty if none.
+}
+
+// Option configures a Bundle to be generated by New.
+type Option func(*config)
+
+type config struct {
+	index         string
+	base          string
+	chunks        []string
+	manifest      string
+	serviceWorker string
+	title         string
+	modTime       time.Time
+	files         map[string]string
+}
+
+// WithIndex sets the path and name of the index file; defaults to
+// "index.html".
+func WithIndex(name string) Option {
+	return func(c *config) { c.index = strings.TrimPrefix(path.Clean("/"+name), "/") }
+}
+
+// WithBase sets the href of the index file's base element; defaults to "./".
+// Setting an empty base omits the base element.
+func WithBase(href string) Option {
+	return func(c *config) { c.base = href }
+}
+
+// WithChunks adds further JavaScript chunks with the specified names, in
+// addition to the default "index" chunk. Each chunk gets a content hash added
+// to its name, such as "assets/vendor-0123abcd.js".
+func WithChunks(names ...string) Option {
+	return func(c *config) { c.chunks = append(c.chunks, names...) }
+}
+
+// WithManifest sets the path and name of the web app manifest; defaults to
+// "manifest.webmanifest". Setting an empty name omits the manifest.
+func WithManifest(name string) Option {
+	return func(c *config) { c.manifest = name }
+}
+
+// WithServiceWorker sets the path and name of the service worker; defaults to
+// "sw.js". Setting an empty name omits the service worker.
+func WithServiceWorker(name string) Option {
+	return func(c *config) { c.serviceWorker = name }
+}
+
+// WithTitle sets the index's document title; defaults to "SPA fixture".
+func WithTitle(title string) Option {
+	return func(c *config) { c.title = title }
+}
+
+// WithModTime sets the modification time of all generated files; defaults to
+// the zero time.
+func WithModTime(t time.Time) Option {
+	return func(c *config) { c.modTime = t }
+}
+
+// WithFile adds an additional file with the specified unrooted path and
+// contents, overwriting any generated file of the same name.
+func WithFile(name string, contents string) Option {
+	return func(c *config) { c.files[name] = contents }
+}
+
+// New returns a new in-memory SPA bundle generated according to the specified
+// options.
+func New(opts ...Option) *Bundle {
+	c := &config{
+		index:         "index.html",
+		base:          "./",
+		chunks:        []string{"index"},
+		manifest:      "manifest.webmanifest",
+		serviceWorker: "sw.js",
+		title:         "SPA fixture",
+		files:         map[string]string{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	b := &Bundle{
+		FS:    fstest.MapFS{},
+		Index: c.index,
+	}
+	add := func(name, contents string) {
+		b.FS[name] = &fstest.MapFile{
+			Data:    []byte(contents),
+			Mode:    0444,
+			ModTime: c.modTime,
+		}
+	}
+
+	var head strings.Builder
+	if c.base != "" {
+		fmt.Fprintf(&head, "    <base href=\"%s\" />\n", c.base)
+	}
+	for _, chunk := range c.chunks {
+		contents := fmt.Sprintf("// chunk %s\nconsole.log(%q);\n", chunk, chunk)
+		name := hashedName("assets/"+chunk, ".js", contents)
+		add(name, contents)
+		b.Chunks = append(b.Chunks, name)
+		fmt.Fprintf(&head, "    <script type=\"module\" crossorigin src=\"%s\"></script>\n", name)
+	}
+	css := "body { margin: 0; }\n"
+	cssName := hashedName("assets/index", ".css", css)
+	add(cssName, css)
+	b.Chunks = append(b.Chunks, cssName)
+	fmt.Fprintf(&head, "    <link rel=\"stylesheet\" href=\"%s\" />\n", cssName)
+	if c.manifest != "" {
+		manifest, _ := json.MarshalIndent(map[string]any{
+			"name":      c.title,
+			"start_url": ".",
+			"scope":     ".",
+			"display":   "standalone",
+			"icons": []map[string]string{
+				{"src": "icon.png", "sizes": "192x192", "type": "image/png"},
+			},
+		}, "", "  ")
+		add(c.manifest, string(manifest)+"\n")
+		add("icon.png", "")
+		b.Manifest = c.manifest
+		fmt.Fprintf(&head, "    <link rel=\"manifest\" href=\"%s\" />\n", c.manifest)
+	}
+	if c.serviceWorker != "" {
+		add(c.serviceWorker, "self.addEventListener('fetch', () => {});\n")
+		b.ServiceWorker = c.serviceWorker
+		fmt.Fprintf(&head, "    <script>navigator.serviceWorker?.register(%q);</script>\n", c.serviceWorker)
+	}
+	add(c.index, fmt.Sprintf(`<!doctype html>
+<html lang="en">
+
+<head>
+    <meta charset="utf-8" />
+%s    <title>%s</title>
+</head>
+
+<body>
+    <div id="root"></div>
+</body>
+
+</html>
+`, head.String(), c.title))
+
+	for name, contents := range c.files {
+		add(name, contents)
+	}
+	return b
+}
+
+// hashedName returns the name with the first eight hex digits of the SHA256
+// hash over the contents appended, as well as the extension.
+func hashedName(name string, ext string, contents string) string {
+	sum := sha256.Sum256([]byte(contents))
+	return name + "-" + hex.EncodeToString(sum[:])[:8] + ext
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package fixture
+
+import (
+	"io/fs"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("SPA bundle fixtures", func() {
+
+	It("generates a default bundle", func() {
+		b := New()
+		Expect(b.Index).To(Equal("index.html"))
+		Expect(b.Manifest).To(Equal("manifest.webmanifest"))
+		Expect(b.ServiceWorker).To(Equal("sw.js"))
+		Expect(b.Chunks).To(ConsistOf(
+			MatchRegexp(`^assets/index-[0-9a-f]{8}\.js$`),
+			MatchRegexp(`^assets/index-[0-9a-f]{8}\.css$`)))
+
+		index := string(Successful(fs.ReadFile(b.FS, b.Index)))
+		Expect(index).To(ContainSubstring(`<base href="./" />`))
+		for _, chunk := range b.Chunks {
+			Expect(index).To(ContainSubstring(chunk))
+		}
+		Expect(fstest.TestFS(b.FS, append(b.Chunks, b.Index, b.Manifest, b.ServiceWorker)...)).To(Succeed())
+	})
+
+	It("generates a customized bundle", func() {
+		b := New(
+			WithIndex("/app/../shell.html"),
+			WithBase(""),
+			WithChunks("vendor"),
+			WithManifest(""),
+			WithServiceWorker(""),
+			WithTitle("FOOBAR"),
+			WithFile("config.json", "{}"))
+		Expect(b.Index).To(Equal("shell.html"))
+		Expect(b.Manifest).To(BeEmpty())
+		Expect(b.ServiceWorker).To(BeEmpty())
+		Expect(b.Chunks).To(ContainElement(MatchRegexp(`^assets/vendor-[0-9a-f]{8}\.js$`)))
+		index := string(Successful(fs.ReadFile(b.FS, b.Index)))
+		Expect(index).NotTo(ContainSubstring("<base"))
+		Expect(index).To(ContainSubstring("<title>FOOBAR</title>"))
+		Expect(fs.ReadFile(b.FS, "config.json")).To(Equal([]byte("{}")))
+	})
+
+})
@@ -0,0 +1 @@
+// CANARY ADMIN JS
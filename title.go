@@ -0,0 +1,79 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"html"
+	"net/http"
+	"regexp"
+)
+
+// TitleFunc returns the document title for the specified route path, which is
+// the request path below the base path of the SPA, such as “/products/42”. It
+// returns false if the index is to be served with its original title.
+type TitleFunc func(route string) (string, bool)
+
+// titleRe matches the (first) title element, capturing its start and end tags.
+var titleRe = regexp.MustCompile(`(?is)(<title(?:\s[^>]*)?>).*?(</title\s*>)`)
+
+// WithTitle replaces the contents of the index's title element with the title
+// returned by the specified function for the requested route, so that users
+// and crawlers see route-specific titles even before the SPA has started. For
+// instance:
+//
+//	h := NewSPAHandler(bundle, "index.html",
+//	    WithTitle(func(route string) (string, bool) {
+//	        if product, ok := strings.CutPrefix(route, "/products/"); ok {
+//	            return "Product " + product + " – ACME Shop", true
+//	        }
+//	        return "", false
+//	    }))
+//
+// The title is properly escaped. If the index lacks a title element, a title
+// element is added at the end of its head element instead.
+func WithTitle(title TitleFunc) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.titleFunc = title
+	}
+}
+
+// rewriteTitle returns the specified index contents with the title for the
+// specified request.
+func (h *SPAHandler) rewriteTitle(r *http.Request, index []byte) []byte {
+	if h.titleFunc == nil {
+		return index
+	}
+	title, ok := h.titleFunc(r.URL.Path)
+	if !ok {
+		return index
+	}
+	title = html.EscapeString(title)
+	if loc := titleRe.FindSubmatchIndex(index); loc != nil {
+		return splice(index, loc[3], loc[4], title)
+	}
+	if loc := headEndRe.FindIndex(index); loc != nil {
+		return splice(index, loc[0], loc[0], "<title>"+title+"</title>")
+	}
+	return index
+}
+
+// splice returns the specified contents with the range from start to end
+// replaced by the specified insertion.
+func splice(contents []byte, start, end int, insertion string) []byte {
+	spliced := make([]byte, 0, len(contents)-(end-start)+len(insertion))
+	spliced = append(spliced, contents[:start]...)
+	spliced = append(spliced, insertion...)
+	return append(spliced, contents[end:]...)
+}
@@ -0,0 +1,65 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("document titles", func() {
+
+	spafs := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(
+			`<head><base href="./" /><Title lang="en">ACME
+Shop</Title></head><body><svg><title>Logo</title></svg></body>`)},
+		"untitled.html": &fstest.MapFile{Data: []byte(`<head><base href="./" /></head>`)},
+		"headless.html": &fstest.MapFile{Data: []byte(`<base href="./" />`)},
+	}
+
+	title := func(route string) (string, bool) {
+		if product, ok := strings.CutPrefix(route, "/products/"); ok {
+			return "Product <" + product + "> – ACME Shop", true
+		}
+		return "", false
+	}
+
+	DescribeTable("rewrites titles",
+		func(index string, path string, expected string) {
+			h := NewSPAHandler(spafs, index, WithTitle(title))
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, path, nil)
+			r.Header.Set(ForwardedPrefixHeader, "/app")
+			h.ServeHTTP(w, r)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(Equal(expected))
+		},
+		Entry("route-specific title", "index.html", "/products/42",
+			`<head><base href="/app/" /><Title lang="en">Product &lt;42&gt; – ACME Shop</Title></head>`+
+				`<body><svg><title>Logo</title></svg></body>`),
+		Entry("original title", "index.html", "/about",
+			"<head><base href=\"/app/\" /><Title lang=\"en\">ACME\nShop</Title></head>"+
+				`<body><svg><title>Logo</title></svg></body>`),
+		Entry("missing title", "untitled.html", "/products/42",
+			`<head><base href="/app/" /><title>Product &lt;42&gt; – ACME Shop</title></head>`),
+		Entry("missing head", "headless.html", "/products/42", `<base href="/app/" />`),
+	)
+
+})
@@ -0,0 +1,140 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// Outcome describes how an SPAHandler served a request.
+type Outcome int
+
+const (
+	OutcomeIndex    Outcome = iota // served the (rewritten) index.
+	OutcomeStatic                  // served a static asset.
+	OutcomeNotFound                // rejected falling back to the index.
+	OutcomeRedirect                // redirected elsewhere.
+	OutcomeRejected                // rejected the request, such as its method.
+	OutcomeOptions                 // answered an OPTIONS request.
+	OutcomeProbe                   // answered a liveness or readiness probe.
+	OutcomePassedOn                // passed the request on to another handler.
+)
+
+// String returns the textual representation of an Outcome, such as "index".
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeIndex:
+		return "index"
+	case OutcomeStatic:
+		return "static"
+	case OutcomeNotFound:
+		return "notfound"
+	case OutcomeRedirect:
+		return "redirect"
+	case OutcomeRejected:
+		return "rejected"
+	case OutcomeOptions:
+		return "options"
+	case OutcomeProbe:
+		return "probe"
+	case OutcomePassedOn:
+		return "passedon"
+	}
+	return "unknown"
+}
+
+// trackingResponseWriter wraps an http.ResponseWriter in order to track the
+// response status code and the number of response body bytes written.
+type trackingResponseWriter struct {
+	http.ResponseWriter
+	status  int
+	written int64
+}
+
+// Status returns the response status code, defaulting to 200 if no status code
+// has been set explicitly.
+func (w *trackingResponseWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// WriteHeader implements http.ResponseWriter, tracking the status code.
+func (w *trackingResponseWriter) WriteHeader(status int) {
+	if w.status == 0 && status >= 200 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements http.ResponseWriter, tracking the number of bytes written.
+func (w *trackingResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.written += int64(n)
+	return n, err
+}
+
+// ReadFrom implements io.ReaderFrom in order to not defeat optimizations, such
+// as sendfile, of the wrapped http.ResponseWriter.
+func (w *trackingResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	var n int64
+	var err error
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = io.Copy(struct{ io.Writer }{w.ResponseWriter}, r)
+	}
+	w.written += n
+	return n, err
+}
+
+// Flush implements http.Flusher, if supported by the wrapped
+// http.ResponseWriter.
+func (w *trackingResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, if supported by the wrapped
+// http.ResponseWriter, such as for WebSocket handshakes. Hijacked connections
+// are tracked with the status code 101 (Switching Protocols).
+func (w *trackingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, rw, err := hj.Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the wrapped http.ResponseWriter for use by
+// http.ResponseController.
+func (w *trackingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,61 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("tracking response writer", func() {
+
+	It("tracks status and bytes written", func() {
+		rec := httptest.NewRecorder()
+		w := &trackingResponseWriter{ResponseWriter: rec}
+		Expect(w.Status()).To(Equal(http.StatusOK))
+		w.WriteHeader(http.StatusTeapot)
+		Expect(w.Write([]byte("foo"))).To(Equal(3))
+		Expect(w.ReadFrom(strings.NewReader("barbaz"))).To(Equal(int64(6)))
+		w.Flush()
+		Expect(w.Status()).To(Equal(http.StatusTeapot))
+		Expect(w.written).To(Equal(int64(9)))
+		Expect(rec.Body.String()).To(Equal("foobarbaz"))
+		Expect(rec.Flushed).To(BeTrue())
+		Expect(w.Unwrap()).To(BeIdenticalTo(rec))
+		Expect(http.NewResponseController(w).Flush()).To(Succeed())
+	})
+
+	It("defaults to 200 on writing", func() {
+		w := &trackingResponseWriter{ResponseWriter: httptest.NewRecorder()}
+		Successful(w.ReadFrom(strings.NewReader("foo")))
+		Expect(w.status).To(Equal(http.StatusOK))
+	})
+
+	It("returns outcome names", func() {
+		Expect(OutcomeIndex.String()).To(Equal("index"))
+		Expect(OutcomeRedirect.String()).To(Equal("redirect"))
+		Expect(OutcomeRejected.String()).To(Equal("rejected"))
+		Expect(OutcomeOptions.String()).To(Equal("options"))
+		Expect(OutcomeProbe.String()).To(Equal("probe"))
+		Expect(OutcomePassedOn.String()).To(Equal("passedon"))
+		Expect(Outcome(-1).String()).To(Equal("unknown"))
+	})
+
+})
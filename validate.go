@@ -0,0 +1,163 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+)
+
+// ErrInvalidConfiguration signals an SPAHandler misconfiguration detected by
+// NewSPAHandlerE.
+var ErrInvalidConfiguration = errors.New("invalid SPA handler configuration")
+
+// NewSPAHandlerE returns a new HTTP handler serving static resources from the
+// specified fs, exactly as NewSPAHandler does. In contrast to NewSPAHandler,
+// NewSPAHandlerE validates the configuration, returning an error wrapping
+// ErrInvalidConfiguration when the index file doesn't exist in the fs,
+// option arguments aren't sane, or options conflict with each other. Thus,
+// misconfigurations surface at startup instead of as 404 or 500 responses to
+// the first requests. For instance:
+//
+//	h, err := NewSPAHandlerE(os.DirFS("/opt/data/myspa"), "index.html")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//
+// The index file is checked in all fs.FSes served, that is, the fs.FS passed to
+// NewSPAHandlerE as well as those of WithCanary and WithHostSPAs; entry
+// documents of WithEntry, the legacy and localized index files of
+// WithLegacyIndex and WithLocalizedIndex, as well as the service worker script
+// of WithServiceWorker are checked in the fs.FS passed to NewSPAHandlerE.
+// Nothing is checked when using WithDevServer, as the dev server might not yet
+// be running.
+func NewSPAHandlerE(fs fs.FS, index string, opts ...SPAHandlerOption) (*SPAHandler, error) {
+	h := newSPAHandler(fs, index, opts...)
+	if err := h.validate(); err != nil {
+		return nil, err
+	}
+	h.startInvalidation()
+	return h, nil
+}
+
+// invalidOption records an invalid argument of the named option, to be
+// reported by NewSPAHandlerE.
+func (h *SPAHandler) invalidOption(option string, format string, args ...any) {
+	h.optionErrs = append(h.optionErrs,
+		fmt.Errorf("%w: %s: %s", ErrInvalidConfiguration, option, fmt.Sprintf(format, args...)))
+}
+
+// validate returns an error describing all problems with the configuration of
+// this SPAHandler, or nil if there are none.
+func (h *SPAHandler) validate() error {
+	errs := append([]error(nil), h.optionErrs...)
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrInvalidConfiguration, fmt.Sprintf(format, args...)))
+	}
+	if len(h.allowedMethods) == 0 {
+		invalid("WithAllowedMethods: no methods allowed")
+	}
+	if h.maxIndexSize < 0 {
+		invalid("WithMaxIndexSize: negative size %d", h.maxIndexSize)
+	}
+	if h.httpsRedirect && (h.httpsPort < 0 || h.httpsPort > 65535) {
+		invalid("WithHTTPSRedirect: port %d not in [0, 65535]", h.httpsPort)
+	}
+	if h.invalidation != nil && h.invalidation.interval <= 0 {
+		invalid("WithCacheInvalidation: non-positive interval %s", h.invalidation.interval)
+	}
+	if h.liveReload != nil && h.liveReload.interval <= 0 {
+		invalid("WithLiveReload: non-positive interval %s", h.liveReload.interval)
+	}
+	if h.devServer != nil && h.canary != nil {
+		invalid("WithDevServer conflicts with WithCanary")
+	}
+	if h.devServer != nil {
+		return errors.Join(errs...)
+	}
+	if h.current().fs == nil && h.hosts == nil {
+		invalid("no fs.FS to serve from")
+	}
+	if err := checkIndexFile(h.current(), h.bundleIndex(h.current())); err != nil {
+		errs = append(errs, err)
+	}
+	for _, e := range h.entries {
+		if err := checkIndexFile(h.current(), e.index); err != nil {
+			errs = append(errs, fmt.Errorf("entry %s: %w", e.prefix, err))
+		}
+	}
+	if h.canary != nil {
+		if err := checkIndexFile(h.canary.bundle, h.bundleIndex(h.canary.bundle)); err != nil {
+			errs = append(errs, fmt.Errorf("canary: %w", err))
+		}
+	}
+	if h.isLegacy != nil {
+		if err := checkIndexFile(h.current(), h.legacyIndex); err != nil {
+			errs = append(errs, fmt.Errorf("WithLegacyIndex: %w", err))
+		}
+	}
+	tags := make([]string, 0, len(h.indexLanguages))
+	for _, tag := range h.indexLanguages {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		if err := checkIndexFile(h.current(), localizedName(h.bundleIndex(h.current()), tag)); err != nil {
+			errs = append(errs, fmt.Errorf("WithLocalizedIndex: %w", err))
+		}
+	}
+	if h.serviceWorker != nil {
+		if err := checkFile(h.current(), "service worker", h.serviceWorker.path[1:]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	hosts := make([]string, 0, len(h.hosts))
+	for host := range h.hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		if err := checkIndexFile(h.hosts[host], h.bundleIndex(h.hosts[host])); err != nil {
+			errs = append(errs, fmt.Errorf("host %s: %w", host, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkIndexFile returns an error if the named index file is missing or isn't
+// a regular file in the specified bundle. A bundle without an fs.FS is fine,
+// as there's nothing to check.
+func checkIndexFile(b *bundle, index string) error {
+	return checkFile(b, "index file", index)
+}
+
+// checkFile returns an error if the named file of the specified kind is
+// missing or isn't a regular file in the specified bundle, see also
+// checkIndexFile.
+func checkFile(b *bundle, kind string, name string) error {
+	if b.fs == nil {
+		return nil
+	}
+	fileInfo, err := fs.Stat(b.fs, name)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrInvalidConfiguration, kind, err)
+	}
+	if !fileInfo.Mode().IsRegular() {
+		return fmt.Errorf("%w: %s %s is not a regular file", ErrInvalidConfiguration, kind, name)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("validating handler configuration", func() {
+
+	spafs := fstest.MapFS{
+		"index.html":     {Data: []byte(`<html><head><base href="./" /></head></html>`)},
+		"app/index.html": {Data: []byte(`<html></html>`)},
+	}
+
+	It("returns a handler for a sane configuration", func() {
+		h := Successful(NewSPAHandlerE(spafs, "index.html",
+			WithCanary(spafs, spafs, 10, "canary"),
+			WithHostSPAs(map[string]fs.FS{"a.example.com": spafs})))
+		Expect(h.Index()).To(Equal("index.html"))
+	})
+
+	It("doesn't check the index when using a dev server", func() {
+		Expect(NewSPAHandlerE(nil, "index.html",
+			WithDevServer(&url.URL{Scheme: "http", Host: "localhost:1"}))).Error().NotTo(HaveOccurred())
+	})
+
+	DescribeTable("rejects invalid configurations",
+		func(fsys fs.FS, index string, opt SPAHandlerOption, expected string) {
+			opts := []SPAHandlerOption{}
+			if opt != nil {
+				opts = append(opts, opt)
+			}
+			h, err := NewSPAHandlerE(fsys, index, opts...)
+			Expect(h).To(BeNil())
+			Expect(err).To(MatchError(ErrInvalidConfiguration))
+			Expect(err).To(MatchError(ContainSubstring(expected)))
+		},
+		Entry(nil, nil, "index.html", nil, "no fs.FS"),
+		Entry(nil, spafs, "missing.html", nil, "index file"),
+		Entry(nil, spafs, "app", nil, "not a regular file"),
+		Entry(nil, spafs, "index.html", WithCanary(spafs, fstest.MapFS{}, 10, "canary"), "canary: "),
+		Entry(nil, spafs, "index.html", WithCanary(spafs, spafs, 110, "canary"), "WithCanary"),
+		Entry(nil, spafs, "index.html", WithHostSPAs(map[string]fs.FS{"a.example.com": fstest.MapFS{}}), "host a.example.com"),
+		Entry(nil, spafs, "index.html", WithCanonicalIndexRedirect(http.StatusFound), "WithCanonicalIndexRedirect"),
+		Entry(nil, spafs, "index.html", WithAllowedMethods(), "WithAllowedMethods"),
+		Entry(nil, spafs, "index.html", WithMaxIndexSize(-1), "WithMaxIndexSize"),
+		Entry(nil, spafs, "index.html", WithHTTPSRedirect(100000), "WithHTTPSRedirect"),
+		Entry(nil, spafs, "index.html", WithCacheInvalidation(context.Background(), 0), "WithCacheInvalidation"),
+		Entry(nil, spafs, "index.html", WithLiveReload(0), "WithLiveReload"),
+		Entry(nil, spafs, "index.html", WithAssetCachePolicy("assets/[", CachePolicy{}), "WithAssetCachePolicy"),
+		Entry(nil, spafs, "index.html", WithCSPNonce("script-src 'self'"), "WithCSPNonce"),
+		Entry(nil, spafs, "index.html", WithLegacyIndex("legacy.html", nil), "WithLegacyIndex"),
+		Entry(nil, spafs, "index.html", WithLocalizedIndex("de"), "WithLocalizedIndex"),
+		Entry(nil, spafs, "index.html", WithServiceWorker("sw.js", ""), "service worker"),
+		Entry(nil, spafs, "index.html", WithCachePrimer(0, 1, 1), "WithCachePrimer"),
+		Entry(nil, spafs, "index.html", WithCachePrimer(10, 0, 1), "WithCachePrimer"),
+		Entry(nil, spafs, "index.html", WithPrerenderFS(nil, nil), "WithPrerenderFS"),
+		Entry(nil, spafs, "index.html", WithSharedAssets(nil, "vendor"), "WithSharedAssets"),
+		Entry(nil, spafs, "index.html", WithCORSPreflight(CORSPreflight{
+			AllowedOrigins: []string{"*"}, AllowCredentials: true,
+		}), "WithCORSPreflight"),
+		Entry(nil, nil, "index.html", func(h *SPAHandler) {
+			WithDevServer(&url.URL{Scheme: "http", Host: "localhost:1"})(h)
+			WithCanary(spafs, spafs, 10, "canary")(h)
+		}, "conflicts"),
+	)
+
+})
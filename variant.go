@@ -0,0 +1,79 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"path"
+)
+
+// IndexSelector returns the (unrooted) path and name of the index file to
+// serve for the specified request, such as for A/B tests based on a cookie or
+// experiment header. Returning an empty string selects the default index file
+// passed to NewSPAHandler.
+type IndexSelector func(r *http.Request) string
+
+// WithIndexSelector sets the IndexSelector choosing between multiple index
+// files per request. All index files get their base elements rewritten the
+// same way as the default index file. For instance:
+//
+//	h := NewSPAHandler(fsys, "index.html",
+//	    WithIndexSelector(func(r *http.Request) string {
+//	        if r.Header.Get("X-Experiment") == "new-checkout" {
+//	            return "index-b.html"
+//	        }
+//	        return ""
+//	    }))
+//
+// Please note that a selected index file that doesn't exist results in 404.
+func WithIndexSelector(selector IndexSelector) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.indexSelector = selector
+	}
+}
+
+// indexFor returns the (unrooted) path and name of the index file to serve for
+// the specified request.
+func (h *SPAHandler) indexFor(r *http.Request) string {
+	if h.indexSelector != nil {
+		if index := path.Clean("/" + h.indexSelector(r))[1:]; index != "" {
+			return index
+		}
+	}
+	if index := h.entryFor(r); index != "" {
+		return index
+	}
+	if index := h.legacyIndexFor(r); index != "" {
+		return index
+	}
+	if index := h.localizedIndex(r); index != "" {
+		return index
+	}
+	return h.bundleIndex(h.bundleFor(r))
+}
+
+// varyIndex adds the request headers the choice of index file depends on to
+// the Vary response header.
+func (h *SPAHandler) varyIndex(w http.ResponseWriter) {
+	if h.isLegacy != nil {
+		w.Header().Add("Vary", "User-Agent")
+	}
+	if len(h.indexLanguages) > 0 {
+		w.Header().Add("Vary", "Accept-Language")
+	}
+	if h.canary != nil {
+		w.Header().Add("Vary", "Cookie")
+	}
+}
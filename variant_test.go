@@ -0,0 +1,75 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("index variants", func() {
+
+	mfs := fstest.MapFS{
+		"index.html":          &fstest.MapFile{Data: []byte(`<base href="./" />A`)},
+		"variants/index.html": &fstest.MapFile{Data: []byte(`<base href="./" />B`)},
+	}
+
+	selector := func(r *http.Request) string {
+		return r.Header.Get("X-Experiment")
+	}
+
+	serve := func(h http.Handler, method string, variant string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/foo", nil)
+		r.Header.Set(ForwardedPrefixHeader, "/app")
+		r.Header.Set("X-Experiment", variant)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	DescribeTable("selects index variants",
+		func(variant string, expectedName string, expectedStatus int, expectedBody string) {
+			var decision Decision
+			h := NewSPAHandler(mfs, "index.html",
+				WithIndexSelector(selector),
+				WithOnIndex(func(_ *http.Request, d Decision) { decision = d }))
+			w := serve(h, http.MethodGet, variant)
+			Expect(w.Code).To(Equal(expectedStatus))
+			if expectedStatus == http.StatusOK {
+				Expect(w.Body.String()).To(Equal(expectedBody))
+			}
+			Expect(decision.Name).To(Equal(expectedName))
+		},
+		Entry("default", "", "index.html", http.StatusOK, `<base href="/app/" />A`),
+		Entry("root", "/", "index.html", http.StatusOK, `<base href="/app/" />A`),
+		Entry("variant", "variants/index.html", "variants/index.html", http.StatusOK, `<base href="/app/" />B`),
+		Entry("sanitized variant", "/../variants/index.html", "variants/index.html", http.StatusOK, `<base href="/app/" />B`),
+		Entry("missing variant", "missing.html", "missing.html", http.StatusNotFound, ""),
+	)
+
+	It("keeps the variant metadata apart", func() {
+		h := NewSPAHandler(mfs, "index.html", WithIndexSelector(selector))
+		a := serve(h, http.MethodGet, "")
+		b := serve(h, http.MethodGet, "variants/index.html")
+		Expect(a.Header().Get("ETag")).NotTo(Equal(b.Header().Get("ETag")))
+		Expect(serve(h, http.MethodHead, "").Header().Get("ETag")).To(Equal(a.Header().Get("ETag")))
+		Expect(serve(h, http.MethodHead, "variants/index.html").Header().Get("ETag")).To(Equal(b.Header().Get("ETag")))
+	})
+
+})
@@ -0,0 +1,122 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// DefaultVersionPath is the default path of the version endpoint.
+const DefaultVersionPath = "/version.json"
+
+// BuildInfo returns the version information of the running binary from its
+// embedded build information, with the following fields, where available:
+//
+//   - "module": the main module path.
+//   - "version": the main module version, such as "v1.2.3" or "(devel)".
+//   - "commit": the VCS revision the binary was built from.
+//   - "commitTime": the time of the VCS revision, in RFC 3339 format.
+//   - "modified": "true" if the working tree had local modifications.
+//   - "goVersion": the Go version the binary was built with.
+func BuildInfo() map[string]string {
+	info := map[string]string{}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info["module"] = bi.Main.Path
+	info["version"] = bi.Main.Version
+	info["goVersion"] = bi.GoVersion
+	for _, setting := range bi.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info["commit"] = setting.Value
+		case "vcs.time":
+			info["commitTime"] = setting.Value
+		case "vcs.modified":
+			info["modified"] = setting.Value
+		}
+	}
+	for key, value := range info {
+		if value == "" {
+			delete(info, key)
+		}
+	}
+	return info
+}
+
+// versionInfo returns the build information merged with the specified
+// fields, where the specified fields take precedence.
+func versionInfo(fields map[string]string) map[string]string {
+	info := BuildInfo()
+	for key, value := range fields {
+		info[key] = value
+	}
+	return info
+}
+
+// WithVersionEndpoint serves the version information from [BuildInfo] merged
+// with the specified fields as a JSON object on the specified path, usually
+// [DefaultVersionPath]. The specified fields take precedence, so they can
+// override the build information, such as with a version set by the CI
+// pipeline:
+//
+//	h := NewSPAHandler(bundle, "index.html",
+//	    WithVersionEndpoint(DefaultVersionPath, map[string]string{
+//	        "version": version, // set using -ldflags "-X main.version=..."
+//	        "frontend": "2.4.1",
+//	    }))
+//
+// The version endpoint is served as a virtual file, see WithVirtualFile, with
+// the commit time as its modification time, if known.
+func WithVersionEndpoint(path string, fields map[string]string) SPAHandlerOption {
+	info := versionInfo(fields)
+	contents, _ := json.Marshal(info) // ...a string map always marshals.
+	modTime, _ := time.Parse(time.RFC3339, info["commitTime"])
+	return WithVirtualFile(path, func(*http.Request) ([]byte, time.Time, error) {
+		return contents, modTime, nil
+	})
+}
+
+// WithVersionMeta injects the version and commit from [BuildInfo] merged with
+// the specified fields as meta tags named “version” and “commit” into the
+// index, right before the end of the head element, so that UI screenshots can
+// be correlated with backend builds. Meta tags already present in the index
+// with the same names get replaced. Index files without a head end tag are
+// served without any version meta tags injected.
+func WithVersionMeta(fields map[string]string) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		info := versionInfo(fields)
+		names := map[string]string{}
+		for _, key := range []string{"version", "commit"} {
+			if value, ok := info[key]; ok {
+				names[key] = value
+			}
+		}
+		h.versionMeta = &MetaTags{Names: names}
+	}
+}
+
+// injectVersionMeta returns the specified index contents with the version
+// meta tags injected, if enabled.
+func (h *SPAHandler) injectVersionMeta(index string) string {
+	if h.versionMeta == nil {
+		return index
+	}
+	return string(injectMeta([]byte(index), h.versionMeta))
+}
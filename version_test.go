@@ -0,0 +1,78 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("version information", func() {
+
+	spafs := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(
+			`<html><head><base href="./" /><meta name="commit" content="old"></head>INDEX</html>`)},
+	}
+
+	get := func(h *SPAHandler, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.org"+path, nil))
+		return w
+	}
+
+	It("returns build information", func() {
+		info := BuildInfo()
+		Expect(info).To(HaveKey("goVersion"))
+		Expect(info).NotTo(ContainElement(""))
+	})
+
+	It("serves the version endpoint", func() {
+		h := NewSPAHandler(spafs, "index.html",
+			WithVersionEndpoint(DefaultVersionPath, map[string]string{
+				"version":  "v1.2.3",
+				"frontend": "2.4.1",
+			}))
+		w := get(h, "/version.json")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("Content-Type")).To(HavePrefix("application/json"))
+		var info map[string]string
+		Expect(json.Unmarshal(w.Body.Bytes(), &info)).To(Succeed())
+		Expect(info).To(HaveKeyWithValue("version", "v1.2.3"))
+		Expect(info).To(HaveKeyWithValue("frontend", "2.4.1"))
+		Expect(info).To(HaveKey("goVersion"))
+	})
+
+	It("injects version meta tags", func() {
+		h := NewSPAHandler(spafs, "index.html",
+			WithVersionMeta(map[string]string{
+				"version": "v1.2.3",
+				"commit":  "c0ffee<>",
+			}))
+		Expect(get(h, "/some/route").Body.String()).To(Equal(
+			`<html><head><base href="/" /><meta name="commit" content="c0ffee&lt;&gt;">` +
+				`<meta name="version" content="v1.2.3"></head>INDEX</html>`))
+	})
+
+	It("doesn't inject version meta tags by default", func() {
+		h := NewSPAHandler(spafs, "index.html")
+		Expect(get(h, "/").Body.String()).To(ContainSubstring(`content="old"`))
+	})
+
+})
@@ -0,0 +1,80 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"net/http"
+	"path"
+	"time"
+)
+
+// VirtualFileGenerator returns the contents and modification time of a
+// dynamically generated file, such as an “env.js” or “runtime-config.json”,
+// for the specified request.
+type VirtualFileGenerator func(r *http.Request) ([]byte, time.Time, error)
+
+// WithVirtualFile serves the contents returned by the specified generator on
+// the specified path, alongside the static files of the SPA and taking
+// precedence over any static file of the same path. For instance:
+//
+//	h := NewSPAHandler(bundle, "index.html",
+//	    WithVirtualFile("env.js", func(*http.Request) ([]byte, time.Time, error) {
+//	        return []byte("window.env = {api: '/api/v2'};"), started, nil
+//	    }))
+//
+// Virtual files are served with a Content-Type based on their file extension
+// (see also WithMIMETypes), a strong ETag computed from their contents, and
+// “Cache-Control: no-cache”, so clients always revalidate them. Conditional
+// and range requests are handled automatically. If the generator returns an
+// error, it gets normalized into an HTTP status code instead.
+func WithVirtualFile(path string, gen VirtualFileGenerator) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		if h.virtualFiles == nil {
+			h.virtualFiles = map[string]VirtualFileGenerator{}
+		}
+		h.virtualFiles[cleanVirtualPath(path)] = gen
+	}
+}
+
+// cleanVirtualPath returns the specified virtual file path rooted and cleaned,
+// so it can be directly compared to sanitized request paths.
+func cleanVirtualPath(p string) string {
+	return path.Clean("/" + p)
+}
+
+// serveVirtualFile serves the virtual file matching the request path, if any,
+// returning true. Otherwise, nothing is served and false is returned.
+//
+// IMPORTANT: the passed r.URL.Path must have already been sanitized.
+func (h *SPAHandler) serveVirtualFile(w http.ResponseWriter, r *http.Request) bool {
+	gen, ok := h.virtualFiles[r.URL.Path]
+	if !ok {
+		return false
+	}
+	contents, modTime, err := gen(r)
+	if err != nil {
+		h.serveError(w, r, err)
+		return true
+	}
+	h.callHooks(h.onStatic, r, r.URL.Path[1:], false)
+	header := w.Header()
+	header.Set("Cache-Control", "no-cache")
+	header.Set("ETag", strongETag(sha256.Sum256(contents)))
+	h.setContentType(w, r.URL.Path)
+	http.ServeContent(w, r, path.Base(r.URL.Path), modTime, bytes.NewReader(contents))
+	return true
+}
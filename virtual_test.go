@@ -0,0 +1,90 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("virtual files", func() {
+
+	spafs := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<base href="./" />INDEX`)},
+		"env.js":     &fstest.MapFile{Data: []byte(`static();`)},
+	}
+	modTime := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	newHandler := func() *SPAHandler {
+		return NewSPAHandler(spafs, "index.html",
+			WithVirtualFile("env.js", func(r *http.Request) ([]byte, time.Time, error) {
+				return []byte(`window.env = {host: "` + r.Host + `"};`), modTime, nil
+			}),
+			WithVirtualFile("/config/../runtime-config.json", func(*http.Request) ([]byte, time.Time, error) {
+				return []byte(`{"answer":42}`), modTime, nil
+			}),
+			WithVirtualFile("broken.json", func(*http.Request) ([]byte, time.Time, error) {
+				return nil, time.Time{}, errors.New("D'oh!")
+			}),
+			WithVirtualFile("missing.json", func(*http.Request) ([]byte, time.Time, error) {
+				return nil, time.Time{}, fs.ErrNotExist
+			}))
+	}
+
+	DescribeTable("serves generated contents",
+		func(path string, expectedStatus int, expectedType string, expectedBody string) {
+			w := httptest.NewRecorder()
+			newHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.org"+path, nil))
+			Expect(w.Code).To(Equal(expectedStatus))
+			if expectedType != "" {
+				Expect(w.Header().Get("Content-Type")).To(HavePrefix(expectedType))
+				Expect(w.Header().Get("Cache-Control")).To(Equal("no-cache"))
+				Expect(w.Header().Get("ETag")).NotTo(BeEmpty())
+				Expect(w.Header().Get("Last-Modified")).To(Equal(modTime.Format(http.TimeFormat)))
+			}
+			Expect(w.Body.String()).To(Equal(expectedBody))
+		},
+		Entry("shadowing a static file", "/env.js", http.StatusOK, "text/javascript",
+			`window.env = {host: "example.org"};`),
+		Entry("cleaned path", "/runtime-config.json", http.StatusOK, "application/json",
+			`{"answer":42}`),
+		Entry("failing generator", "/broken.json", http.StatusInternalServerError, "",
+			"500 Internal Server Error\n"),
+		Entry("missing", "/missing.json", http.StatusNotFound, "", "404 page not found\n"),
+		Entry("index", "/foo", http.StatusOK, "", `<base href="/" />INDEX`),
+	)
+
+	It("answers conditional requests", func() {
+		h := newHandler()
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/runtime-config.json", nil))
+		etag := w.Header().Get("ETag")
+
+		r := httptest.NewRequest(http.MethodGet, "/runtime-config.json", nil)
+		r.Header.Set("If-None-Match", etag)
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		Expect(w.Code).To(Equal(http.StatusNotModified))
+		Expect(w.Body.Len()).To(BeZero())
+	})
+
+})
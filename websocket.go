@@ -0,0 +1,67 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrUpgradeRequired signals a WebSocket upgrade request that cannot be served
+// by an SPAHandler, and gets mapped to the HTTP status code 426 (Upgrade
+// Required).
+var ErrUpgradeRequired = errors.New("websocket upgrade not supported")
+
+// WithWebSocketHandler passes WebSocket upgrade requests not matching any
+// static asset on to the specified handler, such as a WebSocket endpoint of
+// the SPA's backend. Without a WebSocket handler, such requests get rejected
+// with 426 (Upgrade Required) instead of receiving the index document, which
+// would only confuse clients expecting a WebSocket handshake.
+func WithWebSocketHandler(handler http.Handler) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		h.webSocketHandler = handler
+	}
+}
+
+// isWebSocketUpgrade returns true if the specified request asks for an upgrade
+// to the WebSocket protocol.
+func isWebSocketUpgrade(r *http.Request) bool {
+	for _, upgrade := range r.Header.Values("Upgrade") {
+		for _, protocol := range strings.Split(upgrade, ",") {
+			protocol, _, _ = strings.Cut(strings.TrimSpace(protocol), "/")
+			if strings.EqualFold(protocol, "websocket") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// serveWebSocket passes a WebSocket upgrade request on to the WebSocket
+// handler, or otherwise rejects it, returning the outcome and true. If the
+// request isn't a WebSocket upgrade request, it returns false without having
+// served anything.
+func (h *SPAHandler) serveWebSocket(w http.ResponseWriter, r *http.Request) (Outcome, bool) {
+	if !isWebSocketUpgrade(r) {
+		return 0, false
+	}
+	if h.webSocketHandler == nil {
+		h.writeError(w, r, ErrUpgradeRequired)
+		return OutcomeRejected, true
+	}
+	h.webSocketHandler.ServeHTTP(w, r)
+	return OutcomePassedOn, true
+}
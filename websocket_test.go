@@ -0,0 +1,101 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("WebSocket upgrades", func() {
+
+	spafs := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<base href="./" />INDEX`)},
+		"app.js":     &fstest.MapFile{Data: []byte(`app();`)},
+	}
+
+	upgrade := func(h *SPAHandler, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://example.org"+path, nil)
+		r.Header.Set("Connection", "Upgrade")
+		r.Header.Set("Upgrade", "websocket")
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	DescribeTable("detecting WebSocket upgrades",
+		func(upgrades []string, expected bool) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for _, upgrade := range upgrades {
+				r.Header.Add("Upgrade", upgrade)
+			}
+			Expect(isWebSocketUpgrade(r)).To(Equal(expected))
+		},
+		Entry(nil, nil, false),
+		Entry(nil, []string{"h2c"}, false),
+		Entry(nil, []string{"WebSocket"}, true),
+		Entry(nil, []string{"h2c, websocket/13"}, true),
+		Entry(nil, []string{"h2c", "websocket"}, true),
+	)
+
+	It("rejects WebSocket upgrades instead of serving the index", func() {
+		h := NewSPAHandler(spafs, "index.html")
+		Expect(upgrade(h, "/socket").Code).To(Equal(http.StatusUpgradeRequired))
+		Expect(upgrade(h, "/app.js").Body.String()).To(Equal("app();"))
+	})
+
+	It("passes WebSocket upgrades on", func() {
+		h := NewSPAHandler(spafs, "index.html",
+			WithWebSocketHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("WS " + r.URL.Path))
+			})))
+		Expect(upgrade(h, "/socket").Body.String()).To(Equal("WS /socket"))
+	})
+
+	It("supports hijacking while tracking", func() {
+		var status atomic.Int32
+		h := NewSPAHandler(spafs, "index.html",
+			WithWebSocketHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				conn, rw := Successful2R(http.NewResponseController(w).Hijack())
+				defer conn.Close()
+				_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+					"Upgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+				_ = rw.Flush()
+				status.Store(int32(w.(*trackingResponseWriter).Status()))
+			})),
+			WithAccessLog(slog.New(slog.NewTextHandler(io.Discard, nil))))
+		srv := httptest.NewServer(h)
+		DeferCleanup(srv.Close)
+
+		conn := Successful(net.Dial("tcp", srv.Listener.Addr().String()))
+		DeferCleanup(conn.Close)
+		_, _ = conn.Write([]byte("GET /socket HTTP/1.1\r\nHost: example.org\r\n" +
+			"Connection: Upgrade\r\nUpgrade: websocket\r\n\r\n"))
+		resp := Successful(http.ReadResponse(bufio.NewReader(conn), nil))
+		Expect(resp.StatusCode).To(Equal(http.StatusSwitchingProtocols))
+		Eventually(status.Load).Should(Equal(int32(http.StatusSwitchingProtocols)))
+	})
+
+})
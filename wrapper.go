@@ -0,0 +1,69 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"net/http"
+)
+
+// ResponseWrapper wraps the http.ResponseWriter used for serving a static asset
+// in response to the specified request, such as for byte accounting or
+// computing digests on the fly. The returned http.ResponseWriter must pass on
+// the response header, status code, and body to the wrapped w.
+//
+// The wrapped response body gets written in chunks as the asset is streamed
+// from its fs.FS, so ResponseWrapper implementations should process the
+// response body in a streaming fashion, too, instead of buffering it. If a
+// wrapping response writer needs to finish its work after the complete
+// response body has been written, it additionally should implement io.Closer;
+// its Close method then gets called after serving the asset.
+type ResponseWrapper func(w http.ResponseWriter, r *http.Request) http.ResponseWriter
+
+// WithAssetResponseWrapper adds a final-stage ResponseWrapper that wraps the
+// response writer for static asset responses. When specified multiple times,
+// the first wrapper specified wraps the original response writer and each
+// further wrapper wraps the response writer returned by its predecessor. The
+// asset thus gets written into the response writer of the last wrapper
+// specified, which passes it on to the response writer of the wrapper
+// specified before it, and so on.
+func WithAssetResponseWrapper(wrapper ResponseWrapper) SPAHandlerOption {
+	return func(h *SPAHandler) {
+		if wrapper == nil {
+			return
+		}
+		h.assetWrappers = append(h.assetWrappers, wrapper)
+	}
+}
+
+// wrapAssetHandler returns the specified asset handler with the asset response
+// wrappers applied, if any. Otherwise, it returns the unchanged handler.
+func (h *SPAHandler) wrapAssetHandler(handler http.Handler) http.Handler {
+	if len(h.assetWrappers) == 0 {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, wrapper := range h.assetWrappers {
+			ww := wrapper(w, r)
+			if ww == nil {
+				continue
+			}
+			if closer, ok := ww.(interface{ Close() error }); ok {
+				defer func() { _ = closer.Close() }()
+			}
+			w = ww
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
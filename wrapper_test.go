@@ -0,0 +1,105 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+type digestingWriter struct {
+	http.ResponseWriter
+	hash   hash.Hash
+	digest *string
+}
+
+func (w *digestingWriter) Write(b []byte) (int, error) {
+	w.hash.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *digestingWriter) Close() error {
+	*w.digest = hex.EncodeToString(w.hash.Sum(nil))
+	return nil
+}
+
+type namedWriter struct {
+	http.ResponseWriter
+	name   string
+	writes *[]string
+}
+
+func (w *namedWriter) Write(b []byte) (int, error) {
+	*w.writes = append(*w.writes, w.name)
+	return w.ResponseWriter.Write(b)
+}
+
+var _ = Describe("asset response wrappers", func() {
+
+	It("wraps only asset responses", func() {
+		var digest string
+		var wrapped []string
+		h := NewSPAHandler(embStaticFs, "index.html",
+			WithAssetResponseWrapper(func(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+				wrapped = append(wrapped, r.URL.Path)
+				return &digestingWriter{ResponseWriter: w, hash: sha256.New(), digest: &digest}
+			}),
+			WithAssetResponseWrapper(func(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+				return nil
+			}))
+
+		for _, path := range []string{"/", "/static/js/some.js"} {
+			r := &http.Request{
+				Method: "GET",
+				URL:    Successful(url.Parse("http://foo.bar:12345" + path)),
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+		}
+		Expect(wrapped).To(ConsistOf("/static/js/some.js"))
+		sum := sha256.Sum256(Successful(fs.ReadFile(embStaticFs, "static/js/some.js")))
+		Expect(digest).To(Equal(hex.EncodeToString(sum[:])))
+	})
+
+	It("wraps later wrappers around earlier ones", func() {
+		var writes []string
+		var wrappedByB http.ResponseWriter
+		h := NewSPAHandler(embStaticFs, "index.html",
+			WithAssetResponseWrapper(func(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+				return &namedWriter{ResponseWriter: w, name: "A", writes: &writes}
+			}),
+			WithAssetResponseWrapper(func(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+				wrappedByB = w
+				return &namedWriter{ResponseWriter: w, name: "B", writes: &writes}
+			}))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/static/js/some.js", nil))
+		Expect(w.Result().StatusCode).To(Equal(http.StatusOK))
+		Expect(wrappedByB).To(BeAssignableToTypeOf(&namedWriter{}))
+		Expect(wrappedByB.(*namedWriter).name).To(Equal("A"))
+		Expect(writes).To(Equal([]string{"B", "A"}))
+	})
+
+})
@@ -0,0 +1,77 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spaserve
+
+import (
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// fileServer serves static files from an fs.FS. If the fs.FS is backed by a
+// real directory, such as an os.DirFS, then the files get served directly as
+// os.Files, bypassing the http.FS indirection. This allows the Go runtime to
+// use sendfile(2) on Linux so large assets get served zero-copy.
+type fileServer struct {
+	fsys     fs.FS
+	osFiles  bool         // fsys opens *os.Files.
+	fallback http.Handler // handles all the other cases.
+}
+
+// newFileServer returns a handler serving static files from the specified
+// fs.FS.
+func newFileServer(fsys fs.FS) http.Handler {
+	h := &fileServer{
+		fsys:     fsys,
+		fallback: http.FileServer(http.FS(fsys)),
+	}
+	// Probe the fs.FS once for whether it is backed by a real directory.
+	if fsys == nil {
+		return h
+	}
+	if f, err := fsys.Open("."); err == nil {
+		_, h.osFiles = f.(*os.File)
+		_ = f.Close()
+	}
+	return h
+}
+
+// ServeHTTP serves the requested file, directly as an os.File if possible.
+// Please note that http.FileServer redirects requests for “.../index.html” to
+// “.../”, so we leave these to it.
+func (h *fileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.osFiles || !strings.HasPrefix(r.URL.Path, "/") || strings.HasSuffix(r.URL.Path, "/index.html") {
+		h.fallback.ServeHTTP(w, r)
+		return
+	}
+	f, err := h.fsys.Open(r.URL.Path[1:])
+	if err != nil {
+		h.fallback.ServeHTTP(w, r)
+		return
+	}
+	defer func() { _ = f.Close() }()
+	osf, ok := f.(*os.File)
+	if !ok {
+		h.fallback.ServeHTTP(w, r)
+		return
+	}
+	info, err := osf.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		h.fallback.ServeHTTP(w, r)
+		return
+	}
+	http.ServeContent(w, r, info.Name(), info.ModTime(), osf)
+}
@@ -0,0 +1,124 @@
+// Copyright 2023 Harald Albrecht.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may not
+// use this file except in compliance with the License. You may obtain a copy
+// of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spaserve
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/thediveo/success"
+)
+
+var _ = Describe("zero-copy file serving", func() {
+
+	var dir string
+	var blob []byte
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		blob = bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+		Expect(os.WriteFile(filepath.Join(dir, "index.html"),
+			[]byte(`<html><head><base href="./" /></head></html>`), 0644)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(dir, "media"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "media", "blob.wasm"), blob, 0644)).To(Succeed())
+	})
+
+	It("detects directory-backed file systems", func() {
+		Expect(newFileServer(os.DirFS(dir)).(*fileServer).osFiles).To(BeTrue())
+		Expect(newFileServer(embStaticFs).(*fileServer).osFiles).To(BeFalse())
+		Expect(newFileServer(osFileHidingFS{os.DirFS(dir)}).(*fileServer).osFiles).To(BeFalse())
+	})
+
+	It("serves large assets from a directory", func() {
+		srv := httptest.NewServer(NewSPAHandler(os.DirFS(dir), "index.html"))
+		defer srv.Close()
+
+		resp := Successful(http.Get(srv.URL + "/media/blob.wasm"))
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("application/wasm"))
+		Expect(Successful(io.ReadAll(resp.Body))).To(Equal(blob))
+	})
+
+	It("serves ranges and leaves index.html redirects and directories to http.FileServer", func() {
+		h := NewSPAHandler(os.DirFS(dir), "index.html")
+
+		r := httptest.NewRequest(http.MethodGet, "/media/blob.wasm", nil)
+		r.Header.Set("Range", "bytes=16-31")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		Expect(w.Code).To(Equal(http.StatusPartialContent))
+		Expect(w.Body.String()).To(Equal("0123456789abcdef"))
+
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/index.html", nil))
+		Expect(w.Code).To(Equal(http.StatusMovedPermanently))
+
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/media", nil))
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(ContainSubstring(`<base href="/" />`))
+	})
+
+})
+
+// osFileHidingFS hides the *os.Files opened from a directory-backed fs.FS.
+type osFileHidingFS struct{ fs.FS }
+
+func (f osFileHidingFS) Open(name string) (fs.File, error) {
+	file, err := f.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return struct{ *os.File }{file.(*os.File)}, nil
+}
+
+func BenchmarkServeLargeAsset(b *testing.B) {
+	dir := b.TempDir()
+	blob := bytes.Repeat([]byte{42}, 8*1024*1024)
+	if err := os.WriteFile(filepath.Join(dir, "blob.wasm"), blob, 0644); err != nil {
+		b.Fatal(err)
+	}
+	for _, bm := range []struct {
+		name string
+		h    http.Handler
+	}{
+		{"DirFS", NewSPAHandler(os.DirFS(dir), "index.html")},
+		{"http.FS", NewSPAHandler(osFileHidingFS{os.DirFS(dir)}, "index.html")},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			srv := httptest.NewServer(bm.h)
+			defer srv.Close()
+			b.SetBytes(int64(len(blob)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				resp, err := http.Get(srv.URL + "/blob.wasm")
+				if err != nil {
+					b.Fatal(err)
+				}
+				_, _ = io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}
+		})
+	}
+}